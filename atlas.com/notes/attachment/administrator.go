@@ -0,0 +1,30 @@
+package attachment
+
+import (
+	"atlas-notes/database"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// transitionStatus moves an unclaimed attachment to the given status. The update is conditional on the attachment
+// still being unclaimed, so concurrent claims cannot award the same attachment twice.
+func transitionStatus(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) func(status string) error {
+	return func(tenantId uuid.UUID) func(id uint32) func(status string) error {
+		return func(id uint32) func(status string) error {
+			return func(status string) error {
+				return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+					result := tx.Model(&Entity{}).
+						Where("tenant_id = ? AND id = ? AND status = ?", tenantId, id, StatusUnclaimed).
+						Update("status", status)
+					if result.Error != nil {
+						return result.Error
+					}
+					if result.RowsAffected == 0 {
+						return ErrInvalidTransition
+					}
+					return nil
+				})
+			}
+		}
+	}
+}
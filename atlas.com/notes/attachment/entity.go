@@ -0,0 +1,52 @@
+package attachment
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Entity represents a note attachment in the database
+type Entity struct {
+	ID        uint32 `gorm:"primaryKey;autoIncrement"`
+	TenantID  uuid.UUID
+	NoteID    uint32
+	Type      string
+	ItemId    uint32
+	Quantity  uint32
+	Mesos     uint32
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName specifies the database table name for Entity
+func (Entity) TableName() string {
+	return "note_attachments"
+}
+
+// Make converts an Entity to a Model domain model
+func Make(e Entity) (Model, error) {
+	return NewBuilder().
+		SetId(e.ID).
+		SetNoteId(e.NoteID).
+		SetType(e.Type).
+		SetItemId(e.ItemId).
+		SetQuantity(e.Quantity).
+		SetMesos(e.Mesos).
+		SetStatus(e.Status).
+		Build(), nil
+}
+
+// MakeEntity converts a Model domain model to an Entity
+func MakeEntity(tenantId uuid.UUID, m Model) Entity {
+	return Entity{
+		ID:       m.Id(),
+		TenantID: tenantId,
+		NoteID:   m.NoteId(),
+		Type:     m.Type(),
+		ItemId:   m.ItemId(),
+		Quantity: m.Quantity(),
+		Mesos:    m.Mesos(),
+		Status:   m.Status(),
+	}
+}
@@ -0,0 +1,42 @@
+package mock
+
+import (
+	"atlas-notes/attachment"
+	"atlas-notes/kafka/message"
+	"github.com/Chronicle20/atlas-model/model"
+)
+
+type ProcessorMock struct {
+	ByNoteIdProviderFunc func(noteId uint32) model.Provider[[]attachment.Model]
+	ClaimFunc            func(mb *message.Buffer) func(characterId uint32) func(a attachment.Model) error
+	ReturnFunc           func(mb *message.Buffer) func(senderId uint32) func(a attachment.Model) error
+}
+
+func (m *ProcessorMock) ByNoteIdProvider(noteId uint32) model.Provider[[]attachment.Model] {
+	if m.ByNoteIdProviderFunc != nil {
+		return m.ByNoteIdProviderFunc(noteId)
+	}
+	return model.FixedProvider([]attachment.Model{})
+}
+
+func (m *ProcessorMock) Claim(mb *message.Buffer) func(characterId uint32) func(a attachment.Model) error {
+	if m.ClaimFunc != nil {
+		return m.ClaimFunc(mb)
+	}
+	return func(uint32) func(attachment.Model) error {
+		return func(attachment.Model) error {
+			return nil
+		}
+	}
+}
+
+func (m *ProcessorMock) Return(mb *message.Buffer) func(senderId uint32) func(a attachment.Model) error {
+	if m.ReturnFunc != nil {
+		return m.ReturnFunc(mb)
+	}
+	return func(uint32) func(attachment.Model) error {
+		return func(attachment.Model) error {
+			return nil
+		}
+	}
+}
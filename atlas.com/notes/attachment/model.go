@@ -0,0 +1,163 @@
+package attachment
+
+import (
+	"errors"
+)
+
+const (
+	TypeItem = "ITEM"
+	TypeMeso = "MESO"
+
+	StatusUnclaimed = "UNCLAIMED"
+	StatusClaimed   = "CLAIMED"
+	StatusReturned  = "RETURNED"
+)
+
+var (
+	ErrInvalidAttachment = errors.New("invalid attachment")
+	ErrInvalidTransition = errors.New("invalid attachment status transition")
+)
+
+// Model represents an item or meso amount carried by a note
+type Model struct {
+	id       uint32
+	noteId   uint32
+	kind     string
+	itemId   uint32
+	quantity uint32
+	mesos    uint32
+	status   string
+}
+
+// Id returns the attachment's ID
+func (m Model) Id() uint32 {
+	return m.id
+}
+
+// NoteId returns the ID of the note carrying the attachment
+func (m Model) NoteId() uint32 {
+	return m.noteId
+}
+
+// Type returns the kind of attachment (item or meso)
+func (m Model) Type() string {
+	return m.kind
+}
+
+// ItemId returns the template ID of the attached item
+func (m Model) ItemId() uint32 {
+	return m.itemId
+}
+
+// Quantity returns the quantity of the attached item
+func (m Model) Quantity() uint32 {
+	return m.quantity
+}
+
+// Mesos returns the attached meso amount
+func (m Model) Mesos() uint32 {
+	return m.mesos
+}
+
+// Status returns the claim status of the attachment
+func (m Model) Status() string {
+	return m.status
+}
+
+// Unclaimed returns true if the attachment has not been claimed or returned
+func (m Model) Unclaimed() bool {
+	return m.status == StatusUnclaimed
+}
+
+// Valid returns true if the attachment carries a positive item quantity or meso amount
+func (m Model) Valid() bool {
+	switch m.kind {
+	case TypeItem:
+		return m.itemId != 0 && m.quantity > 0
+	case TypeMeso:
+		return m.mesos > 0
+	}
+	return false
+}
+
+// CanTransition returns true if the attachment may move to the given status.
+// Attachments start unclaimed and may only be claimed or returned once.
+func (m Model) CanTransition(status string) bool {
+	if m.status != StatusUnclaimed {
+		return false
+	}
+	return status == StatusClaimed || status == StatusReturned
+}
+
+// Builder is a builder for creating Model instances
+type Builder struct {
+	id       uint32
+	noteId   uint32
+	kind     string
+	itemId   uint32
+	quantity uint32
+	mesos    uint32
+	status   string
+}
+
+// NewBuilder creates a new Builder
+func NewBuilder() *Builder {
+	return &Builder{
+		status: StatusUnclaimed,
+	}
+}
+
+// SetId sets the attachment's ID
+func (b *Builder) SetId(id uint32) *Builder {
+	b.id = id
+	return b
+}
+
+// SetNoteId sets the ID of the note carrying the attachment
+func (b *Builder) SetNoteId(noteId uint32) *Builder {
+	b.noteId = noteId
+	return b
+}
+
+// SetType sets the kind of attachment
+func (b *Builder) SetType(kind string) *Builder {
+	b.kind = kind
+	return b
+}
+
+// SetItemId sets the template ID of the attached item
+func (b *Builder) SetItemId(itemId uint32) *Builder {
+	b.itemId = itemId
+	return b
+}
+
+// SetQuantity sets the quantity of the attached item
+func (b *Builder) SetQuantity(quantity uint32) *Builder {
+	b.quantity = quantity
+	return b
+}
+
+// SetMesos sets the attached meso amount
+func (b *Builder) SetMesos(mesos uint32) *Builder {
+	b.mesos = mesos
+	return b
+}
+
+// SetStatus sets the claim status of the attachment
+func (b *Builder) SetStatus(status string) *Builder {
+	b.status = status
+	return b
+}
+
+// Build creates a new Model with the builder's values
+func (b *Builder) Build() Model {
+	return Model{
+		id:       b.id,
+		noteId:   b.noteId,
+		kind:     b.kind,
+		itemId:   b.itemId,
+		quantity: b.quantity,
+		mesos:    b.mesos,
+		status:   b.status,
+	}
+}
@@ -0,0 +1,88 @@
+package attachment
+
+import (
+	"atlas-notes/kafka/message"
+	"atlas-notes/kafka/message/character"
+	"atlas-notes/kafka/message/compartment"
+	"context"
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Processor interface {
+	ByNoteIdProvider(noteId uint32) model.Provider[[]Model]
+	Claim(mb *message.Buffer) func(characterId uint32) func(a Model) error
+	Return(mb *message.Buffer) func(senderId uint32) func(a Model) error
+}
+
+type ProcessorImpl struct {
+	l   logrus.FieldLogger
+	ctx context.Context
+	r   Repository
+	t   tenant.Model
+}
+
+func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+	return NewRepositoryProcessor(l, ctx, NewGormRepository(db))
+}
+
+// NewRepositoryProcessor creates a Processor which stores attachments in the given Repository
+func NewRepositoryProcessor(l logrus.FieldLogger, ctx context.Context, r Repository) Processor {
+	return &ProcessorImpl{
+		l:   l,
+		ctx: ctx,
+		r:   r,
+		t:   tenant.MustFromContext(ctx),
+	}
+}
+
+// ByNoteIdProvider retrieves all attachments carried by a note
+func (p *ProcessorImpl) ByNoteIdProvider(noteId uint32) model.Provider[[]Model] {
+	return func() ([]Model, error) {
+		return p.r.ByNoteId(p.t.Id(), noteId)
+	}
+}
+
+// Claim marks an attachment claimed and awards its contents to the claiming character
+func (p *ProcessorImpl) Claim(mb *message.Buffer) func(characterId uint32) func(a Model) error {
+	return func(characterId uint32) func(a Model) error {
+		return func(a Model) error {
+			return p.transitionAndAward(mb)(StatusClaimed)(characterId)(a)
+		}
+	}
+}
+
+// Return marks an attachment returned and awards its contents back to the sender
+func (p *ProcessorImpl) Return(mb *message.Buffer) func(senderId uint32) func(a Model) error {
+	return func(senderId uint32) func(a Model) error {
+		return func(a Model) error {
+			return p.transitionAndAward(mb)(StatusReturned)(senderId)(a)
+		}
+	}
+}
+
+func (p *ProcessorImpl) transitionAndAward(mb *message.Buffer) func(status string) func(characterId uint32) func(a Model) error {
+	return func(status string) func(characterId uint32) func(a Model) error {
+		return func(characterId uint32) func(a Model) error {
+			return func(a Model) error {
+				if !a.CanTransition(status) {
+					return ErrInvalidTransition
+				}
+				err := p.r.Transition(p.t.Id(), a.Id(), status)
+				if err != nil {
+					return err
+				}
+				if characterId == 0 {
+					p.l.Warnf("Attachment [%d] of note [%d] moved to [%s] with no character to award.", a.Id(), a.NoteId(), status)
+					return nil
+				}
+				if a.Type() == TypeMeso {
+					return mb.Put(character.EnvCommandTopic, AwardMesoCommandProvider(characterId, a.NoteId(), a.Mesos()))
+				}
+				return mb.Put(compartment.EnvCommandTopic, AwardItemCommandProvider(characterId, a.ItemId(), a.Quantity()))
+			}
+		}
+	}
+}
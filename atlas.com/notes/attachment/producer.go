@@ -0,0 +1,39 @@
+package attachment
+
+import (
+	"atlas-notes/kafka/message/character"
+	"atlas-notes/kafka/message/compartment"
+	"github.com/Chronicle20/atlas-kafka/producer"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/segmentio/kafka-go"
+)
+
+// AwardItemCommandProvider creates a command awarding an attached item to a character
+func AwardItemCommandProvider(characterId uint32, itemId uint32, quantity uint32) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := compartment.Command[compartment.CreateAssetCommandBody]{
+		CharacterId:   characterId,
+		InventoryType: byte(itemId / 1000000),
+		Type:          compartment.CommandCreateAsset,
+		Body: compartment.CreateAssetCommandBody{
+			TemplateId: itemId,
+			Quantity:   quantity,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+// AwardMesoCommandProvider creates a command awarding an attached meso amount to a character
+func AwardMesoCommandProvider(characterId uint32, noteId uint32, amount uint32) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := character.Command[character.RequestChangeMesoBody]{
+		CharacterId: characterId,
+		Type:        character.CommandRequestChangeMeso,
+		Body: character.RequestChangeMesoBody{
+			ActorId:   noteId,
+			ActorType: character.ActorTypeNote,
+			Amount:    int32(amount),
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
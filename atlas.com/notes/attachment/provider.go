@@ -0,0 +1,22 @@
+package attachment
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// getByNoteIdProvider returns a provider for all attachments carried by a note
+func getByNoteIdProvider(tenantId uuid.UUID) func(noteId uint32) database.EntityProvider[[]Entity] {
+	return func(noteId uint32) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Where("tenant_id = ? AND note_id = ?", tenantId, noteId).Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package attachment
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Repository stores the attachments carried by notes
+type Repository interface {
+	// ByNoteId returns all attachments carried by a note
+	ByNoteId(tenantId uuid.UUID, noteId uint32) ([]Model, error)
+	// Transition moves an unclaimed attachment to the given status, returning ErrInvalidTransition if it is no longer
+	// unclaimed
+	Transition(tenantId uuid.UUID, id uint32, status string) error
+}
+
+// GormRepository is a Repository backed by a SQL database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) ByNoteId(tenantId uuid.UUID, noteId uint32) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getByNoteIdProvider(tenantId)(noteId)(database.Reader(r.db)))(model.ParallelMap())()
+}
+
+func (r *GormRepository) Transition(tenantId uuid.UUID, id uint32, status string) error {
+	return transitionStatus(r.db)(tenantId)(id)(status)
+}
@@ -0,0 +1,33 @@
+package attachment
+
+// RestModel is the JSON representation of an attachment embedded in a note resource
+type RestModel struct {
+	Id       uint32 `json:"id"`
+	Type     string `json:"type"`
+	ItemId   uint32 `json:"itemId,omitempty"`
+	Quantity uint32 `json:"quantity,omitempty"`
+	Mesos    uint32 `json:"mesos,omitempty"`
+	Status   string `json:"status"`
+}
+
+// Transform converts a Model domain model to a RestModel
+func Transform(m Model) (RestModel, error) {
+	return RestModel{
+		Id:       m.Id(),
+		Type:     m.Type(),
+		ItemId:   m.ItemId(),
+		Quantity: m.Quantity(),
+		Mesos:    m.Mesos(),
+		Status:   m.Status(),
+	}, nil
+}
+
+// Extract converts a RestModel to a new, unclaimed Model
+func Extract(r RestModel) (Model, error) {
+	return NewBuilder().
+		SetType(r.Type).
+		SetItemId(r.ItemId).
+		SetQuantity(r.Quantity).
+		SetMesos(r.Mesos).
+		Build(), nil
+}
@@ -0,0 +1,48 @@
+package audit
+
+import "context"
+
+const (
+	ActorTypeRest   = "REST"
+	ActorTypeKafka  = "KAFKA"
+	ActorTypeSystem = "SYSTEM"
+
+	systemActorId = "atlas-notes"
+)
+
+// Actor is who made a change: a REST caller, the origin of a Kafka command, or the service itself
+type Actor struct {
+	actorType string
+	id        string
+}
+
+// NewActor creates an Actor of a type, identified by id
+func NewActor(actorType string, id string) Actor {
+	return Actor{actorType: actorType, id: id}
+}
+
+// Type returns whether the actor is a REST caller, a Kafka command or the service itself
+func (a Actor) Type() string {
+	return a.actorType
+}
+
+// Id returns who the actor is, within its type
+func (a Actor) Id() string {
+	return a.id
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the actor on whose behalf changes are made
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFromContext returns the actor a context carries. Changes made without one, such as by background tasks, are
+// made by the service itself.
+func ActorFromContext(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return a
+	}
+	return NewActor(ActorTypeSystem, systemActorId)
+}
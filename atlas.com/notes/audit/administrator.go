@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// appendEntries records entries of a tenant's audit trail. Entries are never changed once recorded.
+func appendEntries(db *gorm.DB) func(tenantId uuid.UUID) func(ms []Model) error {
+	return func(tenantId uuid.UUID) func(ms []Model) error {
+		return func(ms []Model) error {
+			if len(ms) == 0 {
+				return nil
+			}
+			entities := make([]Entity, 0, len(ms))
+			for _, m := range ms {
+				e := MakeEntity(tenantId, m)
+				e.ID = 0
+				entities = append(entities, e)
+			}
+			return db.Create(&entities).Error
+		}
+	}
+}
+
+// purgeEntries permanently removes every entry of a tenant's audit trail, returning how many were removed. The tenant is
+// listed as being purged for the duration, as the trail otherwise refuses deletion.
+func purgeEntries(db *gorm.DB) func(tenantId uuid.UUID) (int64, error) {
+	return func(tenantId uuid.UUID) (int64, error) {
+		var n int64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec("INSERT INTO note_audit_purges (tenant_id) VALUES (?)", tenantId).Error
+			if err != nil {
+				return err
+			}
+			res := tx.Where("tenant_id = ?", tenantId).Delete(&Entity{})
+			if res.Error != nil {
+				return res.Error
+			}
+			n = res.RowsAffected
+			return tx.Exec("DELETE FROM note_audit_purges WHERE tenant_id = ?", tenantId).Error
+		})
+		return n, err
+	}
+}
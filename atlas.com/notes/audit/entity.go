@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Entity represents an entry of the audit trail in the database
+type Entity struct {
+	ID            uint64 `gorm:"primaryKey;autoIncrement"`
+	TenantID      uuid.UUID
+	NoteID        uint32
+	CharacterID   uint32
+	Action        string
+	ActorType     string
+	ActorID       string
+	TransactionID uuid.UUID
+	Before        *string
+	After         *string
+	CreatedAt     time.Time `gorm:"autoCreateTime:false"`
+}
+
+// TableName specifies the database table name for Entity
+func (Entity) TableName() string {
+	return "note_audits"
+}
+
+// Make converts an Entity to a Model domain model
+func Make(e Entity) (Model, error) {
+	b := NewBuilder().
+		SetId(e.ID).
+		SetNoteId(e.NoteID).
+		SetCharacterId(e.CharacterID).
+		SetAction(e.Action).
+		SetActor(NewActor(e.ActorType, e.ActorID)).
+		SetTransactionId(e.TransactionID).
+		SetCreatedAt(e.CreatedAt)
+	if e.Before != nil {
+		b.SetBefore(json.RawMessage(*e.Before))
+	}
+	if e.After != nil {
+		b.SetAfter(json.RawMessage(*e.After))
+	}
+	return b.Build(), nil
+}
+
+// MakeEntity converts a Model domain model to an Entity of a tenant
+func MakeEntity(tenantId uuid.UUID, m Model) Entity {
+	e := Entity{
+		ID:            m.Id(),
+		TenantID:      tenantId,
+		NoteID:        m.NoteId(),
+		CharacterID:   m.CharacterId(),
+		Action:        m.Action(),
+		ActorType:     m.Actor().Type(),
+		ActorID:       m.Actor().Id(),
+		TransactionID: m.TransactionId(),
+		CreatedAt:     m.CreatedAt(),
+	}
+	if m.Before() != nil {
+		before := string(m.Before())
+		e.Before = &before
+	}
+	if m.After() != nil {
+		after := string(m.After())
+		e.After = &after
+	}
+	return e
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	ActionCreate  = "CREATE"
+	ActionUpdate  = "UPDATE"
+	ActionDelete  = "DELETE"
+	ActionDiscard = "DISCARD"
+	ActionExpire  = "EXPIRE"
+	ActionRestore = "RESTORE"
+	// ActionImport records a note imported from an export, and ActionClone one copied from another tenant
+	ActionImport = "IMPORT"
+	ActionClone  = "CLONE"
+	// ActionPurge records a note purged with its tenant. Once the purge completes, the tenant's trail is replaced by a
+	// single entry of this action, holding no note and carrying how many notes were purged in place of the note after.
+	ActionPurge = "PURGE"
+	// ActionDeny records a REST request refused for want of a valid token or role. It carries the request in place of
+	// the note after.
+	ActionDeny = "DENY"
+)
+
+// Model is an entry of the audit trail: a change made to a note, with the state of the note before and after it
+type Model struct {
+	id            uint64
+	noteId        uint32
+	characterId   uint32
+	action        string
+	actor         Actor
+	transactionId uuid.UUID
+	before        json.RawMessage
+	after         json.RawMessage
+	createdAt     time.Time
+}
+
+// Id returns the ID of the entry, which orders entries as they were recorded
+func (m Model) Id() uint64 {
+	return m.id
+}
+
+// NoteId returns the ID of the note changed
+func (m Model) NoteId() uint32 {
+	return m.noteId
+}
+
+// CharacterId returns the ID of the character holding the note changed
+func (m Model) CharacterId() uint32 {
+	return m.characterId
+}
+
+// Action returns what was done to the note
+func (m Model) Action() string {
+	return m.action
+}
+
+// Actor returns who made the change
+func (m Model) Actor() Actor {
+	return m.actor
+}
+
+// TransactionId returns the ID shared by the entries of changes made together
+func (m Model) TransactionId() uuid.UUID {
+	return m.transactionId
+}
+
+// Before returns the state of the note before the change, or nil if it was created by it
+func (m Model) Before() json.RawMessage {
+	return m.before
+}
+
+// After returns the state of the note after the change, or nil if it was deleted by it
+func (m Model) After() json.RawMessage {
+	return m.after
+}
+
+// CreatedAt returns when the change was made
+func (m Model) CreatedAt() time.Time {
+	return m.createdAt
+}
+
+// Builder is a builder for creating Model instances
+type Builder struct {
+	id            uint64
+	noteId        uint32
+	characterId   uint32
+	action        string
+	actor         Actor
+	transactionId uuid.UUID
+	before        json.RawMessage
+	after         json.RawMessage
+	createdAt     time.Time
+}
+
+// NewBuilder creates a new Builder for an entry recording a change made now
+func NewBuilder() *Builder {
+	return &Builder{createdAt: time.Now()}
+}
+
+// SetId sets the ID of the entry
+func (b *Builder) SetId(id uint64) *Builder {
+	b.id = id
+	return b
+}
+
+// SetNoteId sets the ID of the note changed
+func (b *Builder) SetNoteId(noteId uint32) *Builder {
+	b.noteId = noteId
+	return b
+}
+
+// SetCharacterId sets the ID of the character holding the note changed
+func (b *Builder) SetCharacterId(characterId uint32) *Builder {
+	b.characterId = characterId
+	return b
+}
+
+// SetAction sets what was done to the note
+func (b *Builder) SetAction(action string) *Builder {
+	b.action = action
+	return b
+}
+
+// SetActor sets who made the change
+func (b *Builder) SetActor(actor Actor) *Builder {
+	b.actor = actor
+	return b
+}
+
+// SetTransactionId sets the ID shared by the entries of changes made together
+func (b *Builder) SetTransactionId(transactionId uuid.UUID) *Builder {
+	b.transactionId = transactionId
+	return b
+}
+
+// SetBefore sets the state of the note before the change
+func (b *Builder) SetBefore(before json.RawMessage) *Builder {
+	b.before = before
+	return b
+}
+
+// SetAfter sets the state of the note after the change
+func (b *Builder) SetAfter(after json.RawMessage) *Builder {
+	b.after = after
+	return b
+}
+
+// SetCreatedAt sets when the change was made
+func (b *Builder) SetCreatedAt(createdAt time.Time) *Builder {
+	b.createdAt = createdAt
+	return b
+}
+
+// Build creates a new Model with the builder's values
+func (b *Builder) Build() Model {
+	return Model{
+		id:            b.id,
+		noteId:        b.noteId,
+		characterId:   b.characterId,
+		action:        b.action,
+		actor:         b.actor,
+		transactionId: b.transactionId,
+		before:        b.before,
+		after:         b.after,
+		createdAt:     b.createdAt,
+	}
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// getByNoteIdProvider returns a provider for the entries recorded for a note, oldest first
+func getByNoteIdProvider(tenantId uuid.UUID) func(noteId uint32) database.EntityProvider[[]Entity] {
+	return func(noteId uint32) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Where("tenant_id = ? AND note_id = ?", tenantId, noteId).Order("id").Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
+
+// getByCharacterIdProvider returns a provider for the entries recorded for the notes of a character, oldest first
+func getByCharacterIdProvider(tenantId uuid.UUID) func(characterId uint32) database.EntityProvider[[]Entity] {
+	return func(characterId uint32) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Where("tenant_id = ? AND character_id = ?", tenantId, characterId).Order("id").Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
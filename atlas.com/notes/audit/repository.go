@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Repository stores the audit trail of changes made to notes. It is append-only, save for purging a tenant's trail
+// along with its notes.
+type Repository interface {
+	// Append records entries, in the transaction of the changes they record when the repository was handed to one
+	Append(tenantId uuid.UUID, ms ...Model) error
+	// ByNoteId returns the entries recorded for a note, oldest first
+	ByNoteId(tenantId uuid.UUID, noteId uint32) ([]Model, error)
+	// ByCharacterId returns the entries recorded for the notes held by a character, oldest first
+	ByCharacterId(tenantId uuid.UUID, characterId uint32) ([]Model, error)
+	// Purge permanently removes every entry recorded for the tenant, returning how many were removed
+	Purge(tenantId uuid.UUID) (int64, error)
+}
+
+// GormRepository is a Repository backed by a SQL database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) Append(tenantId uuid.UUID, ms ...Model) error {
+	return appendEntries(r.db)(tenantId)(ms)
+}
+
+func (r *GormRepository) ByNoteId(tenantId uuid.UUID, noteId uint32) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getByNoteIdProvider(tenantId)(noteId)(database.Reader(r.db)))()()
+}
+
+func (r *GormRepository) ByCharacterId(tenantId uuid.UUID, characterId uint32) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getByCharacterIdProvider(tenantId)(characterId)(database.Reader(r.db)))()()
+}
+
+func (r *GormRepository) Purge(tenantId uuid.UUID) (int64, error) {
+	return purgeEntries(r.db)(tenantId)
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"strconv"
+	"time"
+)
+
+// RestModel is the JSON:API resource for an entry of the audit trail
+type RestModel struct {
+	Id            uint64          `json:"-"`
+	NoteId        uint32          `json:"noteId"`
+	CharacterId   uint32          `json:"characterId"`
+	Action        string          `json:"action"`
+	ActorType     string          `json:"actorType"`
+	ActorId       string          `json:"actorId"`
+	TransactionId uuid.UUID       `json:"transactionId"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// GetID returns the resource ID
+func (r RestModel) GetID() string {
+	return strconv.FormatUint(r.Id, 10)
+}
+
+// SetID sets the resource ID
+func (r *RestModel) SetID(strId string) error {
+	id, err := strconv.ParseUint(strId, 10, 64)
+	if err != nil {
+		return err
+	}
+	r.Id = id
+	return nil
+}
+
+// GetName returns the resource name
+func (r RestModel) GetName() string {
+	return "audits"
+}
+
+// Transform converts a Model domain model to a RestModel
+func Transform(m Model) (RestModel, error) {
+	return RestModel{
+		Id:            m.Id(),
+		NoteId:        m.NoteId(),
+		CharacterId:   m.CharacterId(),
+		Action:        m.Action(),
+		ActorType:     m.Actor().Type(),
+		ActorId:       m.Actor().Id(),
+		TransactionId: m.TransactionId(),
+		Before:        m.Before(),
+		After:         m.After(),
+		CreatedAt:     m.CreatedAt(),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+)
+
+// HeaderActingCharacterId names the character a REST request or Kafka command is made on behalf of, such as by a
+// player through a web companion app. A character acted for sees and changes only its own notes. Over REST, it is
+// trusted only from services; a player's token names its character itself.
+const HeaderActingCharacterId = "ACTING_CHARACTER_ID"
+
+// ActingCharacter is the character a request is made on behalf of
+type ActingCharacter struct {
+	id    uint32
+	valid bool
+}
+
+// Id returns the ID of the character acted for
+func (a ActingCharacter) Id() uint32 {
+	return a.id
+}
+
+// Is returns true when the character acted for is the one given. A malformed identity is no character at all, so it
+// matches none.
+func (a ActingCharacter) Is(characterId uint32) bool {
+	return a.valid && a.id == characterId
+}
+
+type actingCharacterKey struct{}
+
+// WithActingCharacter returns a context acting on behalf of a character
+func WithActingCharacter(ctx context.Context, characterId uint32) context.Context {
+	return context.WithValue(ctx, actingCharacterKey{}, ActingCharacter{id: characterId, valid: true})
+}
+
+// ActingCharacterFromContext returns the character a context acts on behalf of, if any
+func ActingCharacterFromContext(ctx context.Context) (ActingCharacter, bool) {
+	a, ok := ctx.Value(actingCharacterKey{}).(ActingCharacter)
+	return a, ok
+}
+
+// ParseActingCharacter parses the value of an ACTING_CHARACTER_ID header
+func ParseActingCharacter(val string) (uint32, error) {
+	characterId, err := strconv.ParseUint(val, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(characterId), nil
+}
+
+// ActingCharacterHeaderParser carries the character a Kafka command names in its headers into the context it is
+// handled with. A malformed value acts on behalf of no character at all, so it widens nothing.
+func ActingCharacterHeaderParser(ctx context.Context, headers map[string]string) context.Context {
+	val, ok := headers[HeaderActingCharacterId]
+	if !ok {
+		return ctx
+	}
+	characterId, err := ParseActingCharacter(val)
+	if err != nil {
+		return context.WithValue(ctx, actingCharacterKey{}, ActingCharacter{})
+	}
+	return WithActingCharacter(ctx, characterId)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+)
+
+// Role grants a principal access to the routes which require it
+type Role string
+
+const (
+	// RoleAdmin grants access to every route, including tenant-wide reads and bulk deletes
+	RoleAdmin Role = "admin"
+	// RoleService grants access to the routes other services call, such as creating notes
+	RoleService Role = "service"
+)
+
+// Principal is the caller a bearer token identifies, with the roles it grants and the character it acts on behalf of,
+// if any
+type Principal struct {
+	subject   string
+	roles     []Role
+	character ActingCharacter
+}
+
+func NewPrincipal(subject string, roles ...Role) Principal {
+	return Principal{subject: subject, roles: roles}
+}
+
+// Subject returns the subject of the token, identifying the caller
+func (p Principal) Subject() string {
+	return p.subject
+}
+
+// ActingFor returns the principal acting on behalf of a character
+func (p Principal) ActingFor(characterId uint32) Principal {
+	p.character = ActingCharacter{id: characterId, valid: true}
+	return p
+}
+
+// Character returns the character the token acts on behalf of, if it names one
+func (p Principal) Character() (uint32, bool) {
+	return p.character.id, p.character.valid
+}
+
+// Trusted returns true when the principal is a service, or an administrator, which may act on behalf of any character
+// or of none
+func (p Principal) Trusted() bool {
+	return p.HasRole(RoleService)
+}
+
+// Roles returns the roles the token grants
+func (p Principal) Roles() []Role {
+	return p.roles
+}
+
+// HasRole returns true when the principal holds the role. Administrators hold every role.
+func (p Principal) HasRole(role Role) bool {
+	for _, r := range p.roles {
+		if r == role || r == RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Permits returns true when the principal holds any of the roles, or when none are required
+func (p Principal) Permits(roles ...Role) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, r := range roles {
+		if p.HasRole(r) {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated caller
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, if the context carries one
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
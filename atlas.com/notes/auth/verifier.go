@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	EnvDisabled       = "AUTH_DISABLED"
+	EnvJwksFile       = "AUTH_JWKS_FILE"
+	EnvJwksUrl        = "AUTH_JWKS_URL"
+	EnvIssuer         = "AUTH_ISSUER"
+	EnvAudience       = "AUTH_AUDIENCE"
+	EnvRolesClaim     = "AUTH_ROLES_CLAIM"
+	EnvCharacterClaim = "AUTH_CHARACTER_CLAIM"
+
+	defaultRolesClaim     = "roles"
+	defaultCharacterClaim = "character_id"
+)
+
+var (
+	ErrNoKeys       = errors.New("no JWKS is configured")
+	ErrMissingToken = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid bearer token")
+	ErrForbidden    = errors.New("principal lacks the role required")
+	// ErrNoCharacter is returned for a principal which may act only on behalf of a character, yet names none
+	ErrNoCharacter = errors.New("principal acts on behalf of no character")
+	// ErrOtherCharacter is returned for a principal naming a character other than the one its token acts for
+	ErrOtherCharacter = errors.New("principal may not act on behalf of the character named")
+)
+
+// signingMethods are the asymmetric algorithms a token may be signed with. Symmetric algorithms are refused, as a JWKS
+// publishes public keys only.
+var signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+type Configuration struct {
+	keyfunc        jwt.Keyfunc
+	issuer         string
+	audience       string
+	rolesClaim     string
+	characterClaim string
+}
+
+type Configurator func(c *Configuration)
+
+// SetKeyfunc sets how the key a token is signed with is found, in place of the JWKS configured from the environment
+func SetKeyfunc(keyfunc jwt.Keyfunc) Configurator {
+	return func(c *Configuration) {
+		c.keyfunc = keyfunc
+	}
+}
+
+// SetIssuer sets the issuer tokens must name, if any
+func SetIssuer(issuer string) Configurator {
+	return func(c *Configuration) {
+		c.issuer = issuer
+	}
+}
+
+// SetAudience sets the audience tokens must name, if any
+func SetAudience(audience string) Configurator {
+	return func(c *Configuration) {
+		c.audience = audience
+	}
+}
+
+// SetRolesClaim sets the claim roles are read from. A dotted path reads a nested claim, such as realm_access.roles.
+func SetRolesClaim(claim string) Configurator {
+	return func(c *Configuration) {
+		c.rolesClaim = claim
+	}
+}
+
+// SetCharacterClaim sets the claim the character a player's token acts on behalf of is read from. A dotted path reads a
+// nested claim.
+func SetCharacterClaim(claim string) Configurator {
+	return func(c *Configuration) {
+		c.characterClaim = claim
+	}
+}
+
+// Disabled returns true when authentication is turned off, leaving every route open as it was before tokens were
+// required. It is meant for local development only.
+func Disabled() bool {
+	d, err := strconv.ParseBool(os.Getenv(EnvDisabled))
+	return err == nil && d
+}
+
+// keyfuncFromEnv loads the JWKS from the file in the environment, or else fetches it from the URL, refreshing it in
+// the background until the context is cancelled
+func keyfuncFromEnv(ctx context.Context) (jwt.Keyfunc, error) {
+	if path, ok := os.LookupEnv(EnvJwksFile); ok && path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		k, err := keyfunc.NewJWKSetJSON(raw)
+		if err != nil {
+			return nil, err
+		}
+		return k.Keyfunc, nil
+	}
+	if url, ok := os.LookupEnv(EnvJwksUrl); ok && url != "" {
+		k, err := keyfunc.NewDefaultCtx(ctx, []string{url})
+		if err != nil {
+			return nil, err
+		}
+		return k.Keyfunc, nil
+	}
+	return nil, ErrNoKeys
+}
+
+// Verifier validates bearer tokens against the keys of a JWKS
+type Verifier struct {
+	keyfunc        jwt.Keyfunc
+	parser         *jwt.Parser
+	rolesClaim     string
+	characterClaim string
+}
+
+// NewVerifier creates a Verifier configured from the environment. A JWKS fetched from a URL is refreshed until the
+// context is cancelled.
+func NewVerifier(l logrus.FieldLogger) func(ctx context.Context, configurators ...Configurator) (*Verifier, error) {
+	return func(ctx context.Context, configurators ...Configurator) (*Verifier, error) {
+		c := &Configuration{
+			issuer:         os.Getenv(EnvIssuer),
+			audience:       os.Getenv(EnvAudience),
+			rolesClaim:     os.Getenv(EnvRolesClaim),
+			characterClaim: os.Getenv(EnvCharacterClaim),
+		}
+		for _, configurator := range configurators {
+			configurator(c)
+		}
+		if c.rolesClaim == "" {
+			c.rolesClaim = defaultRolesClaim
+		}
+		if c.characterClaim == "" {
+			c.characterClaim = defaultCharacterClaim
+		}
+		if c.keyfunc == nil {
+			k, err := keyfuncFromEnv(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.keyfunc = k
+		}
+
+		opts := []jwt.ParserOption{jwt.WithValidMethods(signingMethods), jwt.WithExpirationRequired()}
+		if c.issuer != "" {
+			opts = append(opts, jwt.WithIssuer(c.issuer))
+		}
+		if c.audience != "" {
+			opts = append(opts, jwt.WithAudience(c.audience))
+		} else {
+			l.Warnf("No token audience is configured. Tokens issued for any audience are accepted.")
+		}
+		return &Verifier{keyfunc: c.keyfunc, parser: jwt.NewParser(opts...), rolesClaim: c.rolesClaim, characterClaim: c.characterClaim}, nil
+	}
+}
+
+// Verify validates a token, returning the principal it identifies, along with the character it acts on behalf of when
+// it names one
+func (v *Verifier) Verify(token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, v.keyfunc); err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: token names no subject", ErrInvalidToken)
+	}
+	p := NewPrincipal(subject, roles(claims, v.rolesClaim)...)
+	if val := claim(claims, v.characterClaim); val != nil {
+		characterId, err := character(val)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		p = p.ActingFor(characterId)
+	}
+	return p, nil
+}
+
+// VerifyRequest validates the bearer token in the Authorization header of a request
+func (v *Verifier) VerifyRequest(r *http.Request) (Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return Principal{}, ErrMissingToken
+	}
+	return v.Verify(strings.TrimSpace(token))
+}
+
+// claim returns the value of a claim, following a dotted path into nested claims, or nil when the token has none
+func claim(claims jwt.MapClaims, path string) any {
+	var val any = map[string]any(claims)
+	for _, key := range strings.Split(path, ".") {
+		m, ok := val.(map[string]any)
+		if !ok {
+			return nil
+		}
+		val = m[key]
+	}
+	return val
+}
+
+// character reads the character ID a claim holds, given either as a number or as a string
+func character(val any) (uint32, error) {
+	switch v := val.(type) {
+	case float64:
+		if v < 0 || v > math.MaxUint32 || v != math.Trunc(v) {
+			return 0, errors.New("character claim is not a character ID")
+		}
+		return uint32(v), nil
+	case string:
+		return ParseActingCharacter(v)
+	}
+	return 0, errors.New("character claim is not a character ID")
+}
+
+// roles reads the roles in a claim, given either as a list or as a space-separated string
+func roles(claims jwt.MapClaims, path string) []Role {
+	var names []string
+	switch v := claim(claims, path).(type) {
+	case string:
+		names = strings.Fields(v)
+	case []any:
+		for _, n := range v {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	rs := make([]Role, 0, len(names))
+	for _, n := range names {
+		rs = append(rs, Role(n))
+	}
+	return rs
+}
+
+var current *Verifier
+var mutex sync.RWMutex
+
+// Use installs the verifier REST requests are authenticated with
+func Use(v *Verifier) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	current = v
+}
+
+// GetVerifier returns the verifier REST requests are authenticated with, or nil when authentication is disabled
+func GetVerifier() *Verifier {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return current
+}
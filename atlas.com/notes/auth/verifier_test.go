@@ -0,0 +1,228 @@
+package auth_test
+
+import (
+	"atlas-notes/auth"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus/hooks/test"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testKeyId = "test-key"
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+// writeJwks writes a JWKS holding the public half of the key, returning its path
+func writeJwks(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	jwks := map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyId,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	raw, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err = os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyId
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return s
+}
+
+func claims(subject string, roles ...string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   subject,
+		"iss":   "https://issuer.test",
+		"aud":   "atlas-notes",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"roles": roles,
+	}
+}
+
+func testVerifier(t *testing.T, key *rsa.PrivateKey, configurators ...auth.Configurator) *auth.Verifier {
+	t.Helper()
+	t.Setenv(auth.EnvJwksFile, writeJwks(t, key))
+	t.Setenv(auth.EnvIssuer, "https://issuer.test")
+	t.Setenv(auth.EnvAudience, "atlas-notes")
+	l, _ := test.NewNullLogger()
+	v, err := auth.NewVerifier(l)(context.Background(), configurators...)
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	key := testKey(t)
+	v := testVerifier(t, key)
+
+	p, err := v.Verify(sign(t, key, claims("gm-tool", "admin")))
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	if p.Subject() != "gm-tool" || !p.HasRole(auth.RoleAdmin) {
+		t.Fatalf("Expected principal [gm-tool] holding [admin], got [%s] %v.", p.Subject(), p.Roles())
+	}
+	if !p.HasRole(auth.RoleService) {
+		t.Fatalf("Expected an administrator to hold every role.")
+	}
+
+	p, err = v.Verify(sign(t, key, claims("atlas-channel", "service")))
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	if !p.Permits(auth.RoleService) || p.Permits(auth.RoleAdmin) || !p.Permits() {
+		t.Fatalf("Expected a service principal to be permitted service routes only.")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := testKey(t)
+	v := testVerifier(t, key)
+
+	expired := claims("gm-tool", "admin")
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	unexpiring := claims("gm-tool", "admin")
+	delete(unexpiring, "exp")
+	issuer := claims("gm-tool", "admin")
+	issuer["iss"] = "https://elsewhere.test"
+	audience := claims("gm-tool", "admin")
+	audience["aud"] = "atlas-login"
+	subject := claims("", "admin")
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, claims("gm-tool", "admin"))
+	hmac.Header["kid"] = testKeyId
+	hs, err := hmac.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	tokens := map[string]string{
+		"expired":        sign(t, key, expired),
+		"without expiry": sign(t, key, unexpiring),
+		"wrong issuer":   sign(t, key, issuer),
+		"wrong audience": sign(t, key, audience),
+		"no subject":     sign(t, key, subject),
+		"unknown key":    sign(t, testKey(t), claims("gm-tool", "admin")),
+		"symmetric":      hs,
+		"malformed":      "not-a-token",
+	}
+	for name, token := range tokens {
+		if _, err := v.Verify(token); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Fatalf("Expected token [%s] to be rejected, got %v.", name, err)
+		}
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	key := testKey(t)
+	v := testVerifier(t, key)
+
+	r, _ := http.NewRequest(http.MethodGet, "/api/notes", nil)
+	if _, err := v.VerifyRequest(r); !errors.Is(err, auth.ErrMissingToken) {
+		t.Fatalf("Expected a request without a token to be rejected, got %v.", err)
+	}
+	r.Header.Set("Authorization", "Basic Z206dG9vbA==")
+	if _, err := v.VerifyRequest(r); !errors.Is(err, auth.ErrMissingToken) {
+		t.Fatalf("Expected a request without a bearer token to be rejected, got %v.", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+sign(t, key, claims("gm-tool", "admin")))
+	if p, err := v.VerifyRequest(r); err != nil || p.Subject() != "gm-tool" {
+		t.Fatalf("Expected the bearer token to be verified, got %v.", err)
+	}
+}
+
+func TestRolesClaim(t *testing.T) {
+	key := testKey(t)
+	v := testVerifier(t, key, auth.SetRolesClaim("realm_access.roles"))
+
+	c := claims("gm-tool")
+	c["realm_access"] = map[string]any{"roles": []string{"admin"}}
+	p, err := v.Verify(sign(t, key, c))
+	if err != nil || !p.HasRole(auth.RoleAdmin) {
+		t.Fatalf("Expected roles to be read from a nested claim, got %v (%v).", p.Roles(), err)
+	}
+
+	v = testVerifier(t, key, auth.SetRolesClaim("scope"))
+	c = claims("atlas-channel")
+	c["scope"] = "openid service"
+	p, err = v.Verify(sign(t, key, c))
+	if err != nil || !p.HasRole(auth.RoleService) {
+		t.Fatalf("Expected roles to be read from a space-separated claim, got %v (%v).", p.Roles(), err)
+	}
+}
+
+func TestCharacterClaim(t *testing.T) {
+	key := testKey(t)
+	v := testVerifier(t, key)
+
+	p, err := v.Verify(sign(t, key, claims("atlas-channel", "service")))
+	if _, ok := p.Character(); err != nil || ok {
+		t.Fatalf("Expected a token naming no character to act for none, got %v.", err)
+	}
+
+	c := claims("player-1")
+	c["character_id"] = 7
+	p, err = v.Verify(sign(t, key, c))
+	if characterId, ok := p.Character(); err != nil || !ok || characterId != 7 || p.Trusted() {
+		t.Fatalf("Expected an untrusted principal acting for character [7], got [%d] (%v).", characterId, err)
+	}
+
+	v = testVerifier(t, key, auth.SetCharacterClaim("player.character"))
+	c = claims("player-1")
+	c["player"] = map[string]any{"character": "8"}
+	p, err = v.Verify(sign(t, key, c))
+	if characterId, ok := p.Character(); err != nil || !ok || characterId != 8 {
+		t.Fatalf("Expected the character to be read from a nested claim, got [%d] (%v).", characterId, err)
+	}
+
+	c = claims("player-1")
+	c["player"] = map[string]any{"character": -1}
+	if _, err = v.Verify(sign(t, key, c)); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("Expected a token naming a malformed character to be rejected, got %v.", err)
+	}
+}
+
+func TestNoKeys(t *testing.T) {
+	t.Setenv(auth.EnvJwksFile, "")
+	t.Setenv(auth.EnvJwksUrl, "")
+	l, _ := test.NewNullLogger()
+	if _, err := auth.NewVerifier(l)(context.Background()); !errors.Is(err, auth.ErrNoKeys) {
+		t.Fatalf("Expected a verifier without keys to fail, got %v.", err)
+	}
+}
@@ -0,0 +1,20 @@
+package configuration
+
+import (
+	"atlas-notes/database"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// saveConfiguration records the configuration of a tenant, replacing any earlier record for the tenant
+func saveConfiguration(db *gorm.DB) func(t tenant.Model) func(m Model) error {
+	return func(t tenant.Model) func(m Model) error {
+		return func(m Model) error {
+			entity := MakeEntity(t, m)
+			return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+				return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entity).Error
+			})
+		}
+	}
+}
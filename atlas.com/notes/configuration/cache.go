@@ -0,0 +1,85 @@
+package configuration
+
+import (
+	"github.com/google/uuid"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EnvCacheTTL = "TENANT_CONFIGURATION_CACHE_TTL"
+
+	defaultCacheTTL = 5 * time.Minute
+)
+
+// cacheTTL returns how long a tenant's configuration is used before it is loaded again
+func cacheTTL() time.Duration {
+	if val, ok := os.LookupEnv(EnvCacheTTL); ok {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return defaultCacheTTL
+}
+
+type cacheEntry struct {
+	m         Model
+	expiresAt time.Time
+}
+
+// cache holds the configuration of each tenant consulted, as loaded from the database and configuration service. An
+// expired entry is kept, to fall back on should loading it again fail. The values last fetched from the configuration
+// service are held apart, to fall back on should the service fail while the stored configuration still loads.
+type cache struct {
+	mutex   sync.RWMutex
+	entries map[uuid.UUID]cacheEntry
+	fetched map[uuid.UUID]Model
+}
+
+var tenantCache = &cache{entries: make(map[uuid.UUID]cacheEntry), fetched: make(map[uuid.UUID]Model)}
+
+// get returns the configuration of a tenant, and whether it is still fresh
+func (c *cache) get(tenantId uuid.UUID) (Model, bool, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	e, ok := c.entries[tenantId]
+	if !ok {
+		return Model{}, false, false
+	}
+	return e.m, true, time.Now().Before(e.expiresAt)
+}
+
+func (c *cache) put(tenantId uuid.UUID, m Model) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[tenantId] = cacheEntry{m: m, expiresAt: time.Now().Add(cacheTTL())}
+}
+
+// service returns the configuration last fetched from the configuration service for a tenant, if any
+func (c *cache) service(tenantId uuid.UUID) (Model, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	m, ok := c.fetched[tenantId]
+	return m, ok
+}
+
+func (c *cache) putService(tenantId uuid.UUID, m Model) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.fetched[tenantId] = m
+}
+
+func (c *cache) expire(tenantId uuid.UUID) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if e, ok := c.entries[tenantId]; ok {
+		e.expiresAt = time.Time{}
+		c.entries[tenantId] = e
+	}
+}
+
+// Invalidate marks the cached configuration of a tenant as stale, so it is loaded afresh when next consulted
+func Invalidate(tenantId uuid.UUID) {
+	tenantCache.expire(tenantId)
+}
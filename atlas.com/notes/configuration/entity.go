@@ -0,0 +1,86 @@
+package configuration
+
+import (
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Entity represents the configuration of note policies for a tenant in the database. Policies which are not set are
+// NULL. Durations are stored in milliseconds.
+type Entity struct {
+	TenantID               uuid.UUID `gorm:"primaryKey"`
+	Region                 string
+	MajorVersion           uint16
+	MinorVersion           uint16
+	InboxCapacity          *int64
+	MaxMessageLength       *int
+	AttachmentExpirationMs *int64
+	RestoreGracePeriodMs   *int64
+	SendRateLimit          *int
+	SendRateWindowMs       *int64
+	AllowedFlags           *string
+	UpdatedAt              time.Time
+}
+
+// TableName specifies the database table name for Entity
+func (Entity) TableName() string {
+	return "tenant_configurations"
+}
+
+func fromMilliseconds(ms *int64) *time.Duration {
+	if ms == nil {
+		return nil
+	}
+	d := time.Duration(*ms) * time.Millisecond
+	return &d
+}
+
+func toMilliseconds(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	ms := d.Milliseconds()
+	return &ms
+}
+
+// Make converts an Entity to a Model domain model
+func Make(e Entity) (Model, error) {
+	m := Model{
+		inboxCapacity:        e.InboxCapacity,
+		maxMessageLength:     e.MaxMessageLength,
+		attachmentExpiration: fromMilliseconds(e.AttachmentExpirationMs),
+		restoreGracePeriod:   fromMilliseconds(e.RestoreGracePeriodMs),
+		sendRateLimit:        e.SendRateLimit,
+		sendRateWindow:       fromMilliseconds(e.SendRateWindowMs),
+	}
+	if e.AllowedFlags != nil {
+		fs, err := ParseFlags(*e.AllowedFlags)
+		if err != nil {
+			return Model{}, err
+		}
+		m.allowedFlags = &fs
+	}
+	return m, nil
+}
+
+// MakeEntity converts a Model domain model to an Entity for a tenant
+func MakeEntity(t tenant.Model, m Model) Entity {
+	e := Entity{
+		TenantID:               t.Id(),
+		Region:                 t.Region(),
+		MajorVersion:           t.MajorVersion(),
+		MinorVersion:           t.MinorVersion(),
+		InboxCapacity:          m.inboxCapacity,
+		MaxMessageLength:       m.maxMessageLength,
+		AttachmentExpirationMs: toMilliseconds(m.attachmentExpiration),
+		RestoreGracePeriodMs:   toMilliseconds(m.restoreGracePeriod),
+		SendRateLimit:          m.sendRateLimit,
+		SendRateWindowMs:       toMilliseconds(m.sendRateWindow),
+	}
+	if m.allowedFlags != nil {
+		fs := FormatFlags(*m.allowedFlags)
+		e.AllowedFlags = &fs
+	}
+	return e
+}
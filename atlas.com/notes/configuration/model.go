@@ -0,0 +1,245 @@
+package configuration
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvInboxCapacity        = "NOTE_INBOX_CAPACITY"
+	EnvMaxMessageLength     = "NOTE_MAX_MESSAGE_LENGTH"
+	EnvAttachmentExpiration = "NOTE_ATTACHMENT_EXPIRATION"
+	EnvRestoreGracePeriod   = "NOTE_RESTORE_GRACE_PERIOD"
+	EnvSendRateLimit        = "NOTE_SEND_RATE_LIMIT"
+	EnvSendRateWindow       = "NOTE_SEND_RATE_WINDOW"
+	EnvAllowedFlags         = "NOTE_ALLOWED_FLAGS"
+
+	defaultAttachmentExpiration = 30 * 24 * time.Hour
+	defaultRestoreGracePeriod   = 7 * 24 * time.Hour
+	defaultSendRateWindow       = time.Minute
+)
+
+// Model is the configuration of note policies for a tenant. Policies are optional: those which are not set fall back
+// to the service-wide defaults taken from the environment, read when the policy is consulted.
+type Model struct {
+	inboxCapacity        *int64
+	maxMessageLength     *int
+	attachmentExpiration *time.Duration
+	restoreGracePeriod   *time.Duration
+	sendRateLimit        *int
+	sendRateWindow       *time.Duration
+	allowedFlags         *[]byte
+}
+
+func envInt64(key string) (int64, bool) {
+	if val, ok := os.LookupEnv(key); ok {
+		if v, err := strconv.ParseInt(val, 10, 64); err == nil && v > 0 {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func envDuration(key string) (time.Duration, bool) {
+	if val, ok := os.LookupEnv(key); ok {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// InboxCapacity returns how many notes, archived notes aside, a character may hold. Zero means unlimited.
+func (m Model) InboxCapacity() int64 {
+	if m.inboxCapacity != nil {
+		return *m.inboxCapacity
+	}
+	c, _ := envInt64(EnvInboxCapacity)
+	return c
+}
+
+// MaxMessageLength returns the longest message a note may carry, in characters. Zero means unlimited.
+func (m Model) MaxMessageLength() int {
+	if m.maxMessageLength != nil {
+		return *m.maxMessageLength
+	}
+	l, _ := envInt64(EnvMaxMessageLength)
+	return int(l)
+}
+
+// AttachmentExpiration returns how long a note carrying attachments is kept before they are returned to the sender
+func (m Model) AttachmentExpiration() time.Duration {
+	if m.attachmentExpiration != nil {
+		return *m.attachmentExpiration
+	}
+	if d, ok := envDuration(EnvAttachmentExpiration); ok {
+		return d
+	}
+	return defaultAttachmentExpiration
+}
+
+// RestoreGracePeriod returns how long after its deletion a note may still be restored
+func (m Model) RestoreGracePeriod() time.Duration {
+	if m.restoreGracePeriod != nil {
+		return *m.restoreGracePeriod
+	}
+	if d, ok := envDuration(EnvRestoreGracePeriod); ok {
+		return d
+	}
+	return defaultRestoreGracePeriod
+}
+
+// SendRateLimit returns how many notes a character may send within the send rate window. Zero means unlimited.
+func (m Model) SendRateLimit() int {
+	if m.sendRateLimit != nil {
+		return *m.sendRateLimit
+	}
+	l, _ := envInt64(EnvSendRateLimit)
+	return int(l)
+}
+
+// SendRateWindow returns the period over which the notes a character sends are counted against the send rate limit
+func (m Model) SendRateWindow() time.Duration {
+	if m.sendRateWindow != nil {
+		return *m.sendRateWindow
+	}
+	if d, ok := envDuration(EnvSendRateWindow); ok && d > 0 {
+		return d
+	}
+	return defaultSendRateWindow
+}
+
+// AllowedFlags returns the flags a note may carry. Empty means any flag is allowed.
+func (m Model) AllowedFlags() []byte {
+	if m.allowedFlags != nil {
+		return *m.allowedFlags
+	}
+	fs, _ := ParseFlags(os.Getenv(EnvAllowedFlags))
+	return fs
+}
+
+// FlagAllowed returns true if a note may carry the flag
+func (m Model) FlagAllowed(flag byte) bool {
+	fs := m.AllowedFlags()
+	if len(fs) == 0 {
+		return true
+	}
+	for _, f := range fs {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFlags parses a comma-separated list of flags
+func ParseFlags(s string) ([]byte, error) {
+	results := make([]byte, 0)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		f, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, byte(f))
+	}
+	return results, nil
+}
+
+// FormatFlags formats flags as a comma-separated list
+func FormatFlags(flags []byte) string {
+	parts := make([]string, 0, len(flags))
+	for _, f := range flags {
+		parts = append(parts, strconv.Itoa(int(f)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Overlay returns the configuration with the policies set in over replacing its own
+func (m Model) Overlay(over Model) Model {
+	if over.inboxCapacity != nil {
+		m.inboxCapacity = over.inboxCapacity
+	}
+	if over.maxMessageLength != nil {
+		m.maxMessageLength = over.maxMessageLength
+	}
+	if over.attachmentExpiration != nil {
+		m.attachmentExpiration = over.attachmentExpiration
+	}
+	if over.restoreGracePeriod != nil {
+		m.restoreGracePeriod = over.restoreGracePeriod
+	}
+	if over.sendRateLimit != nil {
+		m.sendRateLimit = over.sendRateLimit
+	}
+	if over.sendRateWindow != nil {
+		m.sendRateWindow = over.sendRateWindow
+	}
+	if over.allowedFlags != nil {
+		m.allowedFlags = over.allowedFlags
+	}
+	return m
+}
+
+// Builder is a builder for creating Model instances. Policies which are not set are left to the defaults.
+type Builder struct {
+	m Model
+}
+
+// NewBuilder creates a new Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// SetInboxCapacity sets how many notes, archived notes aside, a character may hold
+func (b *Builder) SetInboxCapacity(inboxCapacity int64) *Builder {
+	b.m.inboxCapacity = &inboxCapacity
+	return b
+}
+
+// SetMaxMessageLength sets the longest message a note may carry
+func (b *Builder) SetMaxMessageLength(maxMessageLength int) *Builder {
+	b.m.maxMessageLength = &maxMessageLength
+	return b
+}
+
+// SetAttachmentExpiration sets how long a note carrying attachments is kept
+func (b *Builder) SetAttachmentExpiration(attachmentExpiration time.Duration) *Builder {
+	b.m.attachmentExpiration = &attachmentExpiration
+	return b
+}
+
+// SetRestoreGracePeriod sets how long after its deletion a note may still be restored
+func (b *Builder) SetRestoreGracePeriod(restoreGracePeriod time.Duration) *Builder {
+	b.m.restoreGracePeriod = &restoreGracePeriod
+	return b
+}
+
+// SetSendRateLimit sets how many notes a character may send within the send rate window
+func (b *Builder) SetSendRateLimit(sendRateLimit int) *Builder {
+	b.m.sendRateLimit = &sendRateLimit
+	return b
+}
+
+// SetSendRateWindow sets the period over which sent notes are counted against the send rate limit
+func (b *Builder) SetSendRateWindow(sendRateWindow time.Duration) *Builder {
+	b.m.sendRateWindow = &sendRateWindow
+	return b
+}
+
+// SetAllowedFlags sets the flags a note may carry
+func (b *Builder) SetAllowedFlags(allowedFlags []byte) *Builder {
+	fs := append([]byte{}, allowedFlags...)
+	b.m.allowedFlags = &fs
+	return b
+}
+
+// Build creates a new Model with the builder's values
+func (b *Builder) Build() Model {
+	return b.m
+}
@@ -0,0 +1,155 @@
+package configuration
+
+import (
+	"atlas-notes/kafka/message"
+	"atlas-notes/kafka/message/configuration"
+	"atlas-notes/kafka/producer"
+	"context"
+	"errors"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"os"
+)
+
+// Accessor returns the configuration in force for a tenant. It never fails: should the configuration not load, the
+// last one loaded is used, or else the defaults.
+type Accessor func() Model
+
+type Processor interface {
+	GetForTenant() (Model, error)
+	Accessor() Accessor
+	Refresh() (Model, error)
+	Update(mb *message.Buffer) func(m Model) (Model, error)
+	UpdateAndEmit(m Model) (Model, error)
+}
+
+type ProcessorImpl struct {
+	l        logrus.FieldLogger
+	ctx      context.Context
+	r        Repository
+	t        tenant.Model
+	producer producer.Provider
+}
+
+// NewProcessor creates a Processor for the configuration of the tenant in the context. A nil database stands for
+// running without one, in which case configurations are held in memory.
+func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+	return &ProcessorImpl{
+		l:        l,
+		ctx:      ctx,
+		r:        repository(db),
+		t:        tenant.MustFromContext(ctx),
+		producer: producer.ProviderImpl(l)(ctx),
+	}
+}
+
+// repository returns the Repository backed by the database, or the shared in-memory one without a database
+func repository(db *gorm.DB) Repository {
+	if db == nil {
+		return getSharedMemoryRepository()
+	}
+	return NewGormRepository(db)
+}
+
+// EventsEnabled returns true if a configuration status topic is configured, through which instances learn of
+// configurations updated elsewhere
+func EventsEnabled() bool {
+	_, ok := os.LookupEnv(configuration.EnvEventTopicConfigurationStatus)
+	return ok
+}
+
+// load returns the configuration of the tenant: that fetched from the configuration service, if one is available,
+// with that stored for the tenant taking precedence over it. Should the service fail, the values last fetched from it
+// are used instead; should none have been, the error is returned, so that no configuration short of the service's
+// values is cached.
+func (p *ProcessorImpl) load() (Model, error) {
+	m := NewBuilder().Build()
+	if serviceConfigured() {
+		rm, err := requestByTenantId(p.t.Id())(p.l, p.ctx)
+		if err == nil {
+			m, err = Extract(rm)
+		}
+		if err == nil {
+			tenantCache.putService(p.t.Id(), m)
+		} else {
+			f, ok := tenantCache.service(p.t.Id())
+			if !ok {
+				return Model{}, err
+			}
+			p.l.WithError(err).Warnf("Unable to fetch configuration from the configuration service, relying on that last fetched.")
+			m = f
+		}
+	}
+
+	s, err := p.r.ByTenantId(p.t.Id())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return m, nil
+	}
+	if err != nil {
+		return Model{}, err
+	}
+	return m.Overlay(s), nil
+}
+
+// GetForTenant returns the configuration of the tenant, loading it if it is not cached or has gone stale
+func (p *ProcessorImpl) GetForTenant() (Model, error) {
+	if m, ok, fresh := tenantCache.get(p.t.Id()); ok && fresh {
+		return m, nil
+	}
+	return p.Refresh()
+}
+
+// Refresh loads the configuration of the tenant afresh, replacing the cached one
+func (p *ProcessorImpl) Refresh() (Model, error) {
+	m, err := p.load()
+	if err != nil {
+		return Model{}, err
+	}
+	tenantCache.put(p.t.Id(), m)
+	return m, nil
+}
+
+// Accessor returns an Accessor of the configuration of the tenant
+func (p *ProcessorImpl) Accessor() Accessor {
+	return func() Model {
+		m, err := p.GetForTenant()
+		if err == nil {
+			return m
+		}
+		if m, ok, _ := tenantCache.get(p.t.Id()); ok {
+			p.l.WithError(err).Warnf("Unable to load configuration, using the last one loaded.")
+			return m
+		}
+		p.l.WithError(err).Warnf("Unable to load configuration, using the defaults.")
+		return NewBuilder().Build()
+	}
+}
+
+// Update stores the policies set in m for the tenant, leaving those it does not set as they were, and returns the
+// configuration in force as a result. When configuration events are enabled, other instances are told to reload it.
+func (p *ProcessorImpl) Update(mb *message.Buffer) func(m Model) (Model, error) {
+	return func(m Model) (Model, error) {
+		s, err := p.r.ByTenantId(p.t.Id())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return Model{}, err
+		}
+		err = p.r.Save(p.t, s.Overlay(m))
+		if err != nil {
+			return Model{}, err
+		}
+		Invalidate(p.t.Id())
+		if EventsEnabled() {
+			err = mb.Put(configuration.EnvEventTopicConfigurationStatus, UpdatedStatusEventProvider(p.t.Id()))
+			if err != nil {
+				return Model{}, err
+			}
+		}
+		return p.Refresh()
+	}
+}
+
+// UpdateAndEmit stores policies for the tenant and emits a status event
+func (p *ProcessorImpl) UpdateAndEmit(m Model) (Model, error) {
+	return message.EmitWithResult[Model, Model](p.producer)(p.Update)(m)
+}
@@ -0,0 +1,130 @@
+package configuration_test
+
+import (
+	"atlas-notes/configuration"
+	"atlas-notes/database"
+	"atlas-notes/kafka/message"
+	"atlas-notes/migrations"
+	"context"
+	"errors"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func testDatabase(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err = database.Migrate(testLogger(), db, migrations.FS, database.LatestVersion); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func testTenant() tenant.Model {
+	t, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	return t
+}
+
+func testLogger() logrus.FieldLogger {
+	l, _ := test.NewNullLogger()
+	return l
+}
+
+func TestProcessorImpl_Update(t *testing.T) {
+	t.Setenv(configuration.EnvInboxCapacity, "10")
+
+	l := testLogger()
+	ctx := tenant.WithContext(context.Background(), testTenant())
+	p := configuration.NewProcessor(l, ctx, testDatabase(t))
+
+	// Without a stored configuration, the defaults apply.
+	m, err := p.GetForTenant()
+	if err != nil {
+		t.Fatalf("Failed to get configuration: %v", err)
+	}
+	if m.InboxCapacity() != 10 || m.RestoreGracePeriod() != 7*24*time.Hour || !m.FlagAllowed(9) {
+		t.Fatalf("Expected the defaults, got capacity [%d], grace period [%s]", m.InboxCapacity(), m.RestoreGracePeriod())
+	}
+
+	m, err = p.Update(message.NewBuffer())(configuration.NewBuilder().SetInboxCapacity(5).SetAllowedFlags([]byte{1}).Build())
+	if err != nil {
+		t.Fatalf("Failed to update configuration: %v", err)
+	}
+	if m.InboxCapacity() != 5 || m.FlagAllowed(9) || !m.FlagAllowed(1) {
+		t.Fatalf("Expected updated policies to apply, got capacity [%d], flags %v", m.InboxCapacity(), m.AllowedFlags())
+	}
+
+	// A later update leaves the policies it does not set as they were.
+	m, err = p.Update(message.NewBuffer())(configuration.NewBuilder().SetMaxMessageLength(20).Build())
+	if err != nil {
+		t.Fatalf("Failed to update configuration: %v", err)
+	}
+	if m.InboxCapacity() != 5 || m.MaxMessageLength() != 20 || m.FlagAllowed(9) {
+		t.Fatalf("Expected earlier policies to be kept, got capacity [%d], length [%d]", m.InboxCapacity(), m.MaxMessageLength())
+	}
+	if p.Accessor()().MaxMessageLength() != 20 {
+		t.Fatalf("Expected accessor to see the updated configuration")
+	}
+}
+
+func TestProcessorImpl_Cache(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+	p := configuration.NewProcessor(l, ctx, db)
+
+	if _, err := p.GetForTenant(); err != nil {
+		t.Fatalf("Failed to get configuration: %v", err)
+	}
+
+	// A configuration stored by another instance is not seen until the cached one is invalidated.
+	err := configuration.NewGormRepository(db).Save(te, configuration.NewBuilder().SetSendRateLimit(3).Build())
+	if err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	m, err := p.GetForTenant()
+	if err != nil || m.SendRateLimit() != 0 {
+		t.Fatalf("Expected the cached configuration, got limit [%d], %v", m.SendRateLimit(), err)
+	}
+	configuration.Invalidate(te.Id())
+	m, err = p.GetForTenant()
+	if err != nil || m.SendRateLimit() != 3 {
+		t.Fatalf("Expected the stored configuration, got limit [%d], %v", m.SendRateLimit(), err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	negative := -1
+	zero := "0s"
+	invalid := "soon"
+	flags := []int{1, 256}
+	for name, rm := range map[string]configuration.RestModel{
+		"negative length": {MaxMessageLength: &negative},
+		"zero window":     {SendRateWindow: &zero},
+		"bad duration":    {AttachmentExpiration: &invalid},
+		"flag range":      {AllowedFlags: &flags},
+	} {
+		if _, err := configuration.Extract(rm); !errors.Is(err, configuration.ErrInvalidConfiguration) {
+			t.Fatalf("Expected %s to be invalid, got %v", name, err)
+		}
+	}
+
+	window := "30s"
+	m, err := configuration.Extract(configuration.RestModel{SendRateWindow: &window})
+	if err != nil {
+		t.Fatalf("Failed to extract configuration: %v", err)
+	}
+	rm, err := configuration.Transform(uuid.New())(m)
+	if err != nil || *rm.SendRateWindow != "30s" || *rm.AttachmentExpiration != (30*24*time.Hour).String() {
+		t.Fatalf("Expected the configuration in force, got %+v, %v", rm, err)
+	}
+}
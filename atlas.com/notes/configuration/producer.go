@@ -0,0 +1,19 @@
+package configuration
+
+import (
+	"atlas-notes/kafka/message/configuration"
+	"github.com/Chronicle20/atlas-kafka/producer"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// UpdatedStatusEventProvider creates a status event for the configuration of a tenant having been updated
+func UpdatedStatusEventProvider(tenantId uuid.UUID) model.Provider[[]kafka.Message] {
+	key := []byte(tenantId.String())
+	value := configuration.StatusEvent{
+		TenantId: tenantId,
+		Type:     configuration.StatusEventTypeUpdated,
+	}
+	return producer.SingleMessageProvider(key, value)
+}
@@ -0,0 +1,20 @@
+package configuration
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// getByTenantIdProvider returns a provider for the configuration of a tenant
+func getByTenantIdProvider(tenantId uuid.UUID) database.EntityProvider[Entity] {
+	return func(db *gorm.DB) model.Provider[Entity] {
+		var entity Entity
+		err := db.Where("tenant_id = ?", tenantId).First(&entity).Error
+		if err != nil {
+			return model.ErrorProvider[Entity](err)
+		}
+		return model.FixedProvider(entity)
+	}
+}
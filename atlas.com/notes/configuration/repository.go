@@ -0,0 +1,73 @@
+package configuration
+
+import (
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// Repository stores the configuration of tenants. A tenant without a configuration is reported with
+// gorm.ErrRecordNotFound.
+type Repository interface {
+	// ByTenantId returns the configuration of a tenant
+	ByTenantId(tenantId uuid.UUID) (Model, error)
+	// Save records the configuration of a tenant, replacing any earlier record for the tenant
+	Save(t tenant.Model, m Model) error
+}
+
+// GormRepository is a Repository backed by a SQL database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) ByTenantId(tenantId uuid.UUID) (Model, error) {
+	return model.Map[Entity, Model](Make)(getByTenantIdProvider(tenantId)(r.db))()
+}
+
+func (r *GormRepository) Save(t tenant.Model, m Model) error {
+	return saveConfiguration(r.db)(t)(m)
+}
+
+// MemoryRepository is a concurrency-safe Repository holding configurations in memory, for running without a database
+type MemoryRepository struct {
+	mutex          sync.RWMutex
+	configurations map[uuid.UUID]Model
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{configurations: make(map[uuid.UUID]Model)}
+}
+
+var sharedMemory *MemoryRepository
+var sharedMemoryOnce sync.Once
+
+// getSharedMemoryRepository returns the in-memory repository shared by processors created without a database
+func getSharedMemoryRepository() *MemoryRepository {
+	sharedMemoryOnce.Do(func() {
+		sharedMemory = NewMemoryRepository()
+	})
+	return sharedMemory
+}
+
+func (r *MemoryRepository) ByTenantId(tenantId uuid.UUID) (Model, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	m, ok := r.configurations[tenantId]
+	if !ok {
+		return Model{}, gorm.ErrRecordNotFound
+	}
+	return m, nil
+}
+
+func (r *MemoryRepository) Save(t tenant.Model, m Model) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.configurations[t.Id()] = m
+	return nil
+}
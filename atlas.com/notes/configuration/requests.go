@@ -0,0 +1,25 @@
+package configuration
+
+import (
+	"atlas-notes/rest"
+	"fmt"
+	"github.com/Chronicle20/atlas-rest/requests"
+	"github.com/google/uuid"
+)
+
+const (
+	Resource = "tenants/%s/notes"
+)
+
+func getBaseRequest() string {
+	return requests.RootUrl("CONFIGURATIONS")
+}
+
+// serviceConfigured returns true if a configuration service is available to fetch configurations from
+func serviceConfigured() bool {
+	return getBaseRequest() != ""
+}
+
+func requestByTenantId(tenantId uuid.UUID) requests.Request[RestModel] {
+	return rest.MakeGetRequest[RestModel](fmt.Sprintf(getBaseRequest()+Resource, tenantId.String()))
+}
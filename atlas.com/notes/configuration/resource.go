@@ -0,0 +1,89 @@
+package configuration
+
+import (
+	"atlas-notes/auth"
+	"atlas-notes/rest"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-rest/server"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/gorilla/mux"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// InitResource registers the configuration routes. They must be registered before the note routes, whose
+// /notes/{noteId} would otherwise match them.
+func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteInitializer {
+	return func(db *gorm.DB) server.RouteInitializer {
+		return func(router *mux.Router, l logrus.FieldLogger) {
+			registerHandler := rest.RegisterHandler(l)(db)(si)
+			registerInputHandler := rest.RegisterInputHandler[RestModel](l)(db)(si)
+
+			// Configuration of note policies in force for the tenant
+			router.HandleFunc("/notes/configuration", registerHandler("get_notes_configuration", GetNotesConfigurationHandler, auth.RoleAdmin)).Methods(http.MethodGet)
+
+			// Update the configuration of note policies for the tenant
+			router.HandleFunc("/notes/configuration", registerInputHandler("update_notes_configuration", UpdateNotesConfigurationHandler, auth.RoleAdmin)).Methods(http.MethodPatch)
+		}
+	}
+}
+
+// GetNotesConfigurationHandler handles GET /api/notes/configuration
+func GetNotesConfigurationHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).GetForTenant()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Unable to load configuration.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rm, err := model.Map(Transform(tenant.MustFromContext(d.Context()).Id()))(model.FixedProvider(m))()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Creating REST model.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		queryParams := jsonapi.ParseQueryFields(&query)
+		server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+	}
+}
+
+// UpdateNotesConfigurationHandler handles PATCH /api/notes/configuration
+func UpdateNotesConfigurationHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i RestModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		im, err := Extract(i)
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Invalid configuration.")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).UpdateAndEmit(im)
+		if err != nil {
+			d.Logger().WithError(err).Errorln("Error updating configuration")
+			if errors.Is(err, ErrInvalidConfiguration) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rm, err := model.Map(Transform(tenant.MustFromContext(d.Context()).Id()))(model.FixedProvider(m))()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Creating REST model.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		queryParams := jsonapi.ParseQueryFields(&query)
+		server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+	}
+}
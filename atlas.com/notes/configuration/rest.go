@@ -0,0 +1,138 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+var ErrInvalidConfiguration = errors.New("invalid configuration")
+
+// RestModel is the JSON:API resource for the configuration of note policies for a tenant. Durations are Go duration
+// strings. As input, and as fetched from the configuration service, omitted attributes are left as they are.
+type RestModel struct {
+	Id                   uuid.UUID `json:"-"`
+	InboxCapacity        *int64    `json:"inboxCapacity,omitempty"`
+	MaxMessageLength     *int      `json:"maxMessageLength,omitempty"`
+	AttachmentExpiration *string   `json:"attachmentExpiration,omitempty"`
+	RestoreGracePeriod   *string   `json:"restoreGracePeriod,omitempty"`
+	SendRateLimit        *int      `json:"sendRateLimit,omitempty"`
+	SendRateWindow       *string   `json:"sendRateWindow,omitempty"`
+	AllowedFlags         *[]int    `json:"allowedFlags,omitempty"`
+}
+
+// GetID returns the resource ID
+func (r RestModel) GetID() string {
+	return r.Id.String()
+}
+
+// SetID sets the resource ID
+func (r *RestModel) SetID(strId string) error {
+	if strId == "" {
+		return nil
+	}
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		return err
+	}
+	r.Id = id
+	return nil
+}
+
+// GetName returns the resource name
+func (r RestModel) GetName() string {
+	return "configurations"
+}
+
+// Transform converts the configuration in force for a tenant to a RestModel, with every policy set
+func Transform(tenantId uuid.UUID) func(m Model) (RestModel, error) {
+	return func(m Model) (RestModel, error) {
+		inboxCapacity := m.InboxCapacity()
+		maxMessageLength := m.MaxMessageLength()
+		attachmentExpiration := m.AttachmentExpiration().String()
+		restoreGracePeriod := m.RestoreGracePeriod().String()
+		sendRateLimit := m.SendRateLimit()
+		sendRateWindow := m.SendRateWindow().String()
+		allowedFlags := make([]int, 0, len(m.AllowedFlags()))
+		for _, f := range m.AllowedFlags() {
+			allowedFlags = append(allowedFlags, int(f))
+		}
+		return RestModel{
+			Id:                   tenantId,
+			InboxCapacity:        &inboxCapacity,
+			MaxMessageLength:     &maxMessageLength,
+			AttachmentExpiration: &attachmentExpiration,
+			RestoreGracePeriod:   &restoreGracePeriod,
+			SendRateLimit:        &sendRateLimit,
+			SendRateWindow:       &sendRateWindow,
+			AllowedFlags:         &allowedFlags,
+		}, nil
+	}
+}
+
+func parseDuration(name string, s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%w: %s must be a non-negative duration", ErrInvalidConfiguration, name)
+	}
+	return d, nil
+}
+
+// Extract converts a RestModel to a Model setting the policies it carries
+func Extract(r RestModel) (Model, error) {
+	b := NewBuilder()
+	if r.InboxCapacity != nil {
+		if *r.InboxCapacity < 0 {
+			return Model{}, fmt.Errorf("%w: inboxCapacity must not be negative", ErrInvalidConfiguration)
+		}
+		b.SetInboxCapacity(*r.InboxCapacity)
+	}
+	if r.MaxMessageLength != nil {
+		if *r.MaxMessageLength < 0 {
+			return Model{}, fmt.Errorf("%w: maxMessageLength must not be negative", ErrInvalidConfiguration)
+		}
+		b.SetMaxMessageLength(*r.MaxMessageLength)
+	}
+	if r.AttachmentExpiration != nil {
+		d, err := parseDuration("attachmentExpiration", *r.AttachmentExpiration)
+		if err != nil {
+			return Model{}, err
+		}
+		b.SetAttachmentExpiration(d)
+	}
+	if r.RestoreGracePeriod != nil {
+		d, err := parseDuration("restoreGracePeriod", *r.RestoreGracePeriod)
+		if err != nil {
+			return Model{}, err
+		}
+		b.SetRestoreGracePeriod(d)
+	}
+	if r.SendRateLimit != nil {
+		if *r.SendRateLimit < 0 {
+			return Model{}, fmt.Errorf("%w: sendRateLimit must not be negative", ErrInvalidConfiguration)
+		}
+		b.SetSendRateLimit(*r.SendRateLimit)
+	}
+	if r.SendRateWindow != nil {
+		d, err := parseDuration("sendRateWindow", *r.SendRateWindow)
+		if err != nil {
+			return Model{}, err
+		}
+		if d == 0 {
+			return Model{}, fmt.Errorf("%w: sendRateWindow must be positive", ErrInvalidConfiguration)
+		}
+		b.SetSendRateWindow(d)
+	}
+	if r.AllowedFlags != nil {
+		fs := make([]byte, 0, len(*r.AllowedFlags))
+		for _, f := range *r.AllowedFlags {
+			if f < 0 || f > 255 {
+				return Model{}, fmt.Errorf("%w: allowedFlags must be between 0 and 255", ErrInvalidConfiguration)
+			}
+			fs = append(fs, byte(f))
+		}
+		b.SetAllowedFlags(fs)
+	}
+	return b.Build(), nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDSNBuilder_Build(t *testing.T) {
+	dsn := NewDSNBuilder().
+		SetHost("db").
+		SetUser("notes").
+		SetPassword("it's secret").
+		SetDatabaseName("notes").
+		SetPort(5432).
+		SetSSLMode("verify-full").
+		SetSSLRootCert("/etc/ssl/ca.pem").
+		SetApplicationName("atlas-notes").
+		SetStatementTimeout(5 * time.Second).
+		SetConnectTimeout(10 * time.Second).
+		Build()
+
+	expected := `host=db user=notes password='it\'s secret' dbname=notes sslmode=verify-full sslrootcert=/etc/ssl/ca.pem application_name=atlas-notes port=5432 statement_timeout=5000 connect_timeout=10 TimeZone=UTC`
+	if dsn != expected {
+		t.Fatalf("Unexpected DSN: %s", dsn)
+	}
+
+	if dsn = NewDSNBuilder().Build(); dsn != "sslmode=disable TimeZone=UTC" {
+		t.Fatalf("Expected unset parameters to be left out, got: %s", dsn)
+	}
+}
+
+func TestNewDSNBuilderFromURL(t *testing.T) {
+	d, err := NewDSNBuilderFromURL("postgres://notes:secret@db:6543/notes?sslmode=require&application_name=atlas-notes&statement_timeout=2500")
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+	expected := "host=db user=notes password=secret dbname=notes sslmode=require application_name=atlas-notes port=6543 statement_timeout=2500 TimeZone=UTC"
+	if dsn := d.Build(); dsn != expected {
+		t.Fatalf("Unexpected DSN: %s", dsn)
+	}
+
+	if _, err = NewDSNBuilderFromURL("mysql://notes@db/notes"); err == nil {
+		t.Fatalf("Expected an unsupported scheme to be refused")
+	}
+}
+
+func TestConfigurationFromEnv(t *testing.T) {
+	t.Setenv("DB_URL", "postgres://notes:secret@db/notes?sslmode=require")
+	t.Setenv("DB_HOST", "replica")
+	t.Setenv("DB_STATEMENT_TIMEOUT", "3s")
+	t.Setenv("DB_MAX_OPEN_CONNS", "50")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "1h")
+	t.Setenv("DB_CONNECT_BACKOFF_MAX", "5s")
+
+	c, err := configurationFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read configuration: %v", err)
+	}
+	if c.dsn != "host=replica user=notes password=secret dbname=notes sslmode=require statement_timeout=3000 TimeZone=UTC" {
+		t.Fatalf("Unexpected DSN: %s", c.dsn)
+	}
+	if c.maxOpenConns != 50 || c.maxIdleConns != 10 || c.connMaxLifetime != time.Hour {
+		t.Fatalf("Unexpected pool configuration")
+	}
+	if c.connectBackoff.Max != 5*time.Second || c.connectBackoff.Initial != 500*time.Millisecond {
+		t.Fatalf("Unexpected connect backoff")
+	}
+}
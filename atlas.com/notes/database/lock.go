@@ -0,0 +1,24 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// TryLock runs fn while holding the Postgres advisory lock of the given name, on a single pooled connection so the lock
+// is released by the session which took it. It returns false without running fn when another session holds the lock,
+// so work which every replica schedules is done by one at a time. Dialects without advisory locks run fn directly.
+func TryLock(db *gorm.DB, name string, fn func() error) (bool, error) {
+	if db.Dialector.Name() != "postgres" {
+		return true, fn()
+	}
+	acquired := false
+	err := db.Connection(func(conn *gorm.DB) error {
+		err := conn.Raw("SELECT pg_try_advisory_lock(hashtextextended(?, 0))", name).Scan(&acquired).Error
+		if err != nil || !acquired {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(hashtextextended(?, 0))", name)
+		return fn()
+	})
+	return acquired, err
+}
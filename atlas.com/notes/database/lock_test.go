@@ -0,0 +1,22 @@
+package database_test
+
+import (
+	"atlas-notes/database"
+	"errors"
+	"testing"
+)
+
+func TestTryLock(t *testing.T) {
+	db := testDatabase(t)
+
+	// Without advisory locks, the work is run directly and its error returned.
+	failed := errors.New("failed")
+	ran := false
+	locked, err := database.TryLock(db, "test", func() error {
+		ran = true
+		return failed
+	})
+	if !locked || !ran || !errors.Is(err, failed) {
+		t.Fatalf("Expected the work to run and fail, got locked [%t], ran [%t], %v", locked, ran, err)
+	}
+}
@@ -0,0 +1,246 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// LatestVersion is a migration target which applies every known migration
+	LatestVersion = ^uint64(0)
+
+	DirectionUp   = "up"
+	DirectionDown = "down"
+
+	// migrationLockKey identifies the Postgres advisory lock held while migrating
+	migrationLockKey int64 = 7_361_201_530_184_220_415
+)
+
+var (
+	ErrDuplicateMigration = errors.New("duplicate migration version")
+	ErrMissingUpMigration = errors.New("migration has no up file")
+	ErrIrreversible       = errors.New("migration has no down file")
+	ErrDryRun             = errors.New("migration dry run")
+
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+// Migration is a versioned change to the schema. Up applies the change, and Down reverts it.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Step is a migration to run in a given direction
+type Step struct {
+	Migration
+	Direction string
+}
+
+// String describes the step for a migration plan
+func (s Step) String() string {
+	return fmt.Sprintf("%s %04d %s", s.Direction, s.Version, s.Name)
+}
+
+// DryRunError is returned by Connect on a migration dry run, in place of migrating, with the version the schema is at
+// and the steps pending to reach the target
+type DryRunError struct {
+	Current uint64
+	Steps   []Step
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("%s: schema is at version %d with %d pending migration(s)", ErrDryRun, e.Current, len(e.Steps))
+}
+
+func (e *DryRunError) Unwrap() error {
+	return ErrDryRun
+}
+
+// versionEntity records an applied migration in the schema version table
+type versionEntity struct {
+	Version   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName specifies the database table name for versionEntity
+func (versionEntity) TableName() string {
+	return "schema_migrations"
+}
+
+// LoadMigrations reads the migrations in a directory, ordered by version. Each version needs a
+// {version}_{name}.up.sql file, and may have a matching {version}_{name}.down.sql file.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		}
+		if m.Name != matches[2] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateMigration, version)
+		}
+		if matches[3] == DirectionUp {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	results := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: %d", ErrMissingUpMigration, m.Version)
+		}
+		results = append(results, *m)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Version < results[j].Version
+	})
+	return results, nil
+}
+
+// migrationsFor loads the migrations written for the database's dialect, found in a directory named after it
+func migrationsFor(db *gorm.DB, fsys fs.FS) ([]Migration, error) {
+	return LoadMigrations(fsys, db.Dialector.Name())
+}
+
+// CurrentVersion returns the version of the newest migration applied to the database, or zero if none have been
+func CurrentVersion(db *gorm.DB) (uint64, error) {
+	if !db.Migrator().HasTable(&versionEntity{}) {
+		return 0, nil
+	}
+	var version uint64
+	err := db.Model(&versionEntity{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Plan returns the steps needed to move the database from its current version to the target version. Targets below
+// the current version roll migrations back, newest first.
+func Plan(db *gorm.DB, fsys fs.FS, target uint64) ([]Step, error) {
+	ms, err := migrationsFor(db, fsys)
+	if err != nil {
+		return nil, err
+	}
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	return plan(ms, current, target)
+}
+
+func plan(ms []Migration, current uint64, target uint64) ([]Step, error) {
+	steps := make([]Step, 0)
+	if target >= current {
+		for _, m := range ms {
+			if m.Version > current && m.Version <= target {
+				steps = append(steps, Step{Migration: m, Direction: DirectionUp})
+			}
+		}
+		return steps, nil
+	}
+
+	for i := len(ms) - 1; i >= 0; i-- {
+		m := ms[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("%w: %d", ErrIrreversible, m.Version)
+		}
+		steps = append(steps, Step{Migration: m, Direction: DirectionDown})
+	}
+	return steps, nil
+}
+
+// Migrate moves the database to the target version. On Postgres an advisory lock is held throughout, so when several
+// replicas start at once only one migrates and the rest find nothing left to do. Each step runs in its own transaction
+// alongside its schema version record.
+func Migrate(l logrus.FieldLogger, db *gorm.DB, fsys fs.FS, target uint64) error {
+	return withMigrationLock(db, func(conn *gorm.DB) error {
+		err := conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP NOT NULL)").Error
+		if err != nil {
+			return err
+		}
+
+		steps, err := Plan(conn, fsys, target)
+		if err != nil {
+			return err
+		}
+		for _, s := range steps {
+			l.Infof("Migrating schema: %s.", s)
+			err = conn.Transaction(func(tx *gorm.DB) error {
+				return applyStep(tx, s)
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d %s: %w", s.Version, s.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func applyStep(tx *gorm.DB, s Step) error {
+	if s.Direction == DirectionDown {
+		err := tx.Exec(s.Down).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("version = ?", s.Version).Delete(&versionEntity{}).Error
+	}
+
+	err := tx.Exec(s.Up).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&versionEntity{Version: s.Version, Name: s.Name, AppliedAt: time.Now().UTC()}).Error
+}
+
+// withMigrationLock runs fn while holding the migration advisory lock, on a single pooled connection so the lock is
+// released by the session which took it. Dialects without advisory locks run fn directly.
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	if db.Dialector.Name() != "postgres" {
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error
+		if err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		return fn(conn)
+	})
+}
@@ -0,0 +1,132 @@
+package database_test
+
+import (
+	"atlas-notes/database"
+	"atlas-notes/migrations"
+	"errors"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"testing/fstest"
+)
+
+func testDatabase(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"sqlite/0001_create_widgets.up.sql":    {Data: []byte("CREATE TABLE widgets (id INTEGER PRIMARY KEY);")},
+		"sqlite/0001_create_widgets.down.sql":  {Data: []byte("DROP TABLE widgets;")},
+		"sqlite/0002_add_widget_name.up.sql":   {Data: []byte("ALTER TABLE widgets ADD COLUMN name TEXT;")},
+		"sqlite/0002_add_widget_name.down.sql": {Data: []byte("ALTER TABLE widgets DROP COLUMN name;")},
+		"sqlite/0003_create_gadgets.up.sql":    {Data: []byte("CREATE TABLE gadgets (id INTEGER PRIMARY KEY);")},
+		"sqlite/README.md":                     {Data: []byte("ignored")},
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	ms, err := database.LoadMigrations(testMigrations(), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(ms) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(ms))
+	}
+	for i, m := range ms {
+		if m.Version != uint64(i+1) {
+			t.Fatalf("Expected migrations in version order, got %d at %d", m.Version, i)
+		}
+	}
+	if ms[2].Down != "" {
+		t.Fatalf("Expected migration 3 to have no down file")
+	}
+
+	fsys := testMigrations()
+	fsys["sqlite/0004_orphan.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err = database.LoadMigrations(fsys, "sqlite"); !errors.Is(err, database.ErrMissingUpMigration) {
+		t.Fatalf("Expected a missing up migration error, got %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	db := testDatabase(t)
+	fsys := testMigrations()
+
+	steps, err := database.Plan(db, fsys, database.LatestVersion)
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+	if len(steps) != 3 || steps[0].Direction != database.DirectionUp {
+		t.Fatalf("Expected 3 pending up migrations, got %v", steps)
+	}
+
+	if err = database.Migrate(l, db, fsys, 2); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if v, _ := database.CurrentVersion(db); v != 2 {
+		t.Fatalf("Expected version 2, got %d", v)
+	}
+	if !db.Migrator().HasColumn("widgets", "name") || db.Migrator().HasTable("gadgets") {
+		t.Fatalf("Expected only migrations up to version 2 to be applied")
+	}
+
+	// Migrating again is a no-op.
+	if err = database.Migrate(l, db, fsys, 2); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	if err = database.Migrate(l, db, fsys, database.LatestVersion); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if v, _ := database.CurrentVersion(db); v != 3 {
+		t.Fatalf("Expected version 3, got %d", v)
+	}
+
+	// Rolling back past a migration without a down file is refused, and changes nothing.
+	if err = database.Migrate(l, db, fsys, 1); !errors.Is(err, database.ErrIrreversible) {
+		t.Fatalf("Expected an irreversible migration error, got %v", err)
+	}
+	if v, _ := database.CurrentVersion(db); v != 3 {
+		t.Fatalf("Expected version 3, got %d", v)
+	}
+
+	fsys["sqlite/0003_create_gadgets.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE gadgets;")}
+	steps, err = database.Plan(db, fsys, 1)
+	if err != nil {
+		t.Fatalf("Failed to plan migration: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Version != 3 || steps[0].Direction != database.DirectionDown {
+		t.Fatalf("Expected rolling back versions 3 and 2, newest first, got %v", steps)
+	}
+	if err = database.Migrate(l, db, fsys, 1); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if v, _ := database.CurrentVersion(db); v != 1 {
+		t.Fatalf("Expected version 1, got %d", v)
+	}
+	if db.Migrator().HasColumn("widgets", "name") || db.Migrator().HasTable("gadgets") {
+		t.Fatalf("Expected migrations after version 1 to be rolled back")
+	}
+}
+
+func TestMigrate_ServiceMigrations(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	db := testDatabase(t)
+
+	if err := database.Migrate(l, db, migrations.FS, database.LatestVersion); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := database.Migrate(l, db, migrations.FS, 0); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if db.Migrator().HasTable("notes") {
+		t.Fatalf("Expected every migration to be rolled back")
+	}
+}
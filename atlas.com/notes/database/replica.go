@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ReplicaHealthTask = "database_replica_health_task"
+
+	replicaRouterName = "atlas:replica_router"
+)
+
+var ErrReplicaLagging = errors.New("replica is lagging behind the primary")
+
+// replica is a read-only copy of the primary, and whether it last passed its health check
+type replica struct {
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+// replicaRouter is a gorm plugin registered on the primary, which hands out healthy replicas for reads
+type replicaRouter struct {
+	replicas []*replica
+	next     atomic.Uint32
+	maxLag   time.Duration
+}
+
+// Name returns the plugin name
+func (r *replicaRouter) Name() string {
+	return replicaRouterName
+}
+
+// Initialize is called when the plugin is registered on the primary
+func (r *replicaRouter) Initialize(*gorm.DB) error {
+	return nil
+}
+
+// reader returns the next healthy replica in turn, or false if none are healthy
+func (r *replicaRouter) reader() (*gorm.DB, bool) {
+	n := uint32(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint32(0); i < n; i++ {
+		rep := r.replicas[(start+i)%n]
+		if rep.healthy.Load() {
+			return rep.db, true
+		}
+	}
+	return nil, false
+}
+
+// check marks each replica healthy if it answers a ping within the timeout, and, when a maximum lag is set, has
+// replayed the primary's changes recently enough
+func (r *replicaRouter) check(l logrus.FieldLogger, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for i, rep := range r.replicas {
+		wg.Add(1)
+		go func(i int, rep *replica) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			err := r.checkReplica(ctx, rep.db)
+			healthy := err == nil
+			if rep.healthy.Swap(healthy) != healthy {
+				if healthy {
+					l.Infof("Database replica [%d] is healthy, routing reads to it.", i)
+				} else {
+					l.WithError(err).Warnf("Database replica [%d] is unhealthy, routing its reads elsewhere.", i)
+				}
+			}
+		}(i, rep)
+	}
+	wg.Wait()
+}
+
+func (r *replicaRouter) checkReplica(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	err = sqlDB.PingContext(ctx)
+	if err != nil {
+		return err
+	}
+	if r.maxLag <= 0 {
+		return nil
+	}
+
+	var lag float64
+	err = db.WithContext(ctx).Raw("SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)").Scan(&lag).Error
+	if err != nil {
+		return err
+	}
+	if time.Duration(lag*float64(time.Second)) > r.maxLag {
+		return ErrReplicaLagging
+	}
+	return nil
+}
+
+// Reader returns a connection to route a read-only query to: a healthy replica when any are configured, otherwise
+// db itself. Transactions always read from themselves, so a read following a write sees it. Callers which must see
+// their own writes outside a transaction should read from db directly.
+func Reader(db *gorm.DB) *gorm.DB {
+	if isTransaction(db) {
+		return db
+	}
+	r, ok := db.Config.Plugins[replicaRouterName].(*replicaRouter)
+	if !ok {
+		return db
+	}
+	rdb, ok := r.reader()
+	if !ok {
+		return db
+	}
+	if db.Statement != nil && db.Statement.Context != nil {
+		return rdb.WithContext(db.Statement.Context)
+	}
+	return rdb
+}
+
+// ReplicaHealth periodically checks the replicas of a primary, so reads are routed only to those which are healthy
+type ReplicaHealth struct {
+	l        logrus.FieldLogger
+	db       *gorm.DB
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func NewReplicaHealthTask(l logrus.FieldLogger, db *gorm.DB, interval time.Duration) *ReplicaHealth {
+	return &ReplicaHealth{l: l, db: db, interval: interval, timeout: interval / 2}
+}
+
+func (t *ReplicaHealth) Run() {
+	r, ok := t.db.Config.Plugins[replicaRouterName].(*replicaRouter)
+	if !ok {
+		return
+	}
+	r.check(t.l.WithField("task", ReplicaHealthTask), t.timeout)
+}
+
+func (t *ReplicaHealth) SleepTime() time.Duration {
+	return t.interval
+}
+
+// useReplicas registers the replicas on the primary and checks their health, so only healthy replicas serve reads
+// from the start
+func useReplicas(l logrus.FieldLogger, primary *gorm.DB, replicas []*gorm.DB, maxLag time.Duration, timeout time.Duration) error {
+	r := &replicaRouter{maxLag: maxLag}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, &replica{db: db})
+	}
+	err := primary.Use(r)
+	if err != nil {
+		return err
+	}
+	r.check(l, timeout)
+	return nil
+}
+
+// Use registers a plugin on the primary and on each of its replicas, so queries routed to a replica are handled by the
+// plugin too
+func Use(primary *gorm.DB, p gorm.Plugin) error {
+	err := primary.Use(p)
+	if err != nil {
+		return err
+	}
+	r, ok := primary.Config.Plugins[replicaRouterName].(*replicaRouter)
+	if !ok {
+		return nil
+	}
+	for _, rep := range r.replicas {
+		err = rep.db.Use(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func testReplicaDatabase(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+name+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func TestReader(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	primary := testReplicaDatabase(t, "primary")
+	if Reader(primary) != primary {
+		t.Fatalf("Expected reads to stay on the primary without replicas")
+	}
+
+	r1 := testReplicaDatabase(t, "replica1")
+	r2 := testReplicaDatabase(t, "replica2")
+	if err := useReplicas(l, primary, []*gorm.DB{r1, r2}, 0, time.Second); err != nil {
+		t.Fatalf("Failed to register replicas: %v", err)
+	}
+
+	// Reads alternate between healthy replicas.
+	seen := map[gorm.ConnPool]bool{}
+	for i := 0; i < 4; i++ {
+		rdb := Reader(primary)
+		if rdb == primary {
+			t.Fatalf("Expected reads to be routed to a replica")
+		}
+		seen[rdb.Statement.ConnPool] = true
+	}
+	if len(seen) != 2 {
+		t.Fatalf("Expected reads to be spread across both replicas")
+	}
+
+	// Transactions read from themselves.
+	err := primary.Transaction(func(tx *gorm.DB) error {
+		if Reader(tx) != tx {
+			t.Fatalf("Expected reads in a transaction to stay in it")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+
+	// An unhealthy replica is skipped, and the primary serves reads once none are healthy.
+	sqlDB, _ := r1.DB()
+	_ = sqlDB.Close()
+	NewReplicaHealthTask(l, primary, time.Second).Run()
+	for i := 0; i < 4; i++ {
+		if Reader(primary).Statement.ConnPool != r2.Statement.ConnPool {
+			t.Fatalf("Expected reads to be routed to the healthy replica")
+		}
+	}
+
+	sqlDB, _ = r2.DB()
+	_ = sqlDB.Close()
+	NewReplicaHealthTask(l, primary, time.Second).Run()
+	if Reader(primary) != primary {
+		t.Fatalf("Expected reads to fall back to the primary")
+	}
+}
+
+// namedPlugin is a plugin recording the databases it is registered on
+type namedPlugin struct {
+	registered []*gorm.DB
+}
+
+func (p *namedPlugin) Name() string {
+	return "test"
+}
+
+func (p *namedPlugin) Initialize(db *gorm.DB) error {
+	p.registered = append(p.registered, db)
+	return nil
+}
+
+func TestUse(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	primary := testReplicaDatabase(t, "primary")
+	r1 := testReplicaDatabase(t, "replica1")
+	r2 := testReplicaDatabase(t, "replica2")
+	if err := useReplicas(l, primary, []*gorm.DB{r1, r2}, 0, time.Second); err != nil {
+		t.Fatalf("Failed to register replicas: %v", err)
+	}
+
+	p := &namedPlugin{}
+	if err := Use(primary, p); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+	if len(p.registered) != 3 || p.registered[0] != primary {
+		t.Fatalf("Expected the plugin to be registered on the primary and both replicas, got %d", len(p.registered))
+	}
+	if _, ok := Reader(primary).Config.Plugins[p.Name()]; !ok {
+		t.Fatalf("Expected reads routed to a replica to use the plugin")
+	}
+}
@@ -0,0 +1,152 @@
+package health
+
+import (
+	"atlas-notes/service"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	ErrTearingDown    = errors.New("service is shutting down")
+	ErrNoBrokers      = errors.New("no Kafka brokers configured")
+	ErrNotInitialized = errors.New("not initialized")
+	ErrGroupNotJoined = errors.New("consumer group not joined")
+)
+
+// Gate is a check which fails until it is opened, for a step of startup which must complete before the service is
+// ready, such as migrating the schema
+type Gate struct {
+	open atomic.Bool
+}
+
+// NewGate creates a closed Gate
+func NewGate() *Gate {
+	return &Gate{}
+}
+
+// Open marks the step the Gate stands for as complete
+func (g *Gate) Open() {
+	g.open.Store(true)
+}
+
+// Check returns a Check which passes once the Gate is opened
+func (g *Gate) Check() Check {
+	return func(ctx context.Context) error {
+		if !g.open.Load() {
+			return ErrNotInitialized
+		}
+		return nil
+	}
+}
+
+// Then returns a Check which passes once the Gate is opened and c passes, for a step whose completion is confirmed
+// only by what follows from it
+func (g *Gate) Then(c Check) Check {
+	return func(ctx context.Context) error {
+		if err := g.Check()(ctx); err != nil {
+			return err
+		}
+		return c(ctx)
+	}
+}
+
+// TeardownCheck returns a Check which fails as soon as the service begins tearing down, so no new traffic is routed
+// to it while it drains
+func TeardownCheck(m *service.Manager) Check {
+	return func(ctx context.Context) error {
+		if m.TearingDown() {
+			return ErrTearingDown
+		}
+		return nil
+	}
+}
+
+// DatabaseCheck returns a Check which pings the database
+func DatabaseCheck(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// ConsumerGroupCheck returns a Check which passes once each of the consumer groups has settled, with partitions
+// assigned to its members
+func ConsumerGroupCheck(brokers []string, groupIds ...string) Check {
+	return func(ctx context.Context) error {
+		addrs := brokerAddrs(brokers)
+		if len(addrs) == 0 {
+			return ErrNoBrokers
+		}
+		c := &kafka.Client{Addr: kafka.TCP(addrs...)}
+		resp, err := c.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: groupIds})
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, g := range resp.Groups {
+			if g.Error != nil {
+				errs = append(errs, fmt.Errorf("group [%s]: %w", g.GroupID, g.Error))
+				continue
+			}
+			if g.GroupState != "Stable" || !assigned(g.Members) {
+				errs = append(errs, fmt.Errorf("group [%s] is [%s]: %w", g.GroupID, g.GroupState, ErrGroupNotJoined))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// assigned returns true if any of the members of a consumer group has partitions assigned
+func assigned(members []kafka.DescribeGroupsResponseMember) bool {
+	for _, m := range members {
+		for _, t := range m.MemberAssignments.Topics {
+			if len(t.Partitions) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// brokerAddrs returns the addresses of the Kafka brokers, each entry possibly listing several
+func brokerAddrs(brokers []string) []string {
+	var addrs []string
+	for _, b := range brokers {
+		for _, addr := range strings.Split(b, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
+
+// BrokerCheck returns a Check which passes if any of the Kafka brokers accepts a connection
+func BrokerCheck(brokers []string) Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		var errs []error
+		for _, addr := range brokerAddrs(brokers) {
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("broker [%s]: %w", addr, err))
+				continue
+			}
+			_ = conn.Close()
+			return nil
+		}
+		if len(errs) == 0 {
+			return ErrNoBrokers
+		}
+		return errors.Join(errs...)
+	}
+}
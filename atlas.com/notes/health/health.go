@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "UP"
+	StatusDown = "DOWN"
+
+	checkTimeout = 2 * time.Second
+)
+
+// Check returns an error if what it checks is not healthy. It should give up once the context is done.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry holds the checks which decide whether the service is live, and whether it is ready to take traffic
+type Registry struct {
+	mutex     sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+var registry *Registry
+var registryOnce sync.Once
+
+// GetRegistry returns the Registry of the service
+func GetRegistry() *Registry {
+	registryOnce.Do(func() {
+		registry = NewRegistry()
+	})
+	return registry
+}
+
+// NewRegistry creates a Registry without any checks
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// AddLivenessCheck adds a check which, failing, means the service should be restarted
+func (r *Registry) AddLivenessCheck(name string, c Check) *Registry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.liveness = append(r.liveness, namedCheck{name: name, check: c})
+	return r
+}
+
+// AddReadinessCheck adds a check which, failing, means the service should be taken out of rotation
+func (r *Registry) AddReadinessCheck(name string, c Check) *Registry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.readiness = append(r.readiness, namedCheck{name: name, check: c})
+	return r
+}
+
+// Report is the outcome of running a set of checks
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Up returns true if every check passed
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+// Live runs the liveness checks
+func (r *Registry) Live(ctx context.Context) Report {
+	r.mutex.RLock()
+	cs := append([]namedCheck{}, r.liveness...)
+	r.mutex.RUnlock()
+	return run(ctx, cs)
+}
+
+// Ready runs the readiness checks
+func (r *Registry) Ready(ctx context.Context) Report {
+	r.mutex.RLock()
+	cs := append([]namedCheck{}, r.readiness...)
+	r.mutex.RUnlock()
+	return run(ctx, cs)
+}
+
+// run runs checks concurrently, each given checkTimeout to pass
+func run(ctx context.Context, cs []namedCheck) Report {
+	results := make([]CheckResult, len(cs))
+	var wg sync.WaitGroup
+	for i, c := range cs {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			results[i] = CheckResult{Name: c.name, Status: StatusUp}
+			if err := c.check(cctx); err != nil {
+				results[i].Status = StatusDown
+				results[i].Error = err.Error()
+			}
+		}(i, c)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	rep := Report{Status: StatusUp, Checks: results}
+	for _, res := range results {
+		if res.Status != StatusUp {
+			rep.Status = StatusDown
+		}
+	}
+	return rep
+}
@@ -0,0 +1,108 @@
+package health_test
+
+import (
+	"atlas-notes/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func probe(t *testing.T, h http.Handler, path string) (int, health.Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var rep health.Report
+	if err := json.NewDecoder(rec.Body).Decode(&rep); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	return rec.Code, rep
+}
+
+func TestHandler(t *testing.T) {
+	g := health.NewGate()
+	var failure error
+	r := health.NewRegistry().
+		AddReadinessCheck("gate", g.Check()).
+		AddReadinessCheck("dependency", func(ctx context.Context) error { return failure })
+	h := health.Handler(r)
+
+	if code, rep := probe(t, h, "/health/live"); code != http.StatusOK || !rep.Up() {
+		t.Fatalf("Expected service to be live, got [%d] %+v", code, rep)
+	}
+
+	code, rep := probe(t, h, "/health/ready")
+	if code != http.StatusServiceUnavailable || len(rep.Checks) != 2 {
+		t.Fatalf("Expected service not to be ready before the gate opens, got [%d] %+v", code, rep)
+	}
+	if rep.Checks[0].Name != "dependency" || rep.Checks[0].Status != health.StatusUp || rep.Checks[1].Status != health.StatusDown {
+		t.Fatalf("Expected only the gate to fail, got %+v", rep.Checks)
+	}
+
+	g.Open()
+	if code, rep = probe(t, h, "/health/ready"); code != http.StatusOK {
+		t.Fatalf("Expected service to be ready, got [%d] %+v", code, rep)
+	}
+
+	failure = errors.New("unavailable")
+	if code, rep = probe(t, h, "/health/ready"); code != http.StatusServiceUnavailable || rep.Checks[0].Error != "unavailable" {
+		t.Fatalf("Expected a failing dependency to make the service unready, got [%d] %+v", code, rep)
+	}
+	if code, _ = probe(t, h, "/health/live"); code != http.StatusOK {
+		t.Fatalf("Expected a failing dependency not to affect liveness, got [%d]", code)
+	}
+}
+
+func TestBrokerCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	if err = health.BrokerCheck([]string{"127.0.0.1:1," + addr})(context.Background()); err != nil {
+		t.Fatalf("Expected a reachable broker to pass, got %v", err)
+	}
+	_ = ln.Close()
+	if err = health.BrokerCheck([]string{addr})(context.Background()); err == nil {
+		t.Fatalf("Expected an unreachable broker to fail")
+	}
+	if err = health.BrokerCheck([]string{""})(context.Background()); !errors.Is(err, health.ErrNoBrokers) {
+		t.Fatalf("Expected no brokers to fail, got %v", err)
+	}
+}
+
+func TestConsumerGroupCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = health.ConsumerGroupCheck([]string{addr}, "group")(ctx); err == nil {
+		t.Fatalf("Expected an unreachable broker to fail")
+	}
+	if err = health.ConsumerGroupCheck([]string{""}, "group")(ctx); !errors.Is(err, health.ErrNoBrokers) {
+		t.Fatalf("Expected no brokers to fail, got %v", err)
+	}
+}
+
+func TestGate_Then(t *testing.T) {
+	g := health.NewGate()
+	failing := errors.New("failing")
+	c := g.Then(func(ctx context.Context) error { return failing })
+	if err := c(context.Background()); !errors.Is(err, health.ErrNotInitialized) {
+		t.Fatalf("Expected a closed gate to fail, got %v", err)
+	}
+	g.Open()
+	if err := c(context.Background()); !errors.Is(err, failing) {
+		t.Fatalf("Expected the check behind an open gate to apply, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	EnvPort = "HEALTH_PORT"
+
+	defaultPort = "8081"
+)
+
+// Handler returns the probe endpoints of the registry: /health/live and /health/ready. Each answers 200 when every one
+// of its checks passes and 503 otherwise, with a report of the checks.
+func Handler(r *Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", probeHandler(r.Live))
+	mux.HandleFunc("/health/ready", probeHandler(r.Ready))
+	return mux
+}
+
+func probeHandler(probe func(ctx context.Context) Report) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rep := probe(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if rep.Up() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(rep)
+	}
+}
+
+// Serve serves operational endpoints, such as the probes of Handler, on HEALTH_PORT until the context is cancelled.
+// They are kept apart from the API so they need no tenant. It is started before anything else, so the service is seen
+// to be live while it connects to its dependencies.
+func Serve(l logrus.FieldLogger, ctx context.Context, wg *sync.WaitGroup) func(h http.Handler) {
+	return func(h http.Handler) {
+		port := defaultPort
+		if val, ok := os.LookupEnv(EnvPort); ok {
+			port = val
+		}
+		srv := &http.Server{Addr: ":" + port, Handler: h, ReadHeaderTimeout: 5 * time.Second}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Infof("Serving health probes and metrics on port [%s].", port)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				l.WithError(err).Errorf("Health probe and metrics server stopped.")
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(sctx)
+		}()
+	}
+}
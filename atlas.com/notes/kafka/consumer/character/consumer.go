@@ -1,6 +1,7 @@
 package character
 
 import (
+	"atlas-notes/audit"
 	consumer2 "atlas-notes/kafka/consumer"
 	character2 "atlas-notes/kafka/message/character"
 	"atlas-notes/note"
@@ -28,6 +29,7 @@ func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic str
 			var t string
 			t, _ = topic.EnvProvider(l)(character2.EnvEventTopicCharacterStatus)()
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleCharacterDeleted(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleCharacterLogin(db))))
 		}
 	}
 }
@@ -37,6 +39,16 @@ func handleCharacterDeleted(db *gorm.DB) message.Handler[character2.StatusEvent[
 		if e.Type != character2.StatusEventTypeDeleted {
 			return
 		}
-		_ = note.NewProcessor(l, ctx, db).DeleteAllAndEmit(e.CharacterId)
+		actx := audit.WithActor(ctx, audit.NewActor(audit.ActorTypeKafka, character2.EnvEventTopicCharacterStatus+":"+e.Type))
+		_ = note.NewProcessor(l, actx, db).DeleteAllAndEmit(e.CharacterId)
+	}
+}
+
+func handleCharacterLogin(db *gorm.DB) message.Handler[character2.StatusEvent[character2.StatusEventLoginBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, e character2.StatusEvent[character2.StatusEventLoginBody]) {
+		if e.Type != character2.StatusEventTypeLogin {
+			return
+		}
+		_ = note.NewProcessor(l, ctx, db).NotifyPendingAndEmit(e.WorldId, e.Body.ChannelId, e.CharacterId)
 	}
 }
@@ -0,0 +1,50 @@
+package configuration
+
+import (
+	"atlas-notes/configuration"
+	consumer2 "atlas-notes/kafka/consumer"
+	configuration2 "atlas-notes/kafka/message/configuration"
+	"context"
+	"github.com/Chronicle20/atlas-kafka/consumer"
+	"github.com/Chronicle20/atlas-kafka/handler"
+	"github.com/Chronicle20/atlas-kafka/message"
+	"github.com/Chronicle20/atlas-kafka/topic"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+func InitConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+	return func(rf func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+		return func(consumerGroupId string) {
+			if !configuration.EventsEnabled() {
+				return
+			}
+			// Every instance caches configurations, so each consumes the events in a group of its own. A new group
+			// starts from the latest, as configurations are loaded afresh on start.
+			rf(consumer2.NewConfig(l)("configuration_status_event")(configuration2.EnvEventTopicConfigurationStatus)(consumer2.InstanceGroupId(consumerGroupId)), consumer.SetHeaderParsers(consumer.SpanHeaderParser), consumer.SetStartOffset(kafka.LastOffset))
+		}
+	}
+}
+
+func InitHandlers(l logrus.FieldLogger) func(rf func(topic string, handler handler.Handler) (string, error)) {
+	return func(rf func(topic string, handler handler.Handler) (string, error)) {
+		if !configuration.EventsEnabled() {
+			return
+		}
+		var t string
+		t, _ = topic.EnvProvider(l)(configuration2.EnvEventTopicConfigurationStatus)()
+		_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleConfigurationUpdated())))
+	}
+}
+
+// handleConfigurationUpdated drops the cached configuration of the tenant, so it is loaded afresh when next consulted
+func handleConfigurationUpdated() message.Handler[configuration2.StatusEvent] {
+	return func(l logrus.FieldLogger, ctx context.Context, e configuration2.StatusEvent) {
+		if e.Type != configuration2.StatusEventTypeUpdated {
+			return
+		}
+		l.Debugf("Configuration of tenant [%s] was updated.", e.TenantId.String())
+		configuration.Invalidate(e.TenantId)
+	}
+}
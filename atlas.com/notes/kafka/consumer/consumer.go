@@ -1,12 +1,18 @@
 package consumer
 
 import (
+	"errors"
+	"fmt"
 	"github.com/Chronicle20/atlas-kafka/consumer"
 	"github.com/Chronicle20/atlas-kafka/topic"
 	"github.com/sirupsen/logrus"
 	"os"
 )
 
+const EnvPodName = "POD_NAME"
+
+var ErrNoInstanceId = errors.New("instance has neither a pod name nor a hostname")
+
 func NewConfig(l logrus.FieldLogger) func(name string) func(token string) func(groupId string) consumer.Config {
 	return func(name string) func(token string) func(groupId string) consumer.Config {
 		return func(token string) func(groupId string) consumer.Config {
@@ -21,3 +27,26 @@ func NewConfig(l logrus.FieldLogger) func(name string) func(token string) func(g
 func LookupBrokers() []string {
 	return []string{os.Getenv("BOOTSTRAP_SERVERS")}
 }
+
+// InstanceId returns the identity of this instance, which it keeps across restarts: its pod name, or else its
+// hostname
+func InstanceId() (string, error) {
+	if name := os.Getenv(EnvPodName); name != "" {
+		return name, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNoInstanceId, err)
+	}
+	if hostname == "" {
+		return "", ErrNoInstanceId
+	}
+	return hostname, nil
+}
+
+// InstanceGroupId returns the consumer group of this instance, for events every instance must see rather than share.
+// A restarted instance rejoins its group. The service refuses to start without an InstanceId, so one is always found.
+func InstanceGroupId(consumerGroupId string) string {
+	id, _ := InstanceId()
+	return consumerGroupId + " " + id
+}
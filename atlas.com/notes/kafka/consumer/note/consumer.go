@@ -1,9 +1,14 @@
 package note
 
 import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/auth"
 	consumer2 "atlas-notes/kafka/consumer"
 	note2 "atlas-notes/kafka/message/note"
+	"atlas-notes/metrics"
 	"atlas-notes/note"
+	"atlas-notes/purge"
 	"context"
 	"github.com/Chronicle20/atlas-kafka/consumer"
 	"github.com/Chronicle20/atlas-kafka/handler"
@@ -17,7 +22,7 @@ import (
 func InitConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
 	return func(rf func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
 		return func(consumerGroupId string) {
-			rf(consumer2.NewConfig(l)("note_command")(note2.EnvCommandTopic)(consumerGroupId), consumer.SetHeaderParsers(consumer.SpanHeaderParser, consumer.TenantHeaderParser))
+			rf(consumer2.NewConfig(l)("note_command")(note2.EnvCommandTopic)(consumerGroupId), consumer.SetHeaderParsers(consumer.SpanHeaderParser, consumer.TenantHeaderParser, auth.ActingCharacterHeaderParser))
 		}
 	}
 }
@@ -29,6 +34,15 @@ func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic str
 			t, _ = topic.EnvProvider(l)(note2.EnvCommandTopic)()
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteCreate(db))))
 			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteDiscard(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteClaim(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteReply(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteStar(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNotePin(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteArchive(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteLabel(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteRestore(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNoteRead(db))))
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleNotePurge(db))))
 		}
 	}
 }
@@ -39,8 +53,30 @@ func handleNoteCreate(db *gorm.DB) message.Handler[note2.Command[note2.CommandCr
 			return
 		}
 
-		// Call the processor to create the note
-		_, _ = note.NewProcessor(l, ctx, db).CreateAndEmit(c.CharacterId, c.Body.SenderId, c.Body.Message, c.Body.Flag)
+		if len(c.Body.Attachments) == 0 {
+			// Call the processor to create the note
+			_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).CreateAndEmit(c.CharacterId, c.Body.SenderId, c.Body.Message, c.Body.Flag)
+			metrics.CommandHandled(c.Type, err)
+			if err != nil {
+				l.WithError(err).Errorf("Unable to create note for character [%d].", c.CharacterId)
+			}
+			return
+		}
+
+		as := make([]attachment.Model, 0, len(c.Body.Attachments))
+		for _, a := range c.Body.Attachments {
+			as = append(as, attachment.NewBuilder().
+				SetType(a.Type).
+				SetItemId(a.ItemId).
+				SetQuantity(a.Quantity).
+				SetMesos(a.Mesos).
+				Build())
+		}
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).CreateWithAttachmentsAndEmit(c.CharacterId, c.Body.SenderId, c.Body.Message, c.Body.Flag, as)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to create note with attachments for character [%d].", c.CharacterId)
+		}
 	}
 }
 
@@ -51,6 +87,141 @@ func handleNoteDiscard(db *gorm.DB) message.Handler[note2.Command[note2.CommandD
 		}
 
 		// Call the processor to discard the notes
-		_ = note.NewProcessor(l, ctx, db).DiscardAndEmit(c.CharacterId, c.Body.NoteIds)
+		err := note.NewProcessor(l, commandContext(ctx, c.Type), db).DiscardAndEmit(c.CharacterId, c.Body.NoteIds, c.Body.Force)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to discard notes for character [%d].", c.CharacterId)
+		}
+	}
+}
+
+func handleNoteClaim(db *gorm.DB) message.Handler[note2.Command[note2.CommandClaimBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandClaimBody]) {
+		if c.Type != note2.CommandTypeClaim {
+			return
+		}
+
+		err := note.NewProcessor(l, commandContext(ctx, c.Type), db).ClaimAndEmit(c.CharacterId, c.Body.NoteId)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to claim attachments of note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNoteReply(db *gorm.DB) message.Handler[note2.Command[note2.CommandReplyBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandReplyBody]) {
+		if c.Type != note2.CommandTypeReply {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).ReplyAndEmit(c.CharacterId, c.Body.NoteId, c.Body.Message, c.Body.Flag)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to reply to note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNoteStar(db *gorm.DB) message.Handler[note2.Command[note2.CommandStarBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandStarBody]) {
+		if c.Type != note2.CommandTypeStar {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).StarAndEmit(c.CharacterId, c.Body.NoteId, c.Body.Starred)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to star note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
 	}
 }
+
+func handleNotePin(db *gorm.DB) message.Handler[note2.Command[note2.CommandPinBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandPinBody]) {
+		if c.Type != note2.CommandTypePin {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).PinAndEmit(c.CharacterId, c.Body.NoteId, c.Body.Pinned)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to pin note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNoteArchive(db *gorm.DB) message.Handler[note2.Command[note2.CommandArchiveBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandArchiveBody]) {
+		if c.Type != note2.CommandTypeArchive {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).ArchiveAndEmit(c.CharacterId, c.Body.NoteId, c.Body.Archived)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to archive note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNoteLabel(db *gorm.DB) message.Handler[note2.Command[note2.CommandLabelBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandLabelBody]) {
+		if c.Type != note2.CommandTypeLabel {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).LabelAndEmit(c.CharacterId, c.Body.NoteId, c.Body.Labels)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to label note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNoteRestore(db *gorm.DB) message.Handler[note2.Command[note2.CommandRestoreBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandRestoreBody]) {
+		if c.Type != note2.CommandTypeRestore {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).RestoreAndEmit(c.CharacterId, c.Body.NoteId)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to restore note [%d] for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNoteRead(db *gorm.DB) message.Handler[note2.Command[note2.CommandReadBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandReadBody]) {
+		if c.Type != note2.CommandTypeRead {
+			return
+		}
+
+		_, err := note.NewProcessor(l, commandContext(ctx, c.Type), db).MarkReadAndEmit(c.CharacterId, c.Body.NoteId)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to mark note [%d] read for character [%d].", c.Body.NoteId, c.CharacterId)
+		}
+	}
+}
+
+func handleNotePurge(db *gorm.DB) message.Handler[note2.Command[note2.CommandPurgeBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, c note2.Command[note2.CommandPurgeBody]) {
+		if c.Type != note2.CommandTypePurge {
+			return
+		}
+
+		_, err := purge.NewProcessor(l, commandContext(ctx, c.Type), db).Start(c.Body.BatchSize, !c.Body.SkipEvents)
+		metrics.CommandHandled(c.Type, err)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to start purge of notes.")
+		}
+	}
+}
+
+// commandContext attributes the changes a command makes to the command
+func commandContext(ctx context.Context, commandType string) context.Context {
+	return audit.WithActor(ctx, audit.NewActor(audit.ActorTypeKafka, note2.EnvCommandTopic+":"+commandType))
+}
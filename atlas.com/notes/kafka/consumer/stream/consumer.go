@@ -0,0 +1,54 @@
+package stream
+
+import (
+	consumer2 "atlas-notes/kafka/consumer"
+	note2 "atlas-notes/kafka/message/note"
+	"atlas-notes/stream"
+	"context"
+	"encoding/json"
+	"github.com/Chronicle20/atlas-kafka/consumer"
+	"github.com/Chronicle20/atlas-kafka/handler"
+	"github.com/Chronicle20/atlas-kafka/message"
+	"github.com/Chronicle20/atlas-kafka/topic"
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+func InitConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+	return func(rf func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+		return func(consumerGroupId string) {
+			// Every instance streams to the clients connected to it, so each consumes the events in a group of its own.
+			// Only live events matter, so a new group starts from the latest.
+			rf(consumer2.NewConfig(l)("note_status_event")(note2.EnvEventTopicNoteStatus)(consumer2.InstanceGroupId(consumerGroupId)), consumer.SetHeaderParsers(consumer.SpanHeaderParser, consumer.TenantHeaderParser), consumer.SetStartOffset(kafka.LastOffset))
+		}
+	}
+}
+
+func InitHandlers(l logrus.FieldLogger) func(h *stream.Hub) func(rf func(topic string, handler handler.Handler) (string, error)) {
+	return func(h *stream.Hub) func(rf func(topic string, handler handler.Handler) (string, error)) {
+		return func(rf func(topic string, handler handler.Handler) (string, error)) {
+			var t string
+			t, _ = topic.EnvProvider(l)(note2.EnvEventTopicNoteStatus)()
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleStatusEvent(h))))
+		}
+	}
+}
+
+// handleStatusEvent publishes a note status event to the streams of the tenant it was produced for
+func handleStatusEvent(h *stream.Hub) message.Handler[note2.StatusEvent[json.RawMessage]] {
+	return func(l logrus.FieldLogger, ctx context.Context, e note2.StatusEvent[json.RawMessage]) {
+		t, err := tenant.FromContext(ctx)()
+		if err != nil {
+			l.WithError(err).Warnf("Unable to stream [%s] event for character [%d] without a tenant.", e.Type, e.CharacterId)
+			return
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to stream [%s] event for character [%d].", e.Type, e.CharacterId)
+			return
+		}
+		h.Publish(t.Id(), e.CharacterId, e.Type, data)
+	}
+}
@@ -0,0 +1,68 @@
+package tenant
+
+import (
+	consumer2 "atlas-notes/kafka/consumer"
+	tenant2 "atlas-notes/kafka/message/tenant"
+	"atlas-notes/purge"
+	"context"
+	"github.com/Chronicle20/atlas-kafka/consumer"
+	"github.com/Chronicle20/atlas-kafka/handler"
+	"github.com/Chronicle20/atlas-kafka/message"
+	"github.com/Chronicle20/atlas-kafka/topic"
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"os"
+)
+
+// Enabled returns true if a tenant status topic is configured. Tenant lifecycle events are optional, so without one
+// the consumer is not registered.
+func Enabled() bool {
+	_, ok := os.LookupEnv(tenant2.EnvEventTopicTenantStatus)
+	return ok
+}
+
+func InitConsumers(l logrus.FieldLogger) func(func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+	return func(rf func(config consumer.Config, decorators ...model.Decorator[consumer.Config])) func(consumerGroupId string) {
+		return func(consumerGroupId string) {
+			if !Enabled() {
+				return
+			}
+			// The tenant an event concerns is carried in its body rather than its headers.
+			rf(consumer2.NewConfig(l)("tenant_status_event")(tenant2.EnvEventTopicTenantStatus)(consumerGroupId), consumer.SetHeaderParsers(consumer.SpanHeaderParser))
+		}
+	}
+}
+
+func InitHandlers(l logrus.FieldLogger) func(db *gorm.DB) func(rf func(topic string, handler handler.Handler) (string, error)) {
+	return func(db *gorm.DB) func(rf func(topic string, handler handler.Handler) (string, error)) {
+		return func(rf func(topic string, handler handler.Handler) (string, error)) {
+			if !Enabled() {
+				return
+			}
+			var t string
+			t, _ = topic.EnvProvider(l)(tenant2.EnvEventTopicTenantStatus)()
+			_, _ = rf(t, message.AdaptHandler(message.PersistentConfig(handleTenantDeleted(db))))
+		}
+	}
+}
+
+func handleTenantDeleted(db *gorm.DB) message.Handler[tenant2.StatusEvent[tenant2.StatusEventDeletedBody]] {
+	return func(l logrus.FieldLogger, ctx context.Context, e tenant2.StatusEvent[tenant2.StatusEventDeletedBody]) {
+		if e.Type != tenant2.StatusEventTypeDeleted {
+			return
+		}
+
+		t, err := tenant.Create(e.TenantId, e.Body.Region, e.Body.MajorVersion, e.Body.MinorVersion)
+		if err != nil {
+			l.WithError(err).Errorf("Unable to identify deleted tenant [%s].", e.TenantId.String())
+			return
+		}
+		tl := l.WithField("tenant", t.Id().String())
+		_, err = purge.NewProcessor(tl, tenant.WithContext(ctx, t), db).Start(0, false)
+		if err != nil {
+			tl.WithError(err).Errorf("Unable to start purge of notes for deleted tenant.")
+		}
+	}
+}
@@ -3,6 +3,12 @@ package character
 const (
 	EnvEventTopicCharacterStatus = "EVENT_TOPIC_CHARACTER_STATUS"
 	StatusEventTypeDeleted       = "DELETED"
+	StatusEventTypeLogin         = "LOGIN"
+
+	EnvCommandTopic          = "COMMAND_TOPIC_CHARACTER"
+	CommandRequestChangeMeso = "REQUEST_CHANGE_MESO"
+
+	ActorTypeNote = "NOTE"
 )
 
 type StatusEvent[E any] struct {
@@ -14,3 +20,23 @@ type StatusEvent[E any] struct {
 
 type StatusEventDeletedBody struct {
 }
+
+type StatusEventLoginBody struct {
+	ChannelId byte   `json:"channelId"`
+	MapId     uint32 `json:"mapId"`
+}
+
+// Command represents a Kafka command for character operations
+type Command[E any] struct {
+	WorldId     byte   `json:"worldId"`
+	CharacterId uint32 `json:"characterId"`
+	Type        string `json:"type"`
+	Body        E      `json:"body"`
+}
+
+// RequestChangeMesoBody contains data for changing a character's meso amount
+type RequestChangeMesoBody struct {
+	ActorId   uint32 `json:"actorId"`
+	ActorType string `json:"actorType"`
+	Amount    int32  `json:"amount"`
+}
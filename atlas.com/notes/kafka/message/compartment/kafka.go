@@ -0,0 +1,20 @@
+package compartment
+
+const (
+	EnvCommandTopic    = "COMMAND_TOPIC_COMPARTMENT"
+	CommandCreateAsset = "CREATE_ASSET"
+)
+
+// Command represents a Kafka command for inventory compartment operations
+type Command[E any] struct {
+	CharacterId   uint32 `json:"characterId"`
+	InventoryType byte   `json:"inventoryType"`
+	Type          string `json:"type"`
+	Body          E      `json:"body"`
+}
+
+// CreateAssetCommandBody contains data for creating an asset in a compartment
+type CreateAssetCommandBody struct {
+	TemplateId uint32 `json:"templateId"`
+	Quantity   uint32 `json:"quantity"`
+}
@@ -0,0 +1,14 @@
+package configuration
+
+import "github.com/google/uuid"
+
+const (
+	EnvEventTopicConfigurationStatus = "EVENT_TOPIC_CONFIGURATION_STATUS"
+	StatusEventTypeUpdated           = "UPDATED"
+)
+
+// StatusEvent represents a Kafka status event for changes to a tenant's configuration
+type StatusEvent struct {
+	TenantId uuid.UUID `json:"tenantId"`
+	Type     string    `json:"type"`
+}
@@ -2,6 +2,7 @@ package message
 
 import (
 	"atlas-notes/kafka/producer"
+	"atlas-notes/metrics"
 	"sync"
 
 	"github.com/Chronicle20/atlas-model/model"
@@ -41,6 +42,17 @@ func (b *Buffer) GetAll() map[string][]kafka.Message {
 	return result
 }
 
+// produce produces the messages buffered for a topic, recording the outcome
+func produce(p producer.Provider, t string, ms []kafka.Message) error {
+	err := p(t)(model.FixedProvider(ms))
+	if err != nil {
+		metrics.EmitFailed(t)
+		return err
+	}
+	metrics.Produced(t, len(ms))
+	return nil
+}
+
 func Emit(p producer.Provider) func(f func(buf *Buffer) error) error {
 	return func(f func(buf *Buffer) error) error {
 		b := NewBuffer()
@@ -49,7 +61,7 @@ func Emit(p producer.Provider) func(f func(buf *Buffer) error) error {
 			return err
 		}
 		for t, ms := range b.GetAll() {
-			err = p(t)(model.FixedProvider(ms))
+			err = produce(p, t, ms)
 			if err != nil {
 				return err
 			}
@@ -67,7 +79,7 @@ func EmitWithResult[M any, B any](p producer.Provider) func(func(*Buffer) func(B
 				return result, err
 			}
 			for t, ms := range buf.GetAll() {
-				if err = p(t)(model.FixedProvider(ms)); err != nil {
+				if err = produce(p, t, ms); err != nil {
 					return result, err
 				}
 			}
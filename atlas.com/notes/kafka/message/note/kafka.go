@@ -8,10 +8,26 @@ const (
 
 	CommandTypeCreate  = "CREATE"
 	CommandTypeDiscard = "DISCARD"
+	CommandTypeClaim   = "CLAIM"
+	CommandTypeReply   = "REPLY"
+	CommandTypeStar    = "STAR"
+	CommandTypePin     = "PIN"
+	CommandTypeArchive = "ARCHIVE"
+	CommandTypeLabel   = "LABEL"
+	CommandTypeRestore = "RESTORE"
+	CommandTypeRead    = "READ"
+	CommandTypePurge   = "PURGE"
 
-	StatusEventTypeCreated = "CREATED"
-	StatusEventTypeUpdated = "UPDATED"
-	StatusEventTypeDeleted = "DELETED"
+	StatusEventTypeCreated   = "CREATED"
+	StatusEventTypeUpdated   = "UPDATED"
+	StatusEventTypeDeleted   = "DELETED"
+	StatusEventTypeClaimed   = "CLAIMED"
+	StatusEventTypeOrganized = "ORGANIZED"
+	StatusEventTypeRestored  = "RESTORED"
+	StatusEventTypeRead      = "READ"
+	StatusEventTypePurged    = "PURGED"
+	StatusEventTypeSummary   = "SUMMARY_CHANGED"
+	StatusEventTypePending   = "PENDING"
 )
 
 // Command represents a Kafka command for note operations
@@ -23,14 +39,76 @@ type Command[E any] struct {
 
 // CommandCreateBody contains data for creating a note
 type CommandCreateBody struct {
-	SenderId uint32 `json:"senderId"`
-	Message  string `json:"message"`
-	Flag     byte   `json:"flag"`
+	SenderId    uint32                  `json:"senderId"`
+	Message     string                  `json:"message"`
+	Flag        byte                    `json:"flag"`
+	Attachments []CommandAttachmentBody `json:"attachments,omitempty"`
+}
+
+// CommandAttachmentBody contains data for an item or meso amount carried by a created note
+type CommandAttachmentBody struct {
+	Type     string `json:"type"`
+	ItemId   uint32 `json:"itemId,omitempty"`
+	Quantity uint32 `json:"quantity,omitempty"`
+	Mesos    uint32 `json:"mesos,omitempty"`
 }
 
 // CommandDiscardBody contains data for discarding notes
 type CommandDiscardBody struct {
 	NoteIds []uint32 `json:"noteIds"`
+	Force   bool     `json:"force,omitempty"`
+}
+
+// CommandClaimBody contains data for claiming the attachments carried by a note
+type CommandClaimBody struct {
+	NoteId uint32 `json:"noteId"`
+}
+
+// CommandReplyBody contains data for replying to a note
+type CommandReplyBody struct {
+	NoteId  uint32 `json:"noteId"`
+	Message string `json:"message"`
+	Flag    byte   `json:"flag"`
+}
+
+// CommandStarBody contains data for starring or unstarring a note
+type CommandStarBody struct {
+	NoteId  uint32 `json:"noteId"`
+	Starred bool   `json:"starred"`
+}
+
+// CommandPinBody contains data for pinning or unpinning a note
+type CommandPinBody struct {
+	NoteId uint32 `json:"noteId"`
+	Pinned bool   `json:"pinned"`
+}
+
+// CommandArchiveBody contains data for archiving or unarchiving a note
+type CommandArchiveBody struct {
+	NoteId   uint32 `json:"noteId"`
+	Archived bool   `json:"archived"`
+}
+
+// CommandLabelBody contains data for replacing the labels a note is filed under
+type CommandLabelBody struct {
+	NoteId uint32   `json:"noteId"`
+	Labels []string `json:"labels"`
+}
+
+// CommandRestoreBody contains data for restoring a deleted note
+type CommandRestoreBody struct {
+	NoteId uint32 `json:"noteId"`
+}
+
+// CommandReadBody contains data for marking a note read by its recipient
+type CommandReadBody struct {
+	NoteId uint32 `json:"noteId"`
+}
+
+// CommandPurgeBody contains data for permanently removing every note in the tenant
+type CommandPurgeBody struct {
+	BatchSize  int  `json:"batchSize,omitempty"`
+	SkipEvents bool `json:"skipEvents,omitempty"`
 }
 
 // StatusEvent represents a Kafka status event for note operations
@@ -62,3 +140,56 @@ type StatusEventUpdatedBody struct {
 type StatusEventDeletedBody struct {
 	NoteId uint32 `json:"noteId"`
 }
+
+// StatusEventRestoredBody contains data for a note restored event
+type StatusEventRestoredBody struct {
+	NoteId uint32 `json:"noteId"`
+}
+
+// StatusEventReadBody contains data for a note read event
+type StatusEventReadBody struct {
+	NoteId uint32    `json:"noteId"`
+	ReadAt time.Time `json:"readAt"`
+}
+
+// StatusEventClaimedBody contains data for a note attachments claimed event
+type StatusEventClaimedBody struct {
+	NoteId        uint32   `json:"noteId"`
+	AttachmentIds []uint32 `json:"attachmentIds"`
+}
+
+// StatusEventOrganizedBody contains data for a note organization changed event
+type StatusEventOrganizedBody struct {
+	NoteId   uint32   `json:"noteId"`
+	Starred  bool     `json:"starred"`
+	Pinned   bool     `json:"pinned"`
+	Archived bool     `json:"archived"`
+	Labels   []string `json:"labels"`
+}
+
+// StatusEventPurgedBody contains data for a tenant's notes purged event
+type StatusEventPurgedBody struct {
+	Purged int64 `json:"purged"`
+}
+
+// StatusEventSummaryBody contains data for a character's note summary changed event
+type StatusEventSummaryBody struct {
+	Total  int64                        `json:"total"`
+	Unread int64                        `json:"unread"`
+	Kinds  []StatusEventSummaryKindBody `json:"kinds"`
+}
+
+// StatusEventSummaryKindBody contains the counts of the notes of a kind in a summary changed event
+type StatusEventSummaryKindBody struct {
+	Flag   byte  `json:"flag"`
+	Total  int64 `json:"total"`
+	Unread int64 `json:"unread"`
+}
+
+// StatusEventPendingBody contains data for a character logging in with unread notes, naming the channel it logged in to
+type StatusEventPendingBody struct {
+	WorldId   byte     `json:"worldId"`
+	ChannelId byte     `json:"channelId"`
+	Count     int      `json:"count"`
+	SenderIds []uint32 `json:"senderIds"`
+}
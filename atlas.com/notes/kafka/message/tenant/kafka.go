@@ -0,0 +1,22 @@
+package tenant
+
+import "github.com/google/uuid"
+
+const (
+	EnvEventTopicTenantStatus = "EVENT_TOPIC_TENANT_STATUS"
+	StatusEventTypeDeleted    = "DELETED"
+)
+
+// StatusEvent represents a Kafka status event for tenant lifecycle changes
+type StatusEvent[E any] struct {
+	TenantId uuid.UUID `json:"tenantId"`
+	Type     string    `json:"type"`
+	Body     E         `json:"body"`
+}
+
+// StatusEventDeletedBody contains data for a tenant deleted event, identifying the tenant's game version
+type StatusEventDeletedBody struct {
+	Region       string `json:"region"`
+	MajorVersion uint16 `json:"majorVersion"`
+	MinorVersion uint16 `json:"minorVersion"`
+}
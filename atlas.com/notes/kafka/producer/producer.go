@@ -1,20 +1,88 @@
 package producer
 
 import (
+	"atlas-notes/tracing"
 	"context"
 	"github.com/Chronicle20/atlas-kafka/producer"
 	"github.com/Chronicle20/atlas-kafka/topic"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
 )
 
 type Provider func(token string) producer.MessageProducer
 
+// publishes counts the publishes in progress, so teardown can wait for them to be written
+type publishes struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+var inFlight = &publishes{}
+
+func (p *publishes) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.n == 0 {
+		p.idle = make(chan struct{})
+	}
+	p.n++
+}
+
+func (p *publishes) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n--
+	if p.n == 0 {
+		close(p.idle)
+	}
+}
+
+// Flush blocks until every publish in progress has been written
+func Flush() {
+	inFlight.mu.Lock()
+	if inFlight.n == 0 {
+		inFlight.mu.Unlock()
+		return
+	}
+	idle := inFlight.idle
+	inFlight.mu.Unlock()
+	<-idle
+}
+
+// ProviderImpl produces messages to the topic a token names. Each publish is made within a span, a child of any in the
+// context, which the messages carry in their headers.
 func ProviderImpl(l logrus.FieldLogger) func(ctx context.Context) func(token string) producer.MessageProducer {
 	return func(ctx context.Context) func(token string) producer.MessageProducer {
-		sd := producer.SpanHeaderDecorator(ctx)
 		td := producer.TenantHeaderDecorator(ctx)
 		return func(token string) producer.MessageProducer {
-			return producer.Produce(l)(producer.WriterProvider(topic.EnvProvider(l)(token)))(sd, td)
+			tp := topic.EnvProvider(l)(token)
+			return func(mp model.Provider[[]kafka.Message]) error {
+				inFlight.start()
+				defer inFlight.done()
+				ms, err := mp()
+				if err != nil {
+					return err
+				}
+				name, _ := tp()
+				sctx, span := tracing.Tracer().Start(ctx, "publish "+name,
+					trace.WithSpanKind(trace.SpanKindProducer),
+					trace.WithAttributes(
+						semconv.MessagingSystemKafka,
+						semconv.MessagingOperationTypePublish,
+						semconv.MessagingDestinationName(name),
+						semconv.MessagingBatchMessageCount(len(ms)),
+					),
+				)
+				sd := producer.SpanHeaderDecorator(sctx)
+				err = producer.Produce(l)(producer.WriterProvider(tp))(sd, td)(model.FixedProvider(ms))
+				tracing.EndSpan(span, err)
+				return err
+			}
 		}
 	}
 }
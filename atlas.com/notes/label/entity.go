@@ -0,0 +1,42 @@
+package label
+
+import (
+	"github.com/google/uuid"
+	"strings"
+)
+
+// Entity represents a user-defined label on a note in the database
+type Entity struct {
+	ID       uint32 `gorm:"primaryKey;autoIncrement"`
+	TenantID uuid.UUID
+	NoteID   uint32
+	Name     string
+}
+
+// TableName specifies the database table name for Entity
+func (Entity) TableName() string {
+	return "note_labels"
+}
+
+// Make converts an Entity to its label name
+func Make(e Entity) (string, error) {
+	return e.Name, nil
+}
+
+// MakeEntities converts label names to Entities for a note, dropping blanks and duplicates
+func MakeEntities(tenantId uuid.UUID, noteId uint32, names []string) []Entity {
+	seen := make(map[string]struct{})
+	results := make([]Entity, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		results = append(results, Entity{TenantID: tenantId, NoteID: noteId, Name: name})
+	}
+	return results
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"atlas-notes/auth"
+	"atlas-notes/configuration"
 	"atlas-notes/database"
+	"atlas-notes/health"
+	kafka_consumer "atlas-notes/kafka/consumer"
 	"atlas-notes/kafka/consumer/character"
+	configuration_consumer "atlas-notes/kafka/consumer/configuration"
 	note_consumer "atlas-notes/kafka/consumer/note"
+	stream_consumer "atlas-notes/kafka/consumer/stream"
+	tenant_consumer "atlas-notes/kafka/consumer/tenant"
+	"atlas-notes/kafka/producer"
 	"atlas-notes/logger"
+	"atlas-notes/metrics"
+	"atlas-notes/migrations"
 	"atlas-notes/note"
+	"atlas-notes/purge"
 	"atlas-notes/service"
+	"atlas-notes/stream"
+	"atlas-notes/tasks"
+	"atlas-notes/thread"
 	"atlas-notes/tracing"
+	"errors"
 	"github.com/Chronicle20/atlas-kafka/consumer"
 	"github.com/Chronicle20/atlas-rest/server"
+	"gorm.io/gorm"
+	"net/http"
 	"os"
+	"time"
 )
 
 const serviceName = "atlas-notes"
@@ -40,32 +58,116 @@ func main() {
 	l := logger.CreateLogger(serviceName)
 	l.Infoln("Starting main service.")
 
+	// Instances consume some events in groups of their own, named after them
+	if _, err := kafka_consumer.InstanceId(); err != nil {
+		l.WithError(err).Fatal("Unable to identify instance.")
+	}
+
 	tdm := service.GetTeardownManager()
 
+	metrics.Use(metrics.NewRegistry())
+
+	// Serve health probes and metrics first, so the service is seen to be live while it connects to its dependencies
+	schema := health.NewGate()
+	// The consumers are ready once registered and each of their groups has assigned this instance partitions
+	consumers := health.NewGate()
+	joined := health.ConsumerGroupCheck(kafka_consumer.LookupBrokers(), consumerGroupId, kafka_consumer.InstanceGroupId(consumerGroupId))
+	hr := health.GetRegistry().
+		AddReadinessCheck("teardown", health.TeardownCheck(tdm)).
+		AddReadinessCheck("migrations", schema.Check()).
+		AddReadinessCheck("kafka.consumers", consumers.Then(joined)).
+		AddReadinessCheck("kafka.brokers", health.BrokerCheck(kafka_consumer.LookupBrokers()))
+	mux := http.NewServeMux()
+	mux.Handle("/health/", health.Handler(hr))
+	mux.Handle("/metrics", metrics.Handler())
+	health.Serve(l, tdm.PhaseContext(service.PhaseProbes), tdm.PhaseWaitGroup(service.PhaseProbes, "health"))(mux)
+
 	tc, err := tracing.InitTracer(l)(serviceName)
 	if err != nil {
 		l.WithError(err).Fatal("Unable to initialize tracer.")
 	}
+	tdm.TeardownFunc(service.PhaseTracer, "tracer", tracing.Teardown(l)(tc))
+
+	// Background tasks stop with the consumers, as both emit messages and use the database
+	tctx := tdm.PhaseContext(service.PhaseConsumers)
+	twg := tdm.PhaseWaitGroup(service.PhaseConsumers, "tasks")
 
-	// Connect to the database
-	db := database.Connect(l, database.SetMigrations(note.Migration))
+	// Connect to the database, unless notes are to be held in memory
+	var db *gorm.DB
+	if database.InMemory() {
+		l.Warnln("Running without a database. Notes are held in memory and lost on shutdown.")
+	} else {
+		db, err = database.Connect(l, database.SetMigrations(migrations.FS))
+		var dr *database.DryRunError
+		if errors.As(err, &dr) {
+			l.Infof("Schema is at version [%d]. [%d] pending migration(s).", dr.Current, len(dr.Steps))
+			for _, s := range dr.Steps {
+				l.Infof("Pending migration [%s].", s)
+			}
+			os.Exit(0)
+		}
+		if err != nil {
+			l.WithError(err).Fatal("Unable to connect to database.")
+		}
+		if err = database.Use(db, metrics.NewGormPlugin()); err != nil {
+			l.WithError(err).Fatal("Unable to instrument database.")
+		}
+		if err = database.Use(db, tracing.NewGormPlugin()); err != nil {
+			l.WithError(err).Fatal("Unable to trace database.")
+		}
+		tasks.Register(l, tctx, twg)(database.NewReplicaHealthTask(l, db, 10*time.Second))
+		hr.AddReadinessCheck("database", health.DatabaseCheck(db))
+		tdm.TeardownFunc(service.PhaseDatabase, "database", database.Teardown(l)(db))
+	}
+	schema.Open()
 
-	cmf := consumer.GetManager().AddConsumer(l, tdm.Context(), tdm.WaitGroup())
+	cmf := consumer.GetManager().AddConsumer(l, tdm.PhaseContext(service.PhaseConsumers), tdm.PhaseWaitGroup(service.PhaseConsumers, "kafka.consumers"))
 	character.InitConsumers(l)(cmf)(consumerGroupId)
 	note_consumer.InitConsumers(l)(cmf)(consumerGroupId)
+	tenant_consumer.InitConsumers(l)(cmf)(consumerGroupId)
+	configuration_consumer.InitConsumers(l)(cmf)(consumerGroupId)
+	stream_consumer.InitConsumers(l)(cmf)(consumerGroupId)
 	character.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
 	note_consumer.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
+	tenant_consumer.InitHandlers(l)(db)(consumer.GetManager().RegisterHandler)
+	configuration_consumer.InitHandlers(l)(consumer.GetManager().RegisterHandler)
+	stream_consumer.InitHandlers(l)(stream.GetHub())(consumer.GetManager().RegisterHandler)
+	consumers.Open()
+
+	tasks.Register(l, tctx, twg)(note.NewExpirationTask(l, db, time.Minute))
+	tasks.Register(l, tctx, twg)(purge.NewResumeTask(l, db, time.Minute))
+	tasks.Register(l, tctx, twg)(note.NewTenantMetricsTask(l, db, time.Minute))
+	tasks.Register(l, tctx, twg)(note.NewSummaryReconcileTask(l, db, 10*time.Minute))
+
+	// Authenticate REST requests, unless turned off for local development. A JWKS fetched from a URL is refreshed until
+	// the REST server stops.
+	if auth.Disabled() {
+		l.Warnln("Authentication is disabled. Every REST route is open to anyone who can reach the service.")
+	} else {
+		v, err := auth.NewVerifier(l)(tdm.PhaseContext(service.PhaseRest))
+		if err != nil {
+			l.WithError(err).Fatal("Unable to initialize token verifier.")
+		}
+		auth.Use(v)
+	}
+
+	// Streams are long-lived, so they are ended for the REST server to finish the requests in progress
+	tdm.TeardownFunc(service.PhaseRest, "stream", stream.GetHub().Close)
 
 	server.New(l).
-		WithContext(tdm.Context()).
-		WithWaitGroup(tdm.WaitGroup()).
+		WithContext(tdm.PhaseContext(service.PhaseRest)).
+		WithWaitGroup(tdm.PhaseWaitGroup(service.PhaseRest, "rest")).
 		SetBasePath(GetServer().GetPrefix()).
 		SetPort(os.Getenv("REST_PORT")).
+		AddRouteInitializer(purge.InitResource(GetServer())(db)).
+		AddRouteInitializer(configuration.InitResource(GetServer())(db)).
+		AddRouteInitializer(stream.InitResource(GetServer())(db)).
 		AddRouteInitializer(note.InitResource(GetServer())(db)).
+		AddRouteInitializer(thread.InitResource(GetServer())(db)).
 		Run()
 
-	tdm.TeardownFunc(tracing.Teardown(l)(tc))
+	tdm.TeardownFunc(service.PhaseProducers, "kafka.producers", producer.Flush)
 
-	tdm.Wait()
+	tdm.Wait(l)
 	l.Infoln("Service shutdown.")
 }
@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"sync"
+)
+
+const namespace = "notes"
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Registry is where collectors are registered and gathered from. Tests use a registry of their own, so they can
+// assert on what was recorded.
+type Registry interface {
+	prometheus.Registerer
+	prometheus.Gatherer
+}
+
+// Metrics holds the collectors of the service
+type Metrics struct {
+	registry Registry
+
+	RequestDuration *prometheus.HistogramVec
+	CommandsHandled *prometheus.CounterVec
+	EventsProduced  *prometheus.CounterVec
+	EmitFailures    *prometheus.CounterVec
+	QueryDuration   *prometheus.HistogramVec
+	NotesPerTenant  *prometheus.GaugeVec
+}
+
+var current *Metrics
+var mutex sync.RWMutex
+
+// NewRegistry creates a Registry holding the Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return r
+}
+
+// Use creates the collectors of the service in the registry, and records to them from then on
+func Use(r Registry) *Metrics {
+	m := newMetrics(r)
+	mutex.Lock()
+	defer mutex.Unlock()
+	current = m
+	return m
+}
+
+func newMetrics(r Registry) *Metrics {
+	m := &Metrics{
+		registry: r,
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of REST requests, by handler and response status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"handler", "method", "status"}),
+		CommandsHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kafka_commands_handled_total",
+			Help:      "Kafka commands handled, by command type and outcome.",
+		}, []string{"type", "outcome"}),
+		EventsProduced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kafka_events_produced_total",
+			Help:      "Kafka messages produced, by topic.",
+		}, []string{"topic"}),
+		EmitFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kafka_emit_failures_total",
+			Help:      "Failures to produce buffered Kafka messages, by topic.",
+		}, []string{"topic"}),
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Latency of database queries, by operation, table and outcome.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table", "outcome"}),
+		NotesPerTenant: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tenant_notes",
+			Help:      "Notes held, deleted notes aside, by tenant.",
+		}, []string{"tenant"}),
+	}
+	r.MustRegister(m.RequestDuration, m.CommandsHandled, m.EventsProduced, m.EmitFailures, m.QueryDuration, m.NotesPerTenant)
+	return m
+}
+
+// Get returns the collectors recorded to, creating them in a registry of their own if none were set up
+func Get() *Metrics {
+	mutex.RLock()
+	m := current
+	mutex.RUnlock()
+	if m != nil {
+		return m
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if current == nil {
+		current = newMetrics(prometheus.NewRegistry())
+	}
+	return current
+}
+
+// Handler serves the collectors recorded to in the Prometheus exposition format
+func Handler() http.Handler {
+	r := Get().registry
+	return promhttp.HandlerFor(r, promhttp.HandlerOpts{Registry: r})
+}
+
+// outcome returns the outcome label of an error
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// CommandHandled records the outcome of handling a Kafka command
+func CommandHandled(commandType string, err error) {
+	Get().CommandsHandled.WithLabelValues(commandType, outcome(err)).Inc()
+}
+
+// Produced records messages produced to the topic
+func Produced(topic string, count int) {
+	Get().EventsProduced.WithLabelValues(topic).Add(float64(count))
+}
+
+// EmitFailed records a failure to produce buffered messages to the topic
+func EmitFailed(topic string) {
+	Get().EmitFailures.WithLabelValues(topic).Inc()
+}
+
+// SetTenantNotes records how many notes a tenant holds
+func SetTenantNotes(tenantId string, count int64) {
+	Get().NotesPerTenant.WithLabelValues(tenantId).Set(float64(count))
+}
+
+// ClearTenantNotes forgets how many notes each tenant holds, for an instance which no longer reports them
+func ClearTenantNotes() {
+	Get().NotesPerTenant.Reset()
+}
@@ -0,0 +1,165 @@
+package metrics_test
+
+import (
+	"atlas-notes/kafka/message"
+	"atlas-notes/kafka/producer"
+	"atlas-notes/metrics"
+	"errors"
+	kproducer "github.com/Chronicle20/atlas-kafka/producer"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/segmentio/kafka-go"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// sampleCount returns how many observations a histogram of the registry holds for the labels
+func sampleCount(t *testing.T, r prometheus.Gatherer, name string, labels map[string]string) uint64 {
+	t.Helper()
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, mt := range mf.GetMetric() {
+			if matches(mt, labels) {
+				return mt.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func matches(mt *dto.Metric, labels map[string]string) bool {
+	for _, lp := range mt.GetLabel() {
+		if v, ok := labels[lp.GetName()]; ok && v != lp.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	r := prometheus.NewRegistry()
+	metrics.Use(r)
+
+	h := metrics.InstrumentHandler("get_note", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("missing") != "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	})
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/notes/1", nil))
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/notes/1", nil))
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/notes/1?missing=true", nil))
+
+	name := "notes_http_request_duration_seconds"
+	if c := sampleCount(t, r, name, map[string]string{"handler": "get_note", "method": "GET", "status": "200"}); c != 2 {
+		t.Fatalf("Expected 2 successful requests, got %d", c)
+	}
+	if c := sampleCount(t, r, name, map[string]string{"handler": "get_note", "method": "GET", "status": "404"}); c != 1 {
+		t.Fatalf("Expected 1 request not found, got %d", c)
+	}
+}
+
+func TestEmit(t *testing.T) {
+	m := metrics.Use(prometheus.NewRegistry())
+
+	failing := errors.New("unavailable")
+	p := producer.Provider(func(token string) kproducer.MessageProducer {
+		return func(mp model.Provider[[]kafka.Message]) error {
+			if token == "EVENT_TOPIC_FAILING" {
+				return failing
+			}
+			_, err := mp()
+			return err
+		}
+	})
+	messages := func(n int) model.Provider[[]kafka.Message] {
+		return model.FixedProvider(make([]kafka.Message, n))
+	}
+
+	err := message.Emit(p)(func(mb *message.Buffer) error {
+		return mb.Put("EVENT_TOPIC_NOTE_STATUS", messages(2))
+	})
+	if err != nil {
+		t.Fatalf("Failed to emit: %v", err)
+	}
+	err = message.Emit(p)(func(mb *message.Buffer) error {
+		return mb.Put("EVENT_TOPIC_FAILING", messages(1))
+	})
+	if !errors.Is(err, failing) {
+		t.Fatalf("Expected emit to fail, got %v", err)
+	}
+
+	if v := testutil.ToFloat64(m.EventsProduced.WithLabelValues("EVENT_TOPIC_NOTE_STATUS")); v != 2 {
+		t.Fatalf("Expected 2 events produced, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.EmitFailures.WithLabelValues("EVENT_TOPIC_FAILING")); v != 1 {
+		t.Fatalf("Expected 1 emit failure, got %v", v)
+	}
+	if c := testutil.CollectAndCount(m.EventsProduced); c != 1 {
+		t.Fatalf("Expected no events recorded for the failing topic, got %d series", c)
+	}
+}
+
+func TestCommandHandled(t *testing.T) {
+	m := metrics.Use(prometheus.NewRegistry())
+
+	metrics.CommandHandled("CREATE", nil)
+	metrics.CommandHandled("CREATE", nil)
+	metrics.CommandHandled("CREATE", errors.New("inbox full"))
+
+	if v := testutil.ToFloat64(m.CommandsHandled.WithLabelValues("CREATE", metrics.OutcomeSuccess)); v != 2 {
+		t.Fatalf("Expected 2 commands handled, got %v", v)
+	}
+	if v := testutil.ToFloat64(m.CommandsHandled.WithLabelValues("CREATE", metrics.OutcomeFailure)); v != 1 {
+		t.Fatalf("Expected 1 command failed, got %v", v)
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	r := prometheus.NewRegistry()
+	metrics.Use(r)
+
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err = db.Use(metrics.NewGormPlugin()); err != nil {
+		t.Fatalf("Failed to install plugin: %v", err)
+	}
+
+	type widget struct {
+		ID   uint32
+		Name string
+	}
+	if err = db.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error; err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	if err = db.Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatalf("Failed to create widget: %v", err)
+	}
+	var w widget
+	if err = db.Where("id = ?", 2).First(&w).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected no widget, got %v", err)
+	}
+
+	name := "notes_db_query_duration_seconds"
+	if c := sampleCount(t, r, name, map[string]string{"operation": "create", "table": "widgets", "outcome": metrics.OutcomeSuccess}); c != 1 {
+		t.Fatalf("Expected 1 insert recorded, got %d", c)
+	}
+	if c := sampleCount(t, r, name, map[string]string{"operation": "query", "table": "widgets", "outcome": metrics.OutcomeSuccess}); c != 1 {
+		t.Fatalf("Expected a query finding nothing to be recorded as a success, got %d", c)
+	}
+}
@@ -0,0 +1,8 @@
+// Package migrations holds the versioned SQL migrations for the service's schema, one directory per database dialect.
+// Files are named {version}_{name}.up.sql and {version}_{name}.down.sql, and are applied in version order.
+package migrations
+
+import "embed"
+
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
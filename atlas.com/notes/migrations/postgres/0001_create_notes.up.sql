@@ -0,0 +1,16 @@
+-- Matches the table previously created by gorm's AutoMigrate, so existing databases adopt it unchanged.
+CREATE TABLE IF NOT EXISTS notes
+(
+    id           BIGSERIAL PRIMARY KEY,
+    tenant_id    UUID,
+    character_id BIGINT,
+    sender_id    BIGINT,
+    message      TEXT,
+    timestamp    TIMESTAMPTZ,
+    flag         INTEGER,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes (deleted_at);
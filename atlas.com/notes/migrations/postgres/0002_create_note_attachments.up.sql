@@ -0,0 +1,19 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS expiration TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_notes_expiration ON notes (expiration);
+
+CREATE TABLE IF NOT EXISTS note_attachments
+(
+    id         BIGSERIAL PRIMARY KEY,
+    tenant_id  UUID,
+    note_id    BIGINT,
+    type       TEXT,
+    item_id    BIGINT,
+    quantity   BIGINT,
+    mesos      BIGINT,
+    status     TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_attachments_note_id ON note_attachments (note_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_notes_thread_id;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS thread_id;
+ALTER TABLE notes DROP COLUMN IF EXISTS in_reply_to;
@@ -0,0 +1,4 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS in_reply_to BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS thread_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_notes_thread_id ON notes (thread_id);
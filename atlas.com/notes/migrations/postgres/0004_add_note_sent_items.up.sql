@@ -0,0 +1,3 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS sender_hidden_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_sender_timestamp ON notes (tenant_id, sender_id, timestamp);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS note_labels;
+
+ALTER TABLE notes DROP COLUMN IF EXISTS archived;
+ALTER TABLE notes DROP COLUMN IF EXISTS pinned;
+ALTER TABLE notes DROP COLUMN IF EXISTS starred;
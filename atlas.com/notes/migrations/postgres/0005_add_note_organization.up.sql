@@ -0,0 +1,13 @@
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS starred BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS note_labels
+(
+    id        BIGSERIAL PRIMARY KEY,
+    tenant_id UUID,
+    note_id   BIGINT,
+    name      TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_note_labels_note_name ON note_labels (note_id, name);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_note_labels_tenant_name;
+DROP INDEX IF EXISTS idx_notes_tenant_expiration;
+DROP INDEX IF EXISTS idx_notes_tenant_sender_timestamp;
+DROP INDEX IF EXISTS idx_notes_tenant_character_deleted_at;
+DROP INDEX IF EXISTS idx_notes_tenant_character_timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_sender_timestamp ON notes (tenant_id, sender_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_notes_expiration ON notes (expiration);
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes (deleted_at);
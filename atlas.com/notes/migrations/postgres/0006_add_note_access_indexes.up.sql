@@ -0,0 +1,19 @@
+-- Indexes follow the queries in note/provider.go. Soft-deleted notes are excluded wherever the query excludes them.
+DROP INDEX IF EXISTS idx_notes_deleted_at;
+DROP INDEX IF EXISTS idx_notes_expiration;
+DROP INDEX IF EXISTS idx_notes_tenant_sender_timestamp;
+
+-- A character's inbox, its capacity count, and deleting all of a character's notes.
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_character_timestamp ON notes (tenant_id, character_id, timestamp) WHERE deleted_at IS NULL;
+
+-- The notes a character deleted and may restore.
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_character_deleted_at ON notes (tenant_id, character_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- A character's sent items. These include notes the recipient deleted, as the sender keeps their copy.
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_sender_timestamp ON notes (tenant_id, sender_id, timestamp) WHERE sender_hidden_at IS NULL;
+
+-- The expiration sweep.
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_expiration ON notes (tenant_id, expiration) WHERE expiration IS NOT NULL AND deleted_at IS NULL;
+
+-- Filtering a character's notes by label.
+CREATE INDEX IF NOT EXISTS idx_note_labels_tenant_name ON note_labels (tenant_id, name);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS note_purges
+(
+    tenant_id     UUID PRIMARY KEY,
+    region        TEXT,
+    major_version INTEGER,
+    minor_version INTEGER,
+    status        TEXT    NOT NULL,
+    batch_size    INTEGER NOT NULL,
+    emit_events   BOOLEAN NOT NULL DEFAULT FALSE,
+    total         BIGINT  NOT NULL DEFAULT 0,
+    purged        BIGINT  NOT NULL DEFAULT 0,
+    started_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ,
+    completed_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_purges_status_updated_at ON note_purges (status, updated_at);
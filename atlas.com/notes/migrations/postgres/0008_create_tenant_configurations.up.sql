@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tenant_configurations
+(
+    tenant_id                UUID PRIMARY KEY,
+    region                   TEXT,
+    major_version            INTEGER,
+    minor_version            INTEGER,
+    inbox_capacity           BIGINT,
+    max_message_length       INTEGER,
+    attachment_expiration_ms BIGINT,
+    restore_grace_period_ms  BIGINT,
+    send_rate_limit          INTEGER,
+    send_rate_window_ms      BIGINT,
+    allowed_flags            TEXT,
+    updated_at               TIMESTAMPTZ
+);
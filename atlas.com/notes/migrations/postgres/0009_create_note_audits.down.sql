@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS note_audits;
+DROP FUNCTION IF EXISTS note_audits_append_only();
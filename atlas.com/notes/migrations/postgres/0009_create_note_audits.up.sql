@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS note_audits
+(
+    id             BIGSERIAL PRIMARY KEY,
+    tenant_id      UUID        NOT NULL,
+    note_id        BIGINT      NOT NULL,
+    character_id   BIGINT      NOT NULL,
+    action         TEXT        NOT NULL,
+    actor_type     TEXT        NOT NULL,
+    actor_id       TEXT        NOT NULL,
+    transaction_id UUID        NOT NULL,
+    before         JSONB,
+    after          JSONB,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_audits_tenant_note ON note_audits (tenant_id, note_id, id);
+CREATE INDEX IF NOT EXISTS idx_note_audits_tenant_character ON note_audits (tenant_id, character_id, id);
+
+-- The audit trail is append-only
+CREATE OR REPLACE FUNCTION note_audits_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'note_audits is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER note_audits_append_only
+    BEFORE UPDATE OR DELETE
+    ON note_audits
+    FOR EACH ROW
+EXECUTE FUNCTION note_audits_append_only();
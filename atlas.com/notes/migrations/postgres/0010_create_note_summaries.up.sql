@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS note_summaries
+(
+    tenant_id    UUID        NOT NULL,
+    character_id BIGINT      NOT NULL,
+    flag         INTEGER     NOT NULL,
+    total        BIGINT      NOT NULL DEFAULT 0,
+    unread       BIGINT      NOT NULL DEFAULT 0,
+    updated_at   TIMESTAMPTZ,
+    PRIMARY KEY (tenant_id, character_id, flag)
+);
+
+-- Count the notes already held
+INSERT INTO note_summaries (tenant_id, character_id, flag, total, unread, updated_at)
+SELECT tenant_id, character_id, COALESCE(flag, 0), COUNT(*), COUNT(*) FILTER (WHERE NOT archived), NOW()
+FROM notes
+WHERE deleted_at IS NULL
+GROUP BY tenant_id, character_id, COALESCE(flag, 0)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS note_tenants
+(
+    tenant_id     UUID PRIMARY KEY,
+    region        TEXT    NOT NULL,
+    major_version INTEGER NOT NULL,
+    minor_version INTEGER NOT NULL,
+    updated_at    TIMESTAMPTZ
+);
+
+-- Record the tenants already known from their configurations and purges
+INSERT INTO note_tenants (tenant_id, region, major_version, minor_version, updated_at)
+SELECT tenant_id, region, major_version, minor_version, NOW()
+FROM tenant_configurations
+WHERE region IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO note_tenants (tenant_id, region, major_version, minor_version, updated_at)
+SELECT tenant_id, region, major_version, minor_version, NOW()
+FROM note_purges
+WHERE region IS NOT NULL
+ON CONFLICT DO NOTHING;
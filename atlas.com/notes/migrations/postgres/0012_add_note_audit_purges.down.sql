@@ -0,0 +1,8 @@
+CREATE OR REPLACE FUNCTION note_audits_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'note_audits is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS note_audit_purges;
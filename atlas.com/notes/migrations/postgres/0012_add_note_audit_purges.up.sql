@@ -0,0 +1,15 @@
+-- A tenant listed here is having its audit trail purged, which is the one way entries may be deleted
+CREATE TABLE IF NOT EXISTS note_audit_purges
+(
+    tenant_id UUID PRIMARY KEY
+);
+
+CREATE OR REPLACE FUNCTION note_audits_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF TG_OP = 'DELETE' AND EXISTS (SELECT 1 FROM note_audit_purges WHERE tenant_id = OLD.tenant_id) THEN
+        RETURN OLD;
+    END IF;
+    RAISE EXCEPTION 'note_audits is append-only';
+END;
+$$ LANGUAGE plpgsql;
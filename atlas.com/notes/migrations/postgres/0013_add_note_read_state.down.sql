@@ -0,0 +1,11 @@
+ALTER TABLE notes DROP COLUMN IF EXISTS read_at;
+
+UPDATE note_summaries
+SET unread     = (SELECT COUNT(*)
+                  FROM notes
+                  WHERE notes.tenant_id = note_summaries.tenant_id
+                    AND notes.character_id = note_summaries.character_id
+                    AND COALESCE(notes.flag, 0) = note_summaries.flag
+                    AND notes.deleted_at IS NULL
+                    AND NOT notes.archived),
+    updated_at = NOW();
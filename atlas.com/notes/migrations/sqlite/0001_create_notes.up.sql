@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS notes
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id    TEXT,
+    character_id INTEGER,
+    sender_id    INTEGER,
+    message      TEXT,
+    timestamp    DATETIME,
+    flag         INTEGER,
+    created_at   DATETIME,
+    updated_at   DATETIME,
+    deleted_at   DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_notes_deleted_at ON notes (deleted_at);
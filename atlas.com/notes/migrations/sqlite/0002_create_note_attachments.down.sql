@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS note_attachments;
+
+DROP INDEX IF EXISTS idx_notes_expiration;
+
+ALTER TABLE notes DROP COLUMN expiration;
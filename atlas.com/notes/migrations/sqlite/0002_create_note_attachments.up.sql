@@ -0,0 +1,19 @@
+ALTER TABLE notes ADD COLUMN expiration DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_notes_expiration ON notes (expiration);
+
+CREATE TABLE IF NOT EXISTS note_attachments
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id  TEXT,
+    note_id    INTEGER,
+    type       TEXT,
+    item_id    INTEGER,
+    quantity   INTEGER,
+    mesos      INTEGER,
+    status     TEXT,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_attachments_note_id ON note_attachments (note_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_notes_thread_id;
+
+ALTER TABLE notes DROP COLUMN thread_id;
+ALTER TABLE notes DROP COLUMN in_reply_to;
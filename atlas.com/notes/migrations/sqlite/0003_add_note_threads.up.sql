@@ -0,0 +1,4 @@
+ALTER TABLE notes ADD COLUMN in_reply_to INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE notes ADD COLUMN thread_id INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_notes_thread_id ON notes (thread_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notes_tenant_sender_timestamp;
+
+ALTER TABLE notes DROP COLUMN sender_hidden_at;
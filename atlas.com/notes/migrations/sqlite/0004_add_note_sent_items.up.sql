@@ -0,0 +1,3 @@
+ALTER TABLE notes ADD COLUMN sender_hidden_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_notes_tenant_sender_timestamp ON notes (tenant_id, sender_id, timestamp);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS note_labels;
+
+ALTER TABLE notes DROP COLUMN archived;
+ALTER TABLE notes DROP COLUMN pinned;
+ALTER TABLE notes DROP COLUMN starred;
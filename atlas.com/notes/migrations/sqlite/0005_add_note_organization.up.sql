@@ -0,0 +1,13 @@
+ALTER TABLE notes ADD COLUMN starred BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS note_labels
+(
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id TEXT,
+    note_id   INTEGER,
+    name      TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_note_labels_note_name ON note_labels (note_id, name);
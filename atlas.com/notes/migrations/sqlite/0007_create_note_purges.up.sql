@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS note_purges
+(
+    tenant_id     TEXT PRIMARY KEY,
+    region        TEXT,
+    major_version INTEGER,
+    minor_version INTEGER,
+    status        TEXT    NOT NULL,
+    batch_size    INTEGER NOT NULL,
+    emit_events   BOOLEAN NOT NULL DEFAULT FALSE,
+    total         INTEGER NOT NULL DEFAULT 0,
+    purged        INTEGER NOT NULL DEFAULT 0,
+    started_at    DATETIME,
+    updated_at    DATETIME,
+    completed_at  DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_purges_status_updated_at ON note_purges (status, updated_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tenant_configurations
+(
+    tenant_id                TEXT PRIMARY KEY,
+    region                   TEXT,
+    major_version            INTEGER,
+    minor_version            INTEGER,
+    inbox_capacity           INTEGER,
+    max_message_length       INTEGER,
+    attachment_expiration_ms INTEGER,
+    restore_grace_period_ms  INTEGER,
+    send_rate_limit          INTEGER,
+    send_rate_window_ms      INTEGER,
+    allowed_flags            TEXT,
+    updated_at               DATETIME
+);
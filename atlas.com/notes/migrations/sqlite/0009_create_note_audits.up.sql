@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS note_audits
+(
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id      TEXT     NOT NULL,
+    note_id        INTEGER  NOT NULL,
+    character_id   INTEGER  NOT NULL,
+    action         TEXT     NOT NULL,
+    actor_type     TEXT     NOT NULL,
+    actor_id       TEXT     NOT NULL,
+    transaction_id TEXT     NOT NULL,
+    before         TEXT,
+    after          TEXT,
+    created_at     DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_audits_tenant_note ON note_audits (tenant_id, note_id, id);
+CREATE INDEX IF NOT EXISTS idx_note_audits_tenant_character ON note_audits (tenant_id, character_id, id);
+
+-- The audit trail is append-only
+CREATE TRIGGER IF NOT EXISTS note_audits_no_update
+    BEFORE UPDATE
+    ON note_audits
+BEGIN
+    SELECT RAISE(ABORT, 'note_audits is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS note_audits_no_delete
+    BEFORE DELETE
+    ON note_audits
+BEGIN
+    SELECT RAISE(ABORT, 'note_audits is append-only');
+END;
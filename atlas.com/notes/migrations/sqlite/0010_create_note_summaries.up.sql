@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS note_summaries
+(
+    tenant_id    TEXT    NOT NULL,
+    character_id INTEGER NOT NULL,
+    flag         INTEGER NOT NULL,
+    total        INTEGER NOT NULL DEFAULT 0,
+    unread       INTEGER NOT NULL DEFAULT 0,
+    updated_at   DATETIME,
+    PRIMARY KEY (tenant_id, character_id, flag)
+);
+
+-- Count the notes already held
+INSERT OR IGNORE INTO note_summaries (tenant_id, character_id, flag, total, unread, updated_at)
+SELECT tenant_id, character_id, COALESCE(flag, 0), COUNT(*), SUM(CASE WHEN archived THEN 0 ELSE 1 END), CURRENT_TIMESTAMP
+FROM notes
+WHERE deleted_at IS NULL
+GROUP BY tenant_id, character_id, COALESCE(flag, 0);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS note_tenants
+(
+    tenant_id     TEXT PRIMARY KEY,
+    region        TEXT    NOT NULL,
+    major_version INTEGER NOT NULL,
+    minor_version INTEGER NOT NULL,
+    updated_at    DATETIME
+);
+
+-- Record the tenants already known from their configurations and purges
+INSERT OR IGNORE INTO note_tenants (tenant_id, region, major_version, minor_version, updated_at)
+SELECT tenant_id, region, major_version, minor_version, CURRENT_TIMESTAMP
+FROM tenant_configurations
+WHERE region IS NOT NULL;
+
+INSERT OR IGNORE INTO note_tenants (tenant_id, region, major_version, minor_version, updated_at)
+SELECT tenant_id, region, major_version, minor_version, CURRENT_TIMESTAMP
+FROM note_purges
+WHERE region IS NOT NULL;
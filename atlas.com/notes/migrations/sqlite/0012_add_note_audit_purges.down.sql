@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS note_audits_no_delete;
+
+CREATE TRIGGER IF NOT EXISTS note_audits_no_delete
+    BEFORE DELETE
+    ON note_audits
+BEGIN
+    SELECT RAISE(ABORT, 'note_audits is append-only');
+END;
+
+DROP TABLE IF EXISTS note_audit_purges;
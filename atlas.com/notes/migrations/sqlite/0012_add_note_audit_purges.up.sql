@@ -0,0 +1,15 @@
+-- A tenant listed here is having its audit trail purged, which is the one way entries may be deleted
+CREATE TABLE IF NOT EXISTS note_audit_purges
+(
+    tenant_id TEXT PRIMARY KEY
+);
+
+DROP TRIGGER IF EXISTS note_audits_no_delete;
+
+CREATE TRIGGER IF NOT EXISTS note_audits_no_delete
+    BEFORE DELETE
+    ON note_audits
+    WHEN NOT EXISTS (SELECT 1 FROM note_audit_purges WHERE tenant_id = OLD.tenant_id)
+BEGIN
+    SELECT RAISE(ABORT, 'note_audits is append-only');
+END;
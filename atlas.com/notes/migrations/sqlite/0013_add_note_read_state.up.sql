@@ -0,0 +1,18 @@
+ALTER TABLE notes ADD COLUMN read_at DATETIME;
+
+-- Archived notes counted as read before reads were recorded, so they are taken to have been read when last changed
+UPDATE notes
+SET read_at = COALESCE(updated_at, CURRENT_TIMESTAMP)
+WHERE archived
+  AND read_at IS NULL;
+
+-- Count the unread notes from when they were read
+UPDATE note_summaries
+SET unread     = (SELECT COUNT(*)
+                  FROM notes
+                  WHERE notes.tenant_id = note_summaries.tenant_id
+                    AND notes.character_id = note_summaries.character_id
+                    AND COALESCE(notes.flag, 0) = note_summaries.flag
+                    AND notes.deleted_at IS NULL
+                    AND notes.read_at IS NULL),
+    updated_at = CURRENT_TIMESTAMP;
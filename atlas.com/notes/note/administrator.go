@@ -1,17 +1,24 @@
 package note
 
 import (
+	"atlas-notes/attachment"
 	"atlas-notes/database"
+	"atlas-notes/label"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
-// createNote creates a new note in the database
+// createNote creates a new note, along with any attachments it carries, in the database
 func createNote(db *gorm.DB) func(tenantId uuid.UUID) func(note Model) (Model, error) {
 	return func(tenantId uuid.UUID) func(note Model) (Model, error) {
 		return func(note Model) (Model, error) {
 			entity := MakeEntity(tenantId, note)
 			entity.ID = 0
+			for i := range entity.Attachments {
+				entity.Attachments[i].ID = 0
+			}
 
 			err := database.ExecuteTransaction(db, func(tx *gorm.DB) error {
 				return tx.Create(&entity).Error
@@ -32,7 +39,7 @@ func updateNote(db *gorm.DB) func(tenantId uuid.UUID) func(note Model) (Model, e
 			entity := MakeEntity(tenantId, note)
 
 			err := database.ExecuteTransaction(db, func(tx *gorm.DB) error {
-				return tx.Where("tenant_id = ? AND id = ?", tenantId, note.Id()).Updates(&entity).Error
+				return tx.Omit(clause.Associations, "starred", "pinned", "archived").Where("tenant_id = ? AND id = ?", tenantId, note.Id()).Updates(&entity).Error
 			})
 			if err != nil {
 				return Model{}, err
@@ -58,6 +65,22 @@ func deleteNote(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) error {
 	}
 }
 
+// restoreNote undoes the deletion of a note. An expiration which has passed in the meantime is cleared, as the note's
+// attachments were settled when it expired.
+func restoreNote(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) error {
+	return func(tenantId uuid.UUID) func(id uint32) error {
+		return func(id uint32) error {
+			return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+				err := tx.Unscoped().Model(&Entity{}).Where("tenant_id = ? AND id = ?", tenantId, id).Update("deleted_at", nil).Error
+				if err != nil {
+					return err
+				}
+				return tx.Model(&Entity{}).Where("tenant_id = ? AND id = ? AND expiration <= ?", tenantId, id, time.Now()).Update("expiration", nil).Error
+			})
+		}
+	}
+}
+
 // deleteAllNotes deletes all notes for a character from the database
 func deleteAllNotes(db *gorm.DB) func(tenantId uuid.UUID) func(characterId uint32) error {
 	return func(tenantId uuid.UUID) func(characterId uint32) error {
@@ -68,3 +91,190 @@ func deleteAllNotes(db *gorm.DB) func(tenantId uuid.UUID) func(characterId uint3
 		}
 	}
 }
+
+// hideSentNotes hides notes from their sender's sent items, leaving the recipient's copy untouched
+func hideSentNotes(db *gorm.DB) func(tenantId uuid.UUID) func(senderId uint32) func(ids []uint32) error {
+	return func(tenantId uuid.UUID) func(senderId uint32) func(ids []uint32) error {
+		return func(senderId uint32) func(ids []uint32) error {
+			return func(ids []uint32) error {
+				return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+					return tx.Unscoped().Model(&Entity{}).Where("tenant_id = ? AND sender_id = ? AND id IN ?", tenantId, senderId, ids).Update("sender_hidden_at", time.Now()).Error
+				})
+			}
+		}
+	}
+}
+
+// updateOrganization sets one of the starred, pinned or archived markers of a note
+func updateOrganization(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) func(column string) func(value bool) error {
+	return func(tenantId uuid.UUID) func(id uint32) func(column string) func(value bool) error {
+		return func(id uint32) func(column string) func(value bool) error {
+			return func(column string) func(value bool) error {
+				return func(value bool) error {
+					return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+						return tx.Model(&Entity{}).Where("tenant_id = ? AND id = ?", tenantId, id).Update(column, value).Error
+					})
+				}
+			}
+		}
+	}
+}
+
+// markRead records when a note was first read by its recipient, leaving a note already read alone
+func markRead(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) func(readAt time.Time) error {
+	return func(tenantId uuid.UUID) func(id uint32) func(readAt time.Time) error {
+		return func(id uint32) func(readAt time.Time) error {
+			return func(readAt time.Time) error {
+				return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+					return tx.Model(&Entity{}).Where("tenant_id = ? AND id = ? AND read_at IS NULL", tenantId, id).Update("read_at", readAt).Error
+				})
+			}
+		}
+	}
+}
+
+// replaceLabels replaces the labels a note is filed under
+func replaceLabels(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) func(names []string) error {
+	return func(tenantId uuid.UUID) func(id uint32) func(names []string) error {
+		return func(id uint32) func(names []string) error {
+			return func(names []string) error {
+				return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+					err := tx.Where("tenant_id = ? AND note_id = ?", tenantId, id).Delete(&label.Entity{}).Error
+					if err != nil {
+						return err
+					}
+					es := label.MakeEntities(tenantId, id, names)
+					if len(es) == 0 {
+						return nil
+					}
+					return tx.Create(&es).Error
+				})
+			}
+		}
+	}
+}
+
+// purgeNotes permanently removes up to limit notes in a tenant, deleted notes included, along with their attachments
+// and labels. Notes are purged in ID order, so an interrupted purge picks up where it left off.
+func purgeNotes(db *gorm.DB) func(tenantId uuid.UUID) func(limit int) ([]Entity, error) {
+	return func(tenantId uuid.UUID) func(limit int) ([]Entity, error) {
+		return func(limit int) ([]Entity, error) {
+			var entities []Entity
+			err := database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+				err := tx.Unscoped().Where("tenant_id = ?", tenantId).Order("id").Limit(limit).Find(&entities).Error
+				if err != nil || len(entities) == 0 {
+					return err
+				}
+				ids := make([]uint32, 0, len(entities))
+				for _, e := range entities {
+					ids = append(ids, e.ID)
+				}
+				err = tx.Where("tenant_id = ? AND note_id IN ?", tenantId, ids).Delete(&attachment.Entity{}).Error
+				if err != nil {
+					return err
+				}
+				err = tx.Where("tenant_id = ? AND note_id IN ?", tenantId, ids).Delete(&label.Entity{}).Error
+				if err != nil {
+					return err
+				}
+				return tx.Unscoped().Where("tenant_id = ? AND id IN ?", tenantId, ids).Delete(&Entity{}).Error
+			})
+			if err != nil {
+				return nil, err
+			}
+			return entities, nil
+		}
+	}
+}
+
+// importNotes stores notes carried over from another tenant or cluster in a single batch, with all of their state,
+// assigning their IDs and those of their attachments
+func importNotes(db *gorm.DB) func(tenantId uuid.UUID) func(notes []Model) ([]Entity, error) {
+	return func(tenantId uuid.UUID) func(notes []Model) ([]Entity, error) {
+		return func(notes []Model) ([]Entity, error) {
+			entities := make([]Entity, 0, len(notes))
+			for _, n := range notes {
+				e := makeRecordEntity(tenantId, n)
+				e.ID = 0
+				for i := range e.Attachments {
+					e.Attachments[i].ID = 0
+				}
+				for i := range e.Labels {
+					e.Labels[i].NoteID = 0
+				}
+				entities = append(entities, e)
+			}
+			if len(entities) == 0 {
+				return entities, nil
+			}
+
+			err := database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+				return tx.Create(&entities).Error
+			})
+			if err != nil {
+				return nil, err
+			}
+			return entities, nil
+		}
+	}
+}
+
+// overwriteNote replaces the whole state of an existing note, deleted or not, with that of another, keeping its ID.
+// Its attachments and labels are replaced too.
+func overwriteNote(db *gorm.DB) func(tenantId uuid.UUID) func(id uint32) func(note Model) (Model, error) {
+	return func(tenantId uuid.UUID) func(id uint32) func(note Model) (Model, error) {
+		return func(id uint32) func(note Model) (Model, error) {
+			return func(note Model) (Model, error) {
+				entity := makeRecordEntity(tenantId, note)
+				entity.ID = id
+
+				err := database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+					res := tx.Unscoped().Model(&Entity{}).
+						Where("tenant_id = ? AND id = ?", tenantId, id).
+						Select("*").Omit(clause.Associations, "id", "tenant_id", "created_at").
+						Updates(&entity)
+					if res.Error != nil {
+						return res.Error
+					}
+					if res.RowsAffected == 0 {
+						return gorm.ErrRecordNotFound
+					}
+					err := tx.Where("tenant_id = ? AND note_id = ?", tenantId, id).Delete(&attachment.Entity{}).Error
+					if err != nil {
+						return err
+					}
+					err = tx.Where("tenant_id = ? AND note_id = ?", tenantId, id).Delete(&label.Entity{}).Error
+					if err != nil {
+						return err
+					}
+					for i := range entity.Attachments {
+						entity.Attachments[i].ID = 0
+						entity.Attachments[i].NoteID = id
+					}
+					if len(entity.Attachments) > 0 {
+						err = tx.Create(&entity.Attachments).Error
+						if err != nil {
+							return err
+						}
+					}
+					for i := range entity.Labels {
+						entity.Labels[i].NoteID = id
+					}
+					if len(entity.Labels) > 0 {
+						return tx.Create(&entity.Labels).Error
+					}
+					return nil
+				})
+				if err != nil {
+					return Model{}, err
+				}
+
+				entity, err = getByIdIncludingDeletedProvider(tenantId)(id)(db)()
+				if err != nil {
+					return Model{}, err
+				}
+				return Make(entity)
+			}
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package note
 
 import (
+	"atlas-notes/attachment"
+	"atlas-notes/label"
+	"github.com/Chronicle20/atlas-model/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"time"
@@ -8,16 +11,26 @@ import (
 
 // Entity represents a note in the database
 type Entity struct {
-	ID          uint32 `gorm:"primaryKey;autoIncrement"`
-	TenantID    uuid.UUID
-	CharacterID uint32
-	SenderID    uint32
-	Message     string
-	Timestamp   time.Time
-	Flag        byte
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	ID             uint32 `gorm:"primaryKey;autoIncrement"`
+	TenantID       uuid.UUID
+	CharacterID    uint32
+	SenderID       uint32
+	Message        string
+	Timestamp      time.Time
+	Flag           byte
+	SenderHiddenAt *time.Time
+	Expiration     *time.Time
+	Attachments    []attachment.Entity `gorm:"foreignKey:NoteID"`
+	InReplyTo      uint32
+	ThreadID       uint32
+	Starred        bool
+	Pinned         bool
+	Archived       bool
+	Labels         []label.Entity `gorm:"foreignKey:NoteID"`
+	ReadAt         *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt
 }
 
 // TableName specifies the database table name for Entity
@@ -27,19 +40,46 @@ func (Entity) TableName() string {
 
 // Make converts an Entity to a Model domain model
 func Make(e Entity) (Model, error) {
-	return NewBuilder().
+	as, err := model.SliceMap[attachment.Entity, attachment.Model](attachment.Make)(model.FixedProvider(e.Attachments))()()
+	if err != nil {
+		return Model{}, err
+	}
+	ls, err := model.SliceMap[label.Entity, string](label.Make)(model.FixedProvider(e.Labels))()()
+	if err != nil {
+		return Model{}, err
+	}
+	b := NewBuilder().
 		SetId(e.ID).
 		SetCharacterId(e.CharacterID).
 		SetSenderId(e.SenderID).
 		SetMessage(e.Message).
 		SetTimestamp(e.Timestamp).
 		SetFlag(e.Flag).
-		Build(), nil
+		SetAttachments(as).
+		SetInReplyTo(e.InReplyTo).
+		SetThreadId(e.ThreadID).
+		SetStarred(e.Starred).
+		SetPinned(e.Pinned).
+		SetArchived(e.Archived).
+		SetLabels(ls)
+	if e.Expiration != nil {
+		b.SetExpiration(*e.Expiration)
+	}
+	if e.DeletedAt.Valid {
+		b.SetDeletedAt(e.DeletedAt.Time)
+	}
+	if e.SenderHiddenAt != nil {
+		b.SetSenderHiddenAt(*e.SenderHiddenAt)
+	}
+	if e.ReadAt != nil {
+		b.SetReadAt(*e.ReadAt)
+	}
+	return b.Build(), nil
 }
 
 // MakeEntity converts a Model domain model to an Entity
 func MakeEntity(tenantId uuid.UUID, n Model) Entity {
-	return Entity{
+	e := Entity{
 		ID:          n.Id(),
 		TenantID:    tenantId,
 		CharacterID: n.CharacterId(),
@@ -47,10 +87,39 @@ func MakeEntity(tenantId uuid.UUID, n Model) Entity {
 		Message:     n.Message(),
 		Timestamp:   n.Timestamp(),
 		Flag:        n.Flag(),
+		InReplyTo:   n.InReplyTo(),
+		Starred:     n.Starred(),
+		Pinned:      n.Pinned(),
+		Archived:    n.Archived(),
 	}
+	if n.InReplyTo() != 0 {
+		e.ThreadID = n.ThreadId()
+	}
+	if !n.Expiration().IsZero() {
+		expiration := n.Expiration()
+		e.Expiration = &expiration
+	}
+	for _, a := range n.Attachments() {
+		e.Attachments = append(e.Attachments, attachment.MakeEntity(tenantId, a))
+	}
+	return e
 }
 
-// Migration sets up the notes table in the database
-func Migration(db *gorm.DB) error {
-	return db.AutoMigrate(&Entity{})
+// makeRecordEntity converts a Model domain model to an Entity carrying all of its state, including its labels, whether
+// its sender hid it and whether its recipient read or deleted it, as when importing it
+func makeRecordEntity(tenantId uuid.UUID, n Model) Entity {
+	e := MakeEntity(tenantId, n)
+	if !n.SenderHiddenAt().IsZero() {
+		senderHiddenAt := n.SenderHiddenAt()
+		e.SenderHiddenAt = &senderHiddenAt
+	}
+	if n.Read() {
+		readAt := n.ReadAt()
+		e.ReadAt = &readAt
+	}
+	if n.Deleted() {
+		e.DeletedAt = gorm.DeletedAt{Time: n.DeletedAt(), Valid: true}
+	}
+	e.Labels = label.MakeEntities(tenantId, n.Id(), n.Labels())
+	return e
 }
@@ -0,0 +1,50 @@
+package note
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Filter narrows the notes retrieved for a character. The zero value matches every note which is not archived.
+// Deleted selects the notes the character has deleted instead, archived or not.
+type Filter struct {
+	Starred  *bool
+	Pinned   *bool
+	Archived bool
+	Deleted  bool
+	Label    string
+}
+
+// ParseFilter reads a Filter from the filter[starred], filter[pinned], filter[archived], filter[deleted] and filter[label] query parameters
+func ParseFilter(query url.Values) (Filter, error) {
+	f := Filter{Label: query.Get("filter[label]")}
+	if val := query.Get("filter[starred]"); val != "" {
+		starred, err := strconv.ParseBool(val)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.Starred = &starred
+	}
+	if val := query.Get("filter[pinned]"); val != "" {
+		pinned, err := strconv.ParseBool(val)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.Pinned = &pinned
+	}
+	if val := query.Get("filter[archived]"); val != "" {
+		archived, err := strconv.ParseBool(val)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.Archived = archived
+	}
+	if val := query.Get("filter[deleted]"); val != "" {
+		deleted, err := strconv.ParseBool(val)
+		if err != nil {
+			return Filter{}, err
+		}
+		f.Deleted = deleted
+	}
+	return f, nil
+}
@@ -0,0 +1,300 @@
+package note
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+)
+
+// ConflictPolicy decides what becomes of an imported note which is already present, being a note the same sender
+// addressed to the same character at the same time
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+
+	importBatchSize = 500
+)
+
+var (
+	ErrUnknownConflictPolicy = errors.New("unknown import conflict policy")
+	ErrRecordOutOfOrder      = errors.New("note record does not follow the previous one in ID order")
+)
+
+// ParseConflictPolicy parses a conflict policy, defaulting to skipping notes already present
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch ConflictPolicy(s) {
+	case "", ConflictSkip:
+		return ConflictSkip, nil
+	case ConflictOverwrite:
+		return ConflictOverwrite, nil
+	}
+	return "", ErrUnknownConflictPolicy
+}
+
+// ImportError is a record which could not be imported, by the line of the stream it was read from
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult tallies the records of an import
+type ImportResult struct {
+	Imported    int
+	Overwritten int
+	Skipped     int
+	Errors      []ImportError
+}
+
+// pendingRecord is a note waiting to be inserted with the rest of its batch
+type pendingRecord struct {
+	source Model
+	m      Model
+}
+
+// sentKey identifies a note by the sender who addressed it, to which character, and when, as conflicts are detected by
+type sentKey struct {
+	characterId uint32
+	senderId    uint32
+	timestamp   int64
+}
+
+func sentKeyOf(m Model) sentKey {
+	return sentKey{characterId: m.CharacterId(), senderId: m.SenderId(), timestamp: m.Timestamp().UnixNano()}
+}
+
+// Importer imports notes exported from another tenant or cluster, in the ID order they were exported in. Character
+// IDs are remapped through a table, those missing from it being kept. Notes are given new IDs, and replies are
+// attached to the new IDs of the notes they reply to; a reply whose conversation was not imported starts a new one.
+type Importer struct {
+	l            logrus.FieldLogger
+	r            Repository
+	tenantId     uuid.UUID
+	characterIds map[uint32]uint32
+	policy       ConflictPolicy
+	// noteIds maps the IDs notes were exported with to the IDs they were imported with
+	noteIds map[uint32]uint32
+	lastId  uint32
+	pending []pendingRecord
+	// pendingIds holds the exported IDs and conversations of the pending notes, which replies cannot refer to until
+	// the batch is inserted
+	pendingIds map[uint32]struct{}
+	// pendingSent holds the pending notes by sender, character and time, so a later record of the same note is not
+	// inserted twice
+	pendingSent map[sentKey]struct{}
+	// audit records the notes inserted or overwritten in the audit trail, within the transaction storing them
+	audit func(r Repository, before []Model, after []Model) error
+	// created, if set, is run on each note inserted
+	created model.Operator[Model]
+	result  ImportResult
+}
+
+func newImporter(l logrus.FieldLogger, r Repository, tenantId uuid.UUID, characterIds map[uint32]uint32, policy ConflictPolicy, audit func(r Repository, before []Model, after []Model) error) *Importer {
+	return &Importer{
+		l:            l,
+		r:            r,
+		tenantId:     tenantId,
+		characterIds: characterIds,
+		policy:       policy,
+		audit:        audit,
+		noteIds:      make(map[uint32]uint32),
+		pendingIds:   make(map[uint32]struct{}),
+		pendingSent:  make(map[sentKey]struct{}),
+	}
+}
+
+// characterId returns the ID a character is imported with
+func (i *Importer) characterId(id uint32) uint32 {
+	if mapped, ok := i.characterIds[id]; ok {
+		return mapped
+	}
+	return id
+}
+
+// Add imports a note read from the given line, as extracted from its record. Notes are inserted in batches, so Flush
+// must be called once every note has been added. An error is returned only if the notes could not be stored.
+func (i *Importer) Add(line int, m Model) error {
+	if m.Id() <= i.lastId {
+		i.Reject(line, ErrRecordOutOfOrder)
+		return nil
+	}
+	i.lastId = m.Id()
+
+	if m.InReplyTo() != 0 {
+		_, ok := i.pendingIds[m.InReplyTo()]
+		if _, tok := i.pendingIds[m.ThreadId()]; ok || tok {
+			if err := i.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	b := Clone(m).
+		SetId(0).
+		SetCharacterId(i.characterId(m.CharacterId())).
+		SetSenderId(i.characterId(m.SenderId())).
+		SetInReplyTo(0).
+		SetThreadId(0)
+	if m.InReplyTo() != 0 {
+		if id, ok := i.noteIds[m.InReplyTo()]; ok {
+			b.SetInReplyTo(id).SetThreadId(i.noteIds[m.ThreadId()])
+		} else if id, ok = i.noteIds[m.ThreadId()]; ok {
+			b.SetInReplyTo(id).SetThreadId(id)
+		}
+	}
+	n := b.Build()
+
+	// A note already pending is inserted first, so the conflict policy applies to this record as to any note present
+	if _, ok := i.pendingSent[sentKeyOf(n)]; ok {
+		if err := i.Flush(); err != nil {
+			return err
+		}
+	}
+
+	o, err := i.r.BySentAt(i.tenantId, n.CharacterId(), n.SenderId(), n.Timestamp())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		if i.policy == ConflictOverwrite {
+			err = i.r.Transaction(func(r Repository) error {
+				w, err := r.Overwrite(i.tenantId, o.Id(), n)
+				if err != nil {
+					return err
+				}
+				err = i.audit(r, []Model{o}, []Model{w})
+				if err != nil {
+					return err
+				}
+				return r.Summary().Apply(i.tenantId, tally([]Model{o}, []Model{w})...)
+			})
+			if err != nil {
+				return err
+			}
+			i.result.Overwritten++
+		} else {
+			i.result.Skipped++
+		}
+		i.imported(m, o.Id())
+		return nil
+	}
+
+	i.pending = append(i.pending, pendingRecord{source: m, m: n})
+	i.pendingIds[m.Id()] = struct{}{}
+	i.pendingIds[m.ThreadId()] = struct{}{}
+	i.pendingSent[sentKeyOf(n)] = struct{}{}
+	if len(i.pending) >= importBatchSize {
+		return i.Flush()
+	}
+	return nil
+}
+
+// imported records the ID a note was imported with, along with that of its conversation if it is the first of it to
+// be imported
+func (i *Importer) imported(m Model, id uint32) {
+	i.noteIds[m.Id()] = id
+	if _, ok := i.noteIds[m.ThreadId()]; !ok {
+		i.noteIds[m.ThreadId()] = id
+	}
+}
+
+// Reject records a line which could not be imported
+func (i *Importer) Reject(line int, err error) {
+	i.result.Errors = append(i.result.Errors, ImportError{Line: line, Error: err.Error()})
+}
+
+// Flush inserts the pending notes as a batch, recording them in the audit trail and counting them in the summaries of
+// their characters
+func (i *Importer) Flush() error {
+	if len(i.pending) == 0 {
+		return nil
+	}
+	ms := make([]Model, 0, len(i.pending))
+	for _, pr := range i.pending {
+		ms = append(ms, pr.m)
+	}
+	var ims []Model
+	err := i.r.Transaction(func(r Repository) error {
+		var err error
+		ims, err = r.Import(i.tenantId, ms)
+		if err != nil {
+			return err
+		}
+		err = i.audit(r, nil, ims)
+		if err != nil {
+			return err
+		}
+		return r.Summary().Apply(i.tenantId, tally(nil, ims)...)
+	})
+	if err != nil {
+		return err
+	}
+	for j, pr := range i.pending {
+		i.imported(pr.source, ims[j].Id())
+		if i.created != nil {
+			if err = i.created(ims[j]); err != nil {
+				return err
+			}
+		}
+	}
+	i.result.Imported += len(ims)
+	i.l.Debugf("Imported a batch of [%d] notes.", len(ims))
+	i.pending = nil
+	i.pendingIds = make(map[uint32]struct{})
+	i.pendingSent = make(map[sentKey]struct{})
+	return nil
+}
+
+// Read imports every record of a newline-delimited JSON stream, then flushes. Lines which are not valid records are
+// rejected, leaving the rest of the stream to be imported. Blank lines are ignored.
+func (i *Importer) Read(rd io.Reader) error {
+	br := bufio.NewReader(rd)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if b = bytes.TrimSpace(b); len(b) > 0 {
+			if aerr := i.read(line, b); aerr != nil {
+				return aerr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return i.Flush()
+		}
+	}
+}
+
+// read imports the record held by a line
+func (i *Importer) read(line int, b []byte) error {
+	var rm RecordModel
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&rm)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after record")
+	}
+	if err != nil {
+		i.Reject(line, fmt.Errorf("%w: %s", ErrInvalidRecord, err))
+		return nil
+	}
+	m, err := ExtractRecord(rm)
+	if err != nil {
+		i.Reject(line, err)
+		return nil
+	}
+	return i.Add(line, m)
+}
+
+// Result returns the tally of the import so far
+func (i *Importer) Result() ImportResult {
+	return i.result
+}
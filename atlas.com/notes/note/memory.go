@@ -0,0 +1,878 @@
+package note
+
+import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/label"
+	"atlas-notes/summary"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrUnknownMarker = errors.New("unknown organization marker")
+
+// memoryState is the content of a MemoryRepository. IDs are assigned from counters shared by every tenant, as a
+// database sequence would.
+type memoryState struct {
+	notes        map[uint32]Entity
+	noteId       uint32
+	attachmentId uint32
+	labelId      uint32
+	audits       []audit.Entity
+	auditId      uint64
+	summaries    map[summaryKey]summary.Entity
+}
+
+// summaryKey identifies the counters of the notes of a kind held by a character
+type summaryKey struct {
+	tenantId    uuid.UUID
+	characterId uint32
+	flag        byte
+}
+
+// clone returns a deep copy of the state, so a transaction can change it without affecting readers
+func (s *memoryState) clone() *memoryState {
+	c := &memoryState{
+		notes:        make(map[uint32]Entity, len(s.notes)),
+		noteId:       s.noteId,
+		attachmentId: s.attachmentId,
+		labelId:      s.labelId,
+		audits:       append([]audit.Entity(nil), s.audits...),
+		auditId:      s.auditId,
+		summaries:    make(map[summaryKey]summary.Entity, len(s.summaries)),
+	}
+	for id, e := range s.notes {
+		c.notes[id] = cloneEntity(e)
+	}
+	for k, e := range s.summaries {
+		c.summaries[k] = e
+	}
+	return c
+}
+
+// cloneEntity returns a copy of an entity which shares nothing mutable with it
+func cloneEntity(e Entity) Entity {
+	if e.SenderHiddenAt != nil {
+		t := *e.SenderHiddenAt
+		e.SenderHiddenAt = &t
+	}
+	if e.Expiration != nil {
+		t := *e.Expiration
+		e.Expiration = &t
+	}
+	if e.ReadAt != nil {
+		t := *e.ReadAt
+		e.ReadAt = &t
+	}
+	e.Attachments = append([]attachment.Entity(nil), e.Attachments...)
+	e.Labels = append([]label.Entity(nil), e.Labels...)
+	return e
+}
+
+// memoryStore is the state shared by a MemoryRepository and the repositories handed to its transactions. Readers
+// take mu for reading. Writers take writeMu, then mu for writing; transactions hold writeMu throughout, and mu only
+// while swapping in their changes, so reads are never blocked by a transaction in progress.
+type memoryStore struct {
+	mu      sync.RWMutex
+	writeMu sync.Mutex
+	state   *memoryState
+}
+
+// MemoryRepository is a concurrency-safe Repository holding notes in memory, for running without a database. It
+// mirrors the semantics of GormRepository, deletes being soft.
+type MemoryRepository struct {
+	store *memoryStore
+	// tx is the state being changed by a transaction in progress, if this repository was handed to one
+	tx *memoryState
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{store: &memoryStore{state: &memoryState{notes: make(map[uint32]Entity), summaries: make(map[summaryKey]summary.Entity)}}}
+}
+
+var sharedMemory *MemoryRepository
+var sharedMemoryOnce sync.Once
+
+// getSharedMemoryRepository returns the in-memory repository shared by processors created without a database
+func getSharedMemoryRepository() *MemoryRepository {
+	sharedMemoryOnce.Do(func() {
+		sharedMemory = NewMemoryRepository()
+	})
+	return sharedMemory
+}
+
+// read runs fn against the current state
+func (r *MemoryRepository) read(fn func(s *memoryState) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return fn(r.store.state)
+}
+
+// write runs fn against the current state, excluding every other reader and writer. fn must check everything which
+// can fail before making any change.
+func (r *MemoryRepository) write(fn func(s *memoryState) error) error {
+	if r.tx != nil {
+		return fn(r.tx)
+	}
+	r.store.writeMu.Lock()
+	defer r.store.writeMu.Unlock()
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return fn(r.store.state)
+}
+
+// find returns the models of the notes in a tenant matching the predicate, sorted by less, or by ID if less is nil
+func (r *MemoryRepository) find(tenantId uuid.UUID, match func(e Entity) bool, less func(a, b Entity) bool) ([]Model, error) {
+	var es []Entity
+	err := r.read(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID == tenantId && match(e) {
+				es = append(es, cloneEntity(e))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if less != nil && less(es[i], es[j]) != less(es[j], es[i]) {
+			return less(es[i], es[j])
+		}
+		return es[i].ID < es[j].ID
+	})
+	results := make([]Model, 0, len(es))
+	for _, e := range es {
+		m, err := Make(e)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+// first returns the model of the note in a tenant with the given ID, provided it matches the predicate
+func (r *MemoryRepository) first(tenantId uuid.UUID, id uint32, match func(e Entity) bool) (Model, error) {
+	var e Entity
+	err := r.read(func(s *memoryState) error {
+		var ok bool
+		e, ok = s.notes[id]
+		if !ok || e.TenantID != tenantId || !match(e) {
+			return gorm.ErrRecordNotFound
+		}
+		e = cloneEntity(e)
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+	return Make(e)
+}
+
+// update applies a change to every note in a tenant matching the predicate
+func (r *MemoryRepository) update(tenantId uuid.UUID, match func(e Entity) bool, change func(e *Entity)) error {
+	return r.write(func(s *memoryState) error {
+		now := time.Now()
+		for id, e := range s.notes {
+			if e.TenantID == tenantId && match(e) {
+				change(&e)
+				e.UpdatedAt = now
+				s.notes[id] = e
+			}
+		}
+		return nil
+	})
+}
+
+func active(e Entity) bool {
+	return !e.DeletedAt.Valid
+}
+
+func (r *MemoryRepository) ById(tenantId uuid.UUID, id uint32) (Model, error) {
+	return r.first(tenantId, id, active)
+}
+
+func (r *MemoryRepository) ByIdIncludingDeleted(tenantId uuid.UUID, id uint32) (Model, error) {
+	return r.first(tenantId, id, func(Entity) bool {
+		return true
+	})
+}
+
+func (r *MemoryRepository) ByCharacter(tenantId uuid.UUID, characterId uint32) ([]Model, error) {
+	return r.find(tenantId, func(e Entity) bool {
+		return active(e) && e.CharacterID == characterId
+	}, nil)
+}
+
+func (r *MemoryRepository) ByCharacterAndFilter(tenantId uuid.UUID, characterId uint32, f Filter) ([]Model, error) {
+	match := func(e Entity) bool {
+		if e.CharacterID != characterId {
+			return false
+		}
+		if f.Deleted {
+			if active(e) {
+				return false
+			}
+		} else if !active(e) || e.Archived != f.Archived {
+			return false
+		}
+		if f.Starred != nil && e.Starred != *f.Starred {
+			return false
+		}
+		if f.Pinned != nil && e.Pinned != *f.Pinned {
+			return false
+		}
+		if f.Label != "" {
+			for _, l := range e.Labels {
+				if l.Name == f.Label {
+					return true
+				}
+			}
+			return false
+		}
+		return true
+	}
+	less := func(a, b Entity) bool {
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	if f.Deleted {
+		less = func(a, b Entity) bool {
+			return a.DeletedAt.Time.After(b.DeletedAt.Time)
+		}
+	}
+	return r.find(tenantId, match, less)
+}
+
+func (r *MemoryRepository) InboxCount(tenantId uuid.UUID, characterId uint32) (int64, error) {
+	var count int64
+	err := r.read(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID == tenantId && active(e) && e.CharacterID == characterId && !e.Archived {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *MemoryRepository) SentCount(tenantId uuid.UUID, senderId uint32, since time.Time) (int64, error) {
+	var count int64
+	err := r.read(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID == tenantId && e.SenderID == senderId && !e.Timestamp.Before(since) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *MemoryRepository) UnreadSenders(tenantId uuid.UUID, characterId uint32, limit int) ([]uint32, error) {
+	ms, err := r.find(tenantId, func(e Entity) bool {
+		return active(e) && e.CharacterID == characterId && e.ReadAt == nil
+	}, func(a, b Entity) bool {
+		return a.Timestamp.After(b.Timestamp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[uint32]struct{})
+	senderIds := make([]uint32, 0, limit)
+	for _, m := range ms {
+		if len(senderIds) == limit {
+			break
+		}
+		if _, ok := seen[m.SenderId()]; ok {
+			continue
+		}
+		seen[m.SenderId()] = struct{}{}
+		senderIds = append(senderIds, m.SenderId())
+	}
+	return senderIds, nil
+}
+
+func (r *MemoryRepository) BySender(tenantId uuid.UUID, senderId uint32) ([]Model, error) {
+	return r.find(tenantId, func(e Entity) bool {
+		return e.SenderID == senderId && e.SenderHiddenAt == nil
+	}, func(a, b Entity) bool {
+		return a.Timestamp.After(b.Timestamp)
+	})
+}
+
+func (r *MemoryRepository) ByParticipant(tenantId uuid.UUID, characterId uint32) ([]Model, error) {
+	return r.find(tenantId, func(e Entity) bool {
+		return (e.CharacterID == characterId && active(e)) || (e.SenderID == characterId && e.SenderHiddenAt == nil)
+	}, nil)
+}
+
+func (r *MemoryRepository) All(tenantId uuid.UUID) ([]Model, error) {
+	return r.find(tenantId, active, nil)
+}
+
+func (r *MemoryRepository) Expired(tenantId uuid.UUID, asOf time.Time) ([]Model, error) {
+	return r.find(tenantId, func(e Entity) bool {
+		return active(e) && e.Expiration != nil && !e.Expiration.After(asOf)
+	}, nil)
+}
+
+func (r *MemoryRepository) Count(tenantId uuid.UUID) (int64, error) {
+	var count int64
+	err := r.read(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID == tenantId && active(e) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *MemoryRepository) CountIncludingDeleted(tenantId uuid.UUID) (int64, error) {
+	var count int64
+	err := r.read(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID == tenantId {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (r *MemoryRepository) AllIncludingDeleted(tenantId uuid.UUID, afterId uint32, limit int) ([]Model, error) {
+	ms, err := r.find(tenantId, func(e Entity) bool {
+		return e.ID > afterId
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(ms) > limit {
+		ms = ms[:limit]
+	}
+	return ms, nil
+}
+
+func (r *MemoryRepository) BySentAt(tenantId uuid.UUID, characterId uint32, senderId uint32, timestamp time.Time) (Model, error) {
+	ms, err := r.find(tenantId, func(e Entity) bool {
+		return e.CharacterID == characterId && e.SenderID == senderId && e.Timestamp.Equal(timestamp)
+	}, nil)
+	if err != nil {
+		return Model{}, err
+	}
+	if len(ms) == 0 {
+		return Model{}, gorm.ErrRecordNotFound
+	}
+	return ms[0], nil
+}
+
+func (r *MemoryRepository) Create(tenantId uuid.UUID, m Model) (Model, error) {
+	e := MakeEntity(tenantId, m)
+	err := r.write(func(s *memoryState) error {
+		e = s.insert(e, time.Now())
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+	return Make(e)
+}
+
+// insert stores a new note, assigning the IDs of the note, its attachments and its labels
+func (s *memoryState) insert(e Entity, now time.Time) Entity {
+	s.noteId++
+	e.ID = s.noteId
+	e.CreatedAt = now
+	e.UpdatedAt = now
+	for i := range e.Attachments {
+		s.attachmentId++
+		e.Attachments[i].ID = s.attachmentId
+		e.Attachments[i].NoteID = e.ID
+		e.Attachments[i].CreatedAt = now
+		e.Attachments[i].UpdatedAt = now
+	}
+	for i := range e.Labels {
+		s.labelId++
+		e.Labels[i].ID = s.labelId
+		e.Labels[i].NoteID = e.ID
+	}
+	s.notes[e.ID] = cloneEntity(e)
+	return e
+}
+
+func (r *MemoryRepository) Update(tenantId uuid.UUID, m Model) (Model, error) {
+	u := MakeEntity(tenantId, m)
+	err := r.write(func(s *memoryState) error {
+		e, ok := s.notes[m.Id()]
+		if !ok || e.TenantID != tenantId || !active(e) {
+			return nil
+		}
+		// As with an update from a struct, only set fields change.
+		if u.CharacterID != 0 {
+			e.CharacterID = u.CharacterID
+		}
+		if u.SenderID != 0 {
+			e.SenderID = u.SenderID
+		}
+		if u.Message != "" {
+			e.Message = u.Message
+		}
+		if !u.Timestamp.IsZero() {
+			e.Timestamp = u.Timestamp
+		}
+		if u.Flag != 0 {
+			e.Flag = u.Flag
+		}
+		if u.Expiration != nil {
+			e.Expiration = u.Expiration
+		}
+		if u.InReplyTo != 0 {
+			e.InReplyTo = u.InReplyTo
+		}
+		if u.ThreadID != 0 {
+			e.ThreadID = u.ThreadID
+		}
+		e.UpdatedAt = time.Now()
+		s.notes[e.ID] = e
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+	return r.ById(tenantId, m.Id())
+}
+
+func (r *MemoryRepository) Delete(tenantId uuid.UUID, id uint32) error {
+	return r.update(tenantId, func(e Entity) bool {
+		return active(e) && e.ID == id
+	}, softDelete)
+}
+
+func (r *MemoryRepository) DeleteAll(tenantId uuid.UUID, characterId uint32) error {
+	return r.update(tenantId, func(e Entity) bool {
+		return active(e) && e.CharacterID == characterId
+	}, softDelete)
+}
+
+func softDelete(e *Entity) {
+	e.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+func (r *MemoryRepository) Restore(tenantId uuid.UUID, id uint32) error {
+	return r.update(tenantId, func(e Entity) bool {
+		return e.ID == id
+	}, func(e *Entity) {
+		e.DeletedAt = gorm.DeletedAt{}
+		if e.Expiration != nil && !e.Expiration.After(time.Now()) {
+			e.Expiration = nil
+		}
+	})
+}
+
+func (r *MemoryRepository) HideSent(tenantId uuid.UUID, senderId uint32, ids []uint32) error {
+	hide := make(map[uint32]struct{}, len(ids))
+	for _, id := range ids {
+		hide[id] = struct{}{}
+	}
+	return r.update(tenantId, func(e Entity) bool {
+		_, ok := hide[e.ID]
+		return ok && e.SenderID == senderId
+	}, func(e *Entity) {
+		now := time.Now()
+		e.SenderHiddenAt = &now
+	})
+}
+
+func (r *MemoryRepository) UpdateOrganization(tenantId uuid.UUID, id uint32, marker string, value bool) error {
+	var change func(e *Entity)
+	switch marker {
+	case MarkerStarred:
+		change = func(e *Entity) { e.Starred = value }
+	case MarkerPinned:
+		change = func(e *Entity) { e.Pinned = value }
+	case MarkerArchived:
+		change = func(e *Entity) { e.Archived = value }
+	default:
+		return ErrUnknownMarker
+	}
+	return r.update(tenantId, func(e Entity) bool {
+		return active(e) && e.ID == id
+	}, change)
+}
+
+func (r *MemoryRepository) MarkRead(tenantId uuid.UUID, id uint32, readAt time.Time) error {
+	return r.update(tenantId, func(e Entity) bool {
+		return active(e) && e.ID == id && e.ReadAt == nil
+	}, func(e *Entity) {
+		e.ReadAt = &readAt
+	})
+}
+
+func (r *MemoryRepository) ReplaceLabels(tenantId uuid.UUID, id uint32, names []string) error {
+	return r.write(func(s *memoryState) error {
+		e, ok := s.notes[id]
+		if !ok || e.TenantID != tenantId {
+			return nil
+		}
+		e.Labels = label.MakeEntities(tenantId, id, names)
+		for i := range e.Labels {
+			s.labelId++
+			e.Labels[i].ID = s.labelId
+		}
+		s.notes[id] = e
+		return nil
+	})
+}
+
+func (r *MemoryRepository) Purge(tenantId uuid.UUID, limit int) ([]Model, error) {
+	var es []Entity
+	err := r.write(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID == tenantId {
+				es = append(es, e)
+			}
+		}
+		sort.Slice(es, func(i, j int) bool {
+			return es[i].ID < es[j].ID
+		})
+		if len(es) > limit {
+			es = es[:limit]
+		}
+		for i, e := range es {
+			delete(s.notes, e.ID)
+			es[i].Attachments = nil
+			es[i].Labels = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Model, 0, len(es))
+	for _, e := range es {
+		m, err := Make(e)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+func (r *MemoryRepository) Import(tenantId uuid.UUID, ms []Model) ([]Model, error) {
+	es := make([]Entity, 0, len(ms))
+	err := r.write(func(s *memoryState) error {
+		now := time.Now()
+		for _, m := range ms {
+			es = append(es, s.insert(makeRecordEntity(tenantId, m), now))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Model, 0, len(es))
+	for _, e := range es {
+		m, err := Make(e)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+func (r *MemoryRepository) Overwrite(tenantId uuid.UUID, id uint32, m Model) (Model, error) {
+	err := r.write(func(s *memoryState) error {
+		o, ok := s.notes[id]
+		if !ok || o.TenantID != tenantId {
+			return gorm.ErrRecordNotFound
+		}
+		now := time.Now()
+		e := makeRecordEntity(tenantId, m)
+		e.ID = id
+		e.CreatedAt = o.CreatedAt
+		e.UpdatedAt = now
+		for i := range e.Attachments {
+			s.attachmentId++
+			e.Attachments[i].ID = s.attachmentId
+			e.Attachments[i].NoteID = id
+			e.Attachments[i].CreatedAt = now
+			e.Attachments[i].UpdatedAt = now
+		}
+		for i := range e.Labels {
+			s.labelId++
+			e.Labels[i].ID = s.labelId
+			e.Labels[i].NoteID = id
+		}
+		s.notes[id] = cloneEntity(e)
+		return nil
+	})
+	if err != nil {
+		return Model{}, err
+	}
+	return r.ByIdIncludingDeleted(tenantId, id)
+}
+
+func (r *MemoryRepository) Attachments() attachment.Repository {
+	return memoryAttachments{r: r}
+}
+
+// Transaction runs fn against a copy of the state, which replaces the state only if fn succeeds. Writers wait for
+// the transaction to finish, while readers see the state as it was before it began.
+func (r *MemoryRepository) Transaction(fn func(r Repository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+	r.store.writeMu.Lock()
+	defer r.store.writeMu.Unlock()
+
+	r.store.mu.RLock()
+	tx := r.store.state.clone()
+	r.store.mu.RUnlock()
+
+	err := fn(&MemoryRepository{store: r.store, tx: tx})
+	if err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	r.store.state = tx
+	r.store.mu.Unlock()
+	return nil
+}
+
+func (r *MemoryRepository) Reader() Repository {
+	return r
+}
+
+func (r *MemoryRepository) WithContext(context.Context) Repository {
+	return r
+}
+
+// memoryAttachments is the attachment.Repository of a MemoryRepository, the attachments being held by their notes
+type memoryAttachments struct {
+	r *MemoryRepository
+}
+
+func (a memoryAttachments) ByNoteId(tenantId uuid.UUID, noteId uint32) ([]attachment.Model, error) {
+	var es []attachment.Entity
+	err := a.r.read(func(s *memoryState) error {
+		e, ok := s.notes[noteId]
+		if !ok {
+			return nil
+		}
+		for _, ae := range e.Attachments {
+			if ae.TenantID == tenantId {
+				es = append(es, ae)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]attachment.Model, 0, len(es))
+	for _, e := range es {
+		m, err := attachment.Make(e)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, m)
+	}
+	return results, nil
+}
+
+func (a memoryAttachments) Transition(tenantId uuid.UUID, id uint32, status string) error {
+	return a.r.write(func(s *memoryState) error {
+		for nid, e := range s.notes {
+			for i, ae := range e.Attachments {
+				if ae.ID != id || ae.TenantID != tenantId {
+					continue
+				}
+				if ae.Status != attachment.StatusUnclaimed {
+					return attachment.ErrInvalidTransition
+				}
+				e.Attachments[i].Status = status
+				e.Attachments[i].UpdatedAt = time.Now()
+				s.notes[nid] = e
+				return nil
+			}
+		}
+		return attachment.ErrInvalidTransition
+	})
+}
+
+func (r *MemoryRepository) Audit() audit.Repository {
+	return memoryAudit{r: r}
+}
+
+// memoryAudit is the audit.Repository of a MemoryRepository, the entries being held alongside the notes
+type memoryAudit struct {
+	r *MemoryRepository
+}
+
+func (a memoryAudit) Append(tenantId uuid.UUID, ms ...audit.Model) error {
+	return a.r.write(func(s *memoryState) error {
+		for _, m := range ms {
+			s.auditId++
+			e := audit.MakeEntity(tenantId, m)
+			e.ID = s.auditId
+			s.audits = append(s.audits, e)
+		}
+		return nil
+	})
+}
+
+func (a memoryAudit) find(match func(e audit.Entity) bool) ([]audit.Model, error) {
+	var results []audit.Model
+	err := a.r.read(func(s *memoryState) error {
+		for _, e := range s.audits {
+			if !match(e) {
+				continue
+			}
+			m, err := audit.Make(e)
+			if err != nil {
+				return err
+			}
+			results = append(results, m)
+		}
+		return nil
+	})
+	return results, err
+}
+
+func (a memoryAudit) ByNoteId(tenantId uuid.UUID, noteId uint32) ([]audit.Model, error) {
+	return a.find(func(e audit.Entity) bool {
+		return e.TenantID == tenantId && e.NoteID == noteId
+	})
+}
+
+func (a memoryAudit) ByCharacterId(tenantId uuid.UUID, characterId uint32) ([]audit.Model, error) {
+	return a.find(func(e audit.Entity) bool {
+		return e.TenantID == tenantId && e.CharacterID == characterId
+	})
+}
+
+func (a memoryAudit) Purge(tenantId uuid.UUID) (int64, error) {
+	var n int64
+	err := a.r.write(func(s *memoryState) error {
+		kept := make([]audit.Entity, 0, len(s.audits))
+		for _, e := range s.audits {
+			if e.TenantID == tenantId {
+				n++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		s.audits = kept
+		return nil
+	})
+	return n, err
+}
+
+func (r *MemoryRepository) Tally(tenantId uuid.UUID) ([]summary.Count, error) {
+	var cs []summary.Count
+	err := r.read(func(s *memoryState) error {
+		for _, e := range s.notes {
+			if e.TenantID != tenantId || e.DeletedAt.Valid {
+				continue
+			}
+			var unread int64
+			if e.ReadAt == nil {
+				unread = 1
+			}
+			cs = append(cs, summary.NewCount(e.CharacterID, e.Flag, 1, unread))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return summary.Merge(cs), nil
+}
+
+func (r *MemoryRepository) Summary() summary.Repository {
+	return memorySummary{r: r}
+}
+
+// memorySummary is the summary.Repository of a MemoryRepository, the counters being held alongside the notes
+type memorySummary struct {
+	r *MemoryRepository
+}
+
+func (m memorySummary) Apply(tenantId uuid.UUID, cs ...summary.Count) error {
+	return m.r.write(func(s *memoryState) error {
+		now := time.Now()
+		for _, c := range summary.Merge(cs) {
+			k := summaryKey{tenantId: tenantId, characterId: c.CharacterId(), flag: c.Flag()}
+			e, ok := s.summaries[k]
+			if !ok {
+				e = summary.MakeEntity(tenantId, summary.NewCount(c.CharacterId(), c.Flag(), 0, 0))
+			}
+			e.Total += c.Total()
+			e.Unread += c.Unread()
+			e.UpdatedAt = now
+			s.summaries[k] = e
+		}
+		return nil
+	})
+}
+
+// find returns the counters of a tenant matching the predicate
+func (m memorySummary) find(tenantId uuid.UUID, match func(e summary.Entity) bool) ([]summary.Count, error) {
+	var cs []summary.Count
+	err := m.r.read(func(s *memoryState) error {
+		for _, e := range s.summaries {
+			if e.TenantID != tenantId || !match(e) {
+				continue
+			}
+			c, err := summary.Make(e)
+			if err != nil {
+				return err
+			}
+			cs = append(cs, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		if cs[i].CharacterId() != cs[j].CharacterId() {
+			return cs[i].CharacterId() < cs[j].CharacterId()
+		}
+		return cs[i].Flag() < cs[j].Flag()
+	})
+	return cs, nil
+}
+
+func (m memorySummary) ByCharacterId(tenantId uuid.UUID, characterId uint32) (summary.Model, error) {
+	cs, err := m.find(tenantId, func(e summary.Entity) bool {
+		return e.CharacterID == characterId
+	})
+	if err != nil {
+		return summary.Model{}, err
+	}
+	return summary.NewModel(characterId, cs), nil
+}
+
+func (m memorySummary) ByTenant(tenantId uuid.UUID) ([]summary.Count, error) {
+	return m.find(tenantId, func(summary.Entity) bool {
+		return true
+	})
+}
@@ -1,23 +1,73 @@
 package mock
 
 import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
 	"atlas-notes/kafka/message"
 	"atlas-notes/note"
+	"atlas-notes/summary"
 	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"time"
 )
 
 type ProcessorMock struct {
-	CreateFunc              func(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (note.Model, error)
-	CreateAndEmitFunc       func(characterId uint32, senderId uint32, msg string, flag byte) (note.Model, error)
-	UpdateFunc              func(mb *message.Buffer) func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (note.Model, error)
-	UpdateAndEmitFunc       func(id uint32, characterId uint32, senderId uint32, msg string, flag byte) (note.Model, error)
-	DeleteFunc              func(mb *message.Buffer) func(id uint32) error
-	DeleteAndEmitFunc       func(id uint32) error
-	DeleteAllFunc           func(mb *message.Buffer) func(characterId uint32) error
-	DeleteAllAndEmitFunc    func(characterId uint32) error
-	ByIdProviderFunc        func(id uint32) model.Provider[note.Model]
-	ByCharacterProviderFunc func(characterId uint32) model.Provider[[]note.Model]
-	InTenantProviderFunc    func() model.Provider[[]note.Model]
+	CreateFunc                        func(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (note.Model, error)
+	CreateAndEmitFunc                 func(characterId uint32, senderId uint32, msg string, flag byte) (note.Model, error)
+	CreateWithAttachmentsFunc         func(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) func(attachments []attachment.Model) (note.Model, error)
+	CreateWithAttachmentsAndEmitFunc  func(characterId uint32, senderId uint32, msg string, flag byte, attachments []attachment.Model) (note.Model, error)
+	ReplyFunc                         func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(msg string) func(flag byte) (note.Model, error)
+	ReplyAndEmitFunc                  func(characterId uint32, noteId uint32, msg string, flag byte) (note.Model, error)
+	UpdateFunc                        func(mb *message.Buffer) func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (note.Model, error)
+	UpdateAndEmitFunc                 func(id uint32, characterId uint32, senderId uint32, msg string, flag byte) (note.Model, error)
+	DeleteFunc                        func(mb *message.Buffer) func(id uint32) error
+	DeleteAndEmitFunc                 func(id uint32) error
+	DeleteAllFunc                     func(mb *message.Buffer) func(characterId uint32) error
+	DeleteAllAndEmitFunc              func(characterId uint32) error
+	DiscardFunc                       func(mb *message.Buffer) func(characterId uint32) func(noteIds []uint32) func(force bool) error
+	DiscardAndEmitFunc                func(characterId uint32, noteIds []uint32, force bool) error
+	StarFunc                          func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(starred bool) (note.Model, error)
+	StarAndEmitFunc                   func(characterId uint32, noteId uint32, starred bool) (note.Model, error)
+	PinFunc                           func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(pinned bool) (note.Model, error)
+	PinAndEmitFunc                    func(characterId uint32, noteId uint32, pinned bool) (note.Model, error)
+	ArchiveFunc                       func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(archived bool) (note.Model, error)
+	ArchiveAndEmitFunc                func(characterId uint32, noteId uint32, archived bool) (note.Model, error)
+	LabelFunc                         func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(labels []string) (note.Model, error)
+	LabelAndEmitFunc                  func(characterId uint32, noteId uint32, labels []string) (note.Model, error)
+	OrganizeFunc                      func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(changes note.Organization) (note.Model, error)
+	OrganizeAndEmitFunc               func(characterId uint32, noteId uint32, changes note.Organization) (note.Model, error)
+	MarkReadFunc                      func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (note.Model, error)
+	MarkReadAndEmitFunc               func(characterId uint32, noteId uint32) (note.Model, error)
+	HideSentFunc                      func(senderId uint32) func(noteIds []uint32) error
+	ClaimFunc                         func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) error
+	ClaimAndEmitFunc                  func(characterId uint32, noteId uint32) error
+	ExpireFunc                        func(mb *message.Buffer) func(id uint32) error
+	ExpireAndEmitFunc                 func(id uint32) error
+	RestoreFunc                       func(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (note.Model, error)
+	RestoreAndEmitFunc                func(characterId uint32, noteId uint32) (note.Model, error)
+	PurgeBatchFunc                    func(mb *message.Buffer) func(limit int) func(emitEvents bool) (int, error)
+	PurgeBatchAndEmitFunc             func(limit int, emitEvents bool) (int, error)
+	PurgeAuditFunc                    func(purged int64) error
+	ExportFunc                        func(o model.Operator[note.Model]) error
+	ImporterFunc                      func(characterIds map[uint32]uint32, policy note.ConflictPolicy) *note.Importer
+	CloneFunc                         func(mb *message.Buffer) func(sourceTenantId uuid.UUID) func(characterIds map[uint32]uint32) func(flags map[byte]byte) (note.ImportResult, error)
+	CloneAndEmitFunc                  func(sourceTenantId uuid.UUID, characterIds map[uint32]uint32, flags map[byte]byte) (note.ImportResult, error)
+	ByIdProviderFunc                  func(id uint32) model.Provider[note.Model]
+	ByCharacterProviderFunc           func(characterId uint32) model.Provider[[]note.Model]
+	ByCharacterAndFilterProviderFunc  func(characterId uint32, f note.Filter) model.Provider[[]note.Model]
+	BySenderProviderFunc              func(senderId uint32) model.Provider[[]note.Model]
+	ByParticipantProviderFunc         func(characterId uint32) model.Provider[[]note.Model]
+	InTenantProviderFunc              func() model.Provider[[]note.Model]
+	ExpiredProviderFunc               func(asOf time.Time) model.Provider[[]note.Model]
+	CountProviderFunc                 func() model.Provider[int64]
+	CountIncludingDeletedProviderFunc func() model.Provider[int64]
+	HistoryProviderFunc               func(noteId uint32) model.Provider[[]audit.Model]
+	AuditByCharacterProviderFunc      func(characterId uint32) model.Provider[[]audit.Model]
+	SummaryProviderFunc               func(characterId uint32) model.Provider[summary.Model]
+	ReconcileFunc                     func(mb *message.Buffer) (int, error)
+	ReconcileAndEmitFunc              func() (int, error)
+	NotifyPendingFunc                 func(mb *message.Buffer) func(worldId byte) func(channelId byte) func(characterId uint32) error
+	NotifyPendingAndEmitFunc          func(worldId byte, channelId byte, characterId uint32) error
 }
 
 func (m *ProcessorMock) Create(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (note.Model, error) {
@@ -42,6 +92,52 @@ func (m *ProcessorMock) CreateAndEmit(characterId uint32, senderId uint32, msg s
 	return note.Model{}, nil
 }
 
+func (m *ProcessorMock) CreateWithAttachments(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) func(attachments []attachment.Model) (note.Model, error) {
+	if m.CreateWithAttachmentsFunc != nil {
+		return m.CreateWithAttachmentsFunc(mb)
+	}
+	return func(uint32) func(uint32) func(string) func(byte) func([]attachment.Model) (note.Model, error) {
+		return func(uint32) func(string) func(byte) func([]attachment.Model) (note.Model, error) {
+			return func(string) func(byte) func([]attachment.Model) (note.Model, error) {
+				return func(byte) func([]attachment.Model) (note.Model, error) {
+					return func([]attachment.Model) (note.Model, error) {
+						return note.Model{}, nil
+					}
+				}
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) CreateWithAttachmentsAndEmit(characterId uint32, senderId uint32, msg string, flag byte, attachments []attachment.Model) (note.Model, error) {
+	if m.CreateWithAttachmentsAndEmitFunc != nil {
+		return m.CreateWithAttachmentsAndEmitFunc(characterId, senderId, msg, flag, attachments)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) Reply(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(msg string) func(flag byte) (note.Model, error) {
+	if m.ReplyFunc != nil {
+		return m.ReplyFunc(mb)
+	}
+	return func(uint32) func(uint32) func(string) func(byte) (note.Model, error) {
+		return func(uint32) func(string) func(byte) (note.Model, error) {
+			return func(string) func(byte) (note.Model, error) {
+				return func(byte) (note.Model, error) {
+					return note.Model{}, nil
+				}
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) ReplyAndEmit(characterId uint32, noteId uint32, msg string, flag byte) (note.Model, error) {
+	if m.ReplyAndEmitFunc != nil {
+		return m.ReplyAndEmitFunc(characterId, noteId, msg, flag)
+	}
+	return note.Model{}, nil
+}
+
 func (m *ProcessorMock) Update(mb *message.Buffer) func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (note.Model, error) {
 	if m.UpdateFunc != nil {
 		return m.UpdateFunc(mb)
@@ -98,6 +194,205 @@ func (m *ProcessorMock) DeleteAllAndEmit(characterId uint32) error {
 	return nil
 }
 
+func (m *ProcessorMock) Discard(mb *message.Buffer) func(characterId uint32) func(noteIds []uint32) func(force bool) error {
+	if m.DiscardFunc != nil {
+		return m.DiscardFunc(mb)
+	}
+	return func(uint32) func([]uint32) func(bool) error {
+		return func([]uint32) func(bool) error {
+			return func(bool) error {
+				return nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) DiscardAndEmit(characterId uint32, noteIds []uint32, force bool) error {
+	if m.DiscardAndEmitFunc != nil {
+		return m.DiscardAndEmitFunc(characterId, noteIds, force)
+	}
+	return nil
+}
+
+func (m *ProcessorMock) Star(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(starred bool) (note.Model, error) {
+	if m.StarFunc != nil {
+		return m.StarFunc(mb)
+	}
+	return func(uint32) func(uint32) func(bool) (note.Model, error) {
+		return func(uint32) func(bool) (note.Model, error) {
+			return func(bool) (note.Model, error) {
+				return note.Model{}, nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) StarAndEmit(characterId uint32, noteId uint32, starred bool) (note.Model, error) {
+	if m.StarAndEmitFunc != nil {
+		return m.StarAndEmitFunc(characterId, noteId, starred)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) Pin(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(pinned bool) (note.Model, error) {
+	if m.PinFunc != nil {
+		return m.PinFunc(mb)
+	}
+	return func(uint32) func(uint32) func(bool) (note.Model, error) {
+		return func(uint32) func(bool) (note.Model, error) {
+			return func(bool) (note.Model, error) {
+				return note.Model{}, nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) PinAndEmit(characterId uint32, noteId uint32, pinned bool) (note.Model, error) {
+	if m.PinAndEmitFunc != nil {
+		return m.PinAndEmitFunc(characterId, noteId, pinned)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) Archive(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(archived bool) (note.Model, error) {
+	if m.ArchiveFunc != nil {
+		return m.ArchiveFunc(mb)
+	}
+	return func(uint32) func(uint32) func(bool) (note.Model, error) {
+		return func(uint32) func(bool) (note.Model, error) {
+			return func(bool) (note.Model, error) {
+				return note.Model{}, nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) ArchiveAndEmit(characterId uint32, noteId uint32, archived bool) (note.Model, error) {
+	if m.ArchiveAndEmitFunc != nil {
+		return m.ArchiveAndEmitFunc(characterId, noteId, archived)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) Label(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(labels []string) (note.Model, error) {
+	if m.LabelFunc != nil {
+		return m.LabelFunc(mb)
+	}
+	return func(uint32) func(uint32) func([]string) (note.Model, error) {
+		return func(uint32) func([]string) (note.Model, error) {
+			return func([]string) (note.Model, error) {
+				return note.Model{}, nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) LabelAndEmit(characterId uint32, noteId uint32, labels []string) (note.Model, error) {
+	if m.LabelAndEmitFunc != nil {
+		return m.LabelAndEmitFunc(characterId, noteId, labels)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) Organize(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(changes note.Organization) (note.Model, error) {
+	if m.OrganizeFunc != nil {
+		return m.OrganizeFunc(mb)
+	}
+	return func(uint32) func(uint32) func(note.Organization) (note.Model, error) {
+		return func(uint32) func(note.Organization) (note.Model, error) {
+			return func(note.Organization) (note.Model, error) {
+				return note.Model{}, nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) OrganizeAndEmit(characterId uint32, noteId uint32, changes note.Organization) (note.Model, error) {
+	if m.OrganizeAndEmitFunc != nil {
+		return m.OrganizeAndEmitFunc(characterId, noteId, changes)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) MarkRead(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (note.Model, error) {
+	if m.MarkReadFunc != nil {
+		return m.MarkReadFunc(mb)
+	}
+	return func(uint32) func(uint32) (note.Model, error) {
+		return func(uint32) (note.Model, error) {
+			return note.Model{}, nil
+		}
+	}
+}
+
+func (m *ProcessorMock) MarkReadAndEmit(characterId uint32, noteId uint32) (note.Model, error) {
+	if m.MarkReadAndEmitFunc != nil {
+		return m.MarkReadAndEmitFunc(characterId, noteId)
+	}
+	return note.Model{}, nil
+}
+
+func (m *ProcessorMock) HideSent(senderId uint32) func(noteIds []uint32) error {
+	if m.HideSentFunc != nil {
+		return m.HideSentFunc(senderId)
+	}
+	return func([]uint32) error {
+		return nil
+	}
+}
+
+func (m *ProcessorMock) Claim(mb *message.Buffer) func(characterId uint32) func(noteId uint32) error {
+	if m.ClaimFunc != nil {
+		return m.ClaimFunc(mb)
+	}
+	return func(uint32) func(uint32) error {
+		return func(uint32) error {
+			return nil
+		}
+	}
+}
+
+func (m *ProcessorMock) ClaimAndEmit(characterId uint32, noteId uint32) error {
+	if m.ClaimAndEmitFunc != nil {
+		return m.ClaimAndEmitFunc(characterId, noteId)
+	}
+	return nil
+}
+
+func (m *ProcessorMock) Expire(mb *message.Buffer) func(id uint32) error {
+	if m.ExpireFunc != nil {
+		return m.ExpireFunc(mb)
+	}
+	return func(id uint32) error {
+		return nil
+	}
+}
+
+func (m *ProcessorMock) ExpireAndEmit(id uint32) error {
+	if m.ExpireAndEmitFunc != nil {
+		return m.ExpireAndEmitFunc(id)
+	}
+	return nil
+}
+
+func (m *ProcessorMock) Restore(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (note.Model, error) {
+	if m.RestoreFunc != nil {
+		return m.RestoreFunc(mb)
+	}
+	return func(uint32) func(uint32) (note.Model, error) {
+		return func(uint32) (note.Model, error) {
+			return note.Model{}, nil
+		}
+	}
+}
+
+func (m *ProcessorMock) RestoreAndEmit(characterId uint32, noteId uint32) (note.Model, error) {
+	if m.RestoreAndEmitFunc != nil {
+		return m.RestoreAndEmitFunc(characterId, noteId)
+	}
+	return note.Model{}, nil
+}
+
 func (m *ProcessorMock) ByIdProvider(id uint32) model.Provider[note.Model] {
 	if m.ByIdProviderFunc != nil {
 		return m.ByIdProviderFunc(id)
@@ -112,9 +407,165 @@ func (m *ProcessorMock) ByCharacterProvider(characterId uint32) model.Provider[[
 	return model.FixedProvider([]note.Model{})
 }
 
+func (m *ProcessorMock) ByCharacterAndFilterProvider(characterId uint32, f note.Filter) model.Provider[[]note.Model] {
+	if m.ByCharacterAndFilterProviderFunc != nil {
+		return m.ByCharacterAndFilterProviderFunc(characterId, f)
+	}
+	return model.FixedProvider([]note.Model{})
+}
+
+func (m *ProcessorMock) BySenderProvider(senderId uint32) model.Provider[[]note.Model] {
+	if m.BySenderProviderFunc != nil {
+		return m.BySenderProviderFunc(senderId)
+	}
+	return model.FixedProvider([]note.Model{})
+}
+
+func (m *ProcessorMock) ByParticipantProvider(characterId uint32) model.Provider[[]note.Model] {
+	if m.ByParticipantProviderFunc != nil {
+		return m.ByParticipantProviderFunc(characterId)
+	}
+	return model.FixedProvider([]note.Model{})
+}
+
 func (m *ProcessorMock) InTenantProvider() model.Provider[[]note.Model] {
 	if m.InTenantProviderFunc != nil {
 		return m.InTenantProviderFunc()
 	}
 	return model.FixedProvider([]note.Model{})
 }
+
+func (m *ProcessorMock) ExpiredProvider(asOf time.Time) model.Provider[[]note.Model] {
+	if m.ExpiredProviderFunc != nil {
+		return m.ExpiredProviderFunc(asOf)
+	}
+	return model.FixedProvider([]note.Model{})
+}
+
+func (m *ProcessorMock) PurgeBatch(mb *message.Buffer) func(limit int) func(emitEvents bool) (int, error) {
+	if m.PurgeBatchFunc != nil {
+		return m.PurgeBatchFunc(mb)
+	}
+	return func(int) func(bool) (int, error) {
+		return func(bool) (int, error) {
+			return 0, nil
+		}
+	}
+}
+
+func (m *ProcessorMock) PurgeBatchAndEmit(limit int, emitEvents bool) (int, error) {
+	if m.PurgeBatchAndEmitFunc != nil {
+		return m.PurgeBatchAndEmitFunc(limit, emitEvents)
+	}
+	return 0, nil
+}
+
+func (m *ProcessorMock) PurgeAudit(purged int64) error {
+	if m.PurgeAuditFunc != nil {
+		return m.PurgeAuditFunc(purged)
+	}
+	return nil
+}
+
+func (m *ProcessorMock) Export(o model.Operator[note.Model]) error {
+	if m.ExportFunc != nil {
+		return m.ExportFunc(o)
+	}
+	return nil
+}
+
+func (m *ProcessorMock) Importer(characterIds map[uint32]uint32, policy note.ConflictPolicy) *note.Importer {
+	if m.ImporterFunc != nil {
+		return m.ImporterFunc(characterIds, policy)
+	}
+	return nil
+}
+
+func (m *ProcessorMock) Clone(mb *message.Buffer) func(sourceTenantId uuid.UUID) func(characterIds map[uint32]uint32) func(flags map[byte]byte) (note.ImportResult, error) {
+	if m.CloneFunc != nil {
+		return m.CloneFunc(mb)
+	}
+	return func(uuid.UUID) func(map[uint32]uint32) func(map[byte]byte) (note.ImportResult, error) {
+		return func(map[uint32]uint32) func(map[byte]byte) (note.ImportResult, error) {
+			return func(map[byte]byte) (note.ImportResult, error) {
+				return note.ImportResult{}, nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) CloneAndEmit(sourceTenantId uuid.UUID, characterIds map[uint32]uint32, flags map[byte]byte) (note.ImportResult, error) {
+	if m.CloneAndEmitFunc != nil {
+		return m.CloneAndEmitFunc(sourceTenantId, characterIds, flags)
+	}
+	return note.ImportResult{}, nil
+}
+
+func (m *ProcessorMock) CountProvider() model.Provider[int64] {
+	if m.CountProviderFunc != nil {
+		return m.CountProviderFunc()
+	}
+	return model.FixedProvider[int64](0)
+}
+
+func (m *ProcessorMock) CountIncludingDeletedProvider() model.Provider[int64] {
+	if m.CountIncludingDeletedProviderFunc != nil {
+		return m.CountIncludingDeletedProviderFunc()
+	}
+	return model.FixedProvider[int64](0)
+}
+
+func (m *ProcessorMock) HistoryProvider(noteId uint32) model.Provider[[]audit.Model] {
+	if m.HistoryProviderFunc != nil {
+		return m.HistoryProviderFunc(noteId)
+	}
+	return model.FixedProvider([]audit.Model{})
+}
+
+func (m *ProcessorMock) AuditByCharacterProvider(characterId uint32) model.Provider[[]audit.Model] {
+	if m.AuditByCharacterProviderFunc != nil {
+		return m.AuditByCharacterProviderFunc(characterId)
+	}
+	return model.FixedProvider([]audit.Model{})
+}
+
+func (m *ProcessorMock) SummaryProvider(characterId uint32) model.Provider[summary.Model] {
+	if m.SummaryProviderFunc != nil {
+		return m.SummaryProviderFunc(characterId)
+	}
+	return model.FixedProvider(summary.Model{})
+}
+
+func (m *ProcessorMock) Reconcile(mb *message.Buffer) (int, error) {
+	if m.ReconcileFunc != nil {
+		return m.ReconcileFunc(mb)
+	}
+	return 0, nil
+}
+
+func (m *ProcessorMock) ReconcileAndEmit() (int, error) {
+	if m.ReconcileAndEmitFunc != nil {
+		return m.ReconcileAndEmitFunc()
+	}
+	return 0, nil
+}
+
+func (m *ProcessorMock) NotifyPending(mb *message.Buffer) func(worldId byte) func(channelId byte) func(characterId uint32) error {
+	if m.NotifyPendingFunc != nil {
+		return m.NotifyPendingFunc(mb)
+	}
+	return func(byte) func(byte) func(uint32) error {
+		return func(byte) func(uint32) error {
+			return func(uint32) error {
+				return nil
+			}
+		}
+	}
+}
+
+func (m *ProcessorMock) NotifyPendingAndEmit(worldId byte, channelId byte, characterId uint32) error {
+	if m.NotifyPendingAndEmitFunc != nil {
+		return m.NotifyPendingAndEmitFunc(worldId, channelId, characterId)
+	}
+	return nil
+}
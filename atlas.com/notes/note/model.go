@@ -1,17 +1,29 @@
 package note
 
 import (
+	"atlas-notes/attachment"
 	"time"
 )
 
 // Model represents a note for a character
 type Model struct {
-	id          uint32
-	characterId uint32
-	senderId    uint32
-	message     string
-	timestamp   time.Time
-	flag        byte
+	id             uint32
+	characterId    uint32
+	senderId       uint32
+	message        string
+	timestamp      time.Time
+	flag           byte
+	expiration     time.Time
+	attachments    []attachment.Model
+	inReplyTo      uint32
+	threadId       uint32
+	starred        bool
+	pinned         bool
+	archived       bool
+	labels         []string
+	deletedAt      time.Time
+	senderHiddenAt time.Time
+	readAt         time.Time
 }
 
 // Id returns the note's ID
@@ -44,14 +56,105 @@ func (n Model) Flag() byte {
 	return n.flag
 }
 
+// Expiration returns when the note expires, or the zero time if it never does
+func (n Model) Expiration() time.Time {
+	return n.expiration
+}
+
+// Attachments returns the items and mesos carried by the note
+func (n Model) Attachments() []attachment.Model {
+	return n.attachments
+}
+
+// HasUnclaimedAttachments returns true if any attachment carried by the note is still unclaimed
+func (n Model) HasUnclaimedAttachments() bool {
+	for _, a := range n.attachments {
+		if a.Unclaimed() {
+			return true
+		}
+	}
+	return false
+}
+
+// InReplyTo returns the ID of the note this note replies to, or 0 if it starts a conversation
+func (n Model) InReplyTo() uint32 {
+	return n.inReplyTo
+}
+
+// ThreadId returns the ID of the conversation the note belongs to. A note which starts a conversation is its own
+// thread.
+func (n Model) ThreadId() uint32 {
+	if n.threadId == 0 {
+		return n.id
+	}
+	return n.threadId
+}
+
+// Starred returns true if the recipient starred the note
+func (n Model) Starred() bool {
+	return n.starred
+}
+
+// Pinned returns true if the recipient pinned the note
+func (n Model) Pinned() bool {
+	return n.pinned
+}
+
+// Archived returns true if the recipient archived the note. Archived notes are kept out of the default list and do not
+// count towards inbox capacity.
+func (n Model) Archived() bool {
+	return n.archived
+}
+
+// Labels returns the user-defined labels the recipient filed the note under
+func (n Model) Labels() []string {
+	return n.labels
+}
+
+// DeletedAt returns when the note was deleted by its recipient, or the zero time if it has not been
+func (n Model) DeletedAt() time.Time {
+	return n.deletedAt
+}
+
+// Deleted returns true if the note has been deleted by its recipient
+func (n Model) Deleted() bool {
+	return !n.deletedAt.IsZero()
+}
+
+// SenderHiddenAt returns when the sender hid the note from their sent items, or the zero time if they have not
+func (n Model) SenderHiddenAt() time.Time {
+	return n.senderHiddenAt
+}
+
+// ReadAt returns when the recipient first read the note, or the zero time if it has not
+func (n Model) ReadAt() time.Time {
+	return n.readAt
+}
+
+// Read returns true if the recipient has read the note. Notes are unread until then, whether archived or not.
+func (n Model) Read() bool {
+	return !n.readAt.IsZero()
+}
+
 // Builder is a builder for creating Model instances
 type Builder struct {
-	id          uint32
-	characterId uint32
-	senderId    uint32
-	message     string
-	timestamp   time.Time
-	flag        byte
+	id             uint32
+	characterId    uint32
+	senderId       uint32
+	message        string
+	timestamp      time.Time
+	flag           byte
+	expiration     time.Time
+	attachments    []attachment.Model
+	inReplyTo      uint32
+	threadId       uint32
+	starred        bool
+	pinned         bool
+	archived       bool
+	labels         []string
+	deletedAt      time.Time
+	senderHiddenAt time.Time
+	readAt         time.Time
 }
 
 // NewBuilder creates a new Builder
@@ -61,6 +164,29 @@ func NewBuilder() *Builder {
 	}
 }
 
+// Clone creates a Builder holding the values of an existing Model
+func Clone(n Model) *Builder {
+	return &Builder{
+		id:             n.id,
+		characterId:    n.characterId,
+		senderId:       n.senderId,
+		message:        n.message,
+		timestamp:      n.timestamp,
+		flag:           n.flag,
+		expiration:     n.expiration,
+		attachments:    n.attachments,
+		inReplyTo:      n.inReplyTo,
+		threadId:       n.threadId,
+		starred:        n.starred,
+		pinned:         n.pinned,
+		archived:       n.archived,
+		labels:         n.labels,
+		deletedAt:      n.deletedAt,
+		senderHiddenAt: n.senderHiddenAt,
+		readAt:         n.readAt,
+	}
+}
+
 // SetId sets the note's ID
 func (b *Builder) SetId(id uint32) *Builder {
 	b.id = id
@@ -97,14 +223,91 @@ func (b *Builder) SetFlag(flag byte) *Builder {
 	return b
 }
 
+// SetExpiration sets when the note expires
+func (b *Builder) SetExpiration(expiration time.Time) *Builder {
+	b.expiration = expiration
+	return b
+}
+
+// SetAttachments sets the items and mesos carried by the note
+func (b *Builder) SetAttachments(attachments []attachment.Model) *Builder {
+	b.attachments = attachments
+	return b
+}
+
+// SetInReplyTo sets the ID of the note this note replies to
+func (b *Builder) SetInReplyTo(inReplyTo uint32) *Builder {
+	b.inReplyTo = inReplyTo
+	return b
+}
+
+// SetThreadId sets the ID of the conversation the note belongs to
+func (b *Builder) SetThreadId(threadId uint32) *Builder {
+	b.threadId = threadId
+	return b
+}
+
+// SetStarred sets whether the recipient starred the note
+func (b *Builder) SetStarred(starred bool) *Builder {
+	b.starred = starred
+	return b
+}
+
+// SetPinned sets whether the recipient pinned the note
+func (b *Builder) SetPinned(pinned bool) *Builder {
+	b.pinned = pinned
+	return b
+}
+
+// SetArchived sets whether the recipient archived the note
+func (b *Builder) SetArchived(archived bool) *Builder {
+	b.archived = archived
+	return b
+}
+
+// SetLabels sets the user-defined labels the recipient filed the note under
+func (b *Builder) SetLabels(labels []string) *Builder {
+	b.labels = labels
+	return b
+}
+
+// SetDeletedAt sets when the note was deleted by its recipient
+func (b *Builder) SetDeletedAt(deletedAt time.Time) *Builder {
+	b.deletedAt = deletedAt
+	return b
+}
+
+// SetSenderHiddenAt sets when the sender hid the note from their sent items
+func (b *Builder) SetSenderHiddenAt(senderHiddenAt time.Time) *Builder {
+	b.senderHiddenAt = senderHiddenAt
+	return b
+}
+
+// SetReadAt sets when the recipient first read the note
+func (b *Builder) SetReadAt(readAt time.Time) *Builder {
+	b.readAt = readAt
+	return b
+}
+
 // Build creates a new Model with the builder's values
 func (b *Builder) Build() Model {
 	return Model{
-		id:          b.id,
-		characterId: b.characterId,
-		senderId:    b.senderId,
-		message:     b.message,
-		timestamp:   b.timestamp,
-		flag:        b.flag,
+		id:             b.id,
+		characterId:    b.characterId,
+		senderId:       b.senderId,
+		message:        b.message,
+		timestamp:      b.timestamp,
+		flag:           b.flag,
+		expiration:     b.expiration,
+		attachments:    b.attachments,
+		inReplyTo:      b.inReplyTo,
+		threadId:       b.threadId,
+		starred:        b.starred,
+		pinned:         b.pinned,
+		archived:       b.archived,
+		labels:         b.labels,
+		deletedAt:      b.deletedAt,
+		senderHiddenAt: b.senderHiddenAt,
+		readAt:         b.readAt,
 	}
 }
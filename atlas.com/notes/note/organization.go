@@ -0,0 +1,15 @@
+package note
+
+// Organization is a change to how a recipient keeps a note. Unset fields are left unchanged, and a set Labels replaces
+// the labels the note is filed under.
+type Organization struct {
+	Starred  *bool
+	Pinned   *bool
+	Archived *bool
+	Labels   *[]string
+}
+
+// Empty returns true if the change sets nothing
+func (o Organization) Empty() bool {
+	return o.Starred == nil && o.Pinned == nil && o.Archived == nil && o.Labels == nil
+}
@@ -1,48 +1,281 @@
 package note
 
 import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/auth"
+	"atlas-notes/configuration"
 	"atlas-notes/kafka/message"
 	"atlas-notes/kafka/message/note"
 	"atlas-notes/kafka/producer"
+	"atlas-notes/summary"
+	"atlas-notes/tracing"
 	"context"
+	"encoding/json"
+	"errors"
 	"github.com/Chronicle20/atlas-model/model"
 	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	EnvAttachmentExpiration = configuration.EnvAttachmentExpiration
+	EnvInboxCapacity        = configuration.EnvInboxCapacity
+	EnvRestoreGracePeriod   = configuration.EnvRestoreGracePeriod
+
+	exportBatchSize = 500
+
+	// pendingSenderLimit is how many of the most recent senders a pending notification names
+	pendingSenderLimit = 5
+
+	AttributeCharacterId = attribute.Key("character.id")
+	AttributeSenderId    = attribute.Key("sender.id")
+	AttributeNoteId      = attribute.Key("note.id")
+	AttributeNoteCount   = attribute.Key("note.count")
+)
+
+var (
+	ErrNotRecipient         = errors.New("note does not belong to character")
+	ErrNotParticipant       = errors.New("character is not a participant of the note")
+	ErrNoReplyAddress       = errors.New("note has no character to reply to")
+	ErrInboxFull            = errors.New("character inbox is full")
+	ErrUnclaimedAttachments = errors.New("note has unclaimed attachments")
+	ErrNotDeleted           = errors.New("note has not been deleted")
+	ErrRestoreWindowClosed  = errors.New("note was deleted too long ago to restore")
+	ErrSameTenant           = errors.New("notes cannot be cloned into the tenant they belong to")
+	ErrMessageTooLong       = errors.New("note message is too long")
+	ErrFlagNotAllowed       = errors.New("note flag is not allowed")
+	ErrRateLimited          = errors.New("character has sent too many notes")
 )
 
 type Processor interface {
 	Create(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error)
 	CreateAndEmit(characterId uint32, senderId uint32, msg string, flag byte) (Model, error)
+	CreateWithAttachments(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) func(attachments []attachment.Model) (Model, error)
+	CreateWithAttachmentsAndEmit(characterId uint32, senderId uint32, msg string, flag byte, attachments []attachment.Model) (Model, error)
+	Reply(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(msg string) func(flag byte) (Model, error)
+	ReplyAndEmit(characterId uint32, noteId uint32, msg string, flag byte) (Model, error)
 	Update(mb *message.Buffer) func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error)
 	UpdateAndEmit(id uint32, characterId uint32, senderId uint32, msg string, flag byte) (Model, error)
 	Delete(mb *message.Buffer) func(id uint32) error
 	DeleteAndEmit(id uint32) error
 	DeleteAll(mb *message.Buffer) func(characterId uint32) error
 	DeleteAllAndEmit(characterId uint32) error
-	Discard(mb *message.Buffer) func(characterId uint32) func(noteIds []uint32) error
-	DiscardAndEmit(characterId uint32, noteIds []uint32) error
+	Discard(mb *message.Buffer) func(characterId uint32) func(noteIds []uint32) func(force bool) error
+	DiscardAndEmit(characterId uint32, noteIds []uint32, force bool) error
+	Star(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(starred bool) (Model, error)
+	StarAndEmit(characterId uint32, noteId uint32, starred bool) (Model, error)
+	Pin(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(pinned bool) (Model, error)
+	PinAndEmit(characterId uint32, noteId uint32, pinned bool) (Model, error)
+	Archive(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(archived bool) (Model, error)
+	ArchiveAndEmit(characterId uint32, noteId uint32, archived bool) (Model, error)
+	Label(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(labels []string) (Model, error)
+	LabelAndEmit(characterId uint32, noteId uint32, labels []string) (Model, error)
+	Organize(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(changes Organization) (Model, error)
+	OrganizeAndEmit(characterId uint32, noteId uint32, changes Organization) (Model, error)
+	MarkRead(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (Model, error)
+	MarkReadAndEmit(characterId uint32, noteId uint32) (Model, error)
+	HideSent(senderId uint32) func(noteIds []uint32) error
+	Claim(mb *message.Buffer) func(characterId uint32) func(noteId uint32) error
+	ClaimAndEmit(characterId uint32, noteId uint32) error
+	Expire(mb *message.Buffer) func(id uint32) error
+	ExpireAndEmit(id uint32) error
+	Restore(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (Model, error)
+	RestoreAndEmit(characterId uint32, noteId uint32) (Model, error)
+	PurgeBatch(mb *message.Buffer) func(limit int) func(emitEvents bool) (int, error)
+	PurgeBatchAndEmit(limit int, emitEvents bool) (int, error)
+	PurgeAudit(purged int64) error
+	Export(o model.Operator[Model]) error
+	Importer(characterIds map[uint32]uint32, policy ConflictPolicy) *Importer
+	Clone(mb *message.Buffer) func(sourceTenantId uuid.UUID) func(characterIds map[uint32]uint32) func(flags map[byte]byte) (ImportResult, error)
+	CloneAndEmit(sourceTenantId uuid.UUID, characterIds map[uint32]uint32, flags map[byte]byte) (ImportResult, error)
 	ByIdProvider(id uint32) model.Provider[Model]
 	ByCharacterProvider(characterId uint32) model.Provider[[]Model]
+	ByCharacterAndFilterProvider(characterId uint32, f Filter) model.Provider[[]Model]
+	BySenderProvider(senderId uint32) model.Provider[[]Model]
+	ByParticipantProvider(characterId uint32) model.Provider[[]Model]
 	InTenantProvider() model.Provider[[]Model]
+	ExpiredProvider(asOf time.Time) model.Provider[[]Model]
+	CountProvider() model.Provider[int64]
+	CountIncludingDeletedProvider() model.Provider[int64]
+	HistoryProvider(noteId uint32) model.Provider[[]audit.Model]
+	AuditByCharacterProvider(characterId uint32) model.Provider[[]audit.Model]
+	SummaryProvider(characterId uint32) model.Provider[summary.Model]
+	Reconcile(mb *message.Buffer) (int, error)
+	ReconcileAndEmit() (int, error)
+	NotifyPending(mb *message.Buffer) func(worldId byte) func(channelId byte) func(characterId uint32) error
+	NotifyPendingAndEmit(worldId byte, channelId byte, characterId uint32) error
 }
 
 type ProcessorImpl struct {
 	l        logrus.FieldLogger
 	ctx      context.Context
-	db       *gorm.DB
+	r        Repository
 	t        tenant.Model
 	producer producer.Provider
+	cfg      configuration.Accessor
+	acting   auth.ActingCharacter
+	scoped   bool
+}
+
+// Storage is what a Processor may store notes in: a database, or a Repository
+type Storage interface {
+	*gorm.DB | *GormRepository | *MemoryRepository
 }
 
-func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+// NewProcessor creates a Processor storing notes in s. A nil database stands for running without one, in which case
+// notes are held in memory, shared by every processor in the process.
+func NewProcessor[S Storage](l logrus.FieldLogger, ctx context.Context, s S) Processor {
+	var r Repository
+	var db *gorm.DB
+	switch v := any(s).(type) {
+	case *gorm.DB:
+		if v == nil {
+			r = getSharedMemoryRepository()
+		} else {
+			r = NewGormRepository(v)
+		}
+		db = v
+	case *GormRepository:
+		r = v
+		db = v.db
+	case *MemoryRepository:
+		r = v
+	}
+
+	t := tenant.MustFromContext(ctx)
+	getTenantRegistry().Add(l, db, t)
+	acting, scoped := auth.ActingCharacterFromContext(ctx)
 	return &ProcessorImpl{
 		l:        l,
 		ctx:      ctx,
-		db:       db,
-		t:        tenant.MustFromContext(ctx),
+		r:        r.WithContext(ctx),
+		t:        t,
 		producer: producer.ProviderImpl(l)(ctx),
+		cfg:      configuration.NewProcessor(l, ctx, db).Accessor(),
+		acting:   acting,
+		scoped:   scoped,
+	}
+}
+
+// traced runs an operation of the processor within a span, a child of any in its context, recording the error the
+// operation returns. The operation is given a processor whose queries are made within the span.
+func traced[R any](p *ProcessorImpl, operation string, attrs []attribute.KeyValue, f func(p *ProcessorImpl) (R, error)) (R, error) {
+	ctx, span := tracing.Tracer().Start(p.ctx, "note."+operation, trace.WithAttributes(attrs...))
+	sp := *p
+	sp.ctx = ctx
+	sp.r = p.r.WithContext(ctx)
+	result, err := f(&sp)
+	tracing.EndSpan(span, err)
+	return result, err
+}
+
+// tracedErr runs an operation of the processor which returns only an error within a span, as traced does
+func tracedErr(p *ProcessorImpl, operation string, attrs []attribute.KeyValue, f func(p *ProcessorImpl) error) error {
+	_, err := traced(p, operation, attrs, func(p *ProcessorImpl) (struct{}, error) {
+		return struct{}{}, f(p)
+	})
+	return err
+}
+
+// setNoteCount records on the span of an operation how many notes it covered
+func (p *ProcessorImpl) setNoteCount(count int) {
+	trace.SpanFromContext(p.ctx).SetAttributes(AttributeNoteCount.Int(count))
+}
+
+// actsFor returns gorm.ErrRecordNotFound when the processor acts on behalf of a character other than the one given, so
+// a character cannot learn of another's notes
+func (p *ProcessorImpl) actsFor(characterId uint32) error {
+	if p.scoped && !p.acting.Is(characterId) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// actsForTenant returns gorm.ErrRecordNotFound when the processor acts on behalf of a character, which may not read
+// the notes of the whole tenant
+func (p *ProcessorImpl) actsForTenant() error {
+	if p.scoped {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// canRead returns gorm.ErrRecordNotFound when the processor acts on behalf of a character which neither received nor
+// sent the note
+func (p *ProcessorImpl) canRead(m Model) error {
+	if p.scoped && !p.acting.Is(m.CharacterId()) && !p.acting.Is(m.SenderId()) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// holds returns gorm.ErrRecordNotFound when the processor acts on behalf of a character which did not receive the note
+func (p *ProcessorImpl) holds(m Model) error {
+	if p.scoped && !p.acting.Is(m.CharacterId()) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// notRecipient is the error for a note a character does not hold. Acting on behalf of the character, the note is not
+// found, as though it did not exist.
+func (p *ProcessorImpl) notRecipient() error {
+	if p.scoped {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrNotRecipient
+}
+
+// checkCapacity returns ErrInboxFull if the character cannot receive another note
+func (p *ProcessorImpl) checkCapacity(characterId uint32) error {
+	c := p.cfg().InboxCapacity()
+	if c == 0 {
+		return nil
+	}
+	count, err := p.r.InboxCount(p.t.Id(), characterId)
+	if err != nil {
+		return err
+	}
+	if count >= c {
+		return ErrInboxFull
+	}
+	return nil
+}
+
+// checkContent returns ErrMessageTooLong or ErrFlagNotAllowed if a note may not carry the message or flag
+func (p *ProcessorImpl) checkContent(msg string, flag byte) error {
+	cfg := p.cfg()
+	if l := cfg.MaxMessageLength(); l > 0 && utf8.RuneCountInString(msg) > l {
+		return ErrMessageTooLong
+	}
+	if !cfg.FlagAllowed(flag) {
+		return ErrFlagNotAllowed
+	}
+	return nil
+}
+
+// checkSendRate returns ErrRateLimited if the character has sent as many notes as it may within the send rate window
+func (p *ProcessorImpl) checkSendRate(senderId uint32) error {
+	cfg := p.cfg()
+	limit := cfg.SendRateLimit()
+	if limit == 0 || senderId == 0 {
+		return nil
 	}
+	count, err := p.r.SentCount(p.t.Id(), senderId, time.Now().Add(-cfg.SendRateWindow()))
+	if err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return ErrRateLimited
+	}
+	return nil
 }
 
 // Create creates a new note
@@ -51,22 +284,7 @@ func (p *ProcessorImpl) Create(mb *message.Buffer) func(characterId uint32) func
 		return func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
 			return func(msg string) func(flag byte) (Model, error) {
 				return func(flag byte) (Model, error) {
-					m := NewBuilder().
-						SetCharacterId(characterId).
-						SetSenderId(senderId).
-						SetMessage(msg).
-						SetFlag(flag).
-						Build()
-
-					m, err := createNote(p.db)(p.t.Id())(m)
-					if err != nil {
-						return Model{}, err
-					}
-					err = mb.Put(note.EnvEventTopicNoteStatus, CreateNoteStatusEventProvider(m.CharacterId(), m.Id(), m.SenderId(), m.Message(), m.Flag(), m.Timestamp()))
-					if err != nil {
-						return Model{}, err
-					}
-					return m, nil
+					return p.CreateWithAttachments(mb)(characterId)(senderId)(msg)(flag)(nil)
 				}
 			}
 		}
@@ -78,30 +296,224 @@ func (p *ProcessorImpl) CreateAndEmit(characterId uint32, senderId uint32, msg s
 	return message.EmitWithResult[Model, byte](p.producer)(model.Flip(model.Flip(model.Flip(p.Create)(characterId))(senderId))(msg))(flag)
 }
 
-// Update updates an existing note
-func (p *ProcessorImpl) Update(mb *message.Buffer) func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
-	return func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
-		return func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
-			return func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
-				return func(msg string) func(flag byte) (Model, error) {
-					return func(flag byte) (Model, error) {
+// CreateWithAttachments creates a new note carrying items or mesos for the recipient to claim. Notes carrying
+// attachments expire, at which point unclaimed attachments are returned to the sender.
+func (p *ProcessorImpl) CreateWithAttachments(mb *message.Buffer) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) func(attachments []attachment.Model) (Model, error) {
+	return func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) func(attachments []attachment.Model) (Model, error) {
+		return func(senderId uint32) func(msg string) func(flag byte) func(attachments []attachment.Model) (Model, error) {
+			return func(msg string) func(flag byte) func(attachments []attachment.Model) (Model, error) {
+				return func(flag byte) func(attachments []attachment.Model) (Model, error) {
+					return func(attachments []attachment.Model) (Model, error) {
+						return traced(p, "Create", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeSenderId.Int64(int64(senderId))}, func(p *ProcessorImpl) (Model, error) {
+							b := NewBuilder().
+								SetCharacterId(characterId).
+								SetSenderId(senderId).
+								SetMessage(msg).
+								SetFlag(flag)
+
+							err := p.checkContent(msg, flag)
+							if err != nil {
+								return Model{}, err
+							}
+							err = p.checkSendRate(senderId)
+							if err != nil {
+								return Model{}, err
+							}
+							err = p.checkCapacity(characterId)
+							if err != nil {
+								return Model{}, err
+							}
+
+							if len(attachments) > 0 {
+								for _, a := range attachments {
+									if !a.Valid() {
+										return Model{}, attachment.ErrInvalidAttachment
+									}
+								}
+								b.SetAttachments(attachments).SetExpiration(time.Now().Add(p.cfg().AttachmentExpiration()))
+							}
+
+							var m Model
+							var changed []uint32
+							err = p.r.Transaction(func(r Repository) error {
+								c, err := r.Create(p.t.Id(), b.Build())
+								if err != nil {
+									return err
+								}
+								m = c
+								changed, err = p.record(r, uuid.New(), audit.ActionCreate, nil, &m)
+								return err
+							})
+							if err != nil {
+								return Model{}, err
+							}
+							err = mb.Put(note.EnvEventTopicNoteStatus, CreateNoteStatusEventProvider(m.CharacterId(), m.Id(), m.SenderId(), m.Message(), m.Flag(), m.Timestamp()))
+							if err != nil {
+								return Model{}, err
+							}
+							err = p.announce(mb, changed)
+							if err != nil {
+								return Model{}, err
+							}
+							return m, nil
+						})
+					}
+				}
+			}
+		}
+	}
+}
+
+// CreateWithAttachmentsAndEmit creates a new note carrying attachments and emits a status event
+func (p *ProcessorImpl) CreateWithAttachmentsAndEmit(characterId uint32, senderId uint32, msg string, flag byte, attachments []attachment.Model) (Model, error) {
+	return message.EmitWithResult[Model, []attachment.Model](p.producer)(model.Flip(model.Flip(model.Flip(model.Flip(p.CreateWithAttachments)(characterId))(senderId))(msg))(flag))(attachments)
+}
+
+// Reply creates a note replying to another, addressed to the other participant of the conversation. The original
+// note only needs to be known, not held, so a character may reply to a note it has since discarded.
+func (p *ProcessorImpl) Reply(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(msg string) func(flag byte) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(msg string) func(flag byte) (Model, error) {
+		return func(noteId uint32) func(msg string) func(flag byte) (Model, error) {
+			return func(msg string) func(flag byte) (Model, error) {
+				return func(flag byte) (Model, error) {
+					return traced(p, "Reply", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+						err := p.actsFor(characterId)
+						if err != nil {
+							return Model{}, err
+						}
+						o, err := p.r.ByIdIncludingDeleted(p.t.Id(), noteId)
+						if err != nil {
+							return Model{}, err
+						}
+						err = p.canRead(o)
+						if err != nil {
+							return Model{}, err
+						}
+
+						var recipientId uint32
+						switch characterId {
+						case o.CharacterId():
+							recipientId = o.SenderId()
+						case o.SenderId():
+							recipientId = o.CharacterId()
+						default:
+							return Model{}, ErrNotParticipant
+						}
+						if recipientId == 0 {
+							return Model{}, ErrNoReplyAddress
+						}
+						err = p.checkContent(msg, flag)
+						if err != nil {
+							return Model{}, err
+						}
+						err = p.checkSendRate(characterId)
+						if err != nil {
+							return Model{}, err
+						}
+						err = p.checkCapacity(recipientId)
+						if err != nil {
+							return Model{}, err
+						}
+
 						m := NewBuilder().
-							SetId(id).
-							SetCharacterId(characterId).
-							SetSenderId(senderId).
+							SetCharacterId(recipientId).
+							SetSenderId(characterId).
 							SetMessage(msg).
 							SetFlag(flag).
+							SetInReplyTo(o.Id()).
+							SetThreadId(o.ThreadId()).
 							Build()
 
-						m, err := updateNote(p.db)(p.t.Id())(m)
+						var changed []uint32
+						err = p.r.Transaction(func(r Repository) error {
+							c, err := r.Create(p.t.Id(), m)
+							if err != nil {
+								return err
+							}
+							m = c
+							changed, err = p.record(r, uuid.New(), audit.ActionCreate, nil, &m)
+							return err
+						})
+						if err != nil {
+							return Model{}, err
+						}
+						err = mb.Put(note.EnvEventTopicNoteStatus, CreateNoteStatusEventProvider(m.CharacterId(), m.Id(), m.SenderId(), m.Message(), m.Flag(), m.Timestamp()))
 						if err != nil {
 							return Model{}, err
 						}
-						err = mb.Put(note.EnvEventTopicNoteStatus, UpdateNoteStatusEventProvider(m.CharacterId(), m.Id(), m.SenderId(), m.Message(), m.Flag(), m.Timestamp()))
+						err = p.announce(mb, changed)
 						if err != nil {
 							return Model{}, err
 						}
 						return m, nil
+					})
+				}
+			}
+		}
+	}
+}
+
+// ReplyAndEmit creates a note replying to another and emits a status event
+func (p *ProcessorImpl) ReplyAndEmit(characterId uint32, noteId uint32, msg string, flag byte) (Model, error) {
+	return message.EmitWithResult[Model, byte](p.producer)(model.Flip(model.Flip(model.Flip(p.Reply)(characterId))(noteId))(msg))(flag)
+}
+
+// Update updates an existing note
+func (p *ProcessorImpl) Update(mb *message.Buffer) func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
+	return func(id uint32) func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
+		return func(characterId uint32) func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
+			return func(senderId uint32) func(msg string) func(flag byte) (Model, error) {
+				return func(msg string) func(flag byte) (Model, error) {
+					return func(flag byte) (Model, error) {
+						return traced(p, "Update", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(id))}, func(p *ProcessorImpl) (Model, error) {
+							err := p.actsFor(characterId)
+							if err != nil {
+								return Model{}, err
+							}
+							err = p.checkContent(msg, flag)
+							if err != nil {
+								return Model{}, err
+							}
+
+							m := NewBuilder().
+								SetId(id).
+								SetCharacterId(characterId).
+								SetSenderId(senderId).
+								SetMessage(msg).
+								SetFlag(flag).
+								Build()
+
+							var changed []uint32
+							err = p.r.Transaction(func(r Repository) error {
+								o, err := r.ById(p.t.Id(), id)
+								if err != nil {
+									return err
+								}
+								err = p.holds(o)
+								if err != nil {
+									return err
+								}
+								u, err := r.Update(p.t.Id(), m)
+								if err != nil {
+									return err
+								}
+								m = u
+								changed, err = p.record(r, uuid.New(), audit.ActionUpdate, &o, &m)
+								return err
+							})
+							if err != nil {
+								return Model{}, err
+							}
+							err = mb.Put(note.EnvEventTopicNoteStatus, UpdateNoteStatusEventProvider(m.CharacterId(), m.Id(), m.SenderId(), m.Message(), m.Flag(), m.Timestamp()))
+							if err != nil {
+								return Model{}, err
+							}
+							err = p.announce(mb, changed)
+							if err != nil {
+								return Model{}, err
+							}
+							return m, nil
+						})
 					}
 				}
 			}
@@ -114,109 +526,1230 @@ func (p *ProcessorImpl) UpdateAndEmit(id uint32, characterId uint32, senderId ui
 	return message.EmitWithResult[Model, byte](p.producer)(model.Flip(model.Flip(model.Flip(model.Flip(p.Update)(id))(characterId))(senderId))(msg))(flag)
 }
 
-// Delete deletes a note
+// Delete deletes a note, returning anything left unclaimed to the sender
 func (p *ProcessorImpl) Delete(mb *message.Buffer) func(id uint32) error {
 	return func(id uint32) error {
-		m, err := p.ByIdProvider(id)()
-		if err != nil {
-			return err
-		}
+		return tracedErr(p, "Delete", []attribute.KeyValue{AttributeNoteId.Int64(int64(id))}, func(p *ProcessorImpl) error {
+			m, err := p.primaryByIdProvider(id)()
+			if err != nil {
+				return err
+			}
+			err = p.holds(m)
+			if err != nil {
+				return err
+			}
 
-		err = deleteNote(p.db)(p.t.Id())(id)
-		if err != nil {
-			return err
-		}
-		err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(m.CharacterId(), id))
-		if err != nil {
-			return err
-		}
-		return nil
+			var changed []uint32
+			err = p.r.Transaction(func(r Repository) error {
+				err := p.returnAttachments(mb)(r)(m)
+				if err != nil {
+					return err
+				}
+				err = r.Delete(p.t.Id(), id)
+				if err != nil {
+					return err
+				}
+				changed, err = p.record(r, uuid.New(), audit.ActionDelete, &m, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(m.CharacterId(), id))
+			if err != nil {
+				return err
+			}
+			return p.announce(mb, changed)
+		})
 	}
 }
 
-// DeleteAndEmit deletes a note and emits a status event
+// DeleteAndEmit deletes a note and emits the resulting return commands and status event
 func (p *ProcessorImpl) DeleteAndEmit(id uint32) error {
 	return message.Emit(p.producer)(model.Flip(p.Delete)(id))
 }
 
-// DeleteAll deletes all notes for a character
+// DeleteAll deletes all notes for a character, returning anything left unclaimed to their senders
 func (p *ProcessorImpl) DeleteAll(mb *message.Buffer) func(characterId uint32) error {
 	return func(characterId uint32) error {
-		ms, err := p.ByCharacterProvider(characterId)()
-		if err != nil {
-			return err
-		}
-		for _, m := range ms {
-			err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(m.CharacterId(), m.Id()))
+		return tracedErr(p, "DeleteAll", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) error {
+			err := p.actsFor(characterId)
 			if err != nil {
 				return err
 			}
-		}
-		err = deleteAllNotes(p.db)(p.t.Id())(characterId)
-		if err != nil {
-			return err
-		}
-		return nil
+			ms, err := p.r.ByCharacter(p.t.Id(), characterId)
+			if err != nil {
+				return err
+			}
+			p.setNoteCount(len(ms))
+			for _, m := range ms {
+				err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(m.CharacterId(), m.Id()))
+				if err != nil {
+					return err
+				}
+			}
+			transactionId := uuid.New()
+			var changed []uint32
+			err = p.r.Transaction(func(r Repository) error {
+				for _, m := range ms {
+					err := p.returnAttachments(mb)(r)(m)
+					if err != nil {
+						return err
+					}
+				}
+				err := r.DeleteAll(p.t.Id(), characterId)
+				if err != nil {
+					return err
+				}
+				entries := make([]audit.Model, 0, len(ms))
+				for _, m := range ms {
+					e, err := p.auditEntry(transactionId, audit.ActionDelete, &m, nil)
+					if err != nil {
+						return err
+					}
+					entries = append(entries, e)
+				}
+				err = r.Audit().Append(p.t.Id(), entries...)
+				if err != nil {
+					return err
+				}
+				changed, err = p.count(r, ms, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			return p.announce(mb, changed)
+		})
 	}
 }
 
-// DeleteAllAndEmit deletes all notes for a character and emits status events
+// DeleteAllAndEmit deletes all notes for a character and emits the resulting return commands and status events
 func (p *ProcessorImpl) DeleteAllAndEmit(characterId uint32) error {
 	return message.Emit(p.producer)(model.Flip(p.DeleteAll)(characterId))
 }
 
-// ByIdProvider retrieves a note by ID
+// ByIdProvider retrieves a note by ID. Reads of this and the other public providers are routed to a read replica when
+// one is available, so they may lag a write made just before.
 func (p *ProcessorImpl) ByIdProvider(id uint32) model.Provider[Model] {
-	return model.Map[Entity, Model](Make)(getByIdProvider(p.t.Id())(id)(p.db))
+	return func() (Model, error) {
+		return traced(p, "ById", []attribute.KeyValue{AttributeNoteId.Int64(int64(id))}, func(p *ProcessorImpl) (Model, error) {
+			m, err := p.r.Reader().ById(p.t.Id(), id)
+			if err != nil {
+				return Model{}, err
+			}
+			err = p.canRead(m)
+			if err != nil {
+				return Model{}, err
+			}
+			return m, nil
+		})
+	}
+}
+
+// primaryByIdProvider retrieves a note by ID from the primary, for operations which go on to change it or which must
+// see a change just made
+func (p *ProcessorImpl) primaryByIdProvider(id uint32) model.Provider[Model] {
+	return func() (Model, error) {
+		return p.r.ById(p.t.Id(), id)
+	}
 }
 
 // ByCharacterProvider retrieves all notes for a character
 func (p *ProcessorImpl) ByCharacterProvider(characterId uint32) model.Provider[[]Model] {
-	return model.SliceMap[Entity, Model](Make)(getByCharacterIdProvider(p.t.Id())(characterId)(p.db))(model.ParallelMap())
+	return func() ([]Model, error) {
+		return traced(p, "ByCharacter", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) ([]Model, error) {
+			err := p.actsFor(characterId)
+			if err != nil {
+				return nil, err
+			}
+			return p.r.Reader().ByCharacter(p.t.Id(), characterId)
+		})
+	}
+}
+
+// ByCharacterAndFilterProvider retrieves the notes for a character which match the filter, pinned notes first
+func (p *ProcessorImpl) ByCharacterAndFilterProvider(characterId uint32, f Filter) model.Provider[[]Model] {
+	return func() ([]Model, error) {
+		return traced(p, "ByCharacterAndFilter", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) ([]Model, error) {
+			err := p.actsFor(characterId)
+			if err != nil {
+				return nil, err
+			}
+			return p.r.Reader().ByCharacterAndFilter(p.t.Id(), characterId, f)
+		})
+	}
+}
+
+// BySenderProvider retrieves all notes a character has sent and not hidden, most recent first
+func (p *ProcessorImpl) BySenderProvider(senderId uint32) model.Provider[[]Model] {
+	return func() ([]Model, error) {
+		return traced(p, "BySender", []attribute.KeyValue{AttributeSenderId.Int64(int64(senderId))}, func(p *ProcessorImpl) ([]Model, error) {
+			err := p.actsFor(senderId)
+			if err != nil {
+				return nil, err
+			}
+			return p.r.Reader().BySender(p.t.Id(), senderId)
+		})
+	}
+}
+
+// ByParticipantProvider retrieves all notes a character has sent or received
+func (p *ProcessorImpl) ByParticipantProvider(characterId uint32) model.Provider[[]Model] {
+	return func() ([]Model, error) {
+		return traced(p, "ByParticipant", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) ([]Model, error) {
+			err := p.actsFor(characterId)
+			if err != nil {
+				return nil, err
+			}
+			return p.r.Reader().ByParticipant(p.t.Id(), characterId)
+		})
+	}
 }
 
 // InTenantProvider retrieves all notes in a tenant
 func (p *ProcessorImpl) InTenantProvider() model.Provider[[]Model] {
-	return model.SliceMap[Entity, Model](Make)(getAllProvider(p.t.Id())(p.db))(model.ParallelMap())
+	return func() ([]Model, error) {
+		return traced(p, "InTenant", nil, func(p *ProcessorImpl) ([]Model, error) {
+			err := p.actsForTenant()
+			if err != nil {
+				return nil, err
+			}
+			return p.r.Reader().All(p.t.Id())
+		})
+	}
 }
 
-// Discard discards multiple notes for a character
-func (p *ProcessorImpl) Discard(mb *message.Buffer) func(characterId uint32) func(noteIds []uint32) error {
-	return func(characterId uint32) func(noteIds []uint32) error {
-		return func(noteIds []uint32) error {
-			for _, noteId := range noteIds {
-				// Check if the note exists and belongs to the character
-				m, err := p.ByIdProvider(noteId)()
-				if err != nil {
-					return err
-				}
+// ExpiredProvider retrieves all notes in a tenant which expired at or before the given time. It reads from the primary,
+// as the notes are then expired.
+func (p *ProcessorImpl) ExpiredProvider(asOf time.Time) model.Provider[[]Model] {
+	return func() ([]Model, error) {
+		return traced(p, "Expired", nil, func(p *ProcessorImpl) ([]Model, error) {
+			return p.r.Expired(p.t.Id(), asOf)
+		})
+	}
+}
 
-				if m.CharacterId() != characterId {
-					continue // Skip notes that don't belong to this character
-				}
+// CountProvider retrieves the number of notes in a tenant, deleted notes aside
+func (p *ProcessorImpl) CountProvider() model.Provider[int64] {
+	return func() (int64, error) {
+		return traced(p, "Count", nil, func(p *ProcessorImpl) (int64, error) {
+			return p.r.Reader().Count(p.t.Id())
+		})
+	}
+}
+
+// CountIncludingDeletedProvider retrieves the number of notes in a tenant, deleted notes included. It reads from the
+// primary, as it measures what is left to purge.
+func (p *ProcessorImpl) CountIncludingDeletedProvider() model.Provider[int64] {
+	return func() (int64, error) {
+		return traced(p, "CountIncludingDeleted", nil, func(p *ProcessorImpl) (int64, error) {
+			return p.r.CountIncludingDeleted(p.t.Id())
+		})
+	}
+}
 
-				// Delete the note
-				err = deleteNote(p.db)(p.t.Id())(noteId)
+// HistoryProvider retrieves the audit trail of a note, oldest change first
+func (p *ProcessorImpl) HistoryProvider(noteId uint32) model.Provider[[]audit.Model] {
+	return func() ([]audit.Model, error) {
+		return traced(p, "History", []attribute.KeyValue{AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) ([]audit.Model, error) {
+			if p.scoped {
+				m, err := p.r.Reader().ByIdIncludingDeleted(p.t.Id(), noteId)
 				if err != nil {
-					return err
+					return nil, err
 				}
-
-				// Add delete event to message buffer
-				err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(characterId, noteId))
+				err = p.canRead(m)
 				if err != nil {
-					return err
+					return nil, err
 				}
+			}
+			return p.r.Audit().ByNoteId(p.t.Id(), noteId)
+		})
+	}
+}
 
-				// TODO award fame when a note is discarded
+// AuditByCharacterProvider retrieves the audit trail of the notes a character has held, oldest change first
+func (p *ProcessorImpl) AuditByCharacterProvider(characterId uint32) model.Provider[[]audit.Model] {
+	return func() ([]audit.Model, error) {
+		return traced(p, "AuditByCharacter", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) ([]audit.Model, error) {
+			err := p.actsFor(characterId)
+			if err != nil {
+				return nil, err
 			}
-			return nil
-		}
+			return p.r.Audit().ByCharacterId(p.t.Id(), characterId)
+		})
 	}
 }
 
-// DiscardAndEmit discards multiple notes for a character and emits status events
-func (p *ProcessorImpl) DiscardAndEmit(characterId uint32, noteIds []uint32) error {
-	return message.Emit(p.producer)(func(mb *message.Buffer) error {
-		return p.Discard(mb)(characterId)(noteIds)
+// SummaryProvider retrieves how many notes a character holds, and how many of them are unread, in total and by kind
+func (p *ProcessorImpl) SummaryProvider(characterId uint32) model.Provider[summary.Model] {
+	return func() (summary.Model, error) {
+		return traced(p, "Summary", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) (summary.Model, error) {
+			err := p.actsFor(characterId)
+			if err != nil {
+				return summary.Model{}, err
+			}
+			return p.r.Summary().ByCharacterId(p.t.Id(), characterId)
+		})
+	}
+}
+
+// Reconcile recounts the notes every character of the tenant holds, correcting the summary counters which have drifted
+// from them, and returns how many were corrected. The summaries corrected are announced.
+func (p *ProcessorImpl) Reconcile(mb *message.Buffer) (int, error) {
+	return traced(p, "Reconcile", nil, func(p *ProcessorImpl) (int, error) {
+		var drift []summary.Count
+		err := p.r.Transaction(func(r Repository) error {
+			// The counters are locked before the notes are counted, so changes in progress wait to move them
+			stored, err := r.Summary().ByTenant(p.t.Id())
+			if err != nil {
+				return err
+			}
+			actual, err := r.Tally(p.t.Id())
+			if err != nil {
+				return err
+			}
+			cs := append([]summary.Count(nil), actual...)
+			for _, c := range stored {
+				cs = append(cs, c.Negate())
+			}
+			drift = summary.Merge(cs)
+			return r.Summary().Apply(p.t.Id(), drift...)
+		})
+		if err != nil {
+			return 0, err
+		}
+		if len(drift) == 0 {
+			return 0, nil
+		}
+		p.l.Warnf("Corrected [%d] note summary counters which had drifted.", len(drift))
+		characterIds := make([]uint32, 0, len(drift))
+		for _, c := range drift {
+			characterIds = append(characterIds, c.CharacterId())
+		}
+		err = p.announce(mb, characterIds)
+		if err != nil {
+			return 0, err
+		}
+		return len(drift), nil
+	})
+}
+
+// ReconcileAndEmit corrects the summary counters of the tenant and emits status events
+func (p *ProcessorImpl) ReconcileAndEmit() (int, error) {
+	var corrected int
+	err := message.Emit(p.producer)(func(mb *message.Buffer) error {
+		var err error
+		corrected, err = p.Reconcile(mb)
+		return err
 	})
+	return corrected, err
+}
+
+// NotifyPending tells a character which logged in to a channel how many unread notes it holds, naming the most recent
+// senders first, so the channel may show them without asking. Nothing is emitted when it holds none.
+func (p *ProcessorImpl) NotifyPending(mb *message.Buffer) func(worldId byte) func(channelId byte) func(characterId uint32) error {
+	return func(worldId byte) func(channelId byte) func(characterId uint32) error {
+		return func(channelId byte) func(characterId uint32) error {
+			return func(characterId uint32) error {
+				return tracedErr(p, "NotifyPending", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId))}, func(p *ProcessorImpl) error {
+					s, err := p.r.Summary().ByCharacterId(p.t.Id(), characterId)
+					if err != nil {
+						return err
+					}
+					if s.Unread() == 0 {
+						return nil
+					}
+					senderIds, err := p.r.UnreadSenders(p.t.Id(), characterId, pendingSenderLimit)
+					if err != nil {
+						return err
+					}
+					return mb.Put(note.EnvEventTopicNoteStatus, PendingNoteStatusEventProvider(worldId, channelId, characterId, int(s.Unread()), senderIds))
+				})
+			}
+		}
+	}
+}
+
+// NotifyPendingAndEmit tells a character which logged in about its unread notes and emits the status event
+func (p *ProcessorImpl) NotifyPendingAndEmit(worldId byte, channelId byte, characterId uint32) error {
+	return message.Emit(p.producer)(func(mb *message.Buffer) error {
+		return p.NotifyPending(mb)(worldId)(channelId)(characterId)
+	})
+}
+
+// Discard discards multiple notes for a character. Notes carrying unclaimed attachments are only discarded when
+// forced, in which case the attachments are returned to the sender.
+func (p *ProcessorImpl) Discard(mb *message.Buffer) func(characterId uint32) func(noteIds []uint32) func(force bool) error {
+	return func(characterId uint32) func(noteIds []uint32) func(force bool) error {
+		return func(noteIds []uint32) func(force bool) error {
+			return func(force bool) error {
+				return tracedErr(p, "Discard", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteCount.Int(len(noteIds))}, func(p *ProcessorImpl) error {
+					err := p.actsFor(characterId)
+					if err != nil {
+						return err
+					}
+					var ms []Model
+					for _, noteId := range noteIds {
+						// Check if the note exists and belongs to the character
+						m, err := p.primaryByIdProvider(noteId)()
+						if err != nil {
+							return err
+						}
+
+						// Acting on behalf of the character, another's note is not found rather than skipped, so its
+						// existence is not leaked
+						err = p.holds(m)
+						if err != nil {
+							return err
+						}
+						if m.CharacterId() != characterId {
+							continue // Skip notes that don't belong to this character
+						}
+
+						if m.HasUnclaimedAttachments() && !force {
+							return ErrUnclaimedAttachments
+						}
+						ms = append(ms, m)
+					}
+
+					transactionId := uuid.New()
+					var changed []uint32
+					for _, m := range ms {
+						// Delete the note, returning anything left unclaimed
+						err := p.r.Transaction(func(r Repository) error {
+							err := p.returnAttachments(mb)(r)(m)
+							if err != nil {
+								return err
+							}
+							err = r.Delete(p.t.Id(), m.Id())
+							if err != nil {
+								return err
+							}
+							c, err := p.record(r, transactionId, audit.ActionDiscard, &m, nil)
+							changed = append(changed, c...)
+							return err
+						})
+						if err != nil {
+							return err
+						}
+
+						// Add delete event to message buffer
+						err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(characterId, m.Id()))
+						if err != nil {
+							return err
+						}
+
+						// TODO award fame when a note is discarded
+					}
+					return p.announce(mb, changed)
+				})
+			}
+		}
+	}
+}
+
+// DiscardAndEmit discards multiple notes for a character and emits status events
+func (p *ProcessorImpl) DiscardAndEmit(characterId uint32, noteIds []uint32, force bool) error {
+	return message.Emit(p.producer)(func(mb *message.Buffer) error {
+		return p.Discard(mb)(characterId)(noteIds)(force)
+	})
+}
+
+// Star marks or unmarks a note as starred by its recipient
+func (p *ProcessorImpl) Star(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(starred bool) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(starred bool) (Model, error) {
+		return func(noteId uint32) func(starred bool) (Model, error) {
+			return func(starred bool) (Model, error) {
+				return traced(p, "Star", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+					return p.organize(mb)(characterId)(noteId)(func(r Repository) error {
+						return r.UpdateOrganization(p.t.Id(), noteId, MarkerStarred, starred)
+					})
+				})
+			}
+		}
+	}
+}
+
+// StarAndEmit marks or unmarks a note as starred and emits a status event
+func (p *ProcessorImpl) StarAndEmit(characterId uint32, noteId uint32, starred bool) (Model, error) {
+	return message.EmitWithResult[Model, bool](p.producer)(model.Flip(model.Flip(p.Star)(characterId))(noteId))(starred)
+}
+
+// Pin marks or unmarks a note as pinned by its recipient
+func (p *ProcessorImpl) Pin(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(pinned bool) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(pinned bool) (Model, error) {
+		return func(noteId uint32) func(pinned bool) (Model, error) {
+			return func(pinned bool) (Model, error) {
+				return traced(p, "Pin", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+					return p.organize(mb)(characterId)(noteId)(func(r Repository) error {
+						return r.UpdateOrganization(p.t.Id(), noteId, MarkerPinned, pinned)
+					})
+				})
+			}
+		}
+	}
+}
+
+// PinAndEmit marks or unmarks a note as pinned and emits a status event
+func (p *ProcessorImpl) PinAndEmit(characterId uint32, noteId uint32, pinned bool) (Model, error) {
+	return message.EmitWithResult[Model, bool](p.producer)(model.Flip(model.Flip(p.Pin)(characterId))(noteId))(pinned)
+}
+
+// Archive moves a note in or out of its recipient's archive
+func (p *ProcessorImpl) Archive(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(archived bool) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(archived bool) (Model, error) {
+		return func(noteId uint32) func(archived bool) (Model, error) {
+			return func(archived bool) (Model, error) {
+				return traced(p, "Archive", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+					return p.organize(mb)(characterId)(noteId)(func(r Repository) error {
+						return r.UpdateOrganization(p.t.Id(), noteId, MarkerArchived, archived)
+					})
+				})
+			}
+		}
+	}
+}
+
+// ArchiveAndEmit moves a note in or out of the archive and emits a status event
+func (p *ProcessorImpl) ArchiveAndEmit(characterId uint32, noteId uint32, archived bool) (Model, error) {
+	return message.EmitWithResult[Model, bool](p.producer)(model.Flip(model.Flip(p.Archive)(characterId))(noteId))(archived)
+}
+
+// Label replaces the labels a note is filed under by its recipient
+func (p *ProcessorImpl) Label(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(labels []string) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(labels []string) (Model, error) {
+		return func(noteId uint32) func(labels []string) (Model, error) {
+			return func(labels []string) (Model, error) {
+				return traced(p, "Label", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+					return p.organize(mb)(characterId)(noteId)(func(r Repository) error {
+						return r.ReplaceLabels(p.t.Id(), noteId, labels)
+					})
+				})
+			}
+		}
+	}
+}
+
+// LabelAndEmit replaces the labels a note is filed under and emits a status event
+func (p *ProcessorImpl) LabelAndEmit(characterId uint32, noteId uint32, labels []string) (Model, error) {
+	return message.EmitWithResult[Model, []string](p.producer)(model.Flip(model.Flip(p.Label)(characterId))(noteId))(labels)
+}
+
+// Organize stars, pins, archives and labels a note held by its recipient in a single transaction, announcing the
+// resulting organization once. An empty change leaves the note as it is and announces nothing.
+func (p *ProcessorImpl) Organize(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(changes Organization) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(changes Organization) (Model, error) {
+		return func(noteId uint32) func(changes Organization) (Model, error) {
+			return func(changes Organization) (Model, error) {
+				return traced(p, "Organize", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+					if changes.Empty() {
+						return p.organize(mb)(characterId)(noteId)(nil)
+					}
+					return p.organize(mb)(characterId)(noteId)(func(r Repository) error {
+						markers := []struct {
+							marker string
+							value  *bool
+						}{
+							{MarkerStarred, changes.Starred},
+							{MarkerPinned, changes.Pinned},
+							{MarkerArchived, changes.Archived},
+						}
+						for _, m := range markers {
+							if m.value == nil {
+								continue
+							}
+							err := r.UpdateOrganization(p.t.Id(), noteId, m.marker, *m.value)
+							if err != nil {
+								return err
+							}
+						}
+						if changes.Labels == nil {
+							return nil
+						}
+						return r.ReplaceLabels(p.t.Id(), noteId, *changes.Labels)
+					})
+				})
+			}
+		}
+	}
+}
+
+// OrganizeAndEmit changes how the recipient keeps a note and emits a status event
+func (p *ProcessorImpl) OrganizeAndEmit(characterId uint32, noteId uint32, changes Organization) (Model, error) {
+	return message.EmitWithResult[Model, Organization](p.producer)(model.Flip(model.Flip(p.Organize)(characterId))(noteId))(changes)
+}
+
+// organize applies a change to how the recipient keeps a note, recording it in the audit trail, and emits the resulting
+// organization. A nil change only checks the note is the recipient's.
+func (p *ProcessorImpl) organize(mb *message.Buffer) func(characterId uint32) func(noteId uint32) func(change func(r Repository) error) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) func(change func(r Repository) error) (Model, error) {
+		return func(noteId uint32) func(change func(r Repository) error) (Model, error) {
+			return func(change func(r Repository) error) (Model, error) {
+				err := p.actsFor(characterId)
+				if err != nil {
+					return Model{}, err
+				}
+				m, err := p.primaryByIdProvider(noteId)()
+				if err != nil {
+					return Model{}, err
+				}
+				if m.CharacterId() != characterId {
+					return Model{}, p.notRecipient()
+				}
+				if change == nil {
+					return m, nil
+				}
+
+				o := m
+				var changed []uint32
+				err = p.r.Transaction(func(r Repository) error {
+					err := change(r)
+					if err != nil {
+						return err
+					}
+					m, err = r.ById(p.t.Id(), noteId)
+					if err != nil {
+						return err
+					}
+					changed, err = p.record(r, uuid.New(), audit.ActionUpdate, &o, &m)
+					return err
+				})
+				if err != nil {
+					return Model{}, err
+				}
+				err = mb.Put(note.EnvEventTopicNoteStatus, OrganizeNoteStatusEventProvider(m.CharacterId(), m.Id(), m.Starred(), m.Pinned(), m.Archived(), m.Labels()))
+				if err != nil {
+					return Model{}, err
+				}
+				err = p.announce(mb, changed)
+				if err != nil {
+					return Model{}, err
+				}
+				return m, nil
+			}
+		}
+	}
+}
+
+// MarkRead records that a character read a note it holds, announcing the read and the summary it changed. A note
+// already read is returned as it is, keeping when it was first read, and nothing is announced.
+func (p *ProcessorImpl) MarkRead(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) (Model, error) {
+		return func(noteId uint32) (Model, error) {
+			return traced(p, "MarkRead", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+				err := p.actsFor(characterId)
+				if err != nil {
+					return Model{}, err
+				}
+				m, err := p.primaryByIdProvider(noteId)()
+				if err != nil {
+					return Model{}, err
+				}
+				if m.CharacterId() != characterId {
+					return Model{}, p.notRecipient()
+				}
+				if m.Read() {
+					return m, nil
+				}
+
+				o := m
+				var changed []uint32
+				err = p.r.Transaction(func(r Repository) error {
+					err := r.MarkRead(p.t.Id(), noteId, time.Now())
+					if err != nil {
+						return err
+					}
+					m, err = r.ById(p.t.Id(), noteId)
+					if err != nil {
+						return err
+					}
+					changed, err = p.record(r, uuid.New(), audit.ActionUpdate, &o, &m)
+					return err
+				})
+				if err != nil {
+					return Model{}, err
+				}
+				err = mb.Put(note.EnvEventTopicNoteStatus, ReadNoteStatusEventProvider(m.CharacterId(), m.Id(), m.ReadAt()))
+				if err != nil {
+					return Model{}, err
+				}
+				err = p.announce(mb, changed)
+				if err != nil {
+					return Model{}, err
+				}
+				return m, nil
+			})
+		}
+	}
+}
+
+// MarkReadAndEmit records that a character read a note and emits status events
+func (p *ProcessorImpl) MarkReadAndEmit(characterId uint32, noteId uint32) (Model, error) {
+	return message.EmitWithResult[Model, uint32](p.producer)(model.Flip(p.MarkRead)(characterId))(noteId)
+}
+
+// HideSent hides notes from their sender's sent items. The recipient's copy is unaffected.
+func (p *ProcessorImpl) HideSent(senderId uint32) func(noteIds []uint32) error {
+	return func(noteIds []uint32) error {
+		return tracedErr(p, "HideSent", []attribute.KeyValue{AttributeSenderId.Int64(int64(senderId)), AttributeNoteCount.Int(len(noteIds))}, func(p *ProcessorImpl) error {
+			err := p.actsFor(senderId)
+			if err != nil {
+				return err
+			}
+			return p.r.Transaction(func(r Repository) error {
+				var before []Model
+				for _, id := range noteIds {
+					m, err := r.ByIdIncludingDeleted(p.t.Id(), id)
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						continue
+					}
+					if err != nil {
+						return err
+					}
+					if m.SenderId() == senderId {
+						before = append(before, m)
+					}
+				}
+				err := r.HideSent(p.t.Id(), senderId, noteIds)
+				if err != nil {
+					return err
+				}
+				after := make([]Model, 0, len(before))
+				for _, m := range before {
+					a, err := r.ByIdIncludingDeleted(p.t.Id(), m.Id())
+					if err != nil {
+						return err
+					}
+					after = append(after, a)
+				}
+				return p.auditor(audit.ActionUpdate)(r, before, after)
+			})
+		})
+	}
+}
+
+// Claim claims every unclaimed attachment carried by a note on behalf of its recipient
+func (p *ProcessorImpl) Claim(mb *message.Buffer) func(characterId uint32) func(noteId uint32) error {
+	return func(characterId uint32) func(noteId uint32) error {
+		return func(noteId uint32) error {
+			return tracedErr(p, "Claim", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) error {
+				err := p.actsFor(characterId)
+				if err != nil {
+					return err
+				}
+				m, err := p.primaryByIdProvider(noteId)()
+				if err != nil {
+					return err
+				}
+				if m.CharacterId() != characterId {
+					return p.notRecipient()
+				}
+
+				var claimed []uint32
+				err = p.r.Transaction(func(r Repository) error {
+					ap := attachment.NewRepositoryProcessor(p.l, p.ctx, r.Attachments())
+					for _, a := range m.Attachments() {
+						if !a.Unclaimed() {
+							continue
+						}
+						err := ap.Claim(mb)(characterId)(a)
+						if err != nil {
+							return err
+						}
+						claimed = append(claimed, a.Id())
+					}
+					if len(claimed) == 0 {
+						return nil
+					}
+					a, err := r.ById(p.t.Id(), noteId)
+					if err != nil {
+						return err
+					}
+					return p.audit(r, uuid.New(), audit.ActionUpdate, &m, &a)
+				})
+				if err != nil {
+					return err
+				}
+				if len(claimed) == 0 {
+					p.l.Debugf("Note [%d] has no unclaimed attachments for character [%d].", noteId, characterId)
+					return nil
+				}
+				return mb.Put(note.EnvEventTopicNoteStatus, ClaimNoteStatusEventProvider(characterId, noteId, claimed))
+			})
+		}
+	}
+}
+
+// ClaimAndEmit claims the attachments carried by a note and emits the resulting award commands and status event
+func (p *ProcessorImpl) ClaimAndEmit(characterId uint32, noteId uint32) error {
+	return message.Emit(p.producer)(func(mb *message.Buffer) error {
+		return p.Claim(mb)(characterId)(noteId)
+	})
+}
+
+// Expire deletes an expired note, returning anything left unclaimed to the sender
+func (p *ProcessorImpl) Expire(mb *message.Buffer) func(id uint32) error {
+	return func(id uint32) error {
+		return tracedErr(p, "Expire", []attribute.KeyValue{AttributeNoteId.Int64(int64(id))}, func(p *ProcessorImpl) error {
+			m, err := p.primaryByIdProvider(id)()
+			if err != nil {
+				return err
+			}
+
+			var changed []uint32
+			err = p.r.Transaction(func(r Repository) error {
+				err := p.returnAttachments(mb)(r)(m)
+				if err != nil {
+					return err
+				}
+				err = r.Delete(p.t.Id(), id)
+				if err != nil {
+					return err
+				}
+				changed, err = p.record(r, uuid.New(), audit.ActionExpire, &m, nil)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(m.CharacterId(), id))
+			if err != nil {
+				return err
+			}
+			return p.announce(mb, changed)
+		})
+	}
+}
+
+// ExpireAndEmit deletes an expired note and emits the resulting return commands and status event
+func (p *ProcessorImpl) ExpireAndEmit(id uint32) error {
+	return message.Emit(p.producer)(model.Flip(p.Expire)(id))
+}
+
+// Restore undoes the deletion of a note by its recipient, provided it was deleted within the grace period
+func (p *ProcessorImpl) Restore(mb *message.Buffer) func(characterId uint32) func(noteId uint32) (Model, error) {
+	return func(characterId uint32) func(noteId uint32) (Model, error) {
+		return func(noteId uint32) (Model, error) {
+			return traced(p, "Restore", []attribute.KeyValue{AttributeCharacterId.Int64(int64(characterId)), AttributeNoteId.Int64(int64(noteId))}, func(p *ProcessorImpl) (Model, error) {
+				err := p.actsFor(characterId)
+				if err != nil {
+					return Model{}, err
+				}
+				m, err := p.r.ByIdIncludingDeleted(p.t.Id(), noteId)
+				if err != nil {
+					return Model{}, err
+				}
+				if m.CharacterId() != characterId {
+					return Model{}, p.notRecipient()
+				}
+				if !m.Deleted() {
+					return Model{}, ErrNotDeleted
+				}
+				if time.Since(m.DeletedAt()) > p.cfg().RestoreGracePeriod() {
+					return Model{}, ErrRestoreWindowClosed
+				}
+				if !m.Archived() {
+					err = p.checkCapacity(characterId)
+					if err != nil {
+						return Model{}, err
+					}
+				}
+
+				o := m
+				var changed []uint32
+				err = p.r.Transaction(func(r Repository) error {
+					err := r.Restore(p.t.Id(), noteId)
+					if err != nil {
+						return err
+					}
+					m, err = r.ById(p.t.Id(), noteId)
+					if err != nil {
+						return err
+					}
+					changed, err = p.record(r, uuid.New(), audit.ActionRestore, &o, &m)
+					return err
+				})
+				if err != nil {
+					return Model{}, err
+				}
+				err = mb.Put(note.EnvEventTopicNoteStatus, RestoreNoteStatusEventProvider(m.CharacterId(), m.Id()))
+				if err != nil {
+					return Model{}, err
+				}
+				err = p.announce(mb, changed)
+				if err != nil {
+					return Model{}, err
+				}
+				return m, nil
+			})
+		}
+	}
+}
+
+// RestoreAndEmit undoes the deletion of a note and emits a status event
+func (p *ProcessorImpl) RestoreAndEmit(characterId uint32, noteId uint32) (Model, error) {
+	return message.EmitWithResult[Model, uint32](p.producer)(model.Flip(p.Restore)(characterId))(noteId)
+}
+
+// PurgeBatch permanently removes up to limit notes in the tenant, deleted notes included, returning how many were
+// purged. Each note purged is recorded in the audit trail until the purge completes and PurgeAudit removes it.
+// Unclaimed attachments are not returned, as the tenant is going away. When emitting events, a deletion is announced
+// for each note which was not already deleted.
+func (p *ProcessorImpl) PurgeBatch(mb *message.Buffer) func(limit int) func(emitEvents bool) (int, error) {
+	return func(limit int) func(emitEvents bool) (int, error) {
+		return func(emitEvents bool) (int, error) {
+			return traced(p, "PurgeBatch", nil, func(p *ProcessorImpl) (int, error) {
+				var ms []Model
+				var changed []uint32
+				err := p.r.Transaction(func(r Repository) error {
+					var err error
+					ms, err = r.Purge(p.t.Id(), limit)
+					if err != nil {
+						return err
+					}
+					err = p.auditor(audit.ActionPurge)(r, ms, nil)
+					if err != nil {
+						return err
+					}
+					changed, err = p.count(r, ms, nil)
+					return err
+				})
+				if err != nil {
+					return 0, err
+				}
+				p.setNoteCount(len(ms))
+				if !emitEvents {
+					return len(ms), nil
+				}
+				for _, m := range ms {
+					if m.Deleted() {
+						continue
+					}
+					err = mb.Put(note.EnvEventTopicNoteStatus, DeleteNoteStatusEventProvider(m.CharacterId(), m.Id()))
+					if err != nil {
+						return 0, err
+					}
+				}
+				err = p.announce(mb, changed)
+				if err != nil {
+					return 0, err
+				}
+				return len(ms), nil
+			})
+		}
+	}
+}
+
+// PurgeBatchAndEmit permanently removes a batch of notes in the tenant and emits status events
+func (p *ProcessorImpl) PurgeBatchAndEmit(limit int, emitEvents bool) (int, error) {
+	return message.EmitWithResult[int, bool](p.producer)(model.Flip(p.PurgeBatch)(limit))(emitEvents)
+}
+
+// PurgeAudit permanently removes the tenant's audit trail once its notes have been purged, as the trail holds their
+// messages. It is replaced by a single entry recording the purge and how many notes it removed.
+func (p *ProcessorImpl) PurgeAudit(purged int64) error {
+	return tracedErr(p, "PurgeAudit", nil, func(p *ProcessorImpl) error {
+		after, err := json.Marshal(map[string]int64{"purged": purged})
+		if err != nil {
+			return err
+		}
+		e := audit.NewBuilder().
+			SetAction(audit.ActionPurge).
+			SetActor(audit.ActorFromContext(p.ctx)).
+			SetTransactionId(uuid.New()).
+			SetAfter(after).
+			Build()
+		return p.r.Transaction(func(r Repository) error {
+			n, err := r.Audit().Purge(p.t.Id())
+			if err != nil {
+				return err
+			}
+			p.l.Debugf("Purged [%d] entries of the audit trail.", n)
+			return r.Audit().Append(p.t.Id(), e)
+		})
+	})
+}
+
+// Export runs o on every note in the tenant, deleted notes included, in ID order. Notes are read in batches, so the
+// tenant's notes are never all held at once.
+func (p *ProcessorImpl) Export(o model.Operator[Model]) error {
+	return tracedErr(p, "Export", nil, func(p *ProcessorImpl) error {
+		err := p.actsForTenant()
+		if err != nil {
+			return err
+		}
+		r := p.r.Reader()
+		var afterId uint32
+		var count int
+		defer func() { p.setNoteCount(count) }()
+		for {
+			ms, err := r.AllIncludingDeleted(p.t.Id(), afterId, exportBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, m := range ms {
+				err = o(m)
+				if err != nil {
+					return err
+				}
+				count++
+			}
+			if len(ms) < exportBatchSize {
+				return nil
+			}
+			afterId = ms[len(ms)-1].Id()
+		}
+	})
+}
+
+// Importer returns an Importer of notes exported from another tenant or cluster into the tenant. No events are
+// emitted for imported notes, as they are not new to their recipients.
+func (p *ProcessorImpl) Importer(characterIds map[uint32]uint32, policy ConflictPolicy) *Importer {
+	return newImporter(p.l, p.r, p.t.Id(), characterIds, policy, p.auditor(audit.ActionImport))
+}
+
+// Clone copies every note of another tenant into the tenant, deleted notes included, in a single transaction, so
+// either all of them are copied or none are. Character IDs are remapped, and flags translated between versions, through
+// the given tables; those missing from them are kept. Notes already present are skipped, so a clone may be repeated. A
+// creation is announced for each note copied which is not deleted.
+func (p *ProcessorImpl) Clone(mb *message.Buffer) func(sourceTenantId uuid.UUID) func(characterIds map[uint32]uint32) func(flags map[byte]byte) (ImportResult, error) {
+	return func(sourceTenantId uuid.UUID) func(characterIds map[uint32]uint32) func(flags map[byte]byte) (ImportResult, error) {
+		return func(characterIds map[uint32]uint32) func(flags map[byte]byte) (ImportResult, error) {
+			return func(flags map[byte]byte) (ImportResult, error) {
+				return traced(p, "Clone", nil, func(p *ProcessorImpl) (ImportResult, error) {
+					if sourceTenantId == p.t.Id() {
+						return ImportResult{}, ErrSameTenant
+					}
+
+					var res ImportResult
+					var changed []uint32
+					err := p.r.Transaction(func(r Repository) error {
+						i := newImporter(p.l, r, p.t.Id(), characterIds, ConflictSkip, p.auditor(audit.ActionClone))
+						i.created = func(m Model) error {
+							if m.Deleted() {
+								return nil
+							}
+							changed = append(changed, m.CharacterId())
+							return mb.Put(note.EnvEventTopicNoteStatus, CreateNoteStatusEventProvider(m.CharacterId(), m.Id(), m.SenderId(), m.Message(), m.Flag(), m.Timestamp()))
+						}
+
+						var afterId uint32
+						for {
+							ms, err := r.AllIncludingDeleted(sourceTenantId, afterId, exportBatchSize)
+							if err != nil {
+								return err
+							}
+							for _, m := range ms {
+								if flag, ok := flags[m.Flag()]; ok {
+									m = Clone(m).SetFlag(flag).Build()
+								}
+								err = i.Add(0, m)
+								if err != nil {
+									return err
+								}
+							}
+							if len(ms) < exportBatchSize {
+								break
+							}
+							afterId = ms[len(ms)-1].Id()
+						}
+						err := i.Flush()
+						if err != nil {
+							return err
+						}
+						res = i.Result()
+						return nil
+					})
+					if err != nil {
+						return ImportResult{}, err
+					}
+					err = p.announce(mb, changed)
+					if err != nil {
+						return ImportResult{}, err
+					}
+					p.setNoteCount(res.Imported)
+					p.l.Infof("Cloned [%d] notes from tenant [%s], skipping [%d] already present.", res.Imported, sourceTenantId, res.Skipped)
+					return res, nil
+				})
+			}
+		}
+	}
+}
+
+// CloneAndEmit copies every note of another tenant into the tenant and emits status events
+func (p *ProcessorImpl) CloneAndEmit(sourceTenantId uuid.UUID, characterIds map[uint32]uint32, flags map[byte]byte) (ImportResult, error) {
+	return message.EmitWithResult[ImportResult, map[byte]byte](p.producer)(model.Flip(model.Flip(p.Clone)(sourceTenantId))(characterIds))(flags)
+}
+
+// returnAttachments returns every unclaimed attachment carried by the note to its sender
+func (p *ProcessorImpl) returnAttachments(mb *message.Buffer) func(r Repository) func(m Model) error {
+	return func(r Repository) func(m Model) error {
+		return func(m Model) error {
+			ap := attachment.NewRepositoryProcessor(p.l, p.ctx, r.Attachments())
+			for _, a := range m.Attachments() {
+				if !a.Unclaimed() {
+					continue
+				}
+				err := ap.Return(mb)(m.SenderId())(a)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+// auditEntry returns an entry of the audit trail recording a change to a note, made by the actor of the processor's
+// context. before is nil for a note the change created, and after for one it deleted.
+func (p *ProcessorImpl) auditEntry(transactionId uuid.UUID, action string, before *Model, after *Model) (audit.Model, error) {
+	b := audit.NewBuilder().
+		SetAction(action).
+		SetActor(audit.ActorFromContext(p.ctx)).
+		SetTransactionId(transactionId)
+	for _, m := range []*Model{before, after} {
+		if m == nil {
+			continue
+		}
+		b.SetNoteId(m.Id()).SetCharacterId(m.CharacterId())
+	}
+	if before != nil {
+		s, err := snapshot(*before)
+		if err != nil {
+			return audit.Model{}, err
+		}
+		b.SetBefore(s)
+	}
+	if after != nil {
+		s, err := snapshot(*after)
+		if err != nil {
+			return audit.Model{}, err
+		}
+		b.SetAfter(s)
+	}
+	return b.Build(), nil
+}
+
+// audit records a change to a note in the audit trail of r, within the transaction r was handed to
+func (p *ProcessorImpl) audit(r Repository, transactionId uuid.UUID, action string, before *Model, after *Model) error {
+	e, err := p.auditEntry(transactionId, action, before, after)
+	if err != nil {
+		return err
+	}
+	return r.Audit().Append(p.t.Id(), e)
+}
+
+// auditor returns a function recording changes to notes in the audit trail of the repository it is handed, all under
+// one transaction ID. The notes before and after each change are paired by index, either side being empty for changes
+// which created or removed notes.
+func (p *ProcessorImpl) auditor(action string) func(r Repository, before []Model, after []Model) error {
+	transactionId := uuid.New()
+	return func(r Repository, before []Model, after []Model) error {
+		es := make([]audit.Model, 0, max(len(before), len(after)))
+		for j := 0; j < max(len(before), len(after)); j++ {
+			var b, a *Model
+			if j < len(before) {
+				b = &before[j]
+			}
+			if j < len(after) {
+				a = &after[j]
+			}
+			e, err := p.auditEntry(transactionId, action, b, a)
+			if err != nil {
+				return err
+			}
+			es = append(es, e)
+		}
+		return r.Audit().Append(p.t.Id(), es...)
+	}
+}
+
+// record records a change to a note in the audit trail and summary counters of r, within the transaction r was handed
+// to, returning the characters whose summaries it changed
+func (p *ProcessorImpl) record(r Repository, transactionId uuid.UUID, action string, before *Model, after *Model) ([]uint32, error) {
+	err := p.audit(r, transactionId, action, before, after)
+	if err != nil {
+		return nil, err
+	}
+	var bs, as []Model
+	if before != nil {
+		bs = append(bs, *before)
+	}
+	if after != nil {
+		as = append(as, *after)
+	}
+	return p.count(r, bs, as)
+}
+
+// count moves the summary counters of r from what the notes before a change counted for to what they count for after,
+// within the transaction r was handed to, returning the characters whose summaries it changed
+func (p *ProcessorImpl) count(r Repository, before []Model, after []Model) ([]uint32, error) {
+	cs := tally(before, after)
+	if len(cs) == 0 {
+		return nil, nil
+	}
+	err := r.Summary().Apply(p.t.Id(), cs...)
+	if err != nil {
+		return nil, err
+	}
+	characterIds := make([]uint32, 0, len(cs))
+	for _, c := range cs {
+		characterIds = append(characterIds, c.CharacterId())
+	}
+	return characterIds, nil
+}
+
+// announce emits the summaries of the characters given, once each, as they stand after the changes which moved them
+func (p *ProcessorImpl) announce(mb *message.Buffer, characterIds []uint32) error {
+	seen := make(map[uint32]struct{}, len(characterIds))
+	for _, characterId := range characterIds {
+		if _, ok := seen[characterId]; ok {
+			continue
+		}
+		seen[characterId] = struct{}{}
+		s, err := p.r.Summary().ByCharacterId(p.t.Id(), characterId)
+		if err != nil {
+			return err
+		}
+		err = mb.Put(note.EnvEventTopicNoteStatus, SummaryNoteStatusEventProvider(s))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tally returns the changes to the summary counters from what notes counted for before a change to what they count for
+// after. A note counts towards the summary of the character holding it until deleted, and is unread until marked read.
+func tally(before []Model, after []Model) []summary.Count {
+	cs := make([]summary.Count, 0, len(before)+len(after))
+	for _, m := range before {
+		cs = append(cs, counts(m, -1))
+	}
+	for _, m := range after {
+		cs = append(cs, counts(m, 1))
+	}
+	return summary.Merge(cs)
+}
+
+// counts returns what a note counts for in the summary of the character holding it, negated for a sign of -1
+func counts(m Model, sign int64) summary.Count {
+	if m.Deleted() {
+		return summary.NewCount(m.CharacterId(), m.Flag(), 0, 0)
+	}
+	var unread int64
+	if !m.Read() {
+		unread = sign
+	}
+	return summary.NewCount(m.CharacterId(), m.Flag(), sign, unread)
+}
+
+// snapshot returns the state of a note as recorded in the audit trail, in the form it is exported in
+func snapshot(m Model) (json.RawMessage, error) {
+	rm, err := TransformRecord(m)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rm)
 }
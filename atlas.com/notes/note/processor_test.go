@@ -1,16 +1,34 @@
 package note_test
 
 import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/auth"
+	"atlas-notes/configuration"
+	"atlas-notes/database"
 	"atlas-notes/kafka/message"
+	"atlas-notes/kafka/message/character"
+	"atlas-notes/kafka/message/compartment"
+	note2 "atlas-notes/kafka/message/note"
+	"atlas-notes/migrations"
 	"atlas-notes/note"
+	"atlas-notes/summary"
+	"atlas-notes/tracing"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	tenant "github.com/Chronicle20/atlas-tenant"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"strings"
 	"testing"
+	"time"
 )
 
 func testDatabase(t *testing.T) *gorm.DB {
@@ -19,13 +37,8 @@ func testDatabase(t *testing.T) *gorm.DB {
 		t.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	var migrators []func(db *gorm.DB) error
-	migrators = append(migrators, note.Migration)
-
-	for _, migrator := range migrators {
-		if err := migrator(db); err != nil {
-			t.Fatalf("Failed to migrate database: %v", err)
-		}
+	if err := database.Migrate(testLogger(), db, migrations.FS, database.LatestVersion); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
 	}
 	return db
 }
@@ -72,3 +85,1145 @@ func TestProcessorImpl_CRUD(t *testing.T) {
 		t.Fatalf("Unexpected flag")
 	}
 }
+
+func testAttachments() []attachment.Model {
+	return []attachment.Model{
+		attachment.NewBuilder().SetType(attachment.TypeItem).SetItemId(2000000).SetQuantity(5).Build(),
+		attachment.NewBuilder().SetType(attachment.TypeMeso).SetMesos(1000).Build(),
+	}
+}
+
+func TestProcessorImpl_Claim(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	characterId := uint32(1)
+	senderId := uint32(2)
+
+	nm, err := np.CreateWithAttachments(message.NewBuffer())(characterId)(senderId)("Gift!")(0)(testAttachments())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if len(nm.Attachments()) != 2 {
+		t.Fatalf("Expected 2 attachments, got %d", len(nm.Attachments()))
+	}
+	if nm.Expiration().IsZero() {
+		t.Fatalf("Expected note carrying attachments to expire")
+	}
+
+	if err = np.Claim(message.NewBuffer())(senderId)(nm.Id()); err != note.ErrNotRecipient {
+		t.Fatalf("Expected claim by non-recipient to fail, got %v", err)
+	}
+
+	mb := message.NewBuffer()
+	if err = np.Claim(mb)(characterId)(nm.Id()); err != nil {
+		t.Fatalf("Failed to claim attachments: %v", err)
+	}
+	ms := mb.GetAll()
+	if len(ms[compartment.EnvCommandTopic]) != 1 || len(ms[character.EnvCommandTopic]) != 1 || len(ms[note2.EnvEventTopicNoteStatus]) != 1 {
+		t.Fatalf("Unexpected messages produced by claim")
+	}
+
+	cm, err := np.ByIdProvider(nm.Id())()
+	if err != nil {
+		t.Fatalf("Failed to retrieve note: %v", err)
+	}
+	for _, a := range cm.Attachments() {
+		if a.Status() != attachment.StatusClaimed {
+			t.Fatalf("Expected attachment [%d] to be claimed, was %s", a.Id(), a.Status())
+		}
+	}
+
+	as, err := np.HistoryProvider(nm.Id())()
+	if err != nil || len(as) != 2 || as[1].Action() != audit.ActionUpdate {
+		t.Fatalf("Expected the claim to be audited as an update, got %d entries (%v)", len(as), err)
+	}
+
+	mb = message.NewBuffer()
+	if err = np.Claim(mb)(characterId)(nm.Id()); err != nil {
+		t.Fatalf("Failed to re-claim attachments: %v", err)
+	}
+	if len(mb.GetAll()) != 0 {
+		t.Fatalf("Expected no messages when re-claiming attachments")
+	}
+}
+
+func TestProcessorImpl_DiscardUnclaimed(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	characterId := uint32(1)
+	senderId := uint32(2)
+
+	nm, err := np.CreateWithAttachments(message.NewBuffer())(characterId)(senderId)("Gift!")(0)(testAttachments())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	if err = np.Discard(message.NewBuffer())(characterId)([]uint32{nm.Id()})(false); err != note.ErrUnclaimedAttachments {
+		t.Fatalf("Expected discard to be refused, got %v", err)
+	}
+	if _, err = np.ByIdProvider(nm.Id())(); err != nil {
+		t.Fatalf("Expected note to remain after refused discard: %v", err)
+	}
+
+	mb := message.NewBuffer()
+	if err = np.Discard(mb)(characterId)([]uint32{nm.Id()})(true); err != nil {
+		t.Fatalf("Failed to force discard: %v", err)
+	}
+	if len(mb.GetAll()[compartment.EnvCommandTopic]) != 1 || len(mb.GetAll()[character.EnvCommandTopic]) != 1 {
+		t.Fatalf("Expected attachments to be returned to sender")
+	}
+	if _, err = np.ByIdProvider(nm.Id())(); err == nil {
+		t.Fatalf("Expected note to be discarded")
+	}
+}
+
+func TestProcessorImpl_Expire(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	nm, err := np.CreateWithAttachments(message.NewBuffer())(1)(2)("Gift!")(0)(testAttachments())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Create(message.NewBuffer())(1)(2)("Hello!")(0); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	ms, err := np.ExpiredProvider(time.Now())()
+	if err != nil {
+		t.Fatalf("Failed to retrieve expired notes: %v", err)
+	}
+	if len(ms) != 0 {
+		t.Fatalf("Expected no expired notes, got %d", len(ms))
+	}
+
+	ms, err = np.ExpiredProvider(nm.Expiration().Add(time.Second))()
+	if err != nil {
+		t.Fatalf("Failed to retrieve expired notes: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Id() != nm.Id() {
+		t.Fatalf("Expected only the note carrying attachments to expire")
+	}
+
+	mb := message.NewBuffer()
+	if err = np.Expire(mb)(nm.Id()); err != nil {
+		t.Fatalf("Failed to expire note: %v", err)
+	}
+	if len(mb.GetAll()[compartment.EnvCommandTopic]) != 1 || len(mb.GetAll()[character.EnvCommandTopic]) != 1 {
+		t.Fatalf("Expected attachments to be returned to sender")
+	}
+}
+
+func TestProcessorImpl_DeleteAllUnclaimed(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	characterId := uint32(1)
+	senderId := uint32(2)
+
+	nm, err := np.CreateWithAttachments(message.NewBuffer())(characterId)(senderId)("Gift!")(0)(testAttachments())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	// Deleting the character's notes returns the parcel it never claimed to the sender.
+	mb := message.NewBuffer()
+	if err = np.DeleteAll(mb)(characterId); err != nil {
+		t.Fatalf("Failed to delete notes: %v", err)
+	}
+	ms := mb.GetAll()
+	if len(ms[compartment.EnvCommandTopic]) != 1 || len(ms[character.EnvCommandTopic]) != 1 {
+		t.Fatalf("Expected attachments to be returned to sender")
+	}
+	var c character.Command[character.RequestChangeMesoBody]
+	if err = json.Unmarshal(ms[character.EnvCommandTopic][0].Value, &c); err != nil {
+		t.Fatalf("Failed to decode award: %v", err)
+	}
+	if c.CharacterId != senderId || c.Body.Amount != 1000 {
+		t.Fatalf("Expected the sender to be awarded the mesos back, got character [%d] and amount [%d]", c.CharacterId, c.Body.Amount)
+	}
+
+	// The parcel is settled, so a purge of the deleted note loses nothing.
+	dm, err := note.NewGormRepository(db).ByIdIncludingDeleted(te.Id(), nm.Id())
+	if err != nil {
+		t.Fatalf("Failed to retrieve deleted note: %v", err)
+	}
+	if dm.HasUnclaimedAttachments() {
+		t.Fatalf("Expected no unclaimed attachments on the deleted note")
+	}
+}
+
+func TestProcessorImpl_Reply(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	recipientId := uint32(1)
+	senderId := uint32(2)
+
+	om, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if om.ThreadId() != om.Id() {
+		t.Fatalf("Expected note to start its own thread")
+	}
+
+	// The recipient no longer holds the original note, but can still reply to it.
+	if err = np.Discard(message.NewBuffer())(recipientId)([]uint32{om.Id()})(false); err != nil {
+		t.Fatalf("Failed to discard note: %v", err)
+	}
+
+	rm, err := np.Reply(message.NewBuffer())(recipientId)(om.Id())("Hi!")(0)
+	if err != nil {
+		t.Fatalf("Failed to reply to note: %v", err)
+	}
+	if rm.CharacterId() != senderId || rm.SenderId() != recipientId {
+		t.Fatalf("Expected reply to be addressed to the original sender")
+	}
+	if rm.InReplyTo() != om.Id() || rm.ThreadId() != om.ThreadId() {
+		t.Fatalf("Expected reply to join the original thread")
+	}
+
+	fm, err := np.Reply(message.NewBuffer())(senderId)(rm.Id())("How are you?")(0)
+	if err != nil {
+		t.Fatalf("Failed to reply to reply: %v", err)
+	}
+	if fm.CharacterId() != recipientId || fm.ThreadId() != om.ThreadId() {
+		t.Fatalf("Expected follow up to stay in the original thread")
+	}
+
+	if _, err = np.Reply(message.NewBuffer())(3)(om.Id())("Intruding!")(0); err != note.ErrNotParticipant {
+		t.Fatalf("Expected reply by non-participant to fail, got %v", err)
+	}
+}
+
+func TestProcessorImpl_SentItems(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	recipientId := uint32(1)
+	senderId := uint32(2)
+
+	m1, err := np.Create(message.NewBuffer())(recipientId)(senderId)("First")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	m2, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Second")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	// The recipient discarding a note leaves the sender's copy.
+	if err = np.Discard(message.NewBuffer())(recipientId)([]uint32{m1.Id()})(false); err != nil {
+		t.Fatalf("Failed to discard note: %v", err)
+	}
+	ms, err := np.BySenderProvider(senderId)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve sent notes: %v", err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("Expected 2 sent notes, got %d", len(ms))
+	}
+
+	// The sender hiding a note leaves the recipient's copy.
+	if err = np.HideSent(senderId)([]uint32{m2.Id()}); err != nil {
+		t.Fatalf("Failed to hide sent note: %v", err)
+	}
+	ms, err = np.BySenderProvider(senderId)()
+	if err != nil {
+		t.Fatalf("Failed to retrieve sent notes: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Id() != m1.Id() {
+		t.Fatalf("Expected only the unhidden note to remain in sent items")
+	}
+	if _, err = np.ByIdProvider(m2.Id())(); err != nil {
+		t.Fatalf("Expected recipient's copy to remain: %v", err)
+	}
+}
+
+func TestProcessorImpl_Organize(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	recipientId := uint32(1)
+	senderId := uint32(2)
+
+	m1, err := np.Create(message.NewBuffer())(recipientId)(senderId)("First")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	m2, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Second")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	m3, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Third")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	if _, err = np.Star(message.NewBuffer())(senderId)(m1.Id())(true); err != note.ErrNotRecipient {
+		t.Fatalf("Expected only the recipient to organize a note, got %v", err)
+	}
+
+	m, err := np.Star(message.NewBuffer())(recipientId)(m1.Id())(true)
+	if err != nil {
+		t.Fatalf("Failed to star note: %v", err)
+	}
+	if !m.Starred() {
+		t.Fatalf("Expected note to be starred")
+	}
+	if _, err = np.Pin(message.NewBuffer())(recipientId)(m2.Id())(true); err != nil {
+		t.Fatalf("Failed to pin note: %v", err)
+	}
+	if _, err = np.Archive(message.NewBuffer())(recipientId)(m3.Id())(true); err != nil {
+		t.Fatalf("Failed to archive note: %v", err)
+	}
+	m, err = np.Label(message.NewBuffer())(recipientId)(m1.Id())([]string{"guild", " guild ", ""})
+	if err != nil {
+		t.Fatalf("Failed to label note: %v", err)
+	}
+	if len(m.Labels()) != 1 || m.Labels()[0] != "guild" {
+		t.Fatalf("Expected a single guild label, got %v", m.Labels())
+	}
+
+	// The default view excludes archived notes and lists pinned notes first.
+	ms, err := np.ByCharacterAndFilterProvider(recipientId, note.Filter{})()
+	if err != nil {
+		t.Fatalf("Failed to retrieve notes: %v", err)
+	}
+	if len(ms) != 2 || ms[0].Id() != m2.Id() {
+		t.Fatalf("Expected pinned note first and archived note excluded")
+	}
+
+	starred := true
+	ms, err = np.ByCharacterAndFilterProvider(recipientId, note.Filter{Starred: &starred, Label: "guild"})()
+	if err != nil {
+		t.Fatalf("Failed to retrieve notes: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Id() != m1.Id() {
+		t.Fatalf("Expected only the starred, labelled note")
+	}
+
+	ms, err = np.ByCharacterAndFilterProvider(recipientId, note.Filter{Archived: true})()
+	if err != nil {
+		t.Fatalf("Failed to retrieve notes: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Id() != m3.Id() {
+		t.Fatalf("Expected only the archived note")
+	}
+
+	// Several changes are made together, and announced once.
+	unstarred, pinned, labels := false, true, []string{"trade"}
+	mb := message.NewBuffer()
+	m, err = np.Organize(mb)(recipientId)(m1.Id())(note.Organization{Starred: &unstarred, Pinned: &pinned, Labels: &labels})
+	if err != nil {
+		t.Fatalf("Failed to organize note: %v", err)
+	}
+	if m.Starred() || !m.Pinned() || m.Archived() || len(m.Labels()) != 1 || m.Labels()[0] != "trade" {
+		t.Fatalf("Expected the note to be unstarred, pinned and relabelled, got %+v", m)
+	}
+	if n := statusEvents(mb, note2.StatusEventTypeOrganized); n != 1 {
+		t.Fatalf("Expected the organization to be announced once, got %d", n)
+	}
+	mb = message.NewBuffer()
+	if m, err = np.Organize(mb)(recipientId)(m1.Id())(note.Organization{}); err != nil || !m.Pinned() || len(mb.GetAll()) != 0 {
+		t.Fatalf("Expected an empty change to leave the note alone and announce nothing (%v)", err)
+	}
+	if _, err = np.Organize(message.NewBuffer())(senderId)(m1.Id())(note.Organization{}); err != note.ErrNotRecipient {
+		t.Fatalf("Expected only the recipient to organize a note, got %v", err)
+	}
+}
+
+func TestProcessorImpl_InboxCapacity(t *testing.T) {
+	t.Setenv(note.EnvInboxCapacity, "1")
+
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	recipientId := uint32(1)
+	senderId := uint32(2)
+
+	m, err := np.Create(message.NewBuffer())(recipientId)(senderId)("First")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Create(message.NewBuffer())(recipientId)(senderId)("Second")(0); err != note.ErrInboxFull {
+		t.Fatalf("Expected inbox to be full, got %v", err)
+	}
+
+	// Archived notes do not count against the inbox.
+	if _, err = np.Archive(message.NewBuffer())(recipientId)(m.Id())(true); err != nil {
+		t.Fatalf("Failed to archive note: %v", err)
+	}
+	if _, err = np.Create(message.NewBuffer())(recipientId)(senderId)("Second")(0); err != nil {
+		t.Fatalf("Failed to create note after archiving: %v", err)
+	}
+}
+
+func TestProcessorImpl_Policies(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	cfg := configuration.NewBuilder().
+		SetMaxMessageLength(5).
+		SetAllowedFlags([]byte{0, 1}).
+		SetSendRateLimit(2).
+		SetSendRateWindow(time.Hour).
+		Build()
+	if _, err := configuration.NewProcessor(l, ctx, db).Update(message.NewBuffer())(cfg); err != nil {
+		t.Fatalf("Failed to configure tenant: %v", err)
+	}
+
+	np := note.NewProcessor(l, ctx, db)
+
+	recipientId := uint32(1)
+	senderId := uint32(2)
+
+	if _, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Too long")(0); !errors.Is(err, note.ErrMessageTooLong) {
+		t.Fatalf("Expected message to be too long, got %v", err)
+	}
+	if _, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Hi")(2); !errors.Is(err, note.ErrFlagNotAllowed) {
+		t.Fatalf("Expected flag not to be allowed, got %v", err)
+	}
+	m, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Hi")(1)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Update(message.NewBuffer())(m.Id())(recipientId)(senderId)("Héllo!")(1); !errors.Is(err, note.ErrMessageTooLong) {
+		t.Fatalf("Expected updated message to be too long, got %v", err)
+	}
+	if _, err = np.Update(message.NewBuffer())(m.Id())(recipientId)(senderId)("Héllo")(1); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+
+	// Deleting sent notes does not lift the send rate limit.
+	if err = np.Delete(message.NewBuffer())(m.Id()); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+	if _, err = np.Create(message.NewBuffer())(recipientId)(senderId)("Hi")(0); err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Create(message.NewBuffer())(recipientId)(senderId)("Hi")(0); !errors.Is(err, note.ErrRateLimited) {
+		t.Fatalf("Expected sender to be rate limited, got %v", err)
+	}
+	if _, err = np.Create(message.NewBuffer())(recipientId)(3)("Hi")(0); err != nil {
+		t.Fatalf("Failed to create note from another sender: %v", err)
+	}
+
+	// Policies are per tenant.
+	ote := testTenant()
+	op := note.NewProcessor(l, tenant.WithContext(context.Background(), ote), db)
+	if _, err = op.Create(message.NewBuffer())(recipientId)(senderId)("Too long")(2); err != nil {
+		t.Fatalf("Failed to create note in another tenant: %v", err)
+	}
+}
+
+func TestProcessorImpl_Restore(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+
+	np := note.NewProcessor(l, ctx, db)
+
+	recipientId := uint32(1)
+	senderId := uint32(2)
+
+	m, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Restore(message.NewBuffer())(recipientId)(m.Id()); err != note.ErrNotDeleted {
+		t.Fatalf("Expected restoring a held note to fail, got %v", err)
+	}
+	if err = np.Discard(message.NewBuffer())(recipientId)([]uint32{m.Id()})(false); err != nil {
+		t.Fatalf("Failed to discard note: %v", err)
+	}
+
+	ms, err := np.ByCharacterAndFilterProvider(recipientId, note.Filter{Deleted: true})()
+	if err != nil {
+		t.Fatalf("Failed to retrieve deleted notes: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Id() != m.Id() || !ms[0].Deleted() {
+		t.Fatalf("Expected the discarded note to be listed as deleted")
+	}
+
+	if _, err = np.Restore(message.NewBuffer())(senderId)(m.Id()); err != note.ErrNotRecipient {
+		t.Fatalf("Expected only the recipient to restore a note, got %v", err)
+	}
+	rm, err := np.Restore(message.NewBuffer())(recipientId)(m.Id())
+	if err != nil {
+		t.Fatalf("Failed to restore note: %v", err)
+	}
+	if rm.Deleted() {
+		t.Fatalf("Expected restored note not to be deleted")
+	}
+	ms, err = np.ByCharacterAndFilterProvider(recipientId, note.Filter{})()
+	if err != nil {
+		t.Fatalf("Failed to retrieve notes: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Id() != m.Id() {
+		t.Fatalf("Expected the restored note back in the inbox")
+	}
+
+	// Notes deleted longer ago than the grace period stay deleted.
+	t.Setenv(note.EnvRestoreGracePeriod, "1ns")
+	if err = np.Discard(message.NewBuffer())(recipientId)([]uint32{m.Id()})(false); err != nil {
+		t.Fatalf("Failed to discard note: %v", err)
+	}
+	if _, err = np.Restore(message.NewBuffer())(recipientId)(m.Id()); err != note.ErrRestoreWindowClosed {
+		t.Fatalf("Expected the restore window to be closed, got %v", err)
+	}
+}
+
+func TestProcessorImpl_ExportImport(t *testing.T) {
+	l := testLogger()
+	ctx := tenant.WithContext(context.Background(), testTenant())
+	np := note.NewProcessor(l, ctx, note.NewMemoryRepository())
+
+	a, err := np.Create(message.NewBuffer())(1)(2)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Label(message.NewBuffer())(1)(a.Id())([]string{"guild"}); err != nil {
+		t.Fatalf("Failed to label note: %v", err)
+	}
+	b, err := np.Reply(message.NewBuffer())(1)(a.Id())("Hi!")(0)
+	if err != nil {
+		t.Fatalf("Failed to reply to note: %v", err)
+	}
+	c, err := np.CreateWithAttachments(message.NewBuffer())(1)(3)("Gift!")(0)(testAttachments())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if err = np.Delete(message.NewBuffer())(c.Id()); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+
+	var export bytes.Buffer
+	enc := json.NewEncoder(&export)
+	err = np.Export(func(m note.Model) error {
+		rm, err := note.TransformRecord(m)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(rm)
+	})
+	if err != nil {
+		t.Fatalf("Failed to export notes: %v", err)
+	}
+	stream := export.String() + "\n{\"id\": 99, \"bogus\": true}\n{\"id\": 100, \"timestamp\": \"2024-01-01T00:00:00Z\"}\nnot json\n"
+
+	ictx := tenant.WithContext(context.Background(), testTenant())
+	ip := note.NewProcessor(l, ictx, note.NewMemoryRepository())
+	characterIds := map[uint32]uint32{1: 101, 2: 102}
+	i := ip.Importer(characterIds, note.ConflictSkip)
+	if err = i.Read(strings.NewReader(stream)); err != nil {
+		t.Fatalf("Failed to import notes: %v", err)
+	}
+	res := i.Result()
+	if res.Imported != 3 || res.Skipped != 0 || res.Overwritten != 0 {
+		t.Fatalf("Expected 3 notes to be imported, got %+v", res)
+	}
+	if len(res.Errors) != 3 || res.Errors[0].Line != 5 || res.Errors[1].Line != 6 || res.Errors[2].Line != 7 {
+		t.Fatalf("Expected the invalid records to be reported by line, got %+v", res.Errors)
+	}
+
+	ms, err := ip.InTenantProvider()()
+	if err != nil {
+		t.Fatalf("Failed to retrieve imported notes: %v", err)
+	}
+	ms = byId(ms)
+	if len(ms) != 2 {
+		t.Fatalf("Expected the 2 notes held to be imported, got %d", len(ms))
+	}
+	ia, ib := ms[0], ms[1]
+	if ia.CharacterId() != 101 || ia.SenderId() != 102 || ia.Message() != "Hello!" || len(ia.Labels()) != 1 {
+		t.Fatalf("Expected the first note to be imported with its characters remapped, got %+v", ia)
+	}
+	if ib.Message() != b.Message() || ib.CharacterId() != 102 || ib.SenderId() != 101 || ib.InReplyTo() != ia.Id() || ib.ThreadId() != ia.Id() {
+		t.Fatalf("Expected the reply to be attached to the imported note, got %+v", ib)
+	}
+	ds, err := ip.ByCharacterAndFilterProvider(101, note.Filter{Deleted: true})()
+	if err != nil || len(ds) != 1 || ds[0].SenderId() != 3 || len(ds[0].Attachments()) != 2 {
+		t.Fatalf("Expected the deleted note to be imported deleted, with its attachments and unmapped sender")
+	}
+
+	// Importing the same notes again leaves them alone, unless they are to be overwritten.
+	i = ip.Importer(characterIds, note.ConflictSkip)
+	if err = i.Read(strings.NewReader(export.String())); err != nil {
+		t.Fatalf("Failed to import notes: %v", err)
+	}
+	if res = i.Result(); res.Imported != 0 || res.Skipped != 3 {
+		t.Fatalf("Expected every note to be skipped, got %+v", res)
+	}
+	i = ip.Importer(characterIds, note.ConflictOverwrite)
+	if err = i.Read(strings.NewReader(export.String())); err != nil {
+		t.Fatalf("Failed to import notes: %v", err)
+	}
+	if res = i.Result(); res.Imported != 0 || res.Overwritten != 3 {
+		t.Fatalf("Expected every note to be overwritten, got %+v", res)
+	}
+	if c, _ := ip.CountIncludingDeletedProvider()(); c != 3 {
+		t.Fatalf("Expected no duplicates, got %d notes", c)
+	}
+	as, err := ip.AuditByCharacterProvider(101)()
+	if err != nil || len(as) != 4 {
+		t.Fatalf("Expected the 2 notes of the character to be audited when imported and overwritten, got [%d] (%v)", len(as), err)
+	}
+	for _, e := range as {
+		if e.Action() != audit.ActionImport || e.After() == nil {
+			t.Fatalf("Expected import entries, got %s", e.Action())
+		}
+	}
+	if as[0].Before() != nil || as[2].Before() == nil || as[0].TransactionId() == as[2].TransactionId() {
+		t.Fatalf("Expected overwrites to record the note before, under the transaction of their own import")
+	}
+	// A note recorded twice in one stream is imported once, the second record being subject to the conflict policy.
+	lines := strings.SplitN(export.String(), "\n", 2)
+	var dup note.RecordModel
+	if err = json.Unmarshal([]byte(lines[0]), &dup); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	dup.Id = 1000
+	dup.Message = "Hello twice!"
+	second, _ := json.Marshal(dup)
+	for policy, expected := range map[note.ConflictPolicy]note.ImportResult{
+		note.ConflictSkip:      {Imported: 1, Skipped: 1},
+		note.ConflictOverwrite: {Imported: 1, Overwritten: 1},
+	} {
+		dp := note.NewProcessor(l, tenant.WithContext(context.Background(), testTenant()), note.NewMemoryRepository())
+		i = dp.Importer(characterIds, policy)
+		if err = i.Read(strings.NewReader(lines[0] + "\n" + string(second) + "\n")); err != nil {
+			t.Fatalf("Failed to import notes: %v", err)
+		}
+		if res = i.Result(); res.Imported != expected.Imported || res.Skipped != expected.Skipped || res.Overwritten != expected.Overwritten {
+			t.Fatalf("Expected %+v importing a duplicate with policy [%s], got %+v", expected, policy, res)
+		}
+		if c, _ := dp.CountIncludingDeletedProvider()(); c != 1 {
+			t.Fatalf("Expected the duplicate to be stored once with policy [%s], got %d notes", policy, c)
+		}
+	}
+
+	if _, err = note.ParseConflictPolicy("merge"); !errors.Is(err, note.ErrUnknownConflictPolicy) {
+		t.Fatalf("Expected an unknown conflict policy to be refused, got %v", err)
+	}
+}
+
+func TestProcessorImpl_Clone(t *testing.T) {
+	l := testLogger()
+	r := note.NewMemoryRepository()
+	source := testTenant()
+	sp := note.NewProcessor(l, tenant.WithContext(context.Background(), source), r)
+
+	a, err := sp.Create(message.NewBuffer())(1)(2)("Hello!")(1)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = sp.Reply(message.NewBuffer())(1)(a.Id())("Hi!")(0); err != nil {
+		t.Fatalf("Failed to reply to note: %v", err)
+	}
+	d, err := sp.Create(message.NewBuffer())(1)(3)("Gone")(1)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if err = sp.Delete(message.NewBuffer())(d.Id()); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+
+	target := testTenant()
+	tp := note.NewProcessor(l, tenant.WithContext(context.Background(), target), r)
+	mb := message.NewBuffer()
+	res, err := tp.Clone(mb)(source.Id())(map[uint32]uint32{1: 11})(map[byte]byte{1: 4})
+	if err != nil {
+		t.Fatalf("Failed to clone notes: %v", err)
+	}
+	if res.Imported != 3 || res.Skipped != 0 {
+		t.Fatalf("Expected 3 notes to be cloned, got %+v", res)
+	}
+	if n := statusEvents(mb, note2.StatusEventTypeCreated); n != 2 {
+		t.Fatalf("Expected a creation to be announced for each note held, got %d", n)
+	}
+	if n := statusEvents(mb, note2.StatusEventTypeSummary); n != 2 {
+		t.Fatalf("Expected the summary of each recipient to be announced, got %d", n)
+	}
+
+	ms, err := tp.InTenantProvider()()
+	if err != nil {
+		t.Fatalf("Failed to retrieve cloned notes: %v", err)
+	}
+	ms = byId(ms)
+	if len(ms) != 2 {
+		t.Fatalf("Expected the 2 notes held to be cloned, got %d", len(ms))
+	}
+	if ms[0].Id() == a.Id() || ms[0].CharacterId() != 11 || ms[0].SenderId() != 2 || ms[0].Flag() != 4 {
+		t.Fatalf("Expected a copy with its recipient and flag mapped, got %+v", ms[0])
+	}
+	if ms[1].SenderId() != 11 || ms[1].Flag() != 0 || ms[1].InReplyTo() != ms[0].Id() {
+		t.Fatalf("Expected the reply to be attached to the copy, got %+v", ms[1])
+	}
+	if c, _ := sp.CountIncludingDeletedProvider()(); c != 3 {
+		t.Fatalf("Expected the source tenant to be left alone, got %d notes", c)
+	}
+	if as, err := tp.AuditByCharacterProvider(11)(); err != nil || len(as) != 2 || as[0].Action() != audit.ActionClone || as[0].TransactionId() != as[1].TransactionId() {
+		t.Fatalf("Expected the notes copied to the character to be audited as one clone, got [%d] (%v)", len(as), err)
+	}
+
+	res, err = tp.Clone(message.NewBuffer())(source.Id())(map[uint32]uint32{1: 11})(map[byte]byte{1: 4})
+	if err != nil || res.Imported != 0 || res.Skipped != 3 {
+		t.Fatalf("Expected cloning again to skip every note, got %+v (%v)", res, err)
+	}
+	if _, err = sp.Clone(message.NewBuffer())(source.Id())(nil)(nil); !errors.Is(err, note.ErrSameTenant) {
+		t.Fatalf("Expected cloning a tenant into itself to be refused, got %v", err)
+	}
+}
+
+func TestProcessorImpl_Tracing(t *testing.T) {
+	l := testLogger()
+	exporter := tracetest.NewInMemoryExporter()
+	tc, err := tracing.InitTracer(l)("atlas-notes", tracing.SetExporter(exporter), tracing.SetSyncExport(true))
+	if err != nil {
+		t.Fatalf("Failed to initialize tracer: %v", err)
+	}
+	defer tracing.Teardown(l)(tc)()
+
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	db := testDatabase(t)
+	if err = db.Use(tracing.NewGormPlugin()); err != nil {
+		t.Fatalf("Failed to install plugin: %v", err)
+	}
+	np := note.NewProcessor(l, ctx, db)
+
+	characterId := uint32(1)
+	for i := 0; i < 2; i++ {
+		if _, err = np.Create(message.NewBuffer())(characterId)(2)("Hello!")(0); err != nil {
+			t.Fatalf("Failed to create note: %v", err)
+		}
+	}
+	exporter.Reset()
+	if err = np.DeleteAll(message.NewBuffer())(characterId); err != nil {
+		t.Fatalf("Failed to delete notes: %v", err)
+	}
+
+	var op tracetest.SpanStub
+	var queries []tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		switch {
+		case s.Name == "note.DeleteAll":
+			op = s
+		case strings.HasPrefix(s.Name, "gorm."):
+			queries = append(queries, s)
+		}
+	}
+	if !op.SpanContext.IsValid() {
+		t.Fatalf("Expected a span for the operation.")
+	}
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, a := range op.Attributes {
+		attrs[a.Key] = a.Value
+	}
+	if v := attrs[note.AttributeCharacterId]; v.AsInt64() != int64(characterId) {
+		t.Fatalf("Expected span to carry character [%d], got [%v]", characterId, v.Emit())
+	}
+	if v := attrs[note.AttributeNoteCount]; v.AsInt64() != 2 {
+		t.Fatalf("Expected span to count 2 notes, got [%v]", v.Emit())
+	}
+	if v := attrs[tracing.AttributeTenantId]; v.AsString() != te.Id().String() {
+		t.Fatalf("Expected span to carry tenant [%s], got [%v]", te.Id().String(), v.Emit())
+	}
+	if len(queries) == 0 {
+		t.Fatalf("Expected spans for the queries of the operation.")
+	}
+	for _, q := range queries {
+		if q.Parent.SpanID() != op.SpanContext.SpanID() {
+			t.Fatalf("Expected query [%s] to be a child of the operation.", q.Name)
+		}
+	}
+}
+
+func TestProcessorImpl_Audit(t *testing.T) {
+	ctx := tenant.WithContext(context.Background(), testTenant())
+	ctx = audit.WithActor(ctx, audit.NewActor(audit.ActorTypeRest, "gm-tool"))
+	processors := map[string]note.Processor{
+		"gorm":   note.NewProcessor(testLogger(), ctx, testDatabase(t)),
+		"memory": note.NewProcessor(testLogger(), ctx, note.NewMemoryRepository()),
+	}
+	for name, np := range processors {
+		t.Run(name, func(t *testing.T) {
+			testAudit(t, np)
+		})
+	}
+}
+
+// statusEvents returns how many status events of a type are buffered
+func statusEvents(mb *message.Buffer, eventType string) int {
+	n := 0
+	for _, m := range mb.GetAll()[note2.EnvEventTopicNoteStatus] {
+		var e note2.StatusEvent[json.RawMessage]
+		if err := json.Unmarshal(m.Value, &e); err == nil && e.Type == eventType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestProcessorImpl_Summary(t *testing.T) {
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	gr := note.NewGormRepository(testDatabase(t))
+	mr := note.NewMemoryRepository()
+	t.Run("gorm", func(t *testing.T) {
+		testSummary(t, note.NewProcessor(testLogger(), ctx, gr), gr, te.Id())
+	})
+	t.Run("memory", func(t *testing.T) {
+		testSummary(t, note.NewProcessor(testLogger(), ctx, mr), mr, te.Id())
+	})
+}
+
+func testSummary(t *testing.T, np note.Processor, r note.Repository, tenantId uuid.UUID) {
+	recipientId := uint32(1)
+	senderId := uint32(2)
+	expect := func(total int64, unread int64, kinds int) {
+		t.Helper()
+		s, err := np.SummaryProvider(recipientId)()
+		if err != nil {
+			t.Fatalf("Failed to retrieve summary: %v", err)
+		}
+		if s.Total() != total || s.Unread() != unread || len(s.Kinds()) != kinds {
+			t.Fatalf("Expected %d notes, %d unread, of %d kinds, got %d, %d, of %d.", total, unread, kinds, s.Total(), s.Unread(), len(s.Kinds()))
+		}
+	}
+	expect(0, 0, 0)
+	mb := message.NewBuffer()
+	a, err := np.Create(mb)(recipientId)(senderId)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if statusEvents(mb, note2.StatusEventTypeSummary) != 1 {
+		t.Fatalf("Expected the summary change to be announced.")
+	}
+	b, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Hello again!")(1)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	expect(2, 2, 2)
+
+	if _, err = np.MarkRead(message.NewBuffer())(senderId)(a.Id()); !errors.Is(err, note.ErrNotRecipient) {
+		t.Fatalf("Expected only the recipient to read a note, got %v", err)
+	}
+	mb = message.NewBuffer()
+	m, err := np.MarkRead(mb)(recipientId)(a.Id())
+	if err != nil {
+		t.Fatalf("Failed to mark note read: %v", err)
+	}
+	if !m.Read() || statusEvents(mb, note2.StatusEventTypeRead) != 1 || statusEvents(mb, note2.StatusEventTypeSummary) != 1 {
+		t.Fatalf("Expected the read and the summary change to be announced.")
+	}
+	expect(2, 1, 2)
+	mb = message.NewBuffer()
+	again, err := np.MarkRead(mb)(recipientId)(a.Id())
+	if err != nil || !again.ReadAt().Equal(m.ReadAt()) || len(mb.GetAll()) != 0 {
+		t.Fatalf("Expected reading a note again to keep when it was first read, and announce nothing (%v).", err)
+	}
+	mb = message.NewBuffer()
+	if _, err = np.Archive(mb)(recipientId)(b.Id())(true); err != nil {
+		t.Fatalf("Failed to archive note: %v", err)
+	}
+	expect(2, 1, 2)
+	if statusEvents(mb, note2.StatusEventTypeSummary) != 0 {
+		t.Fatalf("Expected archiving, which moves no counter, not to be announced.")
+	}
+	if _, err = np.Archive(message.NewBuffer())(recipientId)(b.Id())(false); err != nil {
+		t.Fatalf("Failed to unarchive note: %v", err)
+	}
+	mb = message.NewBuffer()
+	if _, err = np.Star(mb)(recipientId)(a.Id())(true); err != nil {
+		t.Fatalf("Failed to star note: %v", err)
+	}
+	if statusEvents(mb, note2.StatusEventTypeSummary) != 0 {
+		t.Fatalf("Expected a change which moves no counter not to be announced.")
+	}
+
+	if err = np.Discard(message.NewBuffer())(recipientId)([]uint32{b.Id()})(false); err != nil {
+		t.Fatalf("Failed to discard note: %v", err)
+	}
+	expect(1, 0, 1)
+	if _, err = np.Restore(message.NewBuffer())(recipientId)(b.Id()); err != nil {
+		t.Fatalf("Failed to restore note: %v", err)
+	}
+	expect(2, 1, 2)
+
+	if n, err := np.Reconcile(message.NewBuffer()); err != nil || n != 0 {
+		t.Fatalf("Expected no counter to have drifted, got [%d] (%v).", n, err)
+	}
+	if err = r.Summary().Apply(tenantId, summary.NewCount(recipientId, 1, 3, -1), summary.NewCount(5, 0, 1, 1)); err != nil {
+		t.Fatalf("Failed to skew counters: %v", err)
+	}
+	mb = message.NewBuffer()
+	n, err := np.Reconcile(mb)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 drifted counters to be corrected, got [%d] (%v).", n, err)
+	}
+	if statusEvents(mb, note2.StatusEventTypeSummary) != 2 {
+		t.Fatalf("Expected the corrected summaries to be announced, got %d.", statusEvents(mb, note2.StatusEventTypeSummary))
+	}
+	expect(2, 1, 2)
+}
+
+func TestProcessorImpl_NotifyPending(t *testing.T) {
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	gr := note.NewGormRepository(testDatabase(t))
+	mr := note.NewMemoryRepository()
+	t.Run("gorm", func(t *testing.T) {
+		testNotifyPending(t, note.NewProcessor(testLogger(), ctx, gr), gr, te.Id())
+	})
+	t.Run("memory", func(t *testing.T) {
+		testNotifyPending(t, note.NewProcessor(testLogger(), ctx, mr), mr, te.Id())
+	})
+}
+
+func testNotifyPending(t *testing.T, np note.Processor, r note.Repository, tenantId uuid.UUID) {
+	recipientId := uint32(1)
+	mb := message.NewBuffer()
+	if err := np.NotifyPending(mb)(0)(2)(recipientId); err != nil {
+		t.Fatalf("Failed to notify pending notes: %v", err)
+	}
+	if statusEvents(mb, note2.StatusEventTypePending) != 0 {
+		t.Fatalf("Expected a character holding no notes not to be notified.")
+	}
+
+	now := time.Now()
+	for i, senderId := range []uint32{2, 3, 2, 4, 5, 6, 7} {
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(recipientId).SetSenderId(senderId).SetTimestamp(now.Add(time.Duration(i)*time.Minute)))
+	}
+	read := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(recipientId).SetSenderId(8).SetTimestamp(now.Add(time.Hour)))
+	archived := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(recipientId).SetSenderId(9).SetTimestamp(now.Add(-time.Hour)))
+	if err := r.UpdateOrganization(tenantId, archived.Id(), note.MarkerArchived, true); err != nil {
+		t.Fatalf("Failed to archive note: %v", err)
+	}
+	// The notes were stored directly, so their counters are reconciled from them.
+	if _, err := np.Reconcile(message.NewBuffer()); err != nil {
+		t.Fatalf("Failed to reconcile counters: %v", err)
+	}
+	if _, err := np.MarkRead(message.NewBuffer())(recipientId)(read.Id()); err != nil {
+		t.Fatalf("Failed to mark note read: %v", err)
+	}
+
+	mb = message.NewBuffer()
+	if err := np.NotifyPending(mb)(0)(2)(recipientId); err != nil {
+		t.Fatalf("Failed to notify pending notes: %v", err)
+	}
+	ms := mb.GetAll()[note2.EnvEventTopicNoteStatus]
+	if len(ms) != 1 {
+		t.Fatalf("Expected a single notification, got %d", len(ms))
+	}
+	var e note2.StatusEvent[note2.StatusEventPendingBody]
+	if err := json.Unmarshal(ms[0].Value, &e); err != nil {
+		t.Fatalf("Failed to decode notification: %v", err)
+	}
+	if e.Type != note2.StatusEventTypePending || e.CharacterId != recipientId || e.Body.ChannelId != 2 {
+		t.Fatalf("Expected a pending notification for the character's channel, got %+v", e)
+	}
+	if e.Body.Count != 8 {
+		t.Fatalf("Expected the 8 unread notes, archived included, to be counted, got %d", e.Body.Count)
+	}
+	expected := []uint32{7, 6, 5, 4, 2}
+	if len(e.Body.SenderIds) != len(expected) {
+		t.Fatalf("Expected senders %v, got %v", expected, e.Body.SenderIds)
+	}
+	for i, id := range expected {
+		if e.Body.SenderIds[i] != id {
+			t.Fatalf("Expected senders %v, got %v", expected, e.Body.SenderIds)
+		}
+	}
+}
+
+func testAudit(t *testing.T, np note.Processor) {
+	recipientId := uint32(1)
+	senderId := uint32(2)
+	m, err := np.Create(message.NewBuffer())(recipientId)(senderId)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = np.Update(message.NewBuffer())(m.Id())(recipientId)(senderId)("Hello again!")(0); err != nil {
+		t.Fatalf("Failed to update note: %v", err)
+	}
+	if err = np.Discard(message.NewBuffer())(recipientId)([]uint32{m.Id()})(false); err != nil {
+		t.Fatalf("Failed to discard note: %v", err)
+	}
+	if _, err = np.Restore(message.NewBuffer())(recipientId)(m.Id()); err != nil {
+		t.Fatalf("Failed to restore note: %v", err)
+	}
+	if _, err = np.Star(message.NewBuffer())(recipientId)(m.Id())(true); err != nil {
+		t.Fatalf("Failed to star note: %v", err)
+	}
+	if _, err = np.MarkRead(message.NewBuffer())(recipientId)(m.Id()); err != nil {
+		t.Fatalf("Failed to read note: %v", err)
+	}
+	if err = np.HideSent(senderId)([]uint32{m.Id()}); err != nil {
+		t.Fatalf("Failed to hide sent note: %v", err)
+	}
+
+	as, err := np.HistoryProvider(m.Id())()
+	if err != nil {
+		t.Fatalf("Failed to retrieve history: %v", err)
+	}
+	expected := []string{audit.ActionCreate, audit.ActionUpdate, audit.ActionDiscard, audit.ActionRestore, audit.ActionUpdate, audit.ActionUpdate, audit.ActionUpdate}
+	if len(as) != len(expected) {
+		t.Fatalf("Expected [%d] audit entries, got [%d].", len(expected), len(as))
+	}
+	transactions := make(map[uuid.UUID]bool)
+	for i, a := range as {
+		if a.Action() != expected[i] {
+			t.Fatalf("Expected entry [%d] to be [%s], got [%s].", i, expected[i], a.Action())
+		}
+		if a.Actor().Type() != audit.ActorTypeRest || a.Actor().Id() != "gm-tool" {
+			t.Fatalf("Expected entries to be attributed to the caller, got [%s] [%s].", a.Actor().Type(), a.Actor().Id())
+		}
+		if a.NoteId() != m.Id() || a.CharacterId() != recipientId {
+			t.Fatalf("Expected entries to identify the note and its recipient.")
+		}
+		transactions[a.TransactionId()] = true
+	}
+	if len(transactions) != len(expected) {
+		t.Fatalf("Expected each operation to have its own transaction.")
+	}
+
+	var before, after note.RecordModel
+	if as[0].Before() != nil || json.Unmarshal(as[0].After(), &after) != nil || after.Message != "Hello!" {
+		t.Fatalf("Expected creation to record only the note created.")
+	}
+	if json.Unmarshal(as[1].Before(), &before) != nil || json.Unmarshal(as[1].After(), &after) != nil {
+		t.Fatalf("Expected an update to record the note before and after.")
+	}
+	if before.Message != "Hello!" || after.Message != "Hello again!" {
+		t.Fatalf("Expected the update to record the message changing, got [%s] to [%s].", before.Message, after.Message)
+	}
+	if as[2].After() != nil {
+		t.Fatalf("Expected a discard to record only the note discarded.")
+	}
+	before, after = note.RecordModel{}, note.RecordModel{}
+	if json.Unmarshal(as[4].Before(), &before) != nil || json.Unmarshal(as[4].After(), &after) != nil || before.Starred || !after.Starred {
+		t.Fatalf("Expected organizing a note to record it before and after.")
+	}
+	before, after = note.RecordModel{}, note.RecordModel{}
+	if json.Unmarshal(as[5].Before(), &before) != nil || json.Unmarshal(as[5].After(), &after) != nil || before.ReadAt != nil || after.ReadAt == nil {
+		t.Fatalf("Expected reading a note to record it before and after.")
+	}
+	before, after = note.RecordModel{}, note.RecordModel{}
+	if json.Unmarshal(as[6].Before(), &before) != nil || json.Unmarshal(as[6].After(), &after) != nil || before.SenderHiddenAt != nil || after.SenderHiddenAt == nil {
+		t.Fatalf("Expected hiding a sent note to record it before and after.")
+	}
+
+	cs, err := np.AuditByCharacterProvider(recipientId)()
+	if err != nil || len(cs) != len(expected) {
+		t.Fatalf("Expected the character's trail to hold [%d] entries, got [%d] (%v).", len(expected), len(cs), err)
+	}
+	if cs, _ = np.AuditByCharacterProvider(senderId)(); len(cs) != 0 {
+		t.Fatalf("Expected the sender's trail to be empty.")
+	}
+}
+
+func TestProcessorImpl_ActingCharacter(t *testing.T) {
+	l := testLogger()
+	ctx := tenant.WithContext(context.Background(), testTenant())
+	db := testDatabase(t)
+	np := note.NewProcessor(l, ctx, db)
+
+	own, err := np.Create(message.NewBuffer())(1)(2)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	sent, err := np.Create(message.NewBuffer())(3)(1)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	other, err := np.Create(message.NewBuffer())(3)(0)("Welcome!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+
+	ap := note.NewProcessor(l, auth.WithActingCharacter(ctx, 1), db)
+	if _, err = ap.ByIdProvider(own.Id())(); err != nil {
+		t.Fatalf("Expected a character to read a note it received, got %v", err)
+	}
+	if _, err = ap.ByIdProvider(sent.Id())(); err != nil {
+		t.Fatalf("Expected a character to read a note it sent, got %v", err)
+	}
+	if _, err = ap.ByIdProvider(other.Id())(); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected another character's note not to be found, got %v", err)
+	}
+	if _, err = ap.ByCharacterAndFilterProvider(3, note.Filter{})(); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected another character's inbox not to be found, got %v", err)
+	}
+	if _, err = ap.InTenantProvider()(); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected the tenant's notes not to be found, got %v", err)
+	}
+	if _, err = ap.HistoryProvider(other.Id())(); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected another character's note history not to be found, got %v", err)
+	}
+
+	// Discarding another's note is refused as though it did not exist, rather than silently skipped
+	if err = ap.Discard(message.NewBuffer())(1)([]uint32{other.Id()})(false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected another character's note not to be found, got %v", err)
+	}
+	if err = ap.Discard(message.NewBuffer())(3)([]uint32{other.Id()})(false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected another character's inbox not to be found, got %v", err)
+	}
+	if err = ap.Delete(message.NewBuffer())(other.Id()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected another character's note not to be found, got %v", err)
+	}
+	if _, err = ap.Star(message.NewBuffer())(1)(sent.Id())(true); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected a sent note not to be organized by its sender, got %v", err)
+	}
+	if _, err = np.ByIdProvider(other.Id())(); err != nil {
+		t.Fatalf("Expected another character's note to be untouched, got %v", err)
+	}
+	if err = ap.Discard(message.NewBuffer())(1)([]uint32{own.Id()})(false); err != nil {
+		t.Fatalf("Expected a character to discard its own note, got %v", err)
+	}
+
+	// A malformed identity acts for no character, not even the sender of notes from no one
+	mctx := auth.ActingCharacterHeaderParser(ctx, map[string]string{auth.HeaderActingCharacterId: "zero"})
+	if _, err = note.NewProcessor(l, mctx, db).ByIdProvider(other.Id())(); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected a malformed identity to read nothing, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package note
 
 import (
 	"atlas-notes/kafka/message/note"
+	"atlas-notes/summary"
 	"github.com/Chronicle20/atlas-kafka/producer"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/segmentio/kafka-go"
@@ -57,3 +58,103 @@ func DeleteNoteStatusEventProvider(characterId uint32, noteId uint32) model.Prov
 	}
 	return producer.SingleMessageProvider(key, value)
 }
+
+// RestoreNoteStatusEventProvider creates a status event for restoring a deleted note
+func RestoreNoteStatusEventProvider(characterId uint32, noteId uint32) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	body := note.StatusEventRestoredBody{
+		NoteId: noteId,
+	}
+	value := note.StatusEvent[note.StatusEventRestoredBody]{
+		CharacterId: characterId,
+		Type:        note.StatusEventTypeRestored,
+		Body:        body,
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+// ReadNoteStatusEventProvider creates a status event for a note read by its recipient
+func ReadNoteStatusEventProvider(characterId uint32, noteId uint32, readAt time.Time) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	body := note.StatusEventReadBody{
+		NoteId: noteId,
+		ReadAt: readAt,
+	}
+	value := note.StatusEvent[note.StatusEventReadBody]{
+		CharacterId: characterId,
+		Type:        note.StatusEventTypeRead,
+		Body:        body,
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+// ClaimNoteStatusEventProvider creates a status event for claiming note attachments
+func ClaimNoteStatusEventProvider(characterId uint32, noteId uint32, attachmentIds []uint32) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	body := note.StatusEventClaimedBody{
+		NoteId:        noteId,
+		AttachmentIds: attachmentIds,
+	}
+	value := note.StatusEvent[note.StatusEventClaimedBody]{
+		CharacterId: characterId,
+		Type:        note.StatusEventTypeClaimed,
+		Body:        body,
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+// OrganizeNoteStatusEventProvider creates a status event for a change in how the recipient keeps a note
+func OrganizeNoteStatusEventProvider(characterId uint32, noteId uint32, starred bool, pinned bool, archived bool, labels []string) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	body := note.StatusEventOrganizedBody{
+		NoteId:   noteId,
+		Starred:  starred,
+		Pinned:   pinned,
+		Archived: archived,
+		Labels:   labels,
+	}
+	value := note.StatusEvent[note.StatusEventOrganizedBody]{
+		CharacterId: characterId,
+		Type:        note.StatusEventTypeOrganized,
+		Body:        body,
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+// SummaryNoteStatusEventProvider creates a status event for a change in how many notes a character holds, or how many
+// of them are unread
+func SummaryNoteStatusEventProvider(s summary.Model) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(s.CharacterId()))
+	kinds := make([]note.StatusEventSummaryKindBody, 0, len(s.Kinds()))
+	for _, c := range s.Kinds() {
+		kinds = append(kinds, note.StatusEventSummaryKindBody{Flag: c.Flag(), Total: c.Total(), Unread: c.Unread()})
+	}
+	body := note.StatusEventSummaryBody{
+		Total:  s.Total(),
+		Unread: s.Unread(),
+		Kinds:  kinds,
+	}
+	value := note.StatusEvent[note.StatusEventSummaryBody]{
+		CharacterId: s.CharacterId(),
+		Type:        note.StatusEventTypeSummary,
+		Body:        body,
+	}
+	return producer.SingleMessageProvider(key, value)
+}
+
+// PendingNoteStatusEventProvider creates a status event telling a character which logged in how many unread notes it
+// holds, and who most recently sent them
+func PendingNoteStatusEventProvider(worldId byte, channelId byte, characterId uint32, count int, senderIds []uint32) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(int(characterId))
+	value := note.StatusEvent[note.StatusEventPendingBody]{
+		CharacterId: characterId,
+		Type:        note.StatusEventTypePending,
+		Body: note.StatusEventPendingBody{
+			WorldId:   worldId,
+			ChannelId: channelId,
+			Count:     count,
+			SenderIds: senderIds,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
@@ -2,9 +2,13 @@ package note
 
 import (
 	"atlas-notes/database"
+	"atlas-notes/label"
+	"atlas-notes/summary"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 // getByIdProvider returns a provider for a note by its ID
@@ -12,7 +16,21 @@ func getByIdProvider(tenantId uuid.UUID) func(id uint32) database.EntityProvider
 	return func(id uint32) database.EntityProvider[Entity] {
 		return func(db *gorm.DB) model.Provider[Entity] {
 			var entity Entity
-			err := db.Where("tenant_id = ? AND id = ?", tenantId, id).First(&entity).Error
+			err := db.Preload(clause.Associations).Where("tenant_id = ? AND id = ?", tenantId, id).First(&entity).Error
+			if err != nil {
+				return model.ErrorProvider[Entity](err)
+			}
+			return model.FixedProvider(entity)
+		}
+	}
+}
+
+// getByIdIncludingDeletedProvider returns a provider for a note by its ID, even if it has since been deleted
+func getByIdIncludingDeletedProvider(tenantId uuid.UUID) func(id uint32) database.EntityProvider[Entity] {
+	return func(id uint32) database.EntityProvider[Entity] {
+		return func(db *gorm.DB) model.Provider[Entity] {
+			var entity Entity
+			err := db.Unscoped().Preload(clause.Associations).Where("tenant_id = ? AND id = ?", tenantId, id).First(&entity).Error
 			if err != nil {
 				return model.ErrorProvider[Entity](err)
 			}
@@ -26,7 +44,168 @@ func getByCharacterIdProvider(tenantId uuid.UUID) func(characterId uint32) datab
 	return func(characterId uint32) database.EntityProvider[[]Entity] {
 		return func(db *gorm.DB) model.Provider[[]Entity] {
 			var entities []Entity
-			err := db.Where("tenant_id = ? AND character_id = ?", tenantId, characterId).Find(&entities).Error
+			err := db.Preload(clause.Associations).Where("tenant_id = ? AND character_id = ?", tenantId, characterId).Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
+
+// getByCharacterIdAndFilterProvider returns a provider for the notes belonging to a character which match the filter,
+// pinned notes first. Deleted notes are listed most recently deleted first.
+func getByCharacterIdAndFilterProvider(tenantId uuid.UUID) func(characterId uint32) func(f Filter) database.EntityProvider[[]Entity] {
+	return func(characterId uint32) func(f Filter) database.EntityProvider[[]Entity] {
+		return func(f Filter) database.EntityProvider[[]Entity] {
+			return func(db *gorm.DB) model.Provider[[]Entity] {
+				var entities []Entity
+				q := db.Preload(clause.Associations).Where("tenant_id = ? AND character_id = ?", tenantId, characterId)
+				order := "pinned DESC, timestamp ASC"
+				if f.Deleted {
+					q = q.Unscoped().Where("deleted_at IS NOT NULL")
+					order = "deleted_at DESC"
+				} else {
+					q = q.Where("archived = ?", f.Archived)
+				}
+				if f.Starred != nil {
+					q = q.Where("starred = ?", *f.Starred)
+				}
+				if f.Pinned != nil {
+					q = q.Where("pinned = ?", *f.Pinned)
+				}
+				if f.Label != "" {
+					q = q.Where("id IN (?)", db.Model(&label.Entity{}).Select("note_id").Where("tenant_id = ? AND name = ?", tenantId, f.Label))
+				}
+				err := q.Order(order).Find(&entities).Error
+				if err != nil {
+					return model.ErrorProvider[[]Entity](err)
+				}
+				return model.FixedProvider(entities)
+			}
+		}
+	}
+}
+
+// getInboxCountProvider returns a provider for the number of notes held by a character which count towards its
+// inbox capacity. Archived notes do not count.
+func getInboxCountProvider(tenantId uuid.UUID) func(characterId uint32) database.EntityProvider[int64] {
+	return func(characterId uint32) database.EntityProvider[int64] {
+		return func(db *gorm.DB) model.Provider[int64] {
+			var count int64
+			err := db.Model(&Entity{}).Where("tenant_id = ? AND character_id = ? AND archived = ?", tenantId, characterId, false).Count(&count).Error
+			if err != nil {
+				return model.ErrorProvider[int64](err)
+			}
+			return model.FixedProvider(count)
+		}
+	}
+}
+
+// getSentCountProvider returns a provider for the number of notes a character has sent since the given time. Deleted
+// and hidden notes count, so discarding notes does not lift the send rate limit.
+func getSentCountProvider(tenantId uuid.UUID) func(senderId uint32) func(since time.Time) database.EntityProvider[int64] {
+	return func(senderId uint32) func(since time.Time) database.EntityProvider[int64] {
+		return func(since time.Time) database.EntityProvider[int64] {
+			return func(db *gorm.DB) model.Provider[int64] {
+				var count int64
+				err := db.Unscoped().Model(&Entity{}).Where("tenant_id = ? AND sender_id = ? AND timestamp >= ?", tenantId, senderId, since).Count(&count).Error
+				if err != nil {
+					return model.ErrorProvider[int64](err)
+				}
+				return model.FixedProvider(count)
+			}
+		}
+	}
+}
+
+// getUnreadSendersProvider returns a provider for up to limit distinct characters who sent the unread notes a character
+// holds, most recent sender first
+func getUnreadSendersProvider(tenantId uuid.UUID) func(characterId uint32) func(limit int) database.EntityProvider[[]uint32] {
+	return func(characterId uint32) func(limit int) database.EntityProvider[[]uint32] {
+		return func(limit int) database.EntityProvider[[]uint32] {
+			return func(db *gorm.DB) model.Provider[[]uint32] {
+				var senderIds []uint32
+				err := db.Model(&Entity{}).
+					Where("tenant_id = ? AND character_id = ? AND read_at IS NULL", tenantId, characterId).
+					Group("sender_id").
+					Order("MAX(timestamp) DESC").
+					Limit(limit).
+					Pluck("sender_id", &senderIds).Error
+				if err != nil {
+					return model.ErrorProvider[[]uint32](err)
+				}
+				return model.FixedProvider(senderIds)
+			}
+		}
+	}
+}
+
+// getCountProvider returns a provider for the number of notes in a tenant, deleted notes aside
+func getCountProvider(tenantId uuid.UUID) database.EntityProvider[int64] {
+	return func(db *gorm.DB) model.Provider[int64] {
+		var count int64
+		err := db.Model(&Entity{}).Where("tenant_id = ?", tenantId).Count(&count).Error
+		if err != nil {
+			return model.ErrorProvider[int64](err)
+		}
+		return model.FixedProvider(count)
+	}
+}
+
+// getTallyProvider returns a provider for how many notes of each kind every character of a tenant holds, and how many
+// of them are unread, counted from the notes themselves
+func getTallyProvider(tenantId uuid.UUID) database.EntityProvider[[]summary.Entity] {
+	return func(db *gorm.DB) model.Provider[[]summary.Entity] {
+		var entities []summary.Entity
+		err := db.Model(&Entity{}).
+			Select("character_id, COALESCE(flag, 0) AS flag, COUNT(*) AS total, COUNT(CASE WHEN read_at IS NULL THEN 1 END) AS unread").
+			Where("tenant_id = ?", tenantId).
+			Group("character_id, COALESCE(flag, 0)").
+			Scan(&entities).Error
+		if err != nil {
+			return model.ErrorProvider[[]summary.Entity](err)
+		}
+		for i := range entities {
+			entities[i].TenantID = tenantId
+		}
+		return model.FixedProvider(entities)
+	}
+}
+
+// getCountIncludingDeletedProvider returns a provider for the number of notes in a tenant, deleted notes included
+func getCountIncludingDeletedProvider(tenantId uuid.UUID) database.EntityProvider[int64] {
+	return func(db *gorm.DB) model.Provider[int64] {
+		var count int64
+		err := db.Unscoped().Model(&Entity{}).Where("tenant_id = ?", tenantId).Count(&count).Error
+		if err != nil {
+			return model.ErrorProvider[int64](err)
+		}
+		return model.FixedProvider(count)
+	}
+}
+
+// getBySenderIdProvider returns a provider for all notes a character has sent and not hidden. Notes remain visible to
+// the sender after the recipient deletes them.
+func getBySenderIdProvider(tenantId uuid.UUID) func(senderId uint32) database.EntityProvider[[]Entity] {
+	return func(senderId uint32) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Unscoped().Preload(clause.Associations).Where("tenant_id = ? AND sender_id = ? AND sender_hidden_at IS NULL", tenantId, senderId).Order("timestamp DESC").Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
+
+// getByParticipantProvider returns a provider for all notes a character has received and kept, or sent and not hidden
+func getByParticipantProvider(tenantId uuid.UUID) func(characterId uint32) database.EntityProvider[[]Entity] {
+	return func(characterId uint32) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Unscoped().Preload(clause.Associations).Where("tenant_id = ? AND ((character_id = ? AND deleted_at IS NULL) OR (sender_id = ? AND sender_hidden_at IS NULL))", tenantId, characterId, characterId).Find(&entities).Error
 			if err != nil {
 				return model.ErrorProvider[[]Entity](err)
 			}
@@ -39,10 +218,60 @@ func getByCharacterIdProvider(tenantId uuid.UUID) func(characterId uint32) datab
 func getAllProvider(tenantId uuid.UUID) database.EntityProvider[[]Entity] {
 	return func(db *gorm.DB) model.Provider[[]Entity] {
 		var entities []Entity
-		err := db.Where("tenant_id = ?", tenantId).Find(&entities).Error
+		err := db.Preload(clause.Associations).Where("tenant_id = ?", tenantId).Find(&entities).Error
 		if err != nil {
 			return model.ErrorProvider[[]Entity](err)
 		}
 		return model.FixedProvider(entities)
 	}
 }
+
+// getExpiredProvider returns a provider for all notes in a tenant which expired at or before the given time
+func getExpiredProvider(tenantId uuid.UUID) func(asOf time.Time) database.EntityProvider[[]Entity] {
+	return func(asOf time.Time) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Preload(clause.Associations).Where("tenant_id = ? AND expiration IS NOT NULL AND expiration <= ?", tenantId, asOf).Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
+
+// getAllIncludingDeletedProvider returns a provider for up to limit notes in a tenant with IDs above afterId, in ID
+// order, deleted notes included
+func getAllIncludingDeletedProvider(tenantId uuid.UUID) func(afterId uint32) func(limit int) database.EntityProvider[[]Entity] {
+	return func(afterId uint32) func(limit int) database.EntityProvider[[]Entity] {
+		return func(limit int) database.EntityProvider[[]Entity] {
+			return func(db *gorm.DB) model.Provider[[]Entity] {
+				var entities []Entity
+				err := db.Unscoped().Preload(clause.Associations).Where("tenant_id = ? AND id > ?", tenantId, afterId).Order("id").Limit(limit).Find(&entities).Error
+				if err != nil {
+					return model.ErrorProvider[[]Entity](err)
+				}
+				return model.FixedProvider(entities)
+			}
+		}
+	}
+}
+
+// getBySentAtProvider returns a provider for the note a sender addressed to a character at the given time, even if it
+// has since been deleted
+func getBySentAtProvider(tenantId uuid.UUID) func(characterId uint32) func(senderId uint32) func(timestamp time.Time) database.EntityProvider[Entity] {
+	return func(characterId uint32) func(senderId uint32) func(timestamp time.Time) database.EntityProvider[Entity] {
+		return func(senderId uint32) func(timestamp time.Time) database.EntityProvider[Entity] {
+			return func(timestamp time.Time) database.EntityProvider[Entity] {
+				return func(db *gorm.DB) model.Provider[Entity] {
+					var entity Entity
+					err := db.Unscoped().Preload(clause.Associations).Where("tenant_id = ? AND character_id = ? AND sender_id = ? AND timestamp = ?", tenantId, characterId, senderId, timestamp).Order("id").First(&entity).Error
+					if err != nil {
+						return model.ErrorProvider[Entity](err)
+					}
+					return model.FixedProvider(entity)
+				}
+			}
+		}
+	}
+}
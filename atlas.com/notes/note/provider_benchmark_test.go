@@ -0,0 +1,303 @@
+package note
+
+import (
+	"atlas-notes/database"
+	"atlas-notes/label"
+	"atlas-notes/migrations"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The benchmark dataset is seeded into sqlite, or into the Postgres database named by EnvBenchmarkDSN. Postgres is
+// seeded under a fresh tenant each run, so existing data is left alone.
+const (
+	EnvBenchmarkDSN = "NOTE_BENCHMARK_DSN"
+
+	benchmarkTenants           = 4
+	benchmarkCharacters        = 250
+	benchmarkNotesPerCharacter = 100
+
+	// budgetIterations is how many times each query is run when checking its latency budget
+	budgetIterations = 50
+)
+
+// benchmarkBudgets are the latency budgets per query, each including the preloading of associations
+var benchmarkBudgets = map[string]time.Duration{
+	"inbox":   10 * time.Millisecond,
+	"starred": 10 * time.Millisecond,
+	"label":   10 * time.Millisecond,
+	"deleted": 10 * time.Millisecond,
+	"count":   5 * time.Millisecond,
+	"sent":    10 * time.Millisecond,
+	"expired": 100 * time.Millisecond,
+	"by_id":   5 * time.Millisecond,
+}
+
+// benchmarkIndexes are the indexes sqlite is expected to plan each query with
+var benchmarkIndexes = map[string]string{
+	"inbox":   "idx_notes_tenant_character_timestamp",
+	"starred": "idx_notes_tenant_character_timestamp",
+	"deleted": "idx_notes_tenant_character_deleted_at",
+	"count":   "idx_notes_tenant_character_timestamp",
+	"sent":    "idx_notes_tenant_sender_timestamp",
+	"expired": "idx_notes_tenant_expiration",
+}
+
+type benchmarkDataset struct {
+	db       *gorm.DB
+	tenantId uuid.UUID
+	noteId   uint32
+}
+
+var (
+	benchmarkOnce sync.Once
+	benchmarkData benchmarkDataset
+	benchmarkErr  error
+)
+
+// benchmarkDatabase returns the seeded dataset, seeding it on first use
+func benchmarkDatabase(tb testing.TB) benchmarkDataset {
+	tb.Helper()
+	benchmarkOnce.Do(func() {
+		benchmarkData, benchmarkErr = seedBenchmarkDatabase()
+	})
+	if benchmarkErr != nil {
+		tb.Fatalf("Failed to seed benchmark database: %v", benchmarkErr)
+	}
+	return benchmarkData
+}
+
+func seedBenchmarkDatabase() (benchmarkDataset, error) {
+	dialector := sqlite.Open("file:notes_benchmark?mode=memory&cache=shared")
+	if dsn, ok := os.LookupEnv(EnvBenchmarkDSN); ok {
+		dialector = postgres.Open(dsn)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return benchmarkDataset{}, err
+	}
+	l, _ := test.NewNullLogger()
+	err = database.Migrate(l, db, migrations.FS, database.LatestVersion)
+	if err != nil {
+		return benchmarkDataset{}, err
+	}
+
+	now := time.Now()
+	result := benchmarkDataset{db: db}
+	for t := 0; t < benchmarkTenants; t++ {
+		tenantId := uuid.New()
+		if t == 0 {
+			result.tenantId = tenantId
+		}
+		for c := 1; c <= benchmarkCharacters; c++ {
+			es := make([]Entity, 0, benchmarkNotesPerCharacter)
+			for n := 0; n < benchmarkNotesPerCharacter; n++ {
+				e := Entity{
+					TenantID:    tenantId,
+					CharacterID: uint32(c),
+					SenderID:    uint32((c+n)%benchmarkCharacters + 1),
+					Message:     fmt.Sprintf("Note %d for character %d.", n, c),
+					Timestamp:   now.Add(-time.Duration(n) * time.Minute),
+					Starred:     n%10 == 0,
+					Pinned:      n%25 == 0,
+					Archived:    n%20 == 0,
+				}
+				if n%7 == 0 {
+					e.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+				}
+				if n%5 == 0 {
+					e.SenderHiddenAt = &now
+				}
+				if n%9 == 0 {
+					expiration := now.Add(time.Duration(n-50) * time.Hour)
+					e.Expiration = &expiration
+				}
+				es = append(es, e)
+			}
+			err = db.Omit("Attachments", "Labels").CreateInBatches(&es, benchmarkNotesPerCharacter).Error
+			if err != nil {
+				return benchmarkDataset{}, err
+			}
+
+			ls := make([]label.Entity, 0)
+			for n, e := range es {
+				if n%10 == 3 {
+					ls = append(ls, label.MakeEntities(tenantId, e.ID, []string{"guild", "trade"})...)
+				}
+			}
+			err = db.CreateInBatches(&ls, 500).Error
+			if err != nil {
+				return benchmarkDataset{}, err
+			}
+			if result.noteId == 0 {
+				result.noteId = es[1].ID
+			}
+		}
+	}
+	err = db.Exec("ANALYZE").Error
+	if err != nil {
+		return benchmarkDataset{}, err
+	}
+	return result, nil
+}
+
+// benchmarkQueries are the provider queries under test, run for a character in the first seeded tenant
+func benchmarkQueries(d benchmarkDataset) map[string]func(db *gorm.DB) error {
+	starred := true
+	characterId := uint32(benchmarkCharacters / 2)
+	return map[string]func(db *gorm.DB) error{
+		"inbox": func(db *gorm.DB) error {
+			_, err := getByCharacterIdAndFilterProvider(d.tenantId)(characterId)(Filter{})(db)()
+			return err
+		},
+		"starred": func(db *gorm.DB) error {
+			_, err := getByCharacterIdAndFilterProvider(d.tenantId)(characterId)(Filter{Starred: &starred})(db)()
+			return err
+		},
+		"label": func(db *gorm.DB) error {
+			_, err := getByCharacterIdAndFilterProvider(d.tenantId)(characterId)(Filter{Label: "guild"})(db)()
+			return err
+		},
+		"deleted": func(db *gorm.DB) error {
+			_, err := getByCharacterIdAndFilterProvider(d.tenantId)(characterId)(Filter{Deleted: true})(db)()
+			return err
+		},
+		"count": func(db *gorm.DB) error {
+			_, err := getInboxCountProvider(d.tenantId)(characterId)(db)()
+			return err
+		},
+		"sent": func(db *gorm.DB) error {
+			_, err := getBySenderIdProvider(d.tenantId)(characterId)(db)()
+			return err
+		},
+		"expired": func(db *gorm.DB) error {
+			_, err := getExpiredProvider(d.tenantId)(time.Now())(db)()
+			return err
+		},
+		"by_id": func(db *gorm.DB) error {
+			_, err := getByIdProvider(d.tenantId)(d.noteId)(db)()
+			return err
+		},
+	}
+}
+
+func BenchmarkProviders(b *testing.B) {
+	d := benchmarkDatabase(b)
+	for name, q := range benchmarkQueries(d) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := q(d.db); err != nil {
+					b.Fatalf("Query failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestProviderQueryPlans asserts each provider query is planned by sqlite with the index meant for it, against the
+// seeded dataset.
+func TestProviderQueryPlans(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Seeding the benchmark dataset is slow.")
+	}
+	if _, ok := os.LookupEnv(EnvBenchmarkDSN); ok {
+		t.Skip("Query plans are checked on sqlite.")
+	}
+	d := benchmarkDatabase(t)
+	for name, q := range benchmarkQueries(d) {
+		index, ok := benchmarkIndexes[name]
+		if !ok {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			plan := queryPlan(t, d.db, q)
+			if !strings.Contains(plan, index) {
+				t.Fatalf("Expected query to use %s, planned as: %s", index, plan)
+			}
+		})
+	}
+}
+
+// TestProviderLatencyBudgets asserts each provider query stays within its latency budget against the seeded dataset.
+// Timings depend on the machine running them, so the budgets are only checked against the database named by
+// EnvBenchmarkDSN.
+func TestProviderLatencyBudgets(t *testing.T) {
+	if _, ok := os.LookupEnv(EnvBenchmarkDSN); !ok {
+		t.Skipf("Latency budgets are checked when %s is set.", EnvBenchmarkDSN)
+	}
+	d := benchmarkDatabase(t)
+	for name, q := range benchmarkQueries(d) {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			for i := 0; i < budgetIterations; i++ {
+				if err := q(d.db); err != nil {
+					t.Fatalf("Query failed: %v", err)
+				}
+			}
+			if latency := time.Since(start) / budgetIterations; latency > benchmarkBudgets[name] {
+				t.Fatalf("Query took %s, over its budget of %s", latency, benchmarkBudgets[name])
+			}
+		})
+	}
+}
+
+// queryPlan returns sqlite's plan for the main statement a query runs. Statements preloading associations are traced
+// before the statement they belong to completes, so the main statement is the last one recorded.
+func queryPlan(t *testing.T, db *gorm.DB, q func(db *gorm.DB) error) string {
+	r := &statementRecorder{}
+	err := q(db.Session(&gorm.Session{Logger: r}))
+	if err != nil || len(r.statements) == 0 {
+		t.Fatalf("Failed to record query: %v", err)
+	}
+
+	rows, err := db.Raw("EXPLAIN QUERY PLAN " + r.statements[len(r.statements)-1]).Rows()
+	if err != nil {
+		t.Fatalf("Failed to explain query: %v", err)
+	}
+	defer rows.Close()
+
+	var details []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err = rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("Failed to read query plan: %v", err)
+		}
+		details = append(details, detail)
+	}
+	return strings.Join(details, "; ")
+}
+
+// statementRecorder is a gorm logger which records the SQL of every statement run
+type statementRecorder struct {
+	statements []string
+}
+
+func (r *statementRecorder) LogMode(logger.LogLevel) logger.Interface {
+	return r
+}
+
+func (r *statementRecorder) Info(context.Context, string, ...interface{}) {
+}
+
+func (r *statementRecorder) Warn(context.Context, string, ...interface{}) {
+}
+
+func (r *statementRecorder) Error(context.Context, string, ...interface{}) {
+}
+
+func (r *statementRecorder) Trace(_ context.Context, _ time.Time, fc func() (sql string, rowsAffected int64), _ error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
@@ -0,0 +1,150 @@
+package note
+
+import (
+	"atlas-notes/attachment"
+	"errors"
+	"fmt"
+	"github.com/Chronicle20/atlas-model/model"
+	"time"
+)
+
+var ErrInvalidRecord = errors.New("invalid note record")
+
+// RecordModel is a note as exported to, and imported from, a newline-delimited JSON stream. Unlike RestModel it
+// carries the whole state of the note, including whether its sender hid it and its recipient deleted it, so a tenant's
+// notes can be moved between clusters.
+type RecordModel struct {
+	Id             uint32                 `json:"id"`
+	CharacterId    uint32                 `json:"characterId"`
+	SenderId       uint32                 `json:"senderId"`
+	Message        string                 `json:"message"`
+	Flag           byte                   `json:"flag"`
+	Timestamp      time.Time              `json:"timestamp"`
+	Expiration     *time.Time             `json:"expiration,omitempty"`
+	Attachments    []attachment.RestModel `json:"attachments,omitempty"`
+	InReplyTo      uint32                 `json:"inReplyTo,omitempty"`
+	ThreadId       uint32                 `json:"threadId"`
+	Starred        bool                   `json:"starred"`
+	Pinned         bool                   `json:"pinned"`
+	Archived       bool                   `json:"archived"`
+	Labels         []string               `json:"labels,omitempty"`
+	SenderHiddenAt *time.Time             `json:"senderHiddenAt,omitempty"`
+	ReadAt         *time.Time             `json:"readAt,omitempty"`
+	DeletedAt      *time.Time             `json:"deletedAt,omitempty"`
+}
+
+// TransformRecord converts a Model domain model to a RecordModel
+func TransformRecord(n Model) (RecordModel, error) {
+	as, err := model.SliceMap(attachment.Transform)(model.FixedProvider(n.Attachments()))()()
+	if err != nil {
+		return RecordModel{}, err
+	}
+	rm := RecordModel{
+		Id:          n.Id(),
+		CharacterId: n.CharacterId(),
+		SenderId:    n.SenderId(),
+		Message:     n.Message(),
+		Flag:        n.Flag(),
+		Timestamp:   n.Timestamp(),
+		Attachments: as,
+		InReplyTo:   n.InReplyTo(),
+		ThreadId:    n.ThreadId(),
+		Starred:     n.Starred(),
+		Pinned:      n.Pinned(),
+		Archived:    n.Archived(),
+		Labels:      n.Labels(),
+	}
+	if !n.Expiration().IsZero() {
+		expiration := n.Expiration()
+		rm.Expiration = &expiration
+	}
+	if !n.SenderHiddenAt().IsZero() {
+		senderHiddenAt := n.SenderHiddenAt()
+		rm.SenderHiddenAt = &senderHiddenAt
+	}
+	if n.Read() {
+		readAt := n.ReadAt()
+		rm.ReadAt = &readAt
+	}
+	if n.Deleted() {
+		deletedAt := n.DeletedAt()
+		rm.DeletedAt = &deletedAt
+	}
+	return rm, nil
+}
+
+// ExtractRecord validates a RecordModel and converts it to a Model, keeping the IDs it was exported with. Times are
+// normalized to UTC at microsecond precision, as databases store them, so a note imported twice is recognized.
+func ExtractRecord(r RecordModel) (Model, error) {
+	if r.Id == 0 {
+		return Model{}, fmt.Errorf("%w: id is required", ErrInvalidRecord)
+	}
+	if r.CharacterId == 0 {
+		return Model{}, fmt.Errorf("%w: characterId is required", ErrInvalidRecord)
+	}
+	if r.Timestamp.IsZero() {
+		return Model{}, fmt.Errorf("%w: timestamp is required", ErrInvalidRecord)
+	}
+	if r.InReplyTo >= r.Id {
+		return Model{}, fmt.Errorf("%w: inReplyTo must precede the note", ErrInvalidRecord)
+	}
+	if r.ThreadId > r.Id || (r.InReplyTo != 0 && r.ThreadId == r.Id) {
+		return Model{}, fmt.Errorf("%w: threadId must precede the note", ErrInvalidRecord)
+	}
+
+	as := make([]attachment.Model, 0, len(r.Attachments))
+	for i, ra := range r.Attachments {
+		switch ra.Status {
+		case attachment.StatusUnclaimed, attachment.StatusClaimed, attachment.StatusReturned:
+		default:
+			return Model{}, fmt.Errorf("%w: attachment %d has unknown status [%s]", ErrInvalidRecord, i, ra.Status)
+		}
+		a, err := attachment.Extract(ra)
+		if err != nil {
+			return Model{}, err
+		}
+		if !a.Valid() {
+			return Model{}, fmt.Errorf("%w: attachment %d carries no items or mesos", ErrInvalidRecord, i)
+		}
+		as = append(as, attachment.NewBuilder().
+			SetType(a.Type()).
+			SetItemId(a.ItemId()).
+			SetQuantity(a.Quantity()).
+			SetMesos(a.Mesos()).
+			SetStatus(ra.Status).
+			Build())
+	}
+
+	b := NewBuilder().
+		SetId(r.Id).
+		SetCharacterId(r.CharacterId).
+		SetSenderId(r.SenderId).
+		SetMessage(r.Message).
+		SetFlag(r.Flag).
+		SetTimestamp(normalizeTime(r.Timestamp)).
+		SetAttachments(as).
+		SetInReplyTo(r.InReplyTo).
+		SetThreadId(r.ThreadId).
+		SetStarred(r.Starred).
+		SetPinned(r.Pinned).
+		SetArchived(r.Archived).
+		SetLabels(r.Labels)
+	if r.Expiration != nil {
+		b.SetExpiration(normalizeTime(*r.Expiration))
+	}
+	if r.SenderHiddenAt != nil {
+		b.SetSenderHiddenAt(normalizeTime(*r.SenderHiddenAt))
+	}
+	if r.ReadAt != nil {
+		b.SetReadAt(normalizeTime(*r.ReadAt))
+	}
+	if r.DeletedAt != nil {
+		b.SetDeletedAt(normalizeTime(*r.DeletedAt))
+	}
+	return b.Build(), nil
+}
+
+// normalizeTime returns the time in UTC, at the microsecond precision databases store
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,149 @@
+package note
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// tenantRegistry tracks the tenants this instance has served, so background tasks can act on their behalf. Tenants are
+// recorded in the database too, where there is one, so tasks reach them after a restart or from another instance.
+type tenantRegistry struct {
+	mutex   sync.RWMutex
+	tenants map[uuid.UUID]tenant.Model
+}
+
+var tr *tenantRegistry
+var trOnce sync.Once
+
+func getTenantRegistry() *tenantRegistry {
+	trOnce.Do(func() {
+		tr = &tenantRegistry{
+			tenants: make(map[uuid.UUID]tenant.Model),
+		}
+	})
+	return tr
+}
+
+// Add tracks a tenant served, recording it in the database the first time it is served by this instance. A tenant
+// which could not be recorded is tried again the next time it is served.
+func (r *tenantRegistry) Add(l logrus.FieldLogger, db *gorm.DB, t tenant.Model) {
+	r.mutex.RLock()
+	_, ok := r.tenants[t.Id()]
+	r.mutex.RUnlock()
+	if ok {
+		return
+	}
+
+	if db != nil {
+		if err := recordTenant(db, t); err != nil {
+			l.WithError(err).Warnf("Unable to record tenant [%s] for background tasks.", t.Id())
+			return
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tenants[t.Id()] = t
+}
+
+func (r *tenantRegistry) Tenants() []tenant.Model {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	results := make([]tenant.Model, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		results = append(results, t)
+	}
+	return results
+}
+
+// tenantEntity records a tenant served, with the region and version background tasks act on its behalf with
+type tenantEntity struct {
+	TenantID     uuid.UUID `gorm:"primaryKey"`
+	Region       string
+	MajorVersion uint16
+	MinorVersion uint16
+	UpdatedAt    time.Time
+}
+
+// TableName specifies the database table name for tenantEntity
+func (tenantEntity) TableName() string {
+	return "note_tenants"
+}
+
+func makeTenant(e tenantEntity) (tenant.Model, error) {
+	return tenant.Create(e.TenantID, e.Region, e.MajorVersion, e.MinorVersion)
+}
+
+// recordTenant records a tenant served, updating its region and version should they have changed
+func recordTenant(db *gorm.DB, t tenant.Model) error {
+	e := tenantEntity{TenantID: t.Id(), Region: t.Region(), MajorVersion: t.MajorVersion(), MinorVersion: t.MinorVersion(), UpdatedAt: time.Now()}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"region", "major_version", "minor_version", "updated_at"}),
+	}).Create(&e).Error
+}
+
+// getTenantsProvider returns a provider for every tenant recorded
+func getTenantsProvider(db *gorm.DB) model.Provider[[]tenantEntity] {
+	var entities []tenantEntity
+	err := db.Order("tenant_id").Find(&entities).Error
+	if err != nil {
+		return model.ErrorProvider[[]tenantEntity](err)
+	}
+	return model.FixedProvider(entities)
+}
+
+// getExpiredTenantIdsProvider returns a provider for the IDs of the tenants holding notes which have expired
+func getExpiredTenantIdsProvider(asOf time.Time) database.EntityProvider[[]uuid.UUID] {
+	return func(db *gorm.DB) model.Provider[[]uuid.UUID] {
+		var ids []uuid.UUID
+		err := db.Model(&Entity{}).Distinct("tenant_id").Where("expiration IS NOT NULL AND expiration <= ?", asOf).Pluck("tenant_id", &ids).Error
+		if err != nil {
+			return model.ErrorProvider[[]uuid.UUID](err)
+		}
+		return model.FixedProvider(ids)
+	}
+}
+
+// servedTenants returns the tenants background tasks act on behalf of: every tenant recorded in the database, or
+// without one, the tenants this instance has served, as only they hold notes in its memory
+func servedTenants(db *gorm.DB) ([]tenant.Model, error) {
+	if db == nil {
+		return getTenantRegistry().Tenants(), nil
+	}
+	return model.SliceMap(makeTenant)(getTenantsProvider(database.Reader(db)))()()
+}
+
+// expiredTenants returns the tenants holding notes which have expired. Tenants not recorded, whose region and version
+// are unknown until they are next served, are logged and left out.
+func expiredTenants(l logrus.FieldLogger, db *gorm.DB, asOf time.Time) ([]tenant.Model, error) {
+	ts, err := servedTenants(db)
+	if err != nil || db == nil {
+		return ts, err
+	}
+	ids, err := getExpiredTenantIdsProvider(asOf)(db)()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uuid.UUID]tenant.Model, len(ts))
+	for _, t := range ts {
+		known[t.Id()] = t
+	}
+	results := make([]tenant.Model, 0, len(ids))
+	for _, id := range ids {
+		t, ok := known[id]
+		if !ok {
+			l.Warnf("Unable to expire notes of tenant [%s], whose region and version are not yet recorded.", id)
+			continue
+		}
+		results = append(results, t)
+	}
+	return results, nil
+}
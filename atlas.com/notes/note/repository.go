@@ -0,0 +1,259 @@
+package note
+
+import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/database"
+	"atlas-notes/summary"
+	"context"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	MarkerStarred  = "starred"
+	MarkerPinned   = "pinned"
+	MarkerArchived = "archived"
+)
+
+// Repository stores notes. Lookups of a single note return gorm.ErrRecordNotFound when no note matches, whatever the
+// implementation, so callers can treat every Repository alike. Deleted notes are excluded unless stated otherwise.
+type Repository interface {
+	// ById returns a note by its ID
+	ById(tenantId uuid.UUID, id uint32) (Model, error)
+	// ByIdIncludingDeleted returns a note by its ID, even if it has since been deleted
+	ByIdIncludingDeleted(tenantId uuid.UUID, id uint32) (Model, error)
+	// ByCharacter returns all notes belonging to a character
+	ByCharacter(tenantId uuid.UUID, characterId uint32) ([]Model, error)
+	// ByCharacterAndFilter returns the notes belonging to a character which match the filter, pinned notes first.
+	// Deleted notes are listed most recently deleted first.
+	ByCharacterAndFilter(tenantId uuid.UUID, characterId uint32, f Filter) ([]Model, error)
+	// InboxCount returns the number of notes held by a character which count towards its inbox capacity
+	InboxCount(tenantId uuid.UUID, characterId uint32) (int64, error)
+	// SentCount returns the number of notes a character has sent since the given time, deleted notes included
+	SentCount(tenantId uuid.UUID, senderId uint32, since time.Time) (int64, error)
+	// UnreadSenders returns up to limit distinct characters who sent the unread notes a character holds, most recent
+	// sender first
+	UnreadSenders(tenantId uuid.UUID, characterId uint32, limit int) ([]uint32, error)
+	// BySender returns all notes a character has sent and not hidden, most recent first, including those the
+	// recipient deleted
+	BySender(tenantId uuid.UUID, senderId uint32) ([]Model, error)
+	// ByParticipant returns all notes a character has received and kept, or sent and not hidden
+	ByParticipant(tenantId uuid.UUID, characterId uint32) ([]Model, error)
+	// All returns all notes in a tenant
+	All(tenantId uuid.UUID) ([]Model, error)
+	// Expired returns all notes in a tenant which expired at or before the given time
+	Expired(tenantId uuid.UUID, asOf time.Time) ([]Model, error)
+	// Count returns the number of notes in a tenant, deleted notes aside
+	Count(tenantId uuid.UUID) (int64, error)
+	// CountIncludingDeleted returns the number of notes in a tenant, deleted notes included
+	CountIncludingDeleted(tenantId uuid.UUID) (int64, error)
+	// AllIncludingDeleted returns up to limit notes in a tenant with IDs above afterId, in ID order, deleted notes
+	// included, so the tenant's notes can be walked in batches
+	AllIncludingDeleted(tenantId uuid.UUID, afterId uint32, limit int) ([]Model, error)
+	// Tally counts the notes of each kind every character of a tenant holds, and how many of them are unread, from the
+	// notes themselves rather than the summary counters
+	Tally(tenantId uuid.UUID) ([]summary.Count, error)
+	// BySentAt returns the note a sender addressed to a character at the given time, even if it has since been deleted
+	BySentAt(tenantId uuid.UUID, characterId uint32, senderId uint32, timestamp time.Time) (Model, error)
+
+	// Create stores a new note, along with any attachments it carries, assigning their IDs
+	Create(tenantId uuid.UUID, m Model) (Model, error)
+	// Update changes the set fields of an existing note, leaving its attachments, labels and organization alone
+	Update(tenantId uuid.UUID, m Model) (Model, error)
+	// Delete deletes a note
+	Delete(tenantId uuid.UUID, id uint32) error
+	// DeleteAll deletes all notes belonging to a character
+	DeleteAll(tenantId uuid.UUID, characterId uint32) error
+	// Restore undoes the deletion of a note. An expiration which has passed in the meantime is cleared.
+	Restore(tenantId uuid.UUID, id uint32) error
+	// HideSent hides notes from their sender's sent items, leaving the recipient's copy untouched
+	HideSent(tenantId uuid.UUID, senderId uint32, ids []uint32) error
+	// UpdateOrganization sets the starred, pinned or archived marker of a note
+	UpdateOrganization(tenantId uuid.UUID, id uint32, marker string, value bool) error
+	// MarkRead records when a note was read by its recipient. A note already read keeps when it was first read.
+	MarkRead(tenantId uuid.UUID, id uint32, readAt time.Time) error
+	// ReplaceLabels replaces the labels a note is filed under
+	ReplaceLabels(tenantId uuid.UUID, id uint32, names []string) error
+	// Purge permanently removes up to limit notes in a tenant, deleted notes included, along with their attachments
+	// and labels. Notes are purged in ID order. The purged notes are returned, without their associations.
+	Purge(tenantId uuid.UUID, limit int) ([]Model, error)
+	// Import stores notes carried over from elsewhere in a single batch, keeping all of their state, down to their
+	// labels and deletion. New IDs are assigned; the stored notes are returned in the order given.
+	Import(tenantId uuid.UUID, ms []Model) ([]Model, error)
+	// Overwrite replaces the whole state of an existing note, deleted or not, with that of m, keeping its ID. Its
+	// attachments and labels are replaced too.
+	Overwrite(tenantId uuid.UUID, id uint32, m Model) (Model, error)
+
+	// Attachments returns the repository of the attachments carried by notes, sharing any transaction in progress
+	Attachments() attachment.Repository
+	// Audit returns the audit trail of changes made to notes, stored alongside them
+	Audit() audit.Repository
+	// Summary returns the counters summarizing the notes each character holds, stored alongside them
+	Summary() summary.Repository
+	// Transaction runs fn against a repository whose changes are kept only if fn succeeds. Within a transaction, fn
+	// runs directly.
+	Transaction(fn func(r Repository) error) error
+	// Reader returns a repository to route read-only queries to, which may lag changes just made
+	Reader() Repository
+	// WithContext returns a repository whose queries are made within the context, so they are traced and cancelled
+	// with it
+	WithContext(ctx context.Context) Repository
+}
+
+// GormRepository is a Repository backed by a SQL database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) ById(tenantId uuid.UUID, id uint32) (Model, error) {
+	return model.Map[Entity, Model](Make)(getByIdProvider(tenantId)(id)(r.db))()
+}
+
+func (r *GormRepository) ByIdIncludingDeleted(tenantId uuid.UUID, id uint32) (Model, error) {
+	return model.Map[Entity, Model](Make)(getByIdIncludingDeletedProvider(tenantId)(id)(r.db))()
+}
+
+func (r *GormRepository) ByCharacter(tenantId uuid.UUID, characterId uint32) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getByCharacterIdProvider(tenantId)(characterId)(r.db))(model.ParallelMap())()
+}
+
+func (r *GormRepository) ByCharacterAndFilter(tenantId uuid.UUID, characterId uint32, f Filter) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getByCharacterIdAndFilterProvider(tenantId)(characterId)(f)(r.db))()()
+}
+
+func (r *GormRepository) InboxCount(tenantId uuid.UUID, characterId uint32) (int64, error) {
+	return getInboxCountProvider(tenantId)(characterId)(r.db)()
+}
+
+func (r *GormRepository) SentCount(tenantId uuid.UUID, senderId uint32, since time.Time) (int64, error) {
+	return getSentCountProvider(tenantId)(senderId)(since)(r.db)()
+}
+
+func (r *GormRepository) UnreadSenders(tenantId uuid.UUID, characterId uint32, limit int) ([]uint32, error) {
+	return getUnreadSendersProvider(tenantId)(characterId)(limit)(r.db)()
+}
+
+func (r *GormRepository) BySender(tenantId uuid.UUID, senderId uint32) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getBySenderIdProvider(tenantId)(senderId)(r.db))()()
+}
+
+func (r *GormRepository) ByParticipant(tenantId uuid.UUID, characterId uint32) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getByParticipantProvider(tenantId)(characterId)(r.db))(model.ParallelMap())()
+}
+
+func (r *GormRepository) All(tenantId uuid.UUID) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getAllProvider(tenantId)(r.db))(model.ParallelMap())()
+}
+
+func (r *GormRepository) Expired(tenantId uuid.UUID, asOf time.Time) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getExpiredProvider(tenantId)(asOf)(r.db))(model.ParallelMap())()
+}
+
+func (r *GormRepository) Count(tenantId uuid.UUID) (int64, error) {
+	return getCountProvider(tenantId)(r.db)()
+}
+
+func (r *GormRepository) CountIncludingDeleted(tenantId uuid.UUID) (int64, error) {
+	return getCountIncludingDeletedProvider(tenantId)(r.db)()
+}
+
+func (r *GormRepository) AllIncludingDeleted(tenantId uuid.UUID, afterId uint32, limit int) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getAllIncludingDeletedProvider(tenantId)(afterId)(limit)(r.db))()()
+}
+
+func (r *GormRepository) Tally(tenantId uuid.UUID) ([]summary.Count, error) {
+	return model.SliceMap[summary.Entity, summary.Count](summary.Make)(getTallyProvider(tenantId)(r.db))()()
+}
+
+func (r *GormRepository) BySentAt(tenantId uuid.UUID, characterId uint32, senderId uint32, timestamp time.Time) (Model, error) {
+	return model.Map[Entity, Model](Make)(getBySentAtProvider(tenantId)(characterId)(senderId)(timestamp)(r.db))()
+}
+
+func (r *GormRepository) Create(tenantId uuid.UUID, m Model) (Model, error) {
+	return createNote(r.db)(tenantId)(m)
+}
+
+func (r *GormRepository) Update(tenantId uuid.UUID, m Model) (Model, error) {
+	return updateNote(r.db)(tenantId)(m)
+}
+
+func (r *GormRepository) Delete(tenantId uuid.UUID, id uint32) error {
+	return deleteNote(r.db)(tenantId)(id)
+}
+
+func (r *GormRepository) DeleteAll(tenantId uuid.UUID, characterId uint32) error {
+	return deleteAllNotes(r.db)(tenantId)(characterId)
+}
+
+func (r *GormRepository) Restore(tenantId uuid.UUID, id uint32) error {
+	return restoreNote(r.db)(tenantId)(id)
+}
+
+func (r *GormRepository) HideSent(tenantId uuid.UUID, senderId uint32, ids []uint32) error {
+	return hideSentNotes(r.db)(tenantId)(senderId)(ids)
+}
+
+func (r *GormRepository) UpdateOrganization(tenantId uuid.UUID, id uint32, marker string, value bool) error {
+	return updateOrganization(r.db)(tenantId)(id)(marker)(value)
+}
+
+func (r *GormRepository) MarkRead(tenantId uuid.UUID, id uint32, readAt time.Time) error {
+	return markRead(r.db)(tenantId)(id)(readAt)
+}
+
+func (r *GormRepository) ReplaceLabels(tenantId uuid.UUID, id uint32, names []string) error {
+	return replaceLabels(r.db)(tenantId)(id)(names)
+}
+
+func (r *GormRepository) Purge(tenantId uuid.UUID, limit int) ([]Model, error) {
+	es, err := purgeNotes(r.db)(tenantId)(limit)
+	if err != nil {
+		return nil, err
+	}
+	return model.SliceMap[Entity, Model](Make)(model.FixedProvider(es))()()
+}
+
+func (r *GormRepository) Import(tenantId uuid.UUID, ms []Model) ([]Model, error) {
+	es, err := importNotes(r.db)(tenantId)(ms)
+	if err != nil {
+		return nil, err
+	}
+	return model.SliceMap[Entity, Model](Make)(model.FixedProvider(es))()()
+}
+
+func (r *GormRepository) Overwrite(tenantId uuid.UUID, id uint32, m Model) (Model, error) {
+	return overwriteNote(r.db)(tenantId)(id)(m)
+}
+
+func (r *GormRepository) Attachments() attachment.Repository {
+	return attachment.NewGormRepository(r.db)
+}
+
+func (r *GormRepository) Audit() audit.Repository {
+	return audit.NewGormRepository(r.db)
+}
+
+func (r *GormRepository) Summary() summary.Repository {
+	return summary.NewGormRepository(r.db)
+}
+
+func (r *GormRepository) Transaction(fn func(r Repository) error) error {
+	return database.ExecuteTransaction(r.db, func(tx *gorm.DB) error {
+		return fn(NewGormRepository(tx))
+	})
+}
+
+func (r *GormRepository) Reader() Repository {
+	return NewGormRepository(database.Reader(r.db))
+}
+
+func (r *GormRepository) WithContext(ctx context.Context) Repository {
+	return NewGormRepository(r.db.WithContext(ctx))
+}
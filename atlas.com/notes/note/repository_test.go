@@ -0,0 +1,682 @@
+package note_test
+
+import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/database"
+	"atlas-notes/kafka/message"
+	"atlas-notes/migrations"
+	"atlas-notes/note"
+	"atlas-notes/summary"
+	"context"
+	"errors"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRepositories are the Repository implementations the conformance suite runs against, each created empty
+var testRepositories = map[string]func(t *testing.T) note.Repository{
+	"gorm": func(t *testing.T) note.Repository {
+		db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("Failed to connect to database: %v", err)
+		}
+		if err = database.Migrate(testLogger(), db, migrations.FS, database.LatestVersion); err != nil {
+			t.Fatalf("Failed to migrate database: %v", err)
+		}
+		return note.NewGormRepository(db)
+	},
+	"memory": func(t *testing.T) note.Repository {
+		return note.NewMemoryRepository()
+	},
+}
+
+// runConformance runs a test against every Repository implementation
+func runConformance(t *testing.T, test func(t *testing.T, r note.Repository)) {
+	for name, newRepository := range testRepositories {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepository(t))
+		})
+	}
+}
+
+func createTestNote(t *testing.T, r note.Repository, tenantId uuid.UUID, b *note.Builder) note.Model {
+	m, err := r.Create(tenantId, b.Build())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	return m
+}
+
+func noteIds(ms []note.Model) []uint32 {
+	ids := make([]uint32, 0, len(ms))
+	for _, m := range ms {
+		ids = append(ids, m.Id())
+	}
+	return ids
+}
+
+// byId sorts notes by ID, for comparing the results of queries which do not order them
+func byId(ms []note.Model) []note.Model {
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].Id() < ms[j].Id()
+	})
+	return ms
+}
+
+func expectIds(t *testing.T, what string, ms []note.Model, err error, expected ...uint32) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Failed to retrieve %s: %v", what, err)
+	}
+	ids := noteIds(ms)
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected %s %v, got %v", what, expected, ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected %s %v, got %v", what, expected, ids)
+		}
+	}
+}
+
+func TestRepository_Create(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		m := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetMessage("Hello!").SetFlag(1).SetAttachments(testAttachments()))
+		if m.Id() == 0 || m.ThreadId() != m.Id() {
+			t.Fatalf("Expected the note to be assigned an ID and start its own thread")
+		}
+
+		got, err := r.ById(tenantId, m.Id())
+		if err != nil {
+			t.Fatalf("Failed to retrieve note: %v", err)
+		}
+		if got.CharacterId() != 1 || got.SenderId() != 2 || got.Message() != "Hello!" || got.Flag() != 1 {
+			t.Fatalf("Unexpected note retrieved")
+		}
+		if len(got.Attachments()) != 2 {
+			t.Fatalf("Expected 2 attachments, got %d", len(got.Attachments()))
+		}
+		for _, a := range got.Attachments() {
+			if a.Id() == 0 || a.NoteId() != m.Id() || !a.Unclaimed() {
+				t.Fatalf("Expected attachments to be stored unclaimed against the note")
+			}
+		}
+
+		if _, err = r.ById(uuid.New(), m.Id()); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected notes to be isolated by tenant, got %v", err)
+		}
+		if _, err = r.ById(tenantId, m.Id()+100); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected a missing note not to be found, got %v", err)
+		}
+	})
+}
+
+func TestRepository_Update(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		m := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetMessage("Hello!"))
+		if err := r.UpdateOrganization(tenantId, m.Id(), note.MarkerStarred, true); err != nil {
+			t.Fatalf("Failed to star note: %v", err)
+		}
+
+		u, err := r.Update(tenantId, note.NewBuilder().SetId(m.Id()).SetCharacterId(1).SetSenderId(2).SetMessage("Goodbye!").Build())
+		if err != nil {
+			t.Fatalf("Failed to update note: %v", err)
+		}
+		if u.Message() != "Goodbye!" {
+			t.Fatalf("Expected the message to be updated")
+		}
+		if !u.Starred() {
+			t.Fatalf("Expected updating a note to leave its organization alone")
+		}
+
+		_, err = r.Update(tenantId, note.NewBuilder().SetId(m.Id()+100).SetMessage("Missing").Build())
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected updating a missing note to fail, got %v", err)
+		}
+	})
+}
+
+func TestRepository_DeleteAndRestore(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		kept := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2))
+		deleted := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetExpiration(time.Now().Add(-time.Minute)).SetAttachments(testAttachments()))
+
+		if err := r.Delete(tenantId, deleted.Id()); err != nil {
+			t.Fatalf("Failed to delete note: %v", err)
+		}
+		if _, err := r.ById(tenantId, deleted.Id()); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected a deleted note not to be found, got %v", err)
+		}
+		m, err := r.ByIdIncludingDeleted(tenantId, deleted.Id())
+		if err != nil || !m.Deleted() {
+			t.Fatalf("Expected a deleted note to be found as deleted, got %v", err)
+		}
+		if len(m.Attachments()) != 2 {
+			t.Fatalf("Expected a deleted note to be found with its attachments, got %d", len(m.Attachments()))
+		}
+		ms, err := r.ByCharacter(tenantId, 1)
+		expectIds(t, "notes of character", byId(ms), err, kept.Id())
+		ms, err = r.BySender(tenantId, 2)
+		if err != nil || len(ms) != 2 {
+			t.Fatalf("Expected the sender to still see the deleted note, got %v", noteIds(ms))
+		}
+		ms, err = r.Expired(tenantId, time.Now())
+		expectIds(t, "expired notes", byId(ms), err)
+		if count, err := r.Count(tenantId); err != nil || count != 1 {
+			t.Fatalf("Expected 1 note counted, deleted notes aside, got %d, %v", count, err)
+		}
+
+		if err = r.Restore(tenantId, deleted.Id()); err != nil {
+			t.Fatalf("Failed to restore note: %v", err)
+		}
+		m, err = r.ById(tenantId, deleted.Id())
+		if err != nil {
+			t.Fatalf("Expected a restored note to be found, got %v", err)
+		}
+		if !m.Expiration().IsZero() {
+			t.Fatalf("Expected a passed expiration to be cleared on restore")
+		}
+
+		if err = r.DeleteAll(tenantId, 1); err != nil {
+			t.Fatalf("Failed to delete notes: %v", err)
+		}
+		ms, err = r.All(tenantId)
+		expectIds(t, "notes in tenant", byId(ms), err)
+	})
+}
+
+func TestRepository_Filter(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		now := time.Now()
+		var ids []uint32
+		for i := 0; i < 5; i++ {
+			m := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetTimestamp(now.Add(time.Duration(i)*time.Minute)))
+			ids = append(ids, m.Id())
+		}
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(3).SetSenderId(2))
+
+		if err := r.UpdateOrganization(tenantId, ids[0], note.MarkerStarred, true); err != nil {
+			t.Fatalf("Failed to star note: %v", err)
+		}
+		if err := r.UpdateOrganization(tenantId, ids[2], note.MarkerPinned, true); err != nil {
+			t.Fatalf("Failed to pin note: %v", err)
+		}
+		if err := r.UpdateOrganization(tenantId, ids[3], note.MarkerArchived, true); err != nil {
+			t.Fatalf("Failed to archive note: %v", err)
+		}
+		if err := r.ReplaceLabels(tenantId, ids[1], []string{"guild", "trade"}); err != nil {
+			t.Fatalf("Failed to label note: %v", err)
+		}
+		if err := r.ReplaceLabels(tenantId, ids[1], []string{" guild ", "guild"}); err != nil {
+			t.Fatalf("Failed to relabel note: %v", err)
+		}
+		if err := r.Delete(tenantId, ids[4]); err != nil {
+			t.Fatalf("Failed to delete note: %v", err)
+		}
+
+		starred := true
+		ms, err := r.ByCharacterAndFilter(tenantId, 1, note.Filter{})
+		expectIds(t, "inbox", ms, err, ids[2], ids[0], ids[1])
+		ms, err = r.ByCharacterAndFilter(tenantId, 1, note.Filter{Starred: &starred})
+		expectIds(t, "starred notes", ms, err, ids[0])
+		ms, err = r.ByCharacterAndFilter(tenantId, 1, note.Filter{Archived: true})
+		expectIds(t, "archive", ms, err, ids[3])
+		ms, err = r.ByCharacterAndFilter(tenantId, 1, note.Filter{Label: "guild"})
+		expectIds(t, "labelled notes", ms, err, ids[1])
+		if len(ms) == 1 && (len(ms[0].Labels()) != 1 || ms[0].Labels()[0] != "guild") {
+			t.Fatalf("Expected labels to be replaced, got %v", ms[0].Labels())
+		}
+		ms, err = r.ByCharacterAndFilter(tenantId, 1, note.Filter{Label: "trade"})
+		expectIds(t, "labelled notes", ms, err)
+		ms, err = r.ByCharacterAndFilter(tenantId, 1, note.Filter{Deleted: true})
+		expectIds(t, "deleted notes", ms, err, ids[4])
+
+		count, err := r.InboxCount(tenantId, 1)
+		if err != nil || count != 3 {
+			t.Fatalf("Expected an inbox count of 3, got %d (%v)", count, err)
+		}
+	})
+}
+
+func TestRepository_SentItems(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		now := time.Now()
+		older := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetTimestamp(now.Add(-time.Minute)))
+		newer := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(3).SetSenderId(2).SetTimestamp(now))
+		received := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(2).SetSenderId(1))
+
+		ms, err := r.BySender(tenantId, 2)
+		expectIds(t, "sent notes", ms, err, newer.Id(), older.Id())
+		ms, err = r.ByParticipant(tenantId, 2)
+		expectIds(t, "notes of participant", byId(ms), err, older.Id(), newer.Id(), received.Id())
+
+		// Only the sender's own notes are hidden.
+		if err = r.HideSent(tenantId, 2, []uint32{older.Id(), received.Id()}); err != nil {
+			t.Fatalf("Failed to hide sent notes: %v", err)
+		}
+		ms, err = r.BySender(tenantId, 2)
+		expectIds(t, "sent notes", ms, err, newer.Id())
+		ms, err = r.ByParticipant(tenantId, 2)
+		expectIds(t, "notes of participant", byId(ms), err, newer.Id(), received.Id())
+		ms, err = r.ByCharacter(tenantId, 1)
+		expectIds(t, "notes of recipient", byId(ms), err, older.Id())
+	})
+}
+
+func TestRepository_SentCount(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		now := time.Now()
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetTimestamp(now.Add(-time.Hour)))
+		recent := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetTimestamp(now))
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(3).SetSenderId(2).SetTimestamp(now))
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(2).SetSenderId(1).SetTimestamp(now))
+		createTestNote(t, r, uuid.New(), note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetTimestamp(now))
+
+		// Notes the recipient deleted still count.
+		if err := r.Delete(tenantId, recent.Id()); err != nil {
+			t.Fatalf("Failed to delete note: %v", err)
+		}
+		count, err := r.SentCount(tenantId, 2, now.Add(-time.Minute))
+		if err != nil {
+			t.Fatalf("Failed to count sent notes: %v", err)
+		}
+		if count != 2 {
+			t.Fatalf("Expected 2 notes sent, got %d", count)
+		}
+	})
+}
+
+func TestRepository_Expired(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		now := time.Now()
+		expired := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetExpiration(now.Add(-time.Hour)))
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetExpiration(now.Add(time.Hour)))
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1))
+
+		ms, err := r.Expired(tenantId, now)
+		expectIds(t, "expired notes", byId(ms), err, expired.Id())
+	})
+}
+
+func TestRepository_Purge(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		var ids []uint32
+		for i := 0; i < 5; i++ {
+			m := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetAttachments(testAttachments()))
+			ids = append(ids, m.Id())
+		}
+		if err := r.ReplaceLabels(tenantId, ids[0], []string{"guild"}); err != nil {
+			t.Fatalf("Failed to label note: %v", err)
+		}
+		if err := r.Delete(tenantId, ids[1]); err != nil {
+			t.Fatalf("Failed to delete note: %v", err)
+		}
+		otherTenantId := uuid.New()
+		other := createTestNote(t, r, otherTenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2))
+
+		count, err := r.CountIncludingDeleted(tenantId)
+		if err != nil || count != 5 {
+			t.Fatalf("Expected 5 notes including deleted, got %d (%v)", count, err)
+		}
+
+		ms, err := r.Purge(tenantId, 3)
+		expectIds(t, "purged notes", ms, err, ids[0], ids[1], ids[2])
+		if !ms[1].Deleted() {
+			t.Fatalf("Expected a purged note to report it had been deleted")
+		}
+		if _, err = r.ByIdIncludingDeleted(tenantId, ids[1]); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected a purged note to be gone, got %v", err)
+		}
+		if as, _ := r.Attachments().ByNoteId(tenantId, ids[0]); len(as) != 0 {
+			t.Fatalf("Expected the attachments of a purged note to be gone")
+		}
+
+		ms, err = r.Purge(tenantId, 3)
+		expectIds(t, "purged notes", ms, err, ids[3], ids[4])
+		ms, err = r.Purge(tenantId, 3)
+		expectIds(t, "purged notes", ms, err)
+		if _, err = r.ById(otherTenantId, other.Id()); err != nil {
+			t.Fatalf("Expected notes of other tenants to be kept, got %v", err)
+		}
+	})
+}
+
+func TestRepository_ImportAndOverwrite(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		sentAt := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+		deletedAt := sentAt.Add(time.Hour)
+		ms, err := r.Import(tenantId, []note.Model{
+			note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetTimestamp(sentAt).SetMessage("First").SetStarred(true).SetLabels([]string{"guild"}).SetAttachments(testAttachments()).Build(),
+			note.NewBuilder().SetCharacterId(1).SetSenderId(3).SetTimestamp(sentAt).SetMessage("Second").SetDeletedAt(deletedAt).SetSenderHiddenAt(deletedAt).Build(),
+		})
+		if err != nil || len(ms) != 2 {
+			t.Fatalf("Failed to import notes: %v", err)
+		}
+		if ms[0].Id() == 0 || ms[1].Id() <= ms[0].Id() {
+			t.Fatalf("Expected imported notes to be assigned IDs in order, got %v", noteIds(ms))
+		}
+
+		m, err := r.BySentAt(tenantId, 1, 2, sentAt)
+		if err != nil {
+			t.Fatalf("Failed to retrieve note by when it was sent: %v", err)
+		}
+		if m.Id() != ms[0].Id() || !m.Starred() || len(m.Labels()) != 1 || len(m.Attachments()) != 2 {
+			t.Fatalf("Expected the first note with its state and associations, got %+v", m)
+		}
+		m, err = r.BySentAt(tenantId, 1, 3, sentAt)
+		if err != nil {
+			t.Fatalf("Failed to retrieve deleted note by when it was sent: %v", err)
+		}
+		if !m.Deleted() || m.SenderHiddenAt().IsZero() {
+			t.Fatalf("Expected the second note to be imported deleted and hidden from its sender")
+		}
+		if _, err = r.BySentAt(tenantId, 1, 2, sentAt.Add(time.Second)); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected no note sent at another time, got %v", err)
+		}
+
+		o, err := r.Overwrite(tenantId, ms[1].Id(), note.NewBuilder().SetCharacterId(1).SetSenderId(3).SetTimestamp(sentAt).SetMessage("Replaced").SetPinned(true).SetLabels([]string{"kept"}).SetAttachments(testAttachments()[1:]).Build())
+		if err != nil {
+			t.Fatalf("Failed to overwrite note: %v", err)
+		}
+		if o.Id() != ms[1].Id() || o.Message() != "Replaced" || !o.Pinned() || o.Deleted() || !o.SenderHiddenAt().IsZero() {
+			t.Fatalf("Expected the note's state to be replaced, got %+v", o)
+		}
+		if as, _ := r.Attachments().ByNoteId(tenantId, o.Id()); len(as) != 1 {
+			t.Fatalf("Expected the note's attachments to be replaced, got %d", len(as))
+		}
+		if _, err = r.Overwrite(uuid.New(), o.Id(), o); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Expected overwriting a note of another tenant to fail, got %v", err)
+		}
+
+		ms, err = r.AllIncludingDeleted(tenantId, 0, 1)
+		expectIds(t, "first batch", ms, err, o.Id()-1)
+		ms, err = r.AllIncludingDeleted(tenantId, ms[0].Id(), 5)
+		expectIds(t, "second batch", ms, err, o.Id())
+		ms, err = r.AllIncludingDeleted(tenantId, o.Id(), 5)
+		expectIds(t, "last batch", ms, err)
+	})
+}
+
+func TestRepository_Transaction(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		m := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetAttachments(testAttachments()))
+		a := m.Attachments()[0]
+
+		rollback := errors.New("rollback")
+		err := r.Transaction(func(tx note.Repository) error {
+			if err := tx.Attachments().Transition(tenantId, a.Id(), attachment.StatusClaimed); err != nil {
+				return err
+			}
+			if err := tx.Delete(tenantId, m.Id()); err != nil {
+				return err
+			}
+			if _, err := tx.ById(tenantId, m.Id()); !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Fatalf("Expected a transaction to see its own changes, got %v", err)
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Fatalf("Expected the transaction error to be returned, got %v", err)
+		}
+		m, err = r.ById(tenantId, m.Id())
+		if err != nil || !m.Attachments()[0].Unclaimed() {
+			t.Fatalf("Expected a failed transaction to change nothing, got %v", err)
+		}
+
+		err = r.Transaction(func(tx note.Repository) error {
+			return tx.Attachments().Transition(tenantId, a.Id(), attachment.StatusClaimed)
+		})
+		if err != nil {
+			t.Fatalf("Failed to claim attachment: %v", err)
+		}
+		as, err := r.Attachments().ByNoteId(tenantId, m.Id())
+		if err != nil || len(as) != 2 {
+			t.Fatalf("Failed to retrieve attachments: %v", err)
+		}
+		for _, got := range as {
+			if got.Id() == a.Id() && got.Status() != attachment.StatusClaimed {
+				t.Fatalf("Expected a committed transaction to be kept")
+			}
+		}
+		if err = r.Attachments().Transition(tenantId, a.Id(), attachment.StatusReturned); !errors.Is(err, attachment.ErrInvalidTransition) {
+			t.Fatalf("Expected a claimed attachment not to be returned, got %v", err)
+		}
+	})
+}
+
+func TestRepository_Audit(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+		entry := func(noteId uint32, characterId uint32) audit.Model {
+			return audit.NewBuilder().SetNoteId(noteId).SetCharacterId(characterId).SetAction(audit.ActionCreate).SetActor(audit.NewActor(audit.ActorTypeSystem, "test")).SetTransactionId(uuid.New()).Build()
+		}
+
+		rollback := errors.New("rollback")
+		err := r.Transaction(func(tx note.Repository) error {
+			if err := tx.Audit().Append(tenantId, entry(1, 1)); err != nil {
+				return err
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Fatalf("Expected the transaction error to be returned, got %v", err)
+		}
+		if as, err := r.Audit().ByNoteId(tenantId, 1); err != nil || len(as) != 0 {
+			t.Fatalf("Expected entries of a failed transaction not to be kept, got [%d] (%v).", len(as), err)
+		}
+
+		if err = r.Audit().Append(tenantId, entry(1, 1), entry(2, 1), entry(1, 3)); err != nil {
+			t.Fatalf("Failed to append entries: %v", err)
+		}
+		other := uuid.New()
+		if err = r.Audit().Append(other, entry(1, 1)); err != nil {
+			t.Fatalf("Failed to append entries: %v", err)
+		}
+		as, err := r.Audit().ByNoteId(tenantId, 1)
+		if err != nil || len(as) != 2 || as[0].Id() >= as[1].Id() {
+			t.Fatalf("Expected the two entries of the note in order, got [%d] (%v).", len(as), err)
+		}
+		if as[0].Actor().Type() != audit.ActorTypeSystem || as[0].Actor().Id() != "test" {
+			t.Fatalf("Expected the actor to be kept.")
+		}
+		if as, err = r.Audit().ByCharacterId(tenantId, 1); err != nil || len(as) != 2 {
+			t.Fatalf("Expected the two entries of the character, got [%d] (%v).", len(as), err)
+		}
+
+		if n, err := r.Audit().Purge(tenantId); err != nil || n != 3 {
+			t.Fatalf("Expected the 3 entries of the tenant to be purged, got [%d] (%v).", n, err)
+		}
+		if as, err = r.Audit().ByCharacterId(tenantId, 1); err != nil || len(as) != 0 {
+			t.Fatalf("Expected no entries to be left, got [%d] (%v).", len(as), err)
+		}
+		if as, err = r.Audit().ByNoteId(other, 1); err != nil || len(as) != 1 {
+			t.Fatalf("Expected entries of other tenants to be kept, got [%d] (%v).", len(as), err)
+		}
+	})
+}
+
+func TestRepository_Summary(t *testing.T) {
+	runConformance(t, func(t *testing.T, r note.Repository) {
+		tenantId := uuid.New()
+
+		rollback := errors.New("rollback")
+		err := r.Transaction(func(tx note.Repository) error {
+			if err := tx.Summary().Apply(tenantId, summary.NewCount(1, 0, 1, 1)); err != nil {
+				return err
+			}
+			return rollback
+		})
+		if !errors.Is(err, rollback) {
+			t.Fatalf("Expected the transaction error to be returned, got %v", err)
+		}
+		if s, err := r.Summary().ByCharacterId(tenantId, 1); err != nil || s.Total() != 0 || len(s.Kinds()) != 0 {
+			t.Fatalf("Expected counts of a failed transaction not to be kept, got [%d] (%v).", s.Total(), err)
+		}
+
+		err = r.Summary().Apply(tenantId, summary.NewCount(1, 0, 2, 2), summary.NewCount(1, 1, 1, 0), summary.NewCount(1, 0, 1, 1))
+		if err != nil {
+			t.Fatalf("Failed to apply counts: %v", err)
+		}
+		if err = r.Summary().Apply(tenantId, summary.NewCount(1, 0, -1, -2), summary.NewCount(2, 0, 1, 1)); err != nil {
+			t.Fatalf("Failed to apply counts: %v", err)
+		}
+		s, err := r.Summary().ByCharacterId(tenantId, 1)
+		if err != nil {
+			t.Fatalf("Failed to retrieve summary: %v", err)
+		}
+		if s.Total() != 3 || s.Unread() != 1 || len(s.Kinds()) != 2 {
+			t.Fatalf("Expected 3 notes, 1 unread, of 2 kinds, got %d, %d, of %d.", s.Total(), s.Unread(), len(s.Kinds()))
+		}
+		if k := s.Kinds()[0]; k.Flag() != 0 || k.Total() != 2 || k.Unread() != 1 {
+			t.Fatalf("Expected 2 notes of flag 0, 1 unread, got %d, %d.", k.Total(), k.Unread())
+		}
+		if cs, err := r.Summary().ByTenant(tenantId); err != nil || len(cs) != 3 {
+			t.Fatalf("Expected the 3 counters of the tenant, got [%d] (%v).", len(cs), err)
+		}
+
+		createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetFlag(3))
+		read := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetFlag(3))
+		if err = r.MarkRead(tenantId, read.Id(), time.Now()); err != nil {
+			t.Fatalf("Failed to mark note read: %v", err)
+		}
+		archived := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetFlag(3))
+		if err = r.UpdateOrganization(tenantId, archived.Id(), note.MarkerArchived, true); err != nil {
+			t.Fatalf("Failed to archive note: %v", err)
+		}
+		deleted := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(4).SetSenderId(2))
+		if err = r.Delete(tenantId, deleted.Id()); err != nil {
+			t.Fatalf("Failed to delete note: %v", err)
+		}
+		cs, err := r.Tally(tenantId)
+		if err != nil {
+			t.Fatalf("Failed to tally notes: %v", err)
+		}
+		if len(cs) != 1 || cs[0].CharacterId() != 1 || cs[0].Flag() != 3 || cs[0].Total() != 3 || cs[0].Unread() != 2 {
+			t.Fatalf("Expected the 3 held notes of flag 3 to be tallied, 2 unread, got %v.", cs)
+		}
+	})
+}
+
+func TestGormRepository_AuditAppendOnly(t *testing.T) {
+	db := testDatabase(t)
+	tenantId := uuid.New()
+	m := audit.NewBuilder().SetNoteId(1).SetCharacterId(1).SetAction(audit.ActionCreate).SetActor(audit.NewActor(audit.ActorTypeSystem, "test")).SetTransactionId(uuid.New()).Build()
+	if err := note.NewGormRepository(db).Audit().Append(tenantId, m); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+	if err := db.Model(&audit.Entity{}).Where("tenant_id = ?", tenantId).Update("action", audit.ActionDelete).Error; err == nil {
+		t.Fatalf("Expected entries not to be updated.")
+	}
+	if err := db.Where("tenant_id = ?", tenantId).Delete(&audit.Entity{}).Error; err == nil {
+		t.Fatalf("Expected entries not to be deleted.")
+	}
+
+	// Only purging the tenant's trail deletes entries, and only for as long as the purge lasts.
+	if n, err := note.NewGormRepository(db).Audit().Purge(tenantId); err != nil || n != 1 {
+		t.Fatalf("Expected the entry to be purged, got [%d] (%v).", n, err)
+	}
+	if err := note.NewGormRepository(db).Audit().Append(tenantId, m); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+	if err := db.Where("tenant_id = ?", tenantId).Delete(&audit.Entity{}).Error; err == nil {
+		t.Fatalf("Expected entries not to be deleted once purged.")
+	}
+}
+
+func TestMemoryRepository_Concurrency(t *testing.T) {
+	r := note.NewMemoryRepository()
+	tenantId := uuid.New()
+	m := createTestNote(t, r, tenantId, note.NewBuilder().SetCharacterId(1).SetSenderId(2).SetAttachments(testAttachments()))
+	a := m.Attachments()[0]
+
+	const workers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	claims := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = r.Create(tenantId, note.NewBuilder().SetCharacterId(uint32(i%3)+10).SetSenderId(1).Build())
+			_, _ = r.ByCharacterAndFilter(tenantId, 1, note.Filter{})
+			_ = r.UpdateOrganization(tenantId, m.Id(), note.MarkerStarred, i%2 == 0)
+			err := r.Transaction(func(tx note.Repository) error {
+				return tx.Attachments().Transition(tenantId, a.Id(), attachment.StatusClaimed)
+			})
+			if err == nil {
+				mu.Lock()
+				claims++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if claims != 1 {
+		t.Fatalf("Expected the attachment to be claimed exactly once, got %d", claims)
+	}
+	ms, err := r.All(tenantId)
+	if err != nil || len(ms) != workers+1 {
+		t.Fatalf("Expected %d notes, got %d (%v)", workers+1, len(ms), err)
+	}
+	seen := make(map[uint32]struct{})
+	for _, m := range ms {
+		if _, ok := seen[m.Id()]; ok {
+			t.Fatalf("Expected unique note IDs, got %d twice", m.Id())
+		}
+		seen[m.Id()] = struct{}{}
+	}
+}
+
+func TestProcessorImpl_Memory(t *testing.T) {
+	l := testLogger()
+	te := testTenant()
+	ctx := tenant.WithContext(context.Background(), te)
+	r := note.NewMemoryRepository()
+
+	np := note.NewProcessor(l, ctx, r)
+	m, err := np.CreateWithAttachments(message.NewBuffer())(1)(2)("Hello!")(0)(testAttachments())
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	mb := message.NewBuffer()
+	if err = np.Claim(mb)(1)(m.Id()); err != nil {
+		t.Fatalf("Failed to claim note: %v", err)
+	}
+	m, err = np.ByIdProvider(m.Id())()
+	if err != nil {
+		t.Fatalf("Failed to retrieve note: %v", err)
+	}
+	for _, a := range m.Attachments() {
+		if a.Unclaimed() {
+			t.Fatalf("Expected every attachment to be claimed")
+		}
+	}
+
+	// Processors created without a database share notes held in memory.
+	var db *gorm.DB
+	m, err = note.NewProcessor(l, ctx, db).Create(message.NewBuffer())(3)(4)("Hello!")(0)
+	if err != nil {
+		t.Fatalf("Failed to create note: %v", err)
+	}
+	if _, err = note.NewProcessor(l, ctx, db).ByIdProvider(m.Id())(); err != nil {
+		t.Fatalf("Expected processors without a database to share notes, got %v", err)
+	}
+}
@@ -1,13 +1,22 @@
 package note
 
 import (
+	"atlas-notes/attachment"
+	"atlas-notes/audit"
+	"atlas-notes/auth"
+	"atlas-notes/kafka/message"
 	"atlas-notes/rest"
+	"atlas-notes/summary"
+	"encoding/json"
+	"errors"
 	"github.com/Chronicle20/atlas-model/model"
 	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/jtumidanski/api2go/jsonapi"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"io"
 	"net/http"
 )
 
@@ -18,7 +27,10 @@ func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteIn
 			registerInputHandler := rest.RegisterInputHandler[RestModel](l)(db)(si)
 
 			// ByIdProvider all notes
-			router.HandleFunc("/notes", registerHandler("get_all_notes", GetAllNotesHandler)).Methods(http.MethodGet)
+			router.HandleFunc("/notes", registerHandler("get_all_notes", GetAllNotesHandler, auth.RoleAdmin)).Methods(http.MethodGet)
+
+			// Routes without a role are player-facing reads, scoped to the character a player's token acts for. Every
+			// route changing notes requires the service role at least, players changing their notes through a service.
 
 			// ByIdProvider all notes for a character
 			router.HandleFunc(
@@ -26,31 +38,88 @@ func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteIn
 				registerHandler("get_character_notes", GetCharacterNotesHandler),
 			).Methods(http.MethodGet)
 
+			// BySenderProvider all notes sent by a character
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/sent",
+				registerHandler("get_character_sent_notes", GetCharacterSentNotesHandler),
+			).Methods(http.MethodGet)
+
+			// How many notes a character holds, and how many are unread, in total and by kind
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/summary",
+				registerHandler("get_character_notes_summary", GetCharacterNotesSummaryHandler),
+			).Methods(http.MethodGet)
+
+			// The audit trail of the notes a character has held
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/audit",
+				registerHandler("get_character_notes_audit", GetCharacterNotesAuditHandler, auth.RoleAdmin),
+			).Methods(http.MethodGet)
+
+			// Hide a note from a character's sent items
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/sent/{"+noteIdPattern+"}",
+				registerHandler("hide_character_sent_note", HideCharacterSentNoteHandler, auth.RoleService),
+			).Methods(http.MethodDelete)
+
+			// Star, pin, archive or label a note in a character's inbox
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/{"+noteIdPattern+"}/organization",
+				rest.RegisterInputHandler[OrganizationRestModel](l)(db)(si)("organize_character_note", OrganizeCharacterNoteHandler, auth.RoleService),
+			).Methods(http.MethodPatch)
+
+			// Restore a note a character deleted
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/{"+noteIdPattern+"}/restore",
+				registerHandler("restore_character_note", RestoreCharacterNoteHandler, auth.RoleService),
+			).Methods(http.MethodPost)
+
+			// Mark a note read by the character holding it
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/notes/{"+noteIdPattern+"}/read",
+				registerHandler("read_character_note", ReadCharacterNoteHandler, auth.RoleService),
+			).Methods(http.MethodPost)
+
+			// Export all notes in the tenant as newline-delimited JSON
+			router.HandleFunc("/notes/export", registerHandler("export_notes", ExportNotesHandler, auth.RoleAdmin)).Methods(http.MethodGet)
+
+			// Import notes exported from another tenant or cluster
+			router.HandleFunc("/notes/import", registerHandler("import_notes", ImportNotesHandler, auth.RoleAdmin)).Methods(http.MethodPost)
+
+			// Copy the notes of another tenant into the tenant
+			router.HandleFunc("/notes/clone", rest.RegisterInputHandler[CloneRestModel](l)(db)(si)("clone_notes", CloneNotesHandler, auth.RoleAdmin)).Methods(http.MethodPost)
+
 			// ByIdProvider a specific note
 			router.HandleFunc(
 				"/notes/{"+noteIdPattern+"}",
 				registerHandler("get_note", GetNoteHandler),
 			).Methods(http.MethodGet)
 
+			// The audit trail of a note
+			router.HandleFunc(
+				"/notes/{"+noteIdPattern+"}/history",
+				registerHandler("get_note_history", GetNoteHistoryHandler, auth.RoleAdmin),
+			).Methods(http.MethodGet)
+
 			// Create a note
-			router.HandleFunc("/notes", registerInputHandler("create_note", CreateNoteHandler)).Methods(http.MethodPost)
+			router.HandleFunc("/notes", registerInputHandler("create_note", CreateNoteHandler, auth.RoleService)).Methods(http.MethodPost)
 
 			// Update a note
 			router.HandleFunc(
 				"/notes/{"+noteIdPattern+"}",
-				registerInputHandler("update_note", UpdateNoteHandler),
+				registerInputHandler("update_note", UpdateNoteHandler, auth.RoleService),
 			).Methods(http.MethodPatch)
 
 			// Delete a note
 			router.HandleFunc(
 				"/notes/{"+noteIdPattern+"}",
-				registerHandler("delete_note", DeleteNoteHandler),
+				registerHandler("delete_note", DeleteNoteHandler, auth.RoleService),
 			).Methods(http.MethodDelete)
 
 			// Delete all notes for a character
 			router.HandleFunc(
 				"/characters/{"+characterIdPattern+"}/notes",
-				registerHandler("delete_character_notes", DeleteCharacterNotesHandler),
+				registerHandler("delete_character_notes", DeleteCharacterNotesHandler, auth.RoleAdmin),
 			).Methods(http.MethodDelete)
 		}
 	}
@@ -63,6 +132,10 @@ func GetAllNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.
 		rm, err := model.SliceMap(Transform)(mp)(model.ParallelMap())()
 		if err != nil {
 			d.Logger().WithError(err).Errorf("Creating REST model.")
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -73,14 +146,197 @@ func GetAllNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.
 	}
 }
 
+// ExportNotesHandler handles GET /api/notes/export. Notes are streamed one JSON record per line as they are read. A
+// failure before the first is written is answered with an error status; one part way through aborts the response, so
+// the client does not take the truncated stream for a complete one.
+func ExportNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		streaming := false
+		err := NewProcessor(d.Logger(), d.Context(), d.DB()).Export(func(m Model) error {
+			rm, err := TransformRecord(m)
+			if err != nil {
+				return err
+			}
+			if !streaming {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				streaming = true
+			}
+			return enc.Encode(rm)
+		})
+		if err == nil {
+			if !streaming {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+			}
+			return
+		}
+		d.Logger().WithError(err).Errorln("Error exporting notes")
+		if streaming {
+			panic(http.ErrAbortHandler)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// ImportNotesHandler handles POST /api/notes/import. The body is either a newline-delimited JSON stream of notes as
+// exported, or a multipart form whose characterIds part maps exported character IDs to those to import them with,
+// followed by the stream in its notes part. Records which are not valid are reported by line, the rest being imported.
+func ImportNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		policy, err := ParseConflictPolicy(r.URL.Query().Get("conflict"))
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Unable to parse import conflict policy.")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		characterIds, notes, err := importStream(r)
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Unable to read import request.")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		i := NewProcessor(d.Logger(), d.Context(), d.DB()).Importer(characterIds, policy)
+		err = i.Read(notes)
+		if err != nil {
+			d.Logger().WithError(err).Errorln("Error importing notes")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		res := i.Result()
+		d.Logger().Infof("Imported [%d] notes, overwrote [%d] and skipped [%d]. [%d] records were invalid.", res.Imported, res.Overwritten, res.Skipped, len(res.Errors))
+		rm, err := model.Map(TransformImport)(model.FixedProvider(res))()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Creating REST model.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		queryParams := jsonapi.ParseQueryFields(&query)
+		server.MarshalResponse[ImportRestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+	}
+}
+
+// CloneNotesHandler handles POST /api/notes/clone
+func CloneNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i CloneRestModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if i.SourceTenantId == uuid.Nil {
+			d.Logger().Errorf("Clone of notes requires a source tenant.")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		np := NewProcessor(d.Logger(), d.Context(), d.DB())
+		var res ImportResult
+		var err error
+		if i.EmitEvents {
+			res, err = np.CloneAndEmit(i.SourceTenantId, i.CharacterIds, i.Flags)
+		} else {
+			res, err = np.Clone(message.NewBuffer())(i.SourceTenantId)(i.CharacterIds)(i.Flags)
+		}
+		if err != nil {
+			d.Logger().WithError(err).Errorln("Error cloning notes")
+			if errors.Is(err, ErrSameTenant) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rm, err := model.Map(TransformImport)(model.FixedProvider(res))()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Creating REST model.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		queryParams := jsonapi.ParseQueryFields(&query)
+		server.MarshalResponse[ImportRestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+	}
+}
+
+// importStream returns the character ID remapping table and the stream of notes of an import request
+func importStream(r *http.Request) (map[uint32]uint32, io.Reader, error) {
+	mr, err := r.MultipartReader()
+	if errors.Is(err, http.ErrNotMultipart) {
+		return nil, r.Body, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var characterIds map[uint32]uint32
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("import request has no notes part")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch part.FormName() {
+		case "characterIds":
+			err = json.NewDecoder(part).Decode(&characterIds)
+			if err != nil {
+				return nil, nil, err
+			}
+		case "notes":
+			return characterIds, part, nil
+		}
+	}
+}
+
 // GetCharacterNotesHandler handles GET /api/characters/{characterId}/notes
 func GetCharacterNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
 	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).ByCharacterProvider(characterId)
+			query := r.URL.Query()
+			f, err := ParseFilter(query)
+			if err != nil {
+				d.Logger().WithError(err).Errorf("Unable to parse note filter.")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).ByCharacterAndFilterProvider(characterId, f)
 			rm, err := model.SliceMap(Transform)(mp)(model.ParallelMap())()
 			if err != nil {
 				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			queryParams := jsonapi.ParseQueryFields(&query)
+			server.MarshalResponse[[]RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+		}
+	})
+}
+
+// GetCharacterSentNotesHandler handles GET /api/characters/{characterId}/notes/sent
+func GetCharacterSentNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).BySenderProvider(characterId)
+			rm, err := model.SliceMap(Transform)(mp)()()
+			if err != nil {
+				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
@@ -92,6 +348,171 @@ func GetCharacterNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext)
 	})
 }
 
+// GetCharacterNotesSummaryHandler handles GET /api/characters/{characterId}/notes/summary
+func GetCharacterNotesSummaryHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).SummaryProvider(characterId)
+			rm, err := model.Map(summary.Transform)(mp)()
+			if err != nil {
+				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			query := r.URL.Query()
+			queryParams := jsonapi.ParseQueryFields(&query)
+			server.MarshalResponse[summary.RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+		}
+	})
+}
+
+// GetCharacterNotesAuditHandler handles GET /api/characters/{characterId}/notes/audit
+func GetCharacterNotesAuditHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).AuditByCharacterProvider(characterId)
+			rm, err := model.SliceMap(audit.Transform)(mp)()()
+			if err != nil {
+				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			query := r.URL.Query()
+			queryParams := jsonapi.ParseQueryFields(&query)
+			server.MarshalResponse[[]audit.RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+		}
+	})
+}
+
+// HideCharacterSentNoteHandler handles DELETE /api/characters/{characterId}/notes/sent/{noteId}
+func HideCharacterSentNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return rest.ParseNoteId(d.Logger(), func(noteId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				err := NewProcessor(d.Logger(), d.Context(), d.DB()).HideSent(characterId)([]uint32{noteId})
+				if err != nil {
+					d.Logger().WithError(err).Errorln("Error hiding sent note")
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+			}
+		})
+	})
+}
+
+// OrganizeCharacterNoteHandler handles PATCH /api/characters/{characterId}/notes/{noteId}/organization
+func OrganizeCharacterNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i OrganizationRestModel) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return rest.ParseNoteId(d.Logger(), func(noteId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).OrganizeAndEmit(characterId, noteId, ExtractOrganization(i))
+				if err != nil {
+					d.Logger().WithError(err).Errorln("Error organizing note")
+					if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotRecipient) {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				rm, err := model.Map(Transform)(model.FixedProvider(m))()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				query := r.URL.Query()
+				queryParams := jsonapi.ParseQueryFields(&query)
+				server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+			}
+		})
+	})
+}
+
+// RestoreCharacterNoteHandler handles POST /api/characters/{characterId}/notes/{noteId}/restore
+func RestoreCharacterNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return rest.ParseNoteId(d.Logger(), func(noteId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).RestoreAndEmit(characterId, noteId)
+				if err != nil {
+					d.Logger().WithError(err).Errorln("Error restoring note")
+					if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotRecipient) {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					if errors.Is(err, ErrNotDeleted) || errors.Is(err, ErrRestoreWindowClosed) || errors.Is(err, ErrInboxFull) {
+						w.WriteHeader(http.StatusConflict)
+						return
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				rm, err := model.Map(Transform)(model.FixedProvider(m))()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				query := r.URL.Query()
+				queryParams := jsonapi.ParseQueryFields(&query)
+				server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+			}
+		})
+	})
+}
+
+// ReadCharacterNoteHandler handles POST /api/characters/{characterId}/notes/{noteId}/read
+func ReadCharacterNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return rest.ParseNoteId(d.Logger(), func(noteId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).MarkReadAndEmit(characterId, noteId)
+				if err != nil {
+					d.Logger().WithError(err).Errorln("Error marking note read")
+					if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotRecipient) {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				rm, err := model.Map(Transform)(model.FixedProvider(m))()
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Creating REST model.")
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				query := r.URL.Query()
+				queryParams := jsonapi.ParseQueryFields(&query)
+				server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+			}
+		})
+	})
+}
+
 // GetNoteHandler handles GET /api/notes/{noteId}
 func GetNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
 	return rest.ParseNoteId(d.Logger(), func(noteId uint32) http.HandlerFunc {
@@ -100,6 +521,10 @@ func GetNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.Hand
 			rm, err := model.Map(Transform)(mp)()
 			if err != nil {
 				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
@@ -111,6 +536,30 @@ func GetNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.Hand
 	})
 }
 
+// GetNoteHistoryHandler handles GET /api/notes/{noteId}/history. The trail outlives the note, so the history of a
+// purged note may still be read.
+func GetNoteHistoryHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseNoteId(d.Logger(), func(noteId uint32) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).HistoryProvider(noteId)
+			rm, err := model.SliceMap(audit.Transform)(mp)()()
+			if err != nil {
+				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			query := r.URL.Query()
+			queryParams := jsonapi.ParseQueryFields(&query)
+			server.MarshalResponse[[]audit.RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+		}
+	})
+}
+
 // CreateNoteHandler handles POST /api/notes
 func CreateNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i RestModel) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -121,7 +570,24 @@ func CreateNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i Rest
 			return
 		}
 
-		m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).CreateAndEmit(im.CharacterId(), im.SenderId(), im.Message(), im.Flag())
+		m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).CreateWithAttachmentsAndEmit(im.CharacterId(), im.SenderId(), im.Message(), im.Flag(), im.Attachments())
+		if err != nil {
+			d.Logger().WithError(err).Errorln("Error creating note")
+			if errors.Is(err, attachment.ErrInvalidAttachment) || errors.Is(err, ErrMessageTooLong) || errors.Is(err, ErrFlagNotAllowed) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if errors.Is(err, ErrInboxFull) {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			if errors.Is(err, ErrRateLimited) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 		rm, err := model.Map(Transform)(model.FixedProvider(m))()
 		if err != nil {
 			d.Logger().WithError(err).Errorf("Creating REST model.")
@@ -153,6 +619,19 @@ func UpdateNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i Rest
 			}
 
 			m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).UpdateAndEmit(im.Id(), im.CharacterId(), im.SenderId(), im.Message(), im.Flag())
+			if err != nil {
+				d.Logger().WithError(err).Errorln("Error updating note")
+				if errors.Is(err, ErrMessageTooLong) || errors.Is(err, ErrFlagNotAllowed) {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
 			rm, err := model.Map(Transform)(model.FixedProvider(m))()
 			if err != nil {
 				d.Logger().WithError(err).Errorf("Creating REST model.")
@@ -174,6 +653,10 @@ func DeleteNoteHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.H
 			err := NewProcessor(d.Logger(), d.Context(), d.DB()).DeleteAndEmit(noteId)
 			if err != nil {
 				d.Logger().WithError(err).Errorln("Error deleting note")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
@@ -190,6 +673,10 @@ func DeleteCharacterNotesHandler(d *rest.HandlerDependency, c *rest.HandlerConte
 			err := NewProcessor(d.Logger(), d.Context(), d.DB()).DeleteAllAndEmit(characterId)
 			if err != nil {
 				d.Logger().WithError(err).Errorln("Error deleting character notes")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
 				w.WriteHeader(http.StatusInternalServerError)
 				return
 			}
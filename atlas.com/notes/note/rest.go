@@ -1,6 +1,9 @@
 package note
 
 import (
+	"atlas-notes/attachment"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
 	"strconv"
 	"time"
 )
@@ -12,12 +15,22 @@ const (
 
 // RestModel is the JSON:API resource for notes
 type RestModel struct {
-	Id          uint32    `json:"-"`
-	CharacterId uint32    `json:"characterId"`
-	SenderId    uint32    `json:"senderId"`
-	Message     string    `json:"message"`
-	Flag        byte      `json:"flag"`
-	Timestamp   time.Time `json:"timestamp"`
+	Id          uint32                 `json:"-"`
+	CharacterId uint32                 `json:"characterId"`
+	SenderId    uint32                 `json:"senderId"`
+	Message     string                 `json:"message"`
+	Flag        byte                   `json:"flag"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Expiration  *time.Time             `json:"expiration,omitempty"`
+	Attachments []attachment.RestModel `json:"attachments,omitempty"`
+	InReplyTo   uint32                 `json:"inReplyTo,omitempty"`
+	ThreadId    uint32                 `json:"threadId"`
+	Starred     bool                   `json:"starred"`
+	Pinned      bool                   `json:"pinned"`
+	Archived    bool                   `json:"archived"`
+	Labels      []string               `json:"labels"`
+	ReadAt      *time.Time             `json:"readAt,omitempty"`
+	DeletedAt   *time.Time             `json:"deletedAt,omitempty"`
 }
 
 // GetID returns the resource ID
@@ -42,18 +55,46 @@ func (n RestModel) GetName() string {
 
 // Transform converts a Model domain model to a RestModel
 func Transform(n Model) (RestModel, error) {
-	return RestModel{
+	as, err := model.SliceMap(attachment.Transform)(model.FixedProvider(n.Attachments()))()()
+	if err != nil {
+		return RestModel{}, err
+	}
+	rm := RestModel{
 		Id:          n.Id(),
 		CharacterId: n.CharacterId(),
 		SenderId:    n.SenderId(),
 		Message:     n.Message(),
 		Flag:        n.Flag(),
 		Timestamp:   n.Timestamp(),
-	}, nil
+		Attachments: as,
+		InReplyTo:   n.InReplyTo(),
+		ThreadId:    n.ThreadId(),
+		Starred:     n.Starred(),
+		Pinned:      n.Pinned(),
+		Archived:    n.Archived(),
+		Labels:      n.Labels(),
+	}
+	if !n.Expiration().IsZero() {
+		expiration := n.Expiration()
+		rm.Expiration = &expiration
+	}
+	if n.Read() {
+		readAt := n.ReadAt()
+		rm.ReadAt = &readAt
+	}
+	if n.Deleted() {
+		deletedAt := n.DeletedAt()
+		rm.DeletedAt = &deletedAt
+	}
+	return rm, nil
 }
 
 // Extract converts a RestModel to parameters for creating or updating a Model
 func Extract(r RestModel) (Model, error) {
+	as, err := model.SliceMap(attachment.Extract)(model.FixedProvider(r.Attachments))()()
+	if err != nil {
+		return Model{}, err
+	}
 	return NewBuilder().
 		SetId(r.Id).
 		SetCharacterId(r.CharacterId).
@@ -61,5 +102,115 @@ func Extract(r RestModel) (Model, error) {
 		SetMessage(r.Message).
 		SetFlag(r.Flag).
 		SetTimestamp(r.Timestamp).
+		SetAttachments(as).
 		Build(), nil
 }
+
+// OrganizationRestModel is the JSON:API input resource for how a recipient keeps a note. Omitted attributes are left unchanged.
+type OrganizationRestModel struct {
+	Id       uint32    `json:"-"`
+	Starred  *bool     `json:"starred,omitempty"`
+	Pinned   *bool     `json:"pinned,omitempty"`
+	Archived *bool     `json:"archived,omitempty"`
+	Labels   *[]string `json:"labels,omitempty"`
+}
+
+// GetID returns the resource ID
+func (o OrganizationRestModel) GetID() string {
+	return strconv.Itoa(int(o.Id))
+}
+
+// SetID sets the resource ID
+func (o *OrganizationRestModel) SetID(strId string) error {
+	if strId == "" {
+		return nil
+	}
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	o.Id = uint32(id)
+	return nil
+}
+
+// ExtractOrganization converts an OrganizationRestModel to the change it makes to a note
+func ExtractOrganization(r OrganizationRestModel) Organization {
+	return Organization{Starred: r.Starred, Pinned: r.Pinned, Archived: r.Archived, Labels: r.Labels}
+}
+
+// GetName returns the resource name
+func (o OrganizationRestModel) GetName() string {
+	return "organizations"
+}
+
+// ImportRestModel is the JSON:API resource reporting the outcome of an import of notes
+type ImportRestModel struct {
+	Id          uuid.UUID     `json:"-"`
+	Imported    int           `json:"imported"`
+	Overwritten int           `json:"overwritten"`
+	Skipped     int           `json:"skipped"`
+	Failed      int           `json:"failed"`
+	Errors      []ImportError `json:"errors"`
+}
+
+// GetID returns the resource ID
+func (i ImportRestModel) GetID() string {
+	return i.Id.String()
+}
+
+// SetID sets the resource ID
+func (i *ImportRestModel) SetID(strId string) error {
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		return err
+	}
+	i.Id = id
+	return nil
+}
+
+// GetName returns the resource name
+func (i ImportRestModel) GetName() string {
+	return "imports"
+}
+
+// TransformImport converts the tally of an import to an ImportRestModel
+func TransformImport(r ImportResult) (ImportRestModel, error) {
+	es := r.Errors
+	if es == nil {
+		es = []ImportError{}
+	}
+	return ImportRestModel{
+		Id:          uuid.New(),
+		Imported:    r.Imported,
+		Overwritten: r.Overwritten,
+		Skipped:     r.Skipped,
+		Failed:      len(r.Errors),
+		Errors:      es,
+	}, nil
+}
+
+// CloneRestModel is the JSON:API input resource for copying the notes of another tenant. Character IDs and flags are
+// mapped from those of the source tenant to those of the target tenant.
+type CloneRestModel struct {
+	Id             string            `json:"-"`
+	SourceTenantId uuid.UUID         `json:"sourceTenantId"`
+	CharacterIds   map[uint32]uint32 `json:"characterIds,omitempty"`
+	Flags          map[byte]byte     `json:"flags,omitempty"`
+	EmitEvents     bool              `json:"emitEvents"`
+}
+
+// GetID returns the resource ID
+func (c CloneRestModel) GetID() string {
+	return c.Id
+}
+
+// SetID sets the resource ID
+func (c *CloneRestModel) SetID(strId string) error {
+	c.Id = strId
+	return nil
+}
+
+// GetName returns the resource name
+func (c CloneRestModel) GetName() string {
+	return "clones"
+}
@@ -0,0 +1,164 @@
+package note
+
+import (
+	"atlas-notes/database"
+	"atlas-notes/metrics"
+	"atlas-notes/tracing"
+	"context"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	ExpirationTask    = "note_expiration_task"
+	TenantMetricsTask = "note_tenant_metrics_task"
+	SummaryTask       = "note_summary_reconcile_task"
+)
+
+// exclusive runs a sweep of a task on one instance at a time. With a database, the sweep runs under an advisory lock
+// named after the task, and an instance finding another holding it skips its turn, returning false.
+func exclusive(l logrus.FieldLogger, db *gorm.DB, task string, sweep func()) bool {
+	if db == nil {
+		sweep()
+		return true
+	}
+	locked, err := database.TryLock(db, task, func() error {
+		sweep()
+		return nil
+	})
+	if err != nil {
+		l.WithError(err).Errorf("Unable to lock task [%s].", task)
+		return false
+	}
+	if !locked {
+		l.Debugf("Task [%s] is running on another instance.", task)
+	}
+	return locked
+}
+
+// Expiration periodically expires notes carrying attachments, returning anything unclaimed to the sender, in every
+// tenant holding expired notes. One instance expires them at a time.
+type Expiration struct {
+	l        logrus.FieldLogger
+	db       *gorm.DB
+	interval time.Duration
+}
+
+func NewExpirationTask(l logrus.FieldLogger, db *gorm.DB, interval time.Duration) *Expiration {
+	return &Expiration{l: l, db: db, interval: interval}
+}
+
+func (t *Expiration) Run() {
+	sl, ctx, span := tracing.StartSpan(t.l, context.Background(), ExpirationTask)
+	defer span.End()
+
+	exclusive(sl, t.db, ExpirationTask, func() {
+		now := time.Now()
+		ts, err := expiredTenants(sl, t.db, now)
+		if err != nil {
+			sl.WithError(err).Errorf("Unable to retrieve tenants holding expired notes.")
+			return
+		}
+		for _, te := range ts {
+			tctx := tenant.WithContext(ctx, te)
+			tl := sl.WithField("tenant", te.Id().String())
+			p := NewProcessor(tl, tctx, t.db)
+			ms, err := p.ExpiredProvider(now)()
+			if err != nil {
+				tl.WithError(err).Errorf("Unable to retrieve expired notes.")
+				continue
+			}
+			for _, m := range ms {
+				err = p.ExpireAndEmit(m.Id())
+				if err != nil {
+					tl.WithError(err).Errorf("Unable to expire note [%d].", m.Id())
+				}
+			}
+		}
+	})
+}
+
+func (t *Expiration) SleepTime() time.Duration {
+	return t.interval
+}
+
+// TenantMetrics periodically records how many notes each tenant served holds, whether or not this instance has served
+// it. One instance reports them at a time, the others clearing what they last reported, so no tenant is counted twice.
+type TenantMetrics struct {
+	l        logrus.FieldLogger
+	db       *gorm.DB
+	interval time.Duration
+}
+
+func NewTenantMetricsTask(l logrus.FieldLogger, db *gorm.DB, interval time.Duration) *TenantMetrics {
+	return &TenantMetrics{l: l, db: db, interval: interval}
+}
+
+func (t *TenantMetrics) Run() {
+	sl, ctx, span := tracing.StartSpan(t.l, context.Background(), TenantMetricsTask)
+	defer span.End()
+
+	reported := exclusive(sl, t.db, TenantMetricsTask, func() {
+		ts, err := servedTenants(t.db)
+		if err != nil {
+			sl.WithError(err).Errorf("Unable to retrieve tenants served.")
+			return
+		}
+		for _, te := range ts {
+			tctx := tenant.WithContext(ctx, te)
+			tl := sl.WithField("tenant", te.Id().String())
+			count, err := NewProcessor(tl, tctx, t.db).CountProvider()()
+			if err != nil {
+				tl.WithError(err).Errorf("Unable to count notes.")
+				continue
+			}
+			metrics.SetTenantNotes(te.Id().String(), count)
+		}
+	})
+	if !reported {
+		metrics.ClearTenantNotes()
+	}
+}
+
+func (t *TenantMetrics) SleepTime() time.Duration {
+	return t.interval
+}
+
+// SummaryReconcile periodically recounts the notes of each tenant served, by any instance, correcting summary counters
+// which have drifted from them. One instance reconciles them at a time.
+type SummaryReconcile struct {
+	l        logrus.FieldLogger
+	db       *gorm.DB
+	interval time.Duration
+}
+
+func NewSummaryReconcileTask(l logrus.FieldLogger, db *gorm.DB, interval time.Duration) *SummaryReconcile {
+	return &SummaryReconcile{l: l, db: db, interval: interval}
+}
+
+func (t *SummaryReconcile) Run() {
+	sl, ctx, span := tracing.StartSpan(t.l, context.Background(), SummaryTask)
+	defer span.End()
+
+	exclusive(sl, t.db, SummaryTask, func() {
+		ts, err := servedTenants(t.db)
+		if err != nil {
+			sl.WithError(err).Errorf("Unable to retrieve tenants served.")
+			return
+		}
+		for _, te := range ts {
+			tctx := tenant.WithContext(ctx, te)
+			tl := sl.WithField("tenant", te.Id().String())
+			_, err := NewProcessor(tl, tctx, t.db).ReconcileAndEmit()
+			if err != nil {
+				tl.WithError(err).Errorf("Unable to reconcile note summaries.")
+			}
+		}
+	})
+}
+
+func (t *SummaryReconcile) SleepTime() time.Duration {
+	return t.interval
+}
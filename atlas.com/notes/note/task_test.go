@@ -0,0 +1,70 @@
+package note
+
+import (
+	"atlas-notes/database"
+	"atlas-notes/metrics"
+	"atlas-notes/migrations"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// taskDatabase returns an empty database of its own, so the tenants recorded in it are only those a test records
+func taskDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	l, _ := test.NewNullLogger()
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err = database.Migrate(l, db, migrations.FS, database.LatestVersion); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestServedTenants(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	db := taskDatabase(t)
+	r := NewGormRepository(db)
+
+	// Served by another instance, or before a restart, so this instance's registry does not hold it
+	served, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err := recordTenant(db, served); err != nil {
+		t.Fatalf("Failed to record tenant: %v", err)
+	}
+	// Holding notes from before tenants were recorded
+	unrecorded := uuid.New()
+
+	now := time.Now()
+	for _, tenantId := range []uuid.UUID{served.Id(), unrecorded} {
+		for _, characterId := range []uint32{1, 2} {
+			_, err := r.Create(tenantId, NewBuilder().SetCharacterId(characterId).SetSenderId(3).SetExpiration(now.Add(-time.Minute)).Build())
+			if err != nil {
+				t.Fatalf("Failed to create note: %v", err)
+			}
+		}
+	}
+
+	ts, err := servedTenants(db)
+	if err != nil || len(ts) != 1 || ts[0].Id() != served.Id() || ts[0].Region() != "GMS" || ts[0].MajorVersion() != 83 {
+		t.Fatalf("Expected the tenant recorded, with its region and version, got %v (%v).", ts, err)
+	}
+	ts, err = expiredTenants(l, db, now)
+	if err != nil || len(ts) != 1 || ts[0].Id() != served.Id() {
+		t.Fatalf("Expected the recorded tenant holding expired notes, got %v (%v).", ts, err)
+	}
+	if ts, err = expiredTenants(l, db, now.Add(-time.Hour)); err != nil || len(ts) != 0 {
+		t.Fatalf("Expected no tenant before its notes expire, got %v (%v).", ts, err)
+	}
+
+	NewTenantMetricsTask(l, db, time.Minute).Run()
+	if n := testutil.ToFloat64(metrics.Get().NotesPerTenant.WithLabelValues(served.Id().String())); n != 2 {
+		t.Fatalf("Expected the notes of a tenant this instance has not served to be counted, got %v.", n)
+	}
+}
@@ -0,0 +1,17 @@
+package purge
+
+import (
+	"atlas-notes/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// savePurge records the purge of a tenant's notes, replacing any earlier record for the tenant
+func savePurge(db *gorm.DB) func(m Model) error {
+	return func(m Model) error {
+		entity := MakeEntity(m)
+		return database.ExecuteTransaction(db, func(tx *gorm.DB) error {
+			return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&entity).Error
+		})
+	}
+}
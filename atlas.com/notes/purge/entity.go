@@ -0,0 +1,70 @@
+package purge
+
+import (
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Entity represents the purge of a tenant's notes in the database
+type Entity struct {
+	TenantID     uuid.UUID `gorm:"primaryKey"`
+	Region       string
+	MajorVersion uint16
+	MinorVersion uint16
+	Status       string
+	BatchSize    int
+	EmitEvents   bool
+	Total        int64
+	Purged       int64
+	StartedAt    time.Time
+	UpdatedAt    time.Time `gorm:"autoUpdateTime:false"`
+	CompletedAt  *time.Time
+}
+
+// TableName specifies the database table name for Entity
+func (Entity) TableName() string {
+	return "note_purges"
+}
+
+// Make converts an Entity to a Model domain model
+func Make(e Entity) (Model, error) {
+	t, err := tenant.Create(e.TenantID, e.Region, e.MajorVersion, e.MinorVersion)
+	if err != nil {
+		return Model{}, err
+	}
+	b := NewBuilder(t).
+		SetStatus(e.Status).
+		SetBatchSize(e.BatchSize).
+		SetEmitEvents(e.EmitEvents).
+		SetTotal(e.Total).
+		SetPurged(e.Purged).
+		SetStartedAt(e.StartedAt).
+		SetUpdatedAt(e.UpdatedAt)
+	if e.CompletedAt != nil {
+		b.SetCompletedAt(*e.CompletedAt)
+	}
+	return b.Build(), nil
+}
+
+// MakeEntity converts a Model domain model to an Entity
+func MakeEntity(m Model) Entity {
+	e := Entity{
+		TenantID:     m.Tenant().Id(),
+		Region:       m.Tenant().Region(),
+		MajorVersion: m.Tenant().MajorVersion(),
+		MinorVersion: m.Tenant().MinorVersion(),
+		Status:       m.Status(),
+		BatchSize:    m.BatchSize(),
+		EmitEvents:   m.EmitEvents(),
+		Total:        m.Total(),
+		Purged:       m.Purged(),
+		StartedAt:    m.StartedAt(),
+		UpdatedAt:    m.UpdatedAt(),
+	}
+	if !m.CompletedAt().IsZero() {
+		completedAt := m.CompletedAt()
+		e.CompletedAt = &completedAt
+	}
+	return e
+}
@@ -0,0 +1,176 @@
+package purge
+
+import (
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"time"
+)
+
+const (
+	StatusRunning   = "RUNNING"
+	StatusCompleted = "COMPLETED"
+)
+
+// Model represents the purge of every note in a tenant, and how far it has got
+type Model struct {
+	tenant      tenant.Model
+	status      string
+	batchSize   int
+	emitEvents  bool
+	total       int64
+	purged      int64
+	startedAt   time.Time
+	updatedAt   time.Time
+	completedAt time.Time
+}
+
+// Tenant returns the tenant whose notes are purged
+func (m Model) Tenant() tenant.Model {
+	return m.tenant
+}
+
+// Status returns whether the purge is running or completed
+func (m Model) Status() string {
+	return m.status
+}
+
+// Running returns true if the purge has not completed
+func (m Model) Running() bool {
+	return m.status == StatusRunning
+}
+
+// BatchSize returns how many notes are purged at a time
+func (m Model) BatchSize() int {
+	return m.batchSize
+}
+
+// EmitEvents returns true if a deletion event is emitted for each purged note
+func (m Model) EmitEvents() bool {
+	return m.emitEvents
+}
+
+// Total returns how many notes the tenant held when the purge started
+func (m Model) Total() int64 {
+	return m.total
+}
+
+// Purged returns how many notes have been purged so far
+func (m Model) Purged() int64 {
+	return m.purged
+}
+
+// StartedAt returns when the purge started
+func (m Model) StartedAt() time.Time {
+	return m.startedAt
+}
+
+// UpdatedAt returns when the purge last made progress
+func (m Model) UpdatedAt() time.Time {
+	return m.updatedAt
+}
+
+// CompletedAt returns when the purge completed, or the zero time if it is still running
+func (m Model) CompletedAt() time.Time {
+	return m.completedAt
+}
+
+// Progress returns the purge after another batch of notes was purged
+func (m Model) Progress(purged int, at time.Time) Model {
+	m.purged += int64(purged)
+	m.updatedAt = at
+	return m
+}
+
+// Complete returns the purge once every note was purged
+func (m Model) Complete(at time.Time) Model {
+	m.status = StatusCompleted
+	m.updatedAt = at
+	m.completedAt = at
+	return m
+}
+
+// Builder is a builder for creating Model instances
+type Builder struct {
+	tenant      tenant.Model
+	status      string
+	batchSize   int
+	emitEvents  bool
+	total       int64
+	purged      int64
+	startedAt   time.Time
+	updatedAt   time.Time
+	completedAt time.Time
+}
+
+// NewBuilder creates a new Builder for a running purge of a tenant's notes
+func NewBuilder(t tenant.Model) *Builder {
+	now := time.Now()
+	return &Builder{
+		tenant:    t,
+		status:    StatusRunning,
+		startedAt: now,
+		updatedAt: now,
+	}
+}
+
+// SetStatus sets whether the purge is running or completed
+func (b *Builder) SetStatus(status string) *Builder {
+	b.status = status
+	return b
+}
+
+// SetBatchSize sets how many notes are purged at a time
+func (b *Builder) SetBatchSize(batchSize int) *Builder {
+	b.batchSize = batchSize
+	return b
+}
+
+// SetEmitEvents sets whether a deletion event is emitted for each purged note
+func (b *Builder) SetEmitEvents(emitEvents bool) *Builder {
+	b.emitEvents = emitEvents
+	return b
+}
+
+// SetTotal sets how many notes the tenant held when the purge started
+func (b *Builder) SetTotal(total int64) *Builder {
+	b.total = total
+	return b
+}
+
+// SetPurged sets how many notes have been purged so far
+func (b *Builder) SetPurged(purged int64) *Builder {
+	b.purged = purged
+	return b
+}
+
+// SetStartedAt sets when the purge started
+func (b *Builder) SetStartedAt(startedAt time.Time) *Builder {
+	b.startedAt = startedAt
+	return b
+}
+
+// SetUpdatedAt sets when the purge last made progress
+func (b *Builder) SetUpdatedAt(updatedAt time.Time) *Builder {
+	b.updatedAt = updatedAt
+	return b
+}
+
+// SetCompletedAt sets when the purge completed
+func (b *Builder) SetCompletedAt(completedAt time.Time) *Builder {
+	b.completedAt = completedAt
+	return b
+}
+
+// Build creates a new Model with the builder's values
+func (b *Builder) Build() Model {
+	return Model{
+		tenant:      b.tenant,
+		status:      b.status,
+		batchSize:   b.batchSize,
+		emitEvents:  b.emitEvents,
+		total:       b.total,
+		purged:      b.purged,
+		startedAt:   b.startedAt,
+		updatedAt:   b.updatedAt,
+		completedAt: b.completedAt,
+	}
+}
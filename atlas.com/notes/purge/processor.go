@@ -0,0 +1,224 @@
+package purge
+
+import (
+	"atlas-notes/database"
+	"atlas-notes/kafka/message"
+	"atlas-notes/kafka/message/note"
+	"atlas-notes/kafka/producer"
+	note2 "atlas-notes/note"
+	"context"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	EnvPurgeBatchSize = "NOTE_PURGE_BATCH_SIZE"
+
+	defaultBatchSize = 500
+
+	// stallTimeout is how long a running purge may go without progress before it is presumed interrupted
+	stallTimeout = time.Minute
+)
+
+var (
+	ErrInvalidBatchSize = errors.New("purge batch size must be positive")
+	ErrPurgeInProgress  = errors.New("purge is already running")
+)
+
+type Processor interface {
+	Start(batchSize int, emitEvents bool) (Model, error)
+	Run(m Model) (Model, error)
+	ByTenantProvider() model.Provider[Model]
+}
+
+type ProcessorImpl struct {
+	l        logrus.FieldLogger
+	ctx      context.Context
+	db       *gorm.DB
+	r        Repository
+	np       note2.Processor
+	t        tenant.Model
+	producer producer.Provider
+}
+
+func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+	return &ProcessorImpl{
+		l:        l,
+		ctx:      ctx,
+		db:       db,
+		r:        repository(db),
+		np:       note2.NewProcessor(l, ctx, db),
+		t:        tenant.MustFromContext(ctx),
+		producer: producer.ProviderImpl(l)(ctx),
+	}
+}
+
+// repository returns the Repository to record purges in. As with notes, a nil database holds them in memory.
+func repository(db *gorm.DB) Repository {
+	if db == nil {
+		return getSharedMemoryRepository()
+	}
+	return NewGormRepository(db)
+}
+
+// configuredBatchSize returns how many notes are purged at a time when a purge does not say
+func configuredBatchSize() int {
+	if val, ok := os.LookupEnv(EnvPurgeBatchSize); ok {
+		if s, err := strconv.Atoi(val); err == nil && s > 0 {
+			return s
+		}
+	}
+	return defaultBatchSize
+}
+
+// running tracks the tenants whose notes this process is purging, so a purge is never run twice at once by it. Across
+// instances, the purge is leased through a database lock.
+var running sync.Map
+
+func acquire(tenantId uuid.UUID) bool {
+	_, loaded := running.LoadOrStore(tenantId, struct{}{})
+	return !loaded
+}
+
+func release(tenantId uuid.UUID) {
+	running.Delete(tenantId)
+}
+
+// ByTenantProvider retrieves the purge of the tenant's notes
+func (p *ProcessorImpl) ByTenantProvider() model.Provider[Model] {
+	return func() (Model, error) {
+		return p.r.ByTenantId(p.t.Id())
+	}
+}
+
+// Start begins purging every note in the tenant in the background, returning the purge so its progress can be
+// followed. A batch size of zero uses the configured default. If a purge of the tenant is already running, it is
+// returned instead, and resumed if it was interrupted and no other instance is running it.
+func (p *ProcessorImpl) Start(batchSize int, emitEvents bool) (Model, error) {
+	if batchSize < 0 {
+		return Model{}, ErrInvalidBatchSize
+	}
+	if batchSize == 0 {
+		batchSize = configuredBatchSize()
+	}
+
+	m, err := p.r.ByTenantId(p.t.Id())
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return Model{}, err
+	}
+	if err == nil && m.Running() {
+		go p.runDetached(m)
+		return m, nil
+	}
+
+	total, err := p.np.CountIncludingDeletedProvider()()
+	if err != nil {
+		return Model{}, err
+	}
+	m = NewBuilder(p.t).
+		SetBatchSize(batchSize).
+		SetEmitEvents(emitEvents).
+		SetTotal(total).
+		Build()
+	err = p.r.Save(m)
+	if err != nil {
+		return Model{}, err
+	}
+	p.l.Infof("Starting purge of [%d] notes in batches of [%d].", total, batchSize)
+	go p.runDetached(m)
+	return m, nil
+}
+
+// runDetached runs a purge which outlives the request or message which started it
+func (p *ProcessorImpl) runDetached(m Model) {
+	_, err := NewProcessor(p.l, context.WithoutCancel(p.ctx), p.db).Run(m)
+	if err != nil && !errors.Is(err, ErrPurgeInProgress) {
+		p.l.WithError(err).Errorf("Purge of notes was interrupted, and will be resumed.")
+	}
+}
+
+// Run purges the tenant's notes batch by batch, recording progress after each, until none are left. Each batch removes
+// the notes with the lowest IDs, so a purge which was interrupted carries on from where it stopped when run again. Once
+// no notes are left, the tenant's audit trail is purged too. A purge being run by this or another instance is refused
+// with ErrPurgeInProgress.
+func (p *ProcessorImpl) Run(m Model) (Model, error) {
+	if !acquire(m.Tenant().Id()) {
+		return m, ErrPurgeInProgress
+	}
+	defer release(m.Tenant().Id())
+
+	if p.db == nil {
+		return p.run(m)
+	}
+	result := m
+	locked, err := database.TryLock(p.db, lockName(m.Tenant().Id()), func() error {
+		var err error
+		result, err = p.run(m)
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+	if !locked {
+		return m, ErrPurgeInProgress
+	}
+	return result, nil
+}
+
+// lockName returns the name of the database lock leasing the purge of a tenant's notes to one instance
+func lockName(tenantId uuid.UUID) string {
+	return "note_purge:" + tenantId.String()
+}
+
+// run purges the tenant's notes once the purge is leased. The purge is read again first, as another instance may have
+// made progress on it, or completed it, since m was read.
+func (p *ProcessorImpl) run(m Model) (Model, error) {
+	latest, err := p.r.ByTenantId(m.Tenant().Id())
+	if err == nil {
+		m = latest
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return m, err
+	}
+	if !m.Running() {
+		return m, nil
+	}
+
+	for m.Running() {
+		n, err := p.np.PurgeBatchAndEmit(m.BatchSize(), m.EmitEvents())
+		if err != nil {
+			return m, err
+		}
+		if n > 0 {
+			m = m.Progress(n, time.Now())
+			p.l.Infof("Purged [%d] of [%d] notes.", m.Purged(), m.Total())
+		}
+		if n < m.BatchSize() {
+			err = p.np.PurgeAudit(m.Purged())
+			if err != nil {
+				return m, err
+			}
+			m = m.Complete(time.Now())
+		}
+		err = p.r.Save(m)
+		if err != nil {
+			return m, err
+		}
+	}
+
+	p.l.Infof("Purge of notes completed, [%d] notes purged.", m.Purged())
+	err = message.Emit(p.producer)(func(mb *message.Buffer) error {
+		return mb.Put(note.EnvEventTopicNoteStatus, PurgedStatusEventProvider(m.Purged()))
+	})
+	if err != nil {
+		return m, err
+	}
+	return m, nil
+}
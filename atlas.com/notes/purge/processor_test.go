@@ -0,0 +1,147 @@
+package purge
+
+import (
+	"atlas-notes/audit"
+	"atlas-notes/kafka/producer"
+	"atlas-notes/note"
+	"context"
+	"errors"
+	kproducer "github.com/Chronicle20/atlas-kafka/producer"
+	"github.com/Chronicle20/atlas-model/model"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus/hooks/test"
+	"testing"
+	"time"
+)
+
+// testProcessor returns a processor purging the tenant's notes from an in-memory repository, recording the messages
+// it emits
+func testProcessor(nr *note.MemoryRepository, te tenant.Model) (*ProcessorImpl, *[]kafka.Message) {
+	l, _ := test.NewNullLogger()
+	ctx := tenant.WithContext(context.Background(), te)
+	var emitted []kafka.Message
+	return &ProcessorImpl{
+		l:   l,
+		ctx: ctx,
+		r:   NewMemoryRepository(),
+		np:  note.NewProcessor(l, ctx, nr),
+		t:   te,
+		producer: producer.Provider(func(string) kproducer.MessageProducer {
+			return func(p model.Provider[[]kafka.Message]) error {
+				ms, err := p()
+				emitted = append(emitted, ms...)
+				return err
+			}
+		}),
+	}, &emitted
+}
+
+func seedNotes(t *testing.T, nr *note.MemoryRepository, tenantId uuid.UUID, count int) {
+	for i := 0; i < count; i++ {
+		m, err := nr.Create(tenantId, note.NewBuilder().SetCharacterId(uint32(i%3)+1).SetSenderId(9).Build())
+		if err != nil {
+			t.Fatalf("Failed to create note: %v", err)
+		}
+		if i%4 == 0 {
+			if err = nr.Delete(tenantId, m.Id()); err != nil {
+				t.Fatalf("Failed to delete note: %v", err)
+			}
+		}
+	}
+}
+
+func TestProcessorImpl_Run(t *testing.T) {
+	te, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	other := uuid.New()
+	nr := note.NewMemoryRepository()
+	seedNotes(t, nr, te.Id(), 7)
+	seedNotes(t, nr, other, 2)
+	entry := audit.NewBuilder().SetNoteId(1).SetCharacterId(1).SetAction(audit.ActionCreate).SetActor(audit.NewActor(audit.ActorTypeSystem, "test")).SetTransactionId(uuid.New()).Build()
+	for _, id := range []uuid.UUID{te.Id(), other} {
+		if err := nr.Audit().Append(id, entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	p, emitted := testProcessor(nr, te)
+	m, err := p.Run(NewBuilder(te).SetBatchSize(3).SetTotal(7).Build())
+	if err != nil {
+		t.Fatalf("Failed to purge notes: %v", err)
+	}
+	if m.Running() || m.Purged() != 7 || m.CompletedAt().IsZero() {
+		t.Fatalf("Expected a completed purge of 7 notes, got %s with %d purged", m.Status(), m.Purged())
+	}
+	if c, _ := nr.CountIncludingDeleted(te.Id()); c != 0 {
+		t.Fatalf("Expected every note in the tenant to be purged, %d remain", c)
+	}
+	if c, _ := nr.CountIncludingDeleted(other); c != 2 {
+		t.Fatalf("Expected notes of other tenants to be kept, %d remain", c)
+	}
+	if as, _ := nr.Audit().ByNoteId(te.Id(), 1); len(as) != 0 {
+		t.Fatalf("Expected the tenant's audit trail to be purged, %d entries remain", len(as))
+	}
+	if as, _ := nr.Audit().ByNoteId(te.Id(), 0); len(as) != 1 || as[0].Action() != audit.ActionPurge || string(as[0].After()) != `{"purged":7}` {
+		t.Fatalf("Expected the purge to be recorded in place of the trail, got %d entries", len(as))
+	}
+	if as, _ := nr.Audit().ByNoteId(other, 1); len(as) != 1 {
+		t.Fatalf("Expected audit trails of other tenants to be kept, %d entries remain", len(as))
+	}
+	if len(*emitted) != 1 {
+		t.Fatalf("Expected a single purged event, got %d messages", len(*emitted))
+	}
+
+	saved, err := p.r.ByTenantId(te.Id())
+	if err != nil || saved.Status() != StatusCompleted {
+		t.Fatalf("Expected the completed purge to be recorded, got %v", err)
+	}
+}
+
+func TestProcessorImpl_Resume(t *testing.T) {
+	te, _ := tenant.Create(uuid.New(), "GMS", 83, 1)
+	nr := note.NewMemoryRepository()
+	seedNotes(t, nr, te.Id(), 10)
+	p, _ := testProcessor(nr, te)
+
+	// A purge interrupted after its first batch is left running, without progress since.
+	n, err := p.np.PurgeBatchAndEmit(4, false)
+	if err != nil || n != 4 {
+		t.Fatalf("Failed to purge first batch: %v", err)
+	}
+	interrupted := time.Now().Add(-2 * stallTimeout)
+	m := NewBuilder(te).SetBatchSize(4).SetTotal(10).SetPurged(4).SetStartedAt(interrupted).SetUpdatedAt(interrupted).Build()
+	if err = p.r.Save(m); err != nil {
+		t.Fatalf("Failed to record purge: %v", err)
+	}
+
+	ms, err := p.r.Stalled(time.Now().Add(-stallTimeout))
+	if err != nil || len(ms) != 1 {
+		t.Fatalf("Expected the interrupted purge to be stalled, got %d (%v)", len(ms), err)
+	}
+
+	// The purge is only run once at a time.
+	stale := ms[0]
+	acquire(te.Id())
+	if _, err = p.Run(ms[0]); !errors.Is(err, ErrPurgeInProgress) {
+		t.Fatalf("Expected a purge already running to be refused, got %v", err)
+	}
+	release(te.Id())
+
+	m, err = p.Run(ms[0])
+	if err != nil {
+		t.Fatalf("Failed to resume purge: %v", err)
+	}
+	if m.Running() || m.Purged() != 10 {
+		t.Fatalf("Expected the resumed purge to complete with 10 notes purged, got %s with %d", m.Status(), m.Purged())
+	}
+	if ms, _ = p.r.Stalled(time.Now()); len(ms) != 0 {
+		t.Fatalf("Expected no stalled purges once completed")
+	}
+
+	// A purge read before it completed is not run again.
+	m, err = p.Run(stale)
+	if err != nil || m.Running() || m.Purged() != 10 {
+		t.Fatalf("Expected the completed purge to be returned as it is, got %s with %d, %v", m.Status(), m.Purged(), err)
+	}
+}
@@ -0,0 +1,20 @@
+package purge
+
+import (
+	"atlas-notes/kafka/message/note"
+	"github.com/Chronicle20/atlas-kafka/producer"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/segmentio/kafka-go"
+)
+
+// PurgedStatusEventProvider creates a status event for every note in the tenant having been purged
+func PurgedStatusEventProvider(purged int64) model.Provider[[]kafka.Message] {
+	key := producer.CreateKey(0)
+	value := note.StatusEvent[note.StatusEventPurgedBody]{
+		Type: note.StatusEventTypePurged,
+		Body: note.StatusEventPurgedBody{
+			Purged: purged,
+		},
+	}
+	return producer.SingleMessageProvider(key, value)
+}
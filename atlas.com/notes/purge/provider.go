@@ -0,0 +1,34 @@
+package purge
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// getByTenantIdProvider returns a provider for the purge of a tenant's notes
+func getByTenantIdProvider(tenantId uuid.UUID) database.EntityProvider[Entity] {
+	return func(db *gorm.DB) model.Provider[Entity] {
+		var entity Entity
+		err := db.Where("tenant_id = ?", tenantId).First(&entity).Error
+		if err != nil {
+			return model.ErrorProvider[Entity](err)
+		}
+		return model.FixedProvider(entity)
+	}
+}
+
+// getStalledProvider returns a provider for the running purges, in every tenant, which have made no progress since the
+// given time
+func getStalledProvider(since time.Time) database.EntityProvider[[]Entity] {
+	return func(db *gorm.DB) model.Provider[[]Entity] {
+		var entities []Entity
+		err := db.Where("status = ? AND updated_at < ?", StatusRunning, since).Find(&entities).Error
+		if err != nil {
+			return model.ErrorProvider[[]Entity](err)
+		}
+		return model.FixedProvider(entities)
+	}
+}
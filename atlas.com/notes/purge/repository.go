@@ -0,0 +1,90 @@
+package purge
+
+import (
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// Repository stores the purges of tenants' notes. A tenant without a purge is reported with gorm.ErrRecordNotFound.
+type Repository interface {
+	// ByTenantId returns the purge of a tenant's notes
+	ByTenantId(tenantId uuid.UUID) (Model, error)
+	// Stalled returns the running purges, in every tenant, which have made no progress since the given time
+	Stalled(since time.Time) ([]Model, error)
+	// Save records the purge of a tenant's notes, replacing any earlier record for the tenant
+	Save(m Model) error
+}
+
+// GormRepository is a Repository backed by a SQL database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) ByTenantId(tenantId uuid.UUID) (Model, error) {
+	return model.Map[Entity, Model](Make)(getByTenantIdProvider(tenantId)(r.db))()
+}
+
+func (r *GormRepository) Stalled(since time.Time) ([]Model, error) {
+	return model.SliceMap[Entity, Model](Make)(getStalledProvider(since)(r.db))()()
+}
+
+func (r *GormRepository) Save(m Model) error {
+	return savePurge(r.db)(m)
+}
+
+// MemoryRepository is a concurrency-safe Repository holding purges in memory, for running without a database
+type MemoryRepository struct {
+	mutex  sync.RWMutex
+	purges map[uuid.UUID]Model
+}
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{purges: make(map[uuid.UUID]Model)}
+}
+
+var sharedMemory *MemoryRepository
+var sharedMemoryOnce sync.Once
+
+// getSharedMemoryRepository returns the in-memory repository shared by processors created without a database
+func getSharedMemoryRepository() *MemoryRepository {
+	sharedMemoryOnce.Do(func() {
+		sharedMemory = NewMemoryRepository()
+	})
+	return sharedMemory
+}
+
+func (r *MemoryRepository) ByTenantId(tenantId uuid.UUID) (Model, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	m, ok := r.purges[tenantId]
+	if !ok {
+		return Model{}, gorm.ErrRecordNotFound
+	}
+	return m, nil
+}
+
+func (r *MemoryRepository) Stalled(since time.Time) ([]Model, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	results := make([]Model, 0)
+	for _, m := range r.purges {
+		if m.Running() && m.UpdatedAt().Before(since) {
+			results = append(results, m)
+		}
+	}
+	return results, nil
+}
+
+func (r *MemoryRepository) Save(m Model) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.purges[m.Tenant().Id()] = m
+	return nil
+}
@@ -0,0 +1,79 @@
+package purge
+
+import (
+	"atlas-notes/auth"
+	"atlas-notes/rest"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/gorilla/mux"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// InitResource registers the purge routes. They must be registered before the note routes, whose /notes/{noteId}
+// would otherwise match them.
+func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteInitializer {
+	return func(db *gorm.DB) server.RouteInitializer {
+		return func(router *mux.Router, l logrus.FieldLogger) {
+			registerHandler := rest.RegisterHandler(l)(db)(si)
+			registerInputHandler := rest.RegisterInputHandler[RestModel](l)(db)(si)
+
+			// Purge every note in the tenant
+			router.HandleFunc("/notes/purge", registerInputHandler("purge_tenant_notes", PurgeTenantNotesHandler, auth.RoleAdmin)).Methods(http.MethodPost)
+
+			// Progress of the purge of the tenant's notes
+			router.HandleFunc("/notes/purge", registerHandler("get_tenant_notes_purge", GetTenantNotesPurgeHandler, auth.RoleAdmin)).Methods(http.MethodGet)
+		}
+	}
+}
+
+// PurgeTenantNotesHandler handles POST /api/notes/purge
+func PurgeTenantNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext, i RestModel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, err := NewProcessor(d.Logger(), d.Context(), d.DB()).Start(i.BatchSize, !i.SkipEvents)
+		if err != nil {
+			d.Logger().WithError(err).Errorln("Error starting purge of notes")
+			if errors.Is(err, ErrInvalidBatchSize) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rm, err := model.Map(Transform)(model.FixedProvider(m))()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Creating REST model.")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		queryParams := jsonapi.ParseQueryFields(&query)
+		w.WriteHeader(http.StatusAccepted)
+		server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+	}
+}
+
+// GetTenantNotesPurgeHandler handles GET /api/notes/purge
+func GetTenantNotesPurgeHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rm, err := model.Map(Transform)(NewProcessor(d.Logger(), d.Context(), d.DB()).ByTenantProvider())()
+		if err != nil {
+			d.Logger().WithError(err).Errorf("Creating REST model.")
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		queryParams := jsonapi.ParseQueryFields(&query)
+		server.MarshalResponse[RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+	}
+}
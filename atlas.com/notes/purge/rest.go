@@ -0,0 +1,62 @@
+package purge
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// RestModel is the JSON:API resource for the purge of a tenant's notes. On input only the batch size and whether to
+// skip events are read.
+type RestModel struct {
+	Id          uuid.UUID  `json:"-"`
+	Status      string     `json:"status"`
+	BatchSize   int        `json:"batchSize"`
+	SkipEvents  bool       `json:"skipEvents"`
+	Total       int64      `json:"total"`
+	Purged      int64      `json:"purged"`
+	StartedAt   time.Time  `json:"startedAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// GetID returns the resource ID
+func (r RestModel) GetID() string {
+	return r.Id.String()
+}
+
+// SetID sets the resource ID
+func (r *RestModel) SetID(strId string) error {
+	if strId == "" {
+		return nil
+	}
+	id, err := uuid.Parse(strId)
+	if err != nil {
+		return err
+	}
+	r.Id = id
+	return nil
+}
+
+// GetName returns the resource name
+func (r RestModel) GetName() string {
+	return "purges"
+}
+
+// Transform converts a Model domain model to a RestModel
+func Transform(m Model) (RestModel, error) {
+	rm := RestModel{
+		Id:         m.Tenant().Id(),
+		Status:     m.Status(),
+		BatchSize:  m.BatchSize(),
+		SkipEvents: !m.EmitEvents(),
+		Total:      m.Total(),
+		Purged:     m.Purged(),
+		StartedAt:  m.StartedAt(),
+		UpdatedAt:  m.UpdatedAt(),
+	}
+	if !m.CompletedAt().IsZero() {
+		completedAt := m.CompletedAt()
+		rm.CompletedAt = &completedAt
+	}
+	return rm, nil
+}
@@ -0,0 +1,48 @@
+package purge
+
+import (
+	"atlas-notes/tracing"
+	"context"
+	"errors"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+const ResumeTask = "note_purge_resume_task"
+
+// Resume periodically resumes purges which stopped making progress, such as those interrupted by a restart
+type Resume struct {
+	l        logrus.FieldLogger
+	db       *gorm.DB
+	interval time.Duration
+}
+
+func NewResumeTask(l logrus.FieldLogger, db *gorm.DB, interval time.Duration) *Resume {
+	return &Resume{l: l, db: db, interval: interval}
+}
+
+func (t *Resume) Run() {
+	sl, ctx, span := tracing.StartSpan(t.l, context.Background(), ResumeTask)
+	defer span.End()
+
+	ms, err := repository(t.db).Stalled(time.Now().Add(-stallTimeout))
+	if err != nil {
+		sl.WithError(err).Errorf("Unable to retrieve stalled purges.")
+		return
+	}
+	for _, m := range ms {
+		tctx := tenant.WithContext(ctx, m.Tenant())
+		tl := sl.WithField("tenant", m.Tenant().Id().String())
+		tl.Infof("Resuming purge of notes after [%d] of [%d] were purged.", m.Purged(), m.Total())
+		_, err = NewProcessor(tl, tctx, t.db).Run(m)
+		if err != nil && !errors.Is(err, ErrPurgeInProgress) {
+			tl.WithError(err).Errorf("Unable to resume purge of notes.")
+		}
+	}
+}
+
+func (t *Resume) SleepTime() time.Duration {
+	return t.interval
+}
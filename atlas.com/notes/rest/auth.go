@@ -0,0 +1,150 @@
+package rest
+
+import (
+	"atlas-notes/audit"
+	"atlas-notes/auth"
+	"context"
+	"encoding/json"
+	"errors"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// HeaderCallerId identifies the caller of a request when authentication is disabled, to whom the changes it makes are
+// attributed in the audit trail
+const HeaderCallerId = "X-Caller-Id"
+
+// withActor attributes the changes a request makes to the subject of its token. Without one, they are attributed to
+// the caller the request names, or else its remote address.
+func withActor(ctx context.Context, r *http.Request) context.Context {
+	if p, ok := auth.PrincipalFromContext(ctx); ok {
+		return audit.WithActor(ctx, audit.NewActor(audit.ActorTypeRest, p.Subject()))
+	}
+	caller := r.Header.Get(HeaderCallerId)
+	if caller == "" {
+		caller = r.RemoteAddr
+	}
+	return audit.WithActor(ctx, audit.NewActor(audit.ActorTypeRest, caller))
+}
+
+// authenticate verifies the bearer token of a request, and that its principal holds one of the roles the route
+// requires, before handing the request to next with the principal, and any character it acts on behalf of, in its
+// context. Requests without a valid token are refused with 401, those lacking the role, or acting for a character they
+// may not, with 403, and both are recorded in the audit trail. While authentication is disabled, every request is let
+// through.
+func authenticate(l logrus.FieldLogger, ctx context.Context, db *gorm.DB, handlerName string, roles []auth.Role, next func(ctx context.Context) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serve := func(ctx context.Context) {
+			actx, err := withActingCharacter(ctx, r)
+			if errors.Is(err, auth.ErrNoCharacter) || errors.Is(err, auth.ErrOtherCharacter) {
+				deny(l, withActor(ctx, r), db, handlerName, r, err)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				l.WithError(err).Errorf("Unable to properly parse acting character from header.")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			next(withActor(actx, r))(w, r)
+		}
+
+		v := auth.GetVerifier()
+		if v == nil {
+			serve(ctx)
+			return
+		}
+
+		p, err := v.VerifyRequest(r)
+		if err != nil {
+			deny(l, withActor(ctx, r), db, handlerName, r, err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		pctx := auth.WithPrincipal(ctx, p)
+		if !p.Permits(roles...) {
+			deny(l, withActor(pctx, r), db, handlerName, r, auth.ErrForbidden)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		serve(pctx)
+	}
+}
+
+// withActingCharacter acts on behalf of the character a request is made for, if any. A token naming a character acts
+// for it alone, and a request naming another in its header is refused. The header is otherwise trusted only from
+// services and administrators, the only principals which may act for no character at all. While authentication is
+// disabled, the header is trusted.
+func withActingCharacter(ctx context.Context, r *http.Request) (context.Context, error) {
+	val := r.Header.Get(auth.HeaderActingCharacterId)
+	p, authenticated := auth.PrincipalFromContext(ctx)
+	if characterId, ok := p.Character(); authenticated && ok {
+		if val != "" {
+			if named, err := auth.ParseActingCharacter(val); err != nil || named != characterId {
+				return ctx, auth.ErrOtherCharacter
+			}
+		}
+		return auth.WithActingCharacter(ctx, characterId), nil
+	}
+	if authenticated && !p.Trusted() {
+		return ctx, auth.ErrNoCharacter
+	}
+	if val == "" {
+		return ctx, nil
+	}
+	characterId, err := auth.ParseActingCharacter(val)
+	if err != nil {
+		return ctx, err
+	}
+	return auth.WithActingCharacter(ctx, characterId), nil
+}
+
+// denial is the request a DENY entry of the audit trail records
+type denial struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Handler string `json:"handler"`
+	Reason  string `json:"reason"`
+}
+
+// deny records a refused request in the audit trail, against the note and character in its path if any. Without a
+// database, the denial is only logged.
+func deny(l logrus.FieldLogger, ctx context.Context, db *gorm.DB, handlerName string, r *http.Request, reason error) {
+	a := audit.ActorFromContext(ctx)
+	l.WithError(reason).WithFields(logrus.Fields{"actor": a.Id(), "method": r.Method, "path": r.URL.Path}).Warnf("Denied request.")
+	if db == nil {
+		return
+	}
+
+	body, err := json.Marshal(denial{Method: r.Method, Path: r.URL.Path, Handler: handlerName, Reason: reason.Error()})
+	if err != nil {
+		l.WithError(err).Errorf("Unable to record denied request in audit trail.")
+		return
+	}
+	m := audit.NewBuilder().
+		SetNoteId(pathId(r, "noteId")).
+		SetCharacterId(pathId(r, "characterId")).
+		SetAction(audit.ActionDeny).
+		SetActor(a).
+		SetTransactionId(uuid.New()).
+		SetAfter(body).
+		Build()
+	if err = audit.NewGormRepository(db.WithContext(ctx)).Append(tenant.MustFromContext(ctx).Id(), m); err != nil {
+		l.WithError(err).Errorf("Unable to record denied request in audit trail.")
+	}
+}
+
+// pathId returns the ID a path variable holds, or 0 when the route has none
+func pathId(r *http.Request, name string) uint32 {
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(id)
+}
@@ -0,0 +1,207 @@
+package rest_test
+
+import (
+	"atlas-notes/audit"
+	"atlas-notes/auth"
+	"atlas-notes/rest"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testServer struct{}
+
+func (s testServer) GetBaseURL() string {
+	return ""
+}
+
+func (s testServer) GetPrefix() string {
+	return "/api/"
+}
+
+// testRouter serves a route requiring the admin role, recording the actor each request it lets through is made by
+func testRouter(t *testing.T, actor *audit.Actor) *mux.Router {
+	t.Helper()
+	l, _ := test.NewNullLogger()
+	var db *gorm.DB
+	handler := func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*actor = audit.ActorFromContext(d.Context())
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/characters/{characterId}/notes", rest.RegisterHandler(l)(db)(testServer{})("delete_character_notes", handler, auth.RoleAdmin)).Methods(http.MethodDelete)
+	return router
+}
+
+func testRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodDelete, "/characters/1/notes", nil)
+	r.Header.Set("TENANT_ID", "083839c6-c47c-42a6-9585-76492795d123")
+	r.Header.Set("REGION", "GMS")
+	r.Header.Set("MAJOR_VERSION", "83")
+	r.Header.Set("MINOR_VERSION", "1")
+	r.Header.Set(rest.HeaderCallerId, "gm-tool")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	l, _ := test.NewNullLogger()
+	v, err := auth.NewVerifier(l)(context.Background(), auth.SetKeyfunc(func(*jwt.Token) (any, error) {
+		return &key.PublicKey, nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	auth.Use(v)
+	t.Cleanup(func() {
+		auth.Use(nil)
+	})
+	sign := func(subject string, roles ...string) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub":   subject,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"roles": roles,
+		}).SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return s
+	}
+
+	var actor audit.Actor
+	router := testRouter(t, &actor)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, testRequest(""))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("Expected a request without a token to be refused with 401, got [%d].", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, testRequest(sign("atlas-channel", "service")))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected a request without the role to be refused with 403, got [%d].", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, testRequest(sign("admin-console", "admin")))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected a request holding the role to be handled, got [%d].", w.Code)
+	}
+	if actor.Type() != audit.ActorTypeRest || actor.Id() != "admin-console" {
+		t.Fatalf("Expected changes to be attributed to the token subject rather than the caller named, got [%s].", actor.Id())
+	}
+}
+
+func TestAuthenticateActingCharacter(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	l, _ := test.NewNullLogger()
+	v, err := auth.NewVerifier(l)(context.Background(), auth.SetKeyfunc(func(*jwt.Token) (any, error) {
+		return &key.PublicKey, nil
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+	auth.Use(v)
+	t.Cleanup(func() {
+		auth.Use(nil)
+	})
+	sign := func(subject string, characterId any, roles ...string) string {
+		c := jwt.MapClaims{
+			"sub":   subject,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"roles": roles,
+		}
+		if characterId != nil {
+			c["character_id"] = characterId
+		}
+		s, err := jwt.NewWithClaims(jwt.SigningMethodRS256, c).SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return s
+	}
+
+	var acting *auth.ActingCharacter
+	var db *gorm.DB
+	handler := func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			acting = nil
+			if a, ok := auth.ActingCharacterFromContext(d.Context()); ok {
+				acting = &a
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/characters/{characterId}/notes", rest.RegisterHandler(l)(db)(testServer{})("delete_character_notes", handler)).Methods(http.MethodDelete)
+	serve := func(token string, header string) int {
+		r := testRequest(token)
+		if header != "" {
+			r.Header.Set(auth.HeaderActingCharacterId, header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(sign("player-1", nil), ""); code != http.StatusForbidden {
+		t.Fatalf("Expected a player acting for no character to be refused with 403, got [%d].", code)
+	}
+	if code := serve(sign("player-1", nil), "1"); code != http.StatusForbidden {
+		t.Fatalf("Expected a player's header not to be trusted, got [%d].", code)
+	}
+	if code := serve(sign("player-1", 1), "2"); code != http.StatusForbidden {
+		t.Fatalf("Expected a player naming another character to be refused with 403, got [%d].", code)
+	}
+	if code := serve(sign("player-1", 1), ""); code != http.StatusNoContent || acting == nil || !acting.Is(1) {
+		t.Fatalf("Expected a player to act for the character its token names, got [%d].", code)
+	}
+	if code := serve(sign("atlas-channel", nil, "service"), "3"); code != http.StatusNoContent || acting == nil || !acting.Is(3) {
+		t.Fatalf("Expected a service to act for the character its header names, got [%d].", code)
+	}
+	if code := serve(sign("admin-console", nil, "admin"), ""); code != http.StatusNoContent || acting != nil {
+		t.Fatalf("Expected an administrator to act for no character, got [%d].", code)
+	}
+}
+
+func TestAuthenticateDisabled(t *testing.T) {
+	auth.Use(nil)
+
+	var actor audit.Actor
+	w := httptest.NewRecorder()
+	testRouter(t, &actor).ServeHTTP(w, testRequest(""))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected requests to be let through while authentication is disabled, got [%d].", w.Code)
+	}
+	if actor.Id() != "gm-tool" {
+		t.Fatalf("Expected changes to be attributed to the caller named, got [%s].", actor.Id())
+	}
+
+	r := testRequest("")
+	r.Header.Set(auth.HeaderActingCharacterId, "zero")
+	w = httptest.NewRecorder()
+	testRouter(t, &actor).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a malformed acting character to be refused with 400, got [%d].", w.Code)
+	}
+}
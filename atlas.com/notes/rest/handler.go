@@ -1,6 +1,8 @@
 package rest
 
 import (
+	"atlas-notes/auth"
+	"atlas-notes/metrics"
 	"context"
 	"github.com/Chronicle20/atlas-rest/server"
 	"github.com/gorilla/mux"
@@ -63,31 +65,39 @@ func ParseInput[M any](d *HandlerDependency, c *HandlerContext, next InputHandle
 	}
 }
 
-func RegisterHandler(l logrus.FieldLogger) func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler GetHandler) http.HandlerFunc {
-	return func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler GetHandler) http.HandlerFunc {
-		return func(si jsonapi.ServerInformation) func(handlerName string, handler GetHandler) http.HandlerFunc {
-			return func(handlerName string, handler GetHandler) http.HandlerFunc {
-				return server.RetrieveSpan(l, handlerName, context.Background(), func(sl logrus.FieldLogger, sctx context.Context) http.HandlerFunc {
+// RegisterHandler wraps a handler with tracing, metrics, tenant parsing and authentication. A caller must hold one of
+// the roles given, if any.
+func RegisterHandler(l logrus.FieldLogger) func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler GetHandler, roles ...auth.Role) http.HandlerFunc {
+	return func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler GetHandler, roles ...auth.Role) http.HandlerFunc {
+		return func(si jsonapi.ServerInformation) func(handlerName string, handler GetHandler, roles ...auth.Role) http.HandlerFunc {
+			return func(handlerName string, handler GetHandler, roles ...auth.Role) http.HandlerFunc {
+				return metrics.InstrumentHandler(handlerName, server.RetrieveSpan(l, handlerName, context.Background(), func(sl logrus.FieldLogger, sctx context.Context) http.HandlerFunc {
 					fl := sl.WithFields(logrus.Fields{"originator": handlerName, "type": "rest_handler"})
 					return server.ParseTenant(fl, sctx, func(tl logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
-						return handler(&HandlerDependency{l: tl, db: db, ctx: tctx}, &HandlerContext{si: si})
+						return authenticate(tl, tctx, db, handlerName, roles, func(actx context.Context) http.HandlerFunc {
+							return handler(&HandlerDependency{l: tl, db: db, ctx: actx}, &HandlerContext{si: si})
+						})
 					})
-				})
+				}))
 			}
 		}
 	}
 }
 
-func RegisterInputHandler[M any](l logrus.FieldLogger) func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler InputHandler[M]) http.HandlerFunc {
-	return func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler InputHandler[M]) http.HandlerFunc {
-		return func(si jsonapi.ServerInformation) func(handlerName string, handler InputHandler[M]) http.HandlerFunc {
-			return func(handlerName string, handler InputHandler[M]) http.HandlerFunc {
-				return server.RetrieveSpan(l, handlerName, context.Background(), func(sl logrus.FieldLogger, sctx context.Context) http.HandlerFunc {
+// RegisterInputHandler wraps a handler of a request body as RegisterHandler does, authenticating the caller before the
+// body is read
+func RegisterInputHandler[M any](l logrus.FieldLogger) func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler InputHandler[M], roles ...auth.Role) http.HandlerFunc {
+	return func(db *gorm.DB) func(si jsonapi.ServerInformation) func(handlerName string, handler InputHandler[M], roles ...auth.Role) http.HandlerFunc {
+		return func(si jsonapi.ServerInformation) func(handlerName string, handler InputHandler[M], roles ...auth.Role) http.HandlerFunc {
+			return func(handlerName string, handler InputHandler[M], roles ...auth.Role) http.HandlerFunc {
+				return metrics.InstrumentHandler(handlerName, server.RetrieveSpan(l, handlerName, context.Background(), func(sl logrus.FieldLogger, sctx context.Context) http.HandlerFunc {
 					fl := sl.WithFields(logrus.Fields{"originator": handlerName, "type": "rest_handler"})
 					return server.ParseTenant(fl, sctx, func(tl logrus.FieldLogger, tctx context.Context) http.HandlerFunc {
-						return ParseInput[M](&HandlerDependency{l: tl, db: db, ctx: tctx}, &HandlerContext{si: si}, handler)
+						return authenticate(tl, tctx, db, handlerName, roles, func(actx context.Context) http.HandlerFunc {
+							return ParseInput[M](&HandlerDependency{l: tl, db: db, ctx: actx}, &HandlerContext{si: si}, handler)
+						})
 					})
-				})
+				}))
 			}
 		}
 	}
@@ -2,6 +2,8 @@ package retry
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
 	"time"
 )
 
@@ -20,4 +22,46 @@ func Try(fn TryFunc, retries int) error {
 		}
 		time.Sleep(1 * time.Second)
 	}
-}
\ No newline at end of file
+}
+
+// Backoff is the delay between attempts. It starts at Initial and doubles after each failed attempt, up to Max. Each
+// delay is jittered down by up to half, so many callers failing together do not retry in lockstep.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// delay returns the delay to wait after the given failed attempt
+func (b Backoff) delay(attempt int) time.Duration {
+	d := b.Initial
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// TryWithBackoff calls fn until it succeeds, it reports the error should not be retried, or retries attempts have
+// failed, waiting out the backoff between attempts. The last error is returned if fn never succeeds.
+func TryWithBackoff(fn TryFunc, retries int, b Backoff) error {
+	attempt := 1
+	for {
+		cont, err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		if !cont {
+			return err
+		}
+		if attempt >= retries {
+			return fmt.Errorf("max retry reached: %w", err)
+		}
+		time.Sleep(b.delay(attempt))
+		attempt++
+	}
+}
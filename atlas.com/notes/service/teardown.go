@@ -2,60 +2,214 @@ package service
 
 import (
 	"context"
+	"github.com/sirupsen/logrus"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 )
 
+// Phase is a step of teardown. Phases run in order, each once the one before has finished or run out of time.
+type Phase int
+
+const (
+	// PhaseRest stops accepting REST requests and finishes those in progress
+	PhaseRest Phase = iota
+	// PhaseConsumers stops the Kafka consumers and background tasks, finishing the messages and runs in progress
+	PhaseConsumers
+	// PhaseProducers waits for messages being produced to be written
+	PhaseProducers
+	// PhaseDatabase closes the database connections
+	PhaseDatabase
+	// PhaseTracer flushes and closes the tracer
+	PhaseTracer
+	// PhaseProbes stops serving health probes and metrics, which report the service unready throughout teardown
+	PhaseProbes
+)
+
+var phaseNames = []string{"rest", "consumers", "producers", "database", "tracer", "probes"}
+
+// defaultDeadlines fit within the 30 seconds an orchestrator usually allows between SIGTERM and SIGKILL
+var defaultDeadlines = []time.Duration{5 * time.Second, 10 * time.Second, 5 * time.Second, 3 * time.Second, 5 * time.Second, time.Second}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return "unknown"
+	}
+	return phaseNames[p]
+}
+
+// waiter is something a phase waits for: a function it runs, or goroutines tracked by a wait group
+type waiter struct {
+	name string
+	wait func()
+}
+
+type phase struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	deadline time.Duration
+	started  bool
+	waiters  []waiter
+}
+
 type Manager struct {
-	termChan  chan os.Signal
-	doneChan  chan struct{}
-	waitGroup *sync.WaitGroup
-	context   context.Context
-	cancel    context.CancelFunc
+	termChan    chan os.Signal
+	context     context.Context
+	cancel      context.CancelFunc
+	tearingDown atomic.Bool
+	mu          sync.Mutex
+	phases      []*phase
+	once        sync.Once
 }
 
 var manager *Manager
 var once sync.Once
 
+// GetTeardownManager returns the manager of the process, which tears the service down on SIGTERM or an interrupt
 func GetTeardownManager() *Manager {
 	once.Do(func() {
-		ctx, cancel := context.WithCancel(context.Background())
-
-		manager = &Manager{
-			termChan:  make(chan os.Signal),
-			doneChan:  make(chan struct{}),
-			waitGroup: &sync.WaitGroup{},
-			context:   ctx,
-			cancel:    cancel,
-		}
-
+		manager = NewManager()
 		signal.Notify(manager.termChan, os.Interrupt, os.Kill, syscall.SIGTERM, syscall.SIGHUP)
 	})
 	return manager
 }
 
-func (m *Manager) TeardownFunc(f func()) {
-	go func() {
-		m.waitGroup.Add(1)
-		defer m.waitGroup.Done()
-		<-m.doneChan
+// NewManager creates a manager which tears down when Teardown is called, or a signal is sent to it
+func NewManager() *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		termChan: make(chan os.Signal, 1),
+		context:  ctx,
+		cancel:   cancel,
+	}
+	for p := range phaseNames {
+		pctx, pcancel := context.WithCancel(context.Background())
+		m.phases = append(m.phases, &phase{ctx: pctx, cancel: pcancel, deadline: defaultDeadlines[p]})
+	}
+	return m
+}
+
+func (m *Manager) phase(p Phase) *phase {
+	return m.phases[p]
+}
+
+// SetDeadline sets how long a phase may take before teardown moves on to the next
+func (m *Manager) SetDeadline(p Phase, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.phase(p).deadline = d
+}
+
+// TeardownFunc registers f to run when the phase begins. Functions of a phase run concurrently. A function registered
+// once its phase has begun runs at once, before TeardownFunc returns.
+func (m *Manager) TeardownFunc(p Phase, name string, f func()) {
+	m.mu.Lock()
+	ph := m.phase(p)
+	if ph.started {
+		m.mu.Unlock()
 		f()
-	}()
+		return
+	}
+	ph.waiters = append(ph.waiters, waiter{name: name, wait: f})
+	m.mu.Unlock()
 }
 
-func (m *Manager) Wait() {
-	<-m.termChan
-	close(m.doneChan)
-	m.cancel()
-	m.waitGroup.Wait()
+// PhaseContext returns a context which is cancelled when the phase begins, for work to be stopped by it
+func (m *Manager) PhaseContext(p Phase) context.Context {
+	return m.phase(p).ctx
 }
 
-func (m *Manager) WaitGroup() *sync.WaitGroup {
-	return m.waitGroup
+// PhaseWaitGroup returns a wait group which the phase waits for once it has cancelled its context. Goroutines stopped by
+// the phase add themselves to it before they are started. The name identifies them should they fail to stop in time.
+func (m *Manager) PhaseWaitGroup(p Phase, name string) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	m.TeardownFunc(p, name, wg.Wait)
+	return wg
 }
 
+// Context returns a context which is cancelled as soon as teardown begins
 func (m *Manager) Context() context.Context {
 	return m.context
 }
+
+// Wait blocks until the service is told to shut down, then tears it down
+func (m *Manager) Wait(l logrus.FieldLogger) {
+	<-m.termChan
+	m.Teardown(l)
+}
+
+// Teardown runs each phase in turn, returning once all have finished or run out of time. Only the first call tears
+// down; later calls return at once.
+func (m *Manager) Teardown(l logrus.FieldLogger) {
+	m.once.Do(func() {
+		m.tearingDown.Store(true)
+		m.cancel()
+		for p := range m.phases {
+			m.runPhase(l, Phase(p))
+		}
+	})
+}
+
+func (m *Manager) runPhase(l logrus.FieldLogger, p Phase) {
+	m.mu.Lock()
+	ph := m.phase(p)
+	ph.started = true
+	waiters := ph.waiters
+	deadline := ph.deadline
+	m.mu.Unlock()
+
+	pl := l.WithField("phase", p.String())
+	pl.Infof("Starting teardown phase.")
+	started := time.Now()
+	ph.cancel()
+
+	var mu sync.Mutex
+	pending := make(map[string]int)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, w := range waiters {
+		mu.Lock()
+		pending[w.name]++
+		mu.Unlock()
+		wg.Add(1)
+		go func(w waiter) {
+			defer wg.Done()
+			w.wait()
+			mu.Lock()
+			pending[w.name]--
+			mu.Unlock()
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+	select {
+	case <-done:
+		pl.Debugf("Teardown phase finished in [%s].", time.Since(started))
+	case <-timer.C:
+		mu.Lock()
+		var blocked []string
+		for name, n := range pending {
+			if n > 0 {
+				blocked = append(blocked, name)
+			}
+		}
+		mu.Unlock()
+		sort.Strings(blocked)
+		pl.Warnf("Teardown phase did not finish within [%s]. Moving on, while still waiting for [%s].", deadline, strings.Join(blocked, ", "))
+	}
+}
+
+// TearingDown returns true once the service has been told to shut down
+func (m *Manager) TearingDown() bool {
+	return m.tearingDown.Load()
+}
@@ -0,0 +1,149 @@
+package service_test
+
+import (
+	"atlas-notes/service"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTeardownFunc(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	for i := 0; i < 100; i++ {
+		m := service.NewManager()
+		var ran atomic.Bool
+		m.TeardownFunc(service.PhaseTracer, "tracer", func() {
+			ran.Store(true)
+		})
+		m.Teardown(l)
+		if !ran.Load() {
+			t.Fatalf("Expected teardown function to have run before teardown returned.")
+		}
+	}
+}
+
+func TestTeardownFuncConcurrent(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	m := service.NewManager()
+
+	const n = 50
+	var ran atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.TeardownFunc(service.PhaseConsumers, "consumer", func() {
+				ran.Add(1)
+			})
+		}()
+	}
+	m.Teardown(l)
+	wg.Wait()
+	if c := ran.Load(); c != n {
+		t.Fatalf("Expected each of [%d] teardown functions to run once, ran [%d].", n, c)
+	}
+}
+
+func TestTeardownOrder(t *testing.T) {
+	l, _ := test.NewNullLogger()
+	m := service.NewManager()
+
+	var mu sync.Mutex
+	var order []service.Phase
+	phases := []service.Phase{service.PhaseProbes, service.PhaseTracer, service.PhaseDatabase, service.PhaseProducers, service.PhaseConsumers, service.PhaseRest}
+	for _, p := range phases {
+		p := p
+		m.TeardownFunc(p, p.String(), func() {
+			if p == service.PhaseRest && m.PhaseContext(service.PhaseConsumers).Err() != nil {
+				t.Errorf("Expected consumers to keep running while REST is stopped.")
+			}
+			if m.PhaseContext(p).Err() == nil {
+				t.Errorf("Expected context of phase [%s] to be cancelled when it begins.", p)
+			}
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+		})
+	}
+
+	// Goroutines tracked by a phase are waited for before the next phase begins
+	var drained atomic.Bool
+	wg := m.PhaseWaitGroup(service.PhaseConsumers, "kafka.consumers")
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-m.PhaseContext(service.PhaseConsumers).Done()
+		time.Sleep(10 * time.Millisecond)
+		drained.Store(true)
+	}()
+	m.TeardownFunc(service.PhaseProducers, "kafka.producers", func() {
+		if !drained.Load() {
+			t.Errorf("Expected consumers to be drained before producers are flushed.")
+		}
+	})
+
+	if m.Context().Err() != nil {
+		t.Fatalf("Expected context to be live before teardown.")
+	}
+	m.Teardown(l)
+	if m.Context().Err() == nil || !m.TearingDown() {
+		t.Fatalf("Expected teardown to be reported.")
+	}
+	if len(order) != len(phases) {
+		t.Fatalf("Expected [%d] phases to run, got [%d].", len(phases), len(order))
+	}
+	for i, p := range order {
+		if p != service.Phase(i) {
+			t.Fatalf("Expected phase [%s] to run in position [%d], got [%s].", service.Phase(i), i, p)
+		}
+	}
+}
+
+func TestTeardownDeadline(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	m := service.NewManager()
+	m.SetDeadline(service.PhaseConsumers, 20*time.Millisecond)
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+	m.TeardownFunc(service.PhaseConsumers, "kafka.consumers", func() {
+		<-stuck
+	})
+	m.TeardownFunc(service.PhaseConsumers, "tasks", func() {})
+	var closed atomic.Bool
+	m.TeardownFunc(service.PhaseDatabase, "database", func() {
+		closed.Store(true)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		m.Teardown(l)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected teardown to move on from a blocked phase.")
+	}
+	if !closed.Load() {
+		t.Fatalf("Expected phases after a blocked phase to run.")
+	}
+
+	var warned bool
+	for _, e := range hook.AllEntries() {
+		if e.Level == logrus.WarnLevel && e.Data["phase"] == "consumers" {
+			warned = true
+			if !strings.Contains(e.Message, "kafka.consumers") || strings.Contains(e.Message, "tasks") {
+				t.Fatalf("Expected only the blocked function to be reported, got [%s].", e.Message)
+			}
+		}
+	}
+	if !warned {
+		t.Fatalf("Expected the blocked phase to be reported.")
+	}
+}
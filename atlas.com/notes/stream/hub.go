@@ -0,0 +1,275 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBufferSize       = 1024
+	defaultSubscriberBuffer = 64
+
+	// EventTypeReset tells a client the Last-Event-ID it gave can no longer be resumed from, so the notes it holds must
+	// be loaded afresh
+	EventTypeReset = "RESET"
+)
+
+var ErrClosed = errors.New("stream hub is closed")
+
+// Event is a note status event as pushed to the characters subscribed to it
+type Event struct {
+	id          string
+	seq         uint64
+	tenantId    uuid.UUID
+	characterId uint32
+	eventType   string
+	data        json.RawMessage
+}
+
+// Id returns the ID a client resumes from with Last-Event-ID
+func (e Event) Id() string {
+	return e.id
+}
+
+// Type returns the type of the status event, such as CREATED
+func (e Event) Type() string {
+	return e.eventType
+}
+
+// Data returns the status event as published
+func (e Event) Data() json.RawMessage {
+	return e.data
+}
+
+// isFor returns true when the event is for the character of the tenant. Events naming no character, such as a purge of
+// the tenant, are for every character of it.
+func (e Event) isFor(tenantId uuid.UUID, characterId uint32) bool {
+	return e.tenantId == tenantId && (e.characterId == 0 || e.characterId == characterId)
+}
+
+type subscriberKey struct {
+	tenantId    uuid.UUID
+	characterId uint32
+}
+
+// Subscription receives the events for a character of a tenant as they are published
+type Subscription struct {
+	hub     *Hub
+	key     subscriberKey
+	events  chan Event
+	replay  []Event
+	resumed bool
+}
+
+// Events returns the events published since the subscription was made. The channel is closed once the subscription
+// ends, whether closed, dropped for falling behind or by the hub closing.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Replay returns the buffered events published after the one the subscriber resumed from, to be sent before any other.
+// When it could not be resumed, a RESET event is replayed in their place.
+func (s *Subscription) Replay() []Event {
+	return s.replay
+}
+
+// Resumed returns false when the subscriber could not be resumed from the event it named, as it is no longer buffered
+// or was published by another instance or before a restart
+func (s *Subscription) Resumed() bool {
+	return s.resumed
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+type Configuration struct {
+	bufferSize       int
+	subscriberBuffer int
+}
+
+type Configurator func(c *Configuration)
+
+// SetBufferSize sets how many of the latest events are kept for subscribers to resume from
+func SetBufferSize(size int) Configurator {
+	return func(c *Configuration) {
+		c.bufferSize = size
+	}
+}
+
+// SetSubscriberBuffer sets how many events a subscriber may fall behind by before it is dropped
+func SetSubscriberBuffer(size int) Configurator {
+	return func(c *Configuration) {
+		c.subscriberBuffer = size
+	}
+}
+
+// Hub fans note status events out to the subscribers of the characters they are for, keeping the latest in a buffer
+// for subscribers to resume from
+type Hub struct {
+	mu               sync.Mutex
+	epoch            string
+	seq              uint64
+	buffer           []Event
+	subscriberBuffer int
+	subscribers      map[subscriberKey]map[*Subscription]struct{}
+	closed           bool
+}
+
+// NewHub creates a hub. Event IDs carry an epoch unique to the hub, so IDs from another instance or before a restart
+// are not mistaken for its own.
+func NewHub(configurators ...Configurator) *Hub {
+	c := &Configuration{
+		bufferSize:       defaultBufferSize,
+		subscriberBuffer: defaultSubscriberBuffer,
+	}
+	for _, configurator := range configurators {
+		configurator(c)
+	}
+	return &Hub{
+		epoch:            strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:           make([]Event, max(c.bufferSize, 1)),
+		subscriberBuffer: max(c.subscriberBuffer, 1),
+		subscribers:      make(map[subscriberKey]map[*Subscription]struct{}),
+	}
+}
+
+// Publish buffers an event and pushes it to the subscribers it is for. A subscriber too far behind to take it is
+// dropped, to reconnect and resume from the buffer, rather than hold up the others.
+func (h *Hub) Publish(tenantId uuid.UUID, characterId uint32, eventType string, data json.RawMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	e := Event{
+		id:          h.eventId(h.seq),
+		seq:         h.seq,
+		tenantId:    tenantId,
+		characterId: characterId,
+		eventType:   eventType,
+		data:        data,
+	}
+	h.buffer[h.seq%uint64(len(h.buffer))] = e
+
+	if characterId != 0 {
+		h.push(h.subscribers[subscriberKey{tenantId: tenantId, characterId: characterId}], e)
+		return
+	}
+	for k, subs := range h.subscribers {
+		if k.tenantId == tenantId {
+			h.push(subs, e)
+		}
+	}
+}
+
+// push sends an event to each subscriber, dropping those too far behind to take it. The hub must be locked.
+func (h *Hub) push(subs map[*Subscription]struct{}, e Event) {
+	for s := range subs {
+		select {
+		case s.events <- e:
+		default:
+			h.remove(s)
+		}
+	}
+}
+
+// Subscribe subscribes to the events for a character of a tenant. Given the ID of the last event the subscriber saw,
+// the buffered events published since are replayed, unless it can no longer be resumed from.
+func (h *Hub) Subscribe(tenantId uuid.UUID, characterId uint32, lastEventId string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	k := subscriberKey{tenantId: tenantId, characterId: characterId}
+	s := &Subscription{hub: h, key: k, events: make(chan Event, h.subscriberBuffer), resumed: true}
+	if lastEventId != "" {
+		last, ok := h.resumable(lastEventId)
+		s.resumed = ok
+		if !ok {
+			// Positioned at the latest event, a client reconnecting after the reset misses nothing further
+			s.replay = append(s.replay, Event{id: h.eventId(h.seq), seq: h.seq, tenantId: tenantId, characterId: characterId, eventType: EventTypeReset, data: json.RawMessage("{}")})
+		}
+		for seq := last + 1; ok && seq <= h.seq; seq++ {
+			if e := h.buffer[seq%uint64(len(h.buffer))]; e.isFor(tenantId, characterId) {
+				s.replay = append(s.replay, e)
+			}
+		}
+	}
+
+	if _, ok := h.subscribers[k]; !ok {
+		h.subscribers[k] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[k][s] = struct{}{}
+	return s, nil
+}
+
+// eventId returns the ID of the event published in sequence
+func (h *Hub) eventId(seq uint64) string {
+	return fmt.Sprintf("%s-%d", h.epoch, seq)
+}
+
+// resumable returns the sequence of the event named, and whether every event published since is still buffered
+func (h *Hub) resumable(eventId string) (uint64, bool) {
+	epoch, val, ok := strings.Cut(eventId, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(val, 10, 64)
+	if err != nil || seq > h.seq {
+		return 0, false
+	}
+	return seq, h.seq-seq <= uint64(len(h.buffer))
+}
+
+// remove ends a subscription. The hub must be locked.
+func (h *Hub) remove(s *Subscription) {
+	subs, ok := h.subscribers[s.key]
+	if !ok {
+		return
+	}
+	if _, ok = subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subscribers, s.key)
+	}
+	close(s.events)
+}
+
+// Close ends every subscription, so the streams serving them finish
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for s := range subs {
+			h.remove(s)
+		}
+	}
+}
+
+var hub *Hub
+var once sync.Once
+
+// GetHub returns the hub note status events consumed by this instance are published to
+func GetHub() *Hub {
+	once.Do(func() {
+		hub = NewHub()
+	})
+	return hub
+}
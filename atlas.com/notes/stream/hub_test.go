@@ -0,0 +1,172 @@
+package stream_test
+
+import (
+	"atlas-notes/stream"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func next(t *testing.T, s *stream.Subscription) stream.Event {
+	t.Helper()
+	select {
+	case e, ok := <-s.Events():
+		if !ok {
+			t.Fatalf("Expected an event, but the subscription ended.")
+		}
+		return e
+	default:
+		t.Fatalf("Expected an event, got none.")
+	}
+	return stream.Event{}
+}
+
+func none(t *testing.T, s *stream.Subscription) {
+	t.Helper()
+	select {
+	case e, ok := <-s.Events():
+		if ok {
+			t.Fatalf("Expected no event, got [%s].", e.Type())
+		}
+	default:
+	}
+}
+
+func subscribe(t *testing.T, h *stream.Hub, tenantId uuid.UUID, characterId uint32, lastEventId string) *stream.Subscription {
+	t.Helper()
+	s, err := h.Subscribe(tenantId, characterId, lastEventId)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHub_FanOut(t *testing.T) {
+	h := stream.NewHub()
+	t1 := uuid.New()
+	t2 := uuid.New()
+
+	a := subscribe(t, h, t1, 1, "")
+	b := subscribe(t, h, t1, 1, "")
+	other := subscribe(t, h, t1, 2, "")
+	foreign := subscribe(t, h, t2, 1, "")
+
+	h.Publish(t1, 1, "CREATED", json.RawMessage(`{"characterId":1}`))
+	for _, s := range []*stream.Subscription{a, b} {
+		if e := next(t, s); e.Type() != "CREATED" || string(e.Data()) != `{"characterId":1}` {
+			t.Fatalf("Expected the CREATED event, got [%s] %s.", e.Type(), e.Data())
+		}
+	}
+	none(t, other)
+	none(t, foreign)
+
+	h.Publish(t1, 0, "PURGED", json.RawMessage(`{}`))
+	for _, s := range []*stream.Subscription{a, b, other} {
+		if e := next(t, s); e.Type() != "PURGED" {
+			t.Fatalf("Expected an event naming no character to reach every character of the tenant, got [%s].", e.Type())
+		}
+	}
+	none(t, foreign)
+}
+
+func TestHub_Resume(t *testing.T) {
+	h := stream.NewHub(stream.SetBufferSize(4))
+	tenantId := uuid.New()
+
+	s := subscribe(t, h, tenantId, 1, "")
+	h.Publish(tenantId, 1, "CREATED", json.RawMessage(`{}`))
+	last := next(t, s).Id()
+	s.Close()
+
+	h.Publish(tenantId, 1, "UPDATED", json.RawMessage(`{}`))
+	h.Publish(tenantId, 2, "CREATED", json.RawMessage(`{}`))
+	h.Publish(tenantId, 1, "DELETED", json.RawMessage(`{}`))
+
+	s = subscribe(t, h, tenantId, 1, last)
+	if !s.Resumed() {
+		t.Fatalf("Expected the subscriber to be resumed.")
+	}
+	replay := s.Replay()
+	if len(replay) != 2 || replay[0].Type() != "UPDATED" || replay[1].Type() != "DELETED" {
+		t.Fatalf("Expected the events missed by the character to be replayed, got %d.", len(replay))
+	}
+
+	h.Publish(tenantId, 1, "CREATED", json.RawMessage(`{}`))
+	if e := next(t, s); e.Type() != "CREATED" {
+		t.Fatalf("Expected events published after resuming to follow, got [%s].", e.Type())
+	}
+
+	fresh := subscribe(t, h, tenantId, 1, "")
+	if !fresh.Resumed() || len(fresh.Replay()) != 0 {
+		t.Fatalf("Expected a subscriber naming no event to be sent only what follows.")
+	}
+}
+
+func TestHub_Reset(t *testing.T) {
+	h := stream.NewHub(stream.SetBufferSize(2))
+	tenantId := uuid.New()
+
+	s := subscribe(t, h, tenantId, 1, "")
+	h.Publish(tenantId, 1, "CREATED", json.RawMessage(`{}`))
+	last := next(t, s).Id()
+	s.Close()
+	for range 3 {
+		h.Publish(tenantId, 1, "UPDATED", json.RawMessage(`{}`))
+	}
+
+	ids := map[string]string{
+		"evicted":       last,
+		"another epoch": "0-1",
+		"in the future": last + "9",
+		"malformed":     "not-an-id",
+	}
+	for name, id := range ids {
+		s = subscribe(t, h, tenantId, 1, id)
+		replay := s.Replay()
+		if s.Resumed() || len(replay) != 1 || replay[0].Type() != stream.EventTypeReset {
+			t.Fatalf("Expected a subscriber resuming from an event [%s] to be reset.", name)
+		}
+
+		reset := subscribe(t, h, tenantId, 1, replay[0].Id())
+		if !reset.Resumed() || len(reset.Replay()) != 0 {
+			t.Fatalf("Expected a subscriber reconnecting after a reset to be resumed.")
+		}
+	}
+}
+
+func TestHub_SlowSubscriber(t *testing.T) {
+	h := stream.NewHub(stream.SetSubscriberBuffer(2))
+	tenantId := uuid.New()
+
+	slow := subscribe(t, h, tenantId, 1, "")
+	fast := subscribe(t, h, tenantId, 1, "")
+	for i := range 3 {
+		h.Publish(tenantId, 1, "UPDATED", json.RawMessage(`{}`))
+		if i < 2 {
+			next(t, fast)
+		}
+	}
+	next(t, fast)
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	if received != 2 {
+		t.Fatalf("Expected a subscriber falling behind to be dropped after the events it took, got %d.", received)
+	}
+}
+
+func TestHub_Close(t *testing.T) {
+	h := stream.NewHub()
+	s := subscribe(t, h, uuid.New(), 1, "")
+	h.Close()
+	if _, ok := <-s.Events(); ok {
+		t.Fatalf("Expected subscriptions to end when the hub closes.")
+	}
+	if _, err := h.Subscribe(uuid.New(), 1, ""); !errors.Is(err, stream.ErrClosed) {
+		t.Fatalf("Expected subscribing to a closed hub to fail, got %v.", err)
+	}
+}
@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"atlas-notes/auth"
+	"atlas-notes/rest"
+	"fmt"
+	"github.com/Chronicle20/atlas-rest/server"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/gorilla/mux"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// keepaliveInterval is how often an idle stream is written to, so proxies do not time it out
+const keepaliveInterval = 15 * time.Second
+
+// InitResource registers the stream routes. They must be registered before the note routes, whose
+// /characters/{characterId}/notes routes would otherwise be searched first.
+func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteInitializer {
+	return func(db *gorm.DB) server.RouteInitializer {
+		return func(router *mux.Router, l logrus.FieldLogger) {
+			registerHandler := rest.RegisterHandler(l)(db)(si)
+
+			// Changes to the notes of a character, pushed as they happen. Player-facing, scoped to the character a
+			// player's token acts for.
+			router.HandleFunc("/characters/{characterId}/notes/stream", registerHandler("stream_character_notes", StreamCharacterNotesHandler)).Methods(http.MethodGet)
+		}
+	}
+}
+
+// StreamCharacterNotesHandler handles GET /api/characters/{characterId}/notes/stream
+func StreamCharacterNotesHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return StreamHandler(GetHub())(d, c)
+}
+
+// StreamHandler serves the changes to the notes of a character from a hub as Server-Sent Events, until the client
+// goes away or the hub closes. A client reconnecting with Last-Event-ID is sent the changes it missed, or a RESET
+// event when they are no longer buffered.
+func StreamHandler(h *Hub) rest.GetHandler {
+	return func(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+		return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				// Acting on behalf of a character, another's notes are not found
+				if a, ok := auth.ActingCharacterFromContext(d.Context()); ok && !a.Is(characterId) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				s, err := h.Subscribe(tenant.MustFromContext(d.Context()).Id(), characterId, r.Header.Get("Last-Event-ID"))
+				if err != nil {
+					d.Logger().WithError(err).Errorf("Unable to subscribe to notes of character [%d].", characterId)
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				defer s.Close()
+
+				rc := http.NewResponseController(w)
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				w.Header().Set("Connection", "keep-alive")
+				w.Header().Set("X-Accel-Buffering", "no")
+				w.WriteHeader(http.StatusOK)
+
+				for _, e := range s.Replay() {
+					if err = write(w, e); err != nil {
+						break
+					}
+				}
+				if err == nil {
+					err = rc.Flush()
+				}
+
+				keepalive := time.NewTicker(keepaliveInterval)
+				defer keepalive.Stop()
+				for err == nil {
+					select {
+					case <-r.Context().Done():
+						return
+					case e, ok := <-s.Events():
+						if !ok {
+							return
+						}
+						if err = write(w, e); err == nil {
+							err = rc.Flush()
+						}
+					case <-keepalive.C:
+						if _, err = fmt.Fprint(w, ": keepalive\n\n"); err == nil {
+							err = rc.Flush()
+						}
+					}
+				}
+				d.Logger().WithError(err).Debugf("Stream of notes of character [%d] ended.", characterId)
+			}
+		})
+	}
+}
+
+// write writes an event as a Server-Sent Event frame
+func write(w http.ResponseWriter, e Event) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.Id(), e.Type(), e.Data())
+	return err
+}
@@ -0,0 +1,36 @@
+package summary
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// applyCounts adds changes to the counters of a tenant, creating those not yet kept. Changes to the same counter are
+// added up first, as a counter may be changed only once by a statement.
+func applyCounts(db *gorm.DB) func(tenantId uuid.UUID) func(cs []Count) error {
+	return func(tenantId uuid.UUID) func(cs []Count) error {
+		return func(cs []Count) error {
+			cs = Merge(cs)
+			if len(cs) == 0 {
+				return nil
+			}
+			now := time.Now()
+			entities := make([]Entity, 0, len(cs))
+			for _, c := range cs {
+				e := MakeEntity(tenantId, c)
+				e.UpdatedAt = now
+				entities = append(entities, e)
+			}
+			return db.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "tenant_id"}, {Name: "character_id"}, {Name: "flag"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"total":      gorm.Expr("note_summaries.total + excluded.total"),
+					"unread":     gorm.Expr("note_summaries.unread + excluded.unread"),
+					"updated_at": gorm.Expr("excluded.updated_at"),
+				}),
+			}).Create(&entities).Error
+		}
+	}
+}
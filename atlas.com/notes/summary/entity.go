@@ -0,0 +1,37 @@
+package summary
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Entity represents the counters of the notes of a kind held by a character in the database
+type Entity struct {
+	TenantID    uuid.UUID `gorm:"primaryKey"`
+	CharacterID uint32    `gorm:"primaryKey;autoIncrement:false"`
+	Flag        byte      `gorm:"primaryKey;autoIncrement:false"`
+	Total       int64
+	Unread      int64
+	UpdatedAt   time.Time
+}
+
+// TableName specifies the database table name for Entity
+func (Entity) TableName() string {
+	return "note_summaries"
+}
+
+// Make converts an Entity to a Count
+func Make(e Entity) (Count, error) {
+	return NewCount(e.CharacterID, e.Flag, e.Total, e.Unread), nil
+}
+
+// MakeEntity converts a Count to an Entity of a tenant
+func MakeEntity(tenantId uuid.UUID, c Count) Entity {
+	return Entity{
+		TenantID:    tenantId,
+		CharacterID: c.CharacterId(),
+		Flag:        c.Flag(),
+		Total:       c.Total(),
+		Unread:      c.Unread(),
+	}
+}
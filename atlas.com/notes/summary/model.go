@@ -0,0 +1,116 @@
+package summary
+
+import "sort"
+
+// Count is how many notes of a kind a character holds, and how many of them are unread. As a change to the counters,
+// its numbers are added to theirs, and may be negative.
+type Count struct {
+	characterId uint32
+	flag        byte
+	total       int64
+	unread      int64
+}
+
+func NewCount(characterId uint32, flag byte, total int64, unread int64) Count {
+	return Count{characterId: characterId, flag: flag, total: total, unread: unread}
+}
+
+// CharacterId returns the ID of the character holding the notes
+func (c Count) CharacterId() uint32 {
+	return c.characterId
+}
+
+// Flag returns the flag of the notes, which is their kind
+func (c Count) Flag() byte {
+	return c.flag
+}
+
+// Total returns how many notes of the kind the character holds
+func (c Count) Total() int64 {
+	return c.total
+}
+
+// Unread returns how many of the notes are unread
+func (c Count) Unread() int64 {
+	return c.unread
+}
+
+// Negate returns the change taking away what the count holds
+func (c Count) Negate() Count {
+	return NewCount(c.characterId, c.flag, -c.total, -c.unread)
+}
+
+// Zero returns true when the count holds, or changes, nothing
+func (c Count) Zero() bool {
+	return c.total == 0 && c.unread == 0
+}
+
+// Merge adds up counts of the same character and kind, dropping those which come to nothing, in character then flag
+// order
+func Merge(cs []Count) []Count {
+	type key struct {
+		characterId uint32
+		flag        byte
+	}
+	sums := make(map[key]Count)
+	for _, c := range cs {
+		k := key{characterId: c.characterId, flag: c.flag}
+		s := sums[k]
+		sums[k] = NewCount(c.characterId, c.flag, s.total+c.total, s.unread+c.unread)
+	}
+	results := make([]Count, 0, len(sums))
+	for _, s := range sums {
+		if !s.Zero() {
+			results = append(results, s)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].characterId != results[j].characterId {
+			return results[i].characterId < results[j].characterId
+		}
+		return results[i].flag < results[j].flag
+	})
+	return results
+}
+
+// Model summarizes the notes a character holds, for a badge, without loading them
+type Model struct {
+	characterId uint32
+	total       int64
+	unread      int64
+	kinds       []Count
+}
+
+// NewModel summarizes the counts of a character's notes by kind
+func NewModel(characterId uint32, cs []Count) Model {
+	m := Model{characterId: characterId}
+	for _, c := range Merge(cs) {
+		if c.characterId != characterId {
+			continue
+		}
+		m.total += c.total
+		m.unread += c.unread
+		m.kinds = append(m.kinds, c)
+	}
+	return m
+}
+
+// CharacterId returns the ID of the character summarized
+func (m Model) CharacterId() uint32 {
+	return m.characterId
+}
+
+// Total returns how many notes the character holds
+func (m Model) Total() int64 {
+	return m.total
+}
+
+// Unread returns how many of the notes are unread
+func (m Model) Unread() int64 {
+	return m.unread
+}
+
+// Kinds returns the counts of the notes by flag, for the kinds the character holds
+func (m Model) Kinds() []Count {
+	return m.kinds
+}
@@ -0,0 +1,36 @@
+package summary
+
+import (
+	"atlas-notes/database"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// getByCharacterIdProvider returns a provider for the counters of a character, by flag
+func getByCharacterIdProvider(tenantId uuid.UUID) func(characterId uint32) database.EntityProvider[[]Entity] {
+	return func(characterId uint32) database.EntityProvider[[]Entity] {
+		return func(db *gorm.DB) model.Provider[[]Entity] {
+			var entities []Entity
+			err := db.Where("tenant_id = ? AND character_id = ?", tenantId, characterId).Order("flag").Find(&entities).Error
+			if err != nil {
+				return model.ErrorProvider[[]Entity](err)
+			}
+			return model.FixedProvider(entities)
+		}
+	}
+}
+
+// getByTenantProvider returns a provider for the counters of every character of a tenant, locked until the end of the
+// transaction they are read in
+func getByTenantProvider(tenantId uuid.UUID) database.EntityProvider[[]Entity] {
+	return func(db *gorm.DB) model.Provider[[]Entity] {
+		var entities []Entity
+		err := db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("tenant_id = ?", tenantId).Order("character_id, flag").Find(&entities).Error
+		if err != nil {
+			return model.ErrorProvider[[]Entity](err)
+		}
+		return model.FixedProvider(entities)
+	}
+}
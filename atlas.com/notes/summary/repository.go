@@ -0,0 +1,44 @@
+package summary
+
+import (
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Repository stores the counters summarizing the notes each character holds
+type Repository interface {
+	// Apply adds changes to the counters, in the transaction of the changes to notes they follow when the repository
+	// was handed to one
+	Apply(tenantId uuid.UUID, cs ...Count) error
+	// ByCharacterId returns the summary of the notes a character holds. A character holding none has an empty summary.
+	ByCharacterId(tenantId uuid.UUID, characterId uint32) (Model, error)
+	// ByTenant returns the counters of every character of a tenant. Within a transaction, they are locked until it ends,
+	// so changes to notes wait to move them while they are reconciled.
+	ByTenant(tenantId uuid.UUID) ([]Count, error)
+}
+
+// GormRepository is a Repository backed by a SQL database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+func (r *GormRepository) Apply(tenantId uuid.UUID, cs ...Count) error {
+	return applyCounts(r.db)(tenantId)(cs)
+}
+
+func (r *GormRepository) ByCharacterId(tenantId uuid.UUID, characterId uint32) (Model, error) {
+	cs, err := model.SliceMap[Entity, Count](Make)(getByCharacterIdProvider(tenantId)(characterId)(r.db))()()
+	if err != nil {
+		return Model{}, err
+	}
+	return NewModel(characterId, cs), nil
+}
+
+func (r *GormRepository) ByTenant(tenantId uuid.UUID) ([]Count, error) {
+	return model.SliceMap[Entity, Count](Make)(getByTenantProvider(tenantId)(r.db))()()
+}
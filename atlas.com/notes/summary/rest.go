@@ -0,0 +1,54 @@
+package summary
+
+import (
+	"strconv"
+)
+
+// RestModel is the JSON:API resource summarizing the notes a character holds
+type RestModel struct {
+	Id     uint32          `json:"-"`
+	Total  int64           `json:"total"`
+	Unread int64           `json:"unread"`
+	Kinds  []KindRestModel `json:"kinds"`
+}
+
+// KindRestModel counts the notes of a kind, being the flag they carry
+type KindRestModel struct {
+	Flag   byte  `json:"flag"`
+	Total  int64 `json:"total"`
+	Unread int64 `json:"unread"`
+}
+
+// GetID returns the resource ID, being that of the character summarized
+func (r RestModel) GetID() string {
+	return strconv.Itoa(int(r.Id))
+}
+
+// SetID sets the resource ID
+func (r *RestModel) SetID(strId string) error {
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	r.Id = uint32(id)
+	return nil
+}
+
+// GetName returns the resource name
+func (r RestModel) GetName() string {
+	return "summaries"
+}
+
+// Transform converts a Model domain model to a RestModel
+func Transform(m Model) (RestModel, error) {
+	kinds := make([]KindRestModel, 0, len(m.Kinds()))
+	for _, c := range m.Kinds() {
+		kinds = append(kinds, KindRestModel{Flag: c.Flag(), Total: c.Total(), Unread: c.Unread()})
+	}
+	return RestModel{
+		Id:     m.CharacterId(),
+		Total:  m.Total(),
+		Unread: m.Unread(),
+		Kinds:  kinds,
+	}, nil
+}
@@ -0,0 +1,36 @@
+package tasks
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+type Task interface {
+	Run()
+
+	SleepTime() time.Duration
+}
+
+// Register runs the task on its interval until the context is cancelled. A run in progress when it is cancelled is
+// finished before the wait group is released.
+func Register(l logrus.FieldLogger, ctx context.Context, wg *sync.WaitGroup) func(t Task) {
+	return func(t Task) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(t.SleepTime())
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					l.Debugf("Stopping task execution.")
+					return
+				case <-ticker.C:
+					t.Run()
+				}
+			}
+		}()
+	}
+}
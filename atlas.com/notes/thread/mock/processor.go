@@ -0,0 +1,17 @@
+package mock
+
+import (
+	"atlas-notes/thread"
+	"github.com/Chronicle20/atlas-model/model"
+)
+
+type ProcessorMock struct {
+	ByCharacterProviderFunc func(characterId uint32) model.Provider[[]thread.Model]
+}
+
+func (m *ProcessorMock) ByCharacterProvider(characterId uint32) model.Provider[[]thread.Model] {
+	if m.ByCharacterProviderFunc != nil {
+		return m.ByCharacterProviderFunc(characterId)
+	}
+	return model.FixedProvider([]thread.Model{})
+}
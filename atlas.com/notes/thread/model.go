@@ -0,0 +1,80 @@
+package thread
+
+import (
+	"atlas-notes/note"
+)
+
+// Model represents a conversation of notes between a character and another participant
+type Model struct {
+	id            uint32
+	participantId uint32
+	noteCount     uint32
+	last          note.Model
+}
+
+// Id returns the thread's ID
+func (m Model) Id() uint32 {
+	return m.id
+}
+
+// ParticipantId returns the ID of the other character in the conversation
+func (m Model) ParticipantId() uint32 {
+	return m.participantId
+}
+
+// NoteCount returns the number of notes in the conversation
+func (m Model) NoteCount() uint32 {
+	return m.noteCount
+}
+
+// LastNote returns the most recent note in the conversation
+func (m Model) LastNote() note.Model {
+	return m.last
+}
+
+// Builder is a builder for creating Model instances
+type Builder struct {
+	id            uint32
+	participantId uint32
+	noteCount     uint32
+	last          note.Model
+}
+
+// NewBuilder creates a new Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// SetId sets the thread's ID
+func (b *Builder) SetId(id uint32) *Builder {
+	b.id = id
+	return b
+}
+
+// SetParticipantId sets the ID of the other character in the conversation
+func (b *Builder) SetParticipantId(participantId uint32) *Builder {
+	b.participantId = participantId
+	return b
+}
+
+// SetNoteCount sets the number of notes in the conversation
+func (b *Builder) SetNoteCount(noteCount uint32) *Builder {
+	b.noteCount = noteCount
+	return b
+}
+
+// SetLastNote sets the most recent note in the conversation
+func (b *Builder) SetLastNote(last note.Model) *Builder {
+	b.last = last
+	return b
+}
+
+// Build creates a new Model with the builder's values
+func (b *Builder) Build() Model {
+	return Model{
+		id:            b.id,
+		participantId: b.participantId,
+		noteCount:     b.noteCount,
+		last:          b.last,
+	}
+}
@@ -0,0 +1,65 @@
+package thread
+
+import (
+	"atlas-notes/note"
+	"context"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"sort"
+)
+
+type Processor interface {
+	ByCharacterProvider(characterId uint32) model.Provider[[]Model]
+}
+
+type ProcessorImpl struct {
+	l   logrus.FieldLogger
+	ctx context.Context
+	np  note.Processor
+}
+
+func NewProcessor(l logrus.FieldLogger, ctx context.Context, db *gorm.DB) Processor {
+	return &ProcessorImpl{
+		l:   l,
+		ctx: ctx,
+		np:  note.NewProcessor(l, ctx, db),
+	}
+}
+
+// ByCharacterProvider retrieves all conversations a character takes part in, most recently active first
+func (p *ProcessorImpl) ByCharacterProvider(characterId uint32) model.Provider[[]Model] {
+	return model.Map(groupByThread(characterId))(p.np.ByParticipantProvider(characterId))
+}
+
+// groupByThread summarizes notes into the conversations they belong to, from the point of view of the character
+func groupByThread(characterId uint32) model.Transformer[[]note.Model, []Model] {
+	return func(ns []note.Model) ([]Model, error) {
+		counts := make(map[uint32]uint32)
+		lasts := make(map[uint32]note.Model)
+		for _, n := range ns {
+			counts[n.ThreadId()]++
+			if l, ok := lasts[n.ThreadId()]; !ok || n.Timestamp().After(l.Timestamp()) {
+				lasts[n.ThreadId()] = n
+			}
+		}
+
+		results := make([]Model, 0, len(lasts))
+		for id, last := range lasts {
+			participantId := last.CharacterId()
+			if participantId == characterId {
+				participantId = last.SenderId()
+			}
+			results = append(results, NewBuilder().
+				SetId(id).
+				SetParticipantId(participantId).
+				SetNoteCount(counts[id]).
+				SetLastNote(last).
+				Build())
+		}
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].LastNote().Timestamp().After(results[j].LastNote().Timestamp())
+		})
+		return results, nil
+	}
+}
@@ -0,0 +1,51 @@
+package thread
+
+import (
+	"atlas-notes/rest"
+	"errors"
+	"github.com/Chronicle20/atlas-model/model"
+	"github.com/Chronicle20/atlas-rest/server"
+	"github.com/gorilla/mux"
+	"github.com/jtumidanski/api2go/jsonapi"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+func InitResource(si jsonapi.ServerInformation) func(db *gorm.DB) server.RouteInitializer {
+	return func(db *gorm.DB) server.RouteInitializer {
+		return func(router *mux.Router, l logrus.FieldLogger) {
+			registerHandler := rest.RegisterHandler(l)(db)(si)
+
+			// ByCharacterProvider all conversations for a character. Player-facing, scoped to the character a player's
+			// token acts for.
+			router.HandleFunc(
+				"/characters/{"+characterIdPattern+"}/threads",
+				registerHandler("get_character_threads", GetCharacterThreadsHandler),
+			).Methods(http.MethodGet)
+		}
+	}
+}
+
+// GetCharacterThreadsHandler handles GET /api/characters/{characterId}/threads
+func GetCharacterThreadsHandler(d *rest.HandlerDependency, c *rest.HandlerContext) http.HandlerFunc {
+	return rest.ParseCharacterId(d.Logger(), func(characterId uint32) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mp := NewProcessor(d.Logger(), d.Context(), d.DB()).ByCharacterProvider(characterId)
+			rm, err := model.SliceMap(Transform)(mp)(model.ParallelMap())()
+			if err != nil {
+				d.Logger().WithError(err).Errorf("Creating REST model.")
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			query := r.URL.Query()
+			queryParams := jsonapi.ParseQueryFields(&query)
+			server.MarshalResponse[[]RestModel](d.Logger())(w)(c.ServerInformation())(queryParams)(rm)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package thread
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	characterIdPattern = "characterId"
+)
+
+// RestModel is the JSON:API resource for note threads
+type RestModel struct {
+	Id            uint32    `json:"-"`
+	ParticipantId uint32    `json:"participantId"`
+	NoteCount     uint32    `json:"noteCount"`
+	LastNoteId    uint32    `json:"lastNoteId"`
+	LastSenderId  uint32    `json:"lastSenderId"`
+	LastMessage   string    `json:"lastMessage"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+}
+
+// GetID returns the resource ID
+func (r RestModel) GetID() string {
+	return strconv.Itoa(int(r.Id))
+}
+
+// SetID sets the resource ID
+func (r *RestModel) SetID(strId string) error {
+	id, err := strconv.Atoi(strId)
+	if err != nil {
+		return err
+	}
+	r.Id = uint32(id)
+	return nil
+}
+
+// GetName returns the resource name
+func (r RestModel) GetName() string {
+	return "threads"
+}
+
+// Transform converts a Model domain model to a RestModel
+func Transform(m Model) (RestModel, error) {
+	return RestModel{
+		Id:            m.Id(),
+		ParticipantId: m.ParticipantId(),
+		NoteCount:     m.NoteCount(),
+		LastNoteId:    m.LastNote().Id(),
+		LastSenderId:  m.LastNote().SenderId(),
+		LastMessage:   m.LastNote().Message(),
+		LastTimestamp: m.LastNote().Timestamp(),
+	}, nil
+}
@@ -0,0 +1,31 @@
+package tracing
+
+import (
+	"context"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// AttributeTenantId is the span attribute naming the tenant a span was started on behalf of
+const AttributeTenantId = attribute.Key("tenant.id")
+
+// tenantSpanProcessor stamps each span with the tenant in its context. The service serves many tenants, so the tenant
+// belongs to the span rather than to the resource.
+type tenantSpanProcessor struct{}
+
+func (tenantSpanProcessor) OnStart(ctx context.Context, s sdktrace.ReadWriteSpan) {
+	if t, err := tenant.FromContext(ctx)(); err == nil {
+		s.SetAttributes(AttributeTenantId.String(t.Id().String()))
+	}
+}
+
+func (tenantSpanProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+func (tenantSpanProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (tenantSpanProcessor) ForceFlush(context.Context) error {
+	return nil
+}
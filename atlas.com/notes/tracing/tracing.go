@@ -1,31 +1,172 @@
 package tracing
 
 import (
-	"fmt"
+	"context"
 	"github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
-	"github.com/uber/jaeger-client-go/config"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"os"
+	"strconv"
+	"time"
 )
 
-func InitTracer(l logrus.FieldLogger) func(serviceName string) (io.Closer, error) {
-	return func(serviceName string) (io.Closer, error) {
-		jaegerHostPort := os.Getenv("JAEGER_HOST_PORT")
-		cfg := &config.Configuration{
-			ServiceName: serviceName,
-			Sampler:     &config.SamplerConfig{Type: "const", Param: 1},
-			Reporter:    &config.ReporterConfig{LogSpans: true, LocalAgentHostPort: jaegerHostPort},
+const (
+	EnvExporterEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvExporterTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	EnvExporterProtocol       = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvSampler                = "OTEL_TRACES_SAMPLER"
+	EnvSamplerArg             = "OTEL_TRACES_SAMPLER_ARG"
+
+	ProtocolGRPC = "grpc"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+type Configuration struct {
+	exporter sdktrace.SpanExporter
+	sampler  sdktrace.Sampler
+	syncer   bool
+}
+
+type Configurator func(c *Configuration)
+
+// SetExporter sets where finished spans are exported to, in place of the OTLP exporter configured from the environment
+func SetExporter(exporter sdktrace.SpanExporter) Configurator {
+	return func(c *Configuration) {
+		c.exporter = exporter
+	}
+}
+
+// SetSyncExport sets whether spans are exported as soon as they end rather than in batches, as tests want
+func SetSyncExport(syncer bool) Configurator {
+	return func(c *Configuration) {
+		c.syncer = syncer
+	}
+}
+
+// SetSampler sets which traces are sampled, in place of the sampler configured from the environment
+func SetSampler(sampler sdktrace.Sampler) Configurator {
+	return func(c *Configuration) {
+		c.sampler = sampler
+	}
+}
+
+// Propagator returns how trace context is carried in Kafka and HTTP headers: W3C trace context and baggage, along
+// with the uber-trace-id header of the Jaeger client, so services which have not migrated still join traces
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}, jaeger.Jaeger{})
+}
+
+// exporterFromEnv creates an OTLP exporter for the endpoint in the environment, over gRPC or else HTTP. Without an
+// endpoint, spans are not exported, though trace context is still propagated.
+func exporterFromEnv(ctx context.Context) (sdktrace.SpanExporter, error) {
+	_, ok := os.LookupEnv(EnvExporterEndpoint)
+	if _, tok := os.LookupEnv(EnvExporterTracesEndpoint); !ok && !tok {
+		return nil, nil
+	}
+	if os.Getenv(EnvExporterProtocol) == ProtocolGRPC {
+		return otlptracegrpc.New(ctx)
+	}
+	return otlptracehttp.New(ctx)
+}
+
+// samplerFromEnv samples the ratio of traces in OTEL_TRACES_SAMPLER_ARG, all by default, following the decision of
+// the parent span where there is one. When OTEL_TRACES_SAMPLER is set, the sampler it names is left to the SDK.
+func samplerFromEnv() sdktrace.Sampler {
+	if _, ok := os.LookupEnv(EnvSampler); ok {
+		return nil
+	}
+	ratio := 1.0
+	if val, ok := os.LookupEnv(EnvSamplerArg); ok {
+		if r, err := strconv.ParseFloat(val, 64); err == nil && r >= 0 && r <= 1 {
+			ratio = r
 		}
-		tracer, closer, err := cfg.NewTracer(config.Logger(LogrusAdapter{logger: l}))
+	}
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
+
+// InitTracer installs an OpenTelemetry tracer provider for the service. It is bridged to opentracing, so spans
+// started through opentracing, as the Kafka and REST libraries do, become OpenTelemetry spans.
+func InitTracer(l logrus.FieldLogger) func(serviceName string, configurators ...Configurator) (io.Closer, error) {
+	return func(serviceName string, configurators ...Configurator) (io.Closer, error) {
+		c := &Configuration{sampler: samplerFromEnv()}
+		for _, configurator := range configurators {
+			configurator(c)
+		}
+
+		ctx := context.Background()
+		if c.exporter == nil {
+			exporter, err := exporterFromEnv(ctx)
+			if err != nil {
+				return nil, err
+			}
+			c.exporter = exporter
+		}
+
+		res, err := resource.New(ctx,
+			resource.WithFromEnv(),
+			resource.WithAttributes(semconv.ServiceName(serviceName)),
+		)
 		if err != nil {
 			return nil, err
 		}
-		opentracing.SetGlobalTracer(tracer)
-		return closer, nil
+
+		opts := []sdktrace.TracerProviderOption{
+			sdktrace.WithResource(res),
+			sdktrace.WithSpanProcessor(tenantSpanProcessor{}),
+		}
+		if c.sampler != nil {
+			opts = append(opts, sdktrace.WithSampler(c.sampler))
+		}
+		if c.exporter != nil {
+			if c.syncer {
+				opts = append(opts, sdktrace.WithSyncer(c.exporter))
+			} else {
+				opts = append(opts, sdktrace.WithBatcher(c.exporter))
+			}
+		} else {
+			l.Warnf("No OTLP endpoint is configured. Spans are not exported.")
+		}
+		tp := sdktrace.NewTracerProvider(opts...)
+
+		prop := Propagator()
+		bt, wtp := otbridge.NewTracerPair(tp.Tracer(serviceName))
+		bt.SetTextMapPropagator(prop)
+		bt.SetWarningHandler(func(msg string) {
+			l.Debugf("Tracing bridge: %s", msg)
+		})
+		opentracing.SetGlobalTracer(bt)
+		otel.SetTracerProvider(wtp)
+		otel.SetTextMapPropagator(prop)
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+			l.WithError(err).Warnf("Tracing error.")
+		}))
+		return closer{tp: tp}, nil
 	}
 }
 
+// closer flushes and stops a tracer provider
+type closer struct {
+	tp *sdktrace.TracerProvider
+}
+
+func (c closer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return c.tp.Shutdown(ctx)
+}
+
 func Teardown(l logrus.FieldLogger) func(tc io.Closer) func() {
 	return func(tc io.Closer) func() {
 		return func() {
@@ -37,20 +178,28 @@ func Teardown(l logrus.FieldLogger) func(tc io.Closer) func() {
 	}
 }
 
-type LogrusAdapter struct {
-	logger logrus.FieldLogger
+// Tracer returns the tracer the service starts its own spans with
+func Tracer() trace.Tracer {
+	return otel.Tracer("atlas-notes")
 }
 
-func (l LogrusAdapter) Error(msg string) {
-	l.logger.Error(msg)
+// StartSpan starts a span as a child of any in the context, returning the context carrying it and a logger annotated
+// with its IDs
+func StartSpan(l logrus.FieldLogger, ctx context.Context, name string, opts ...trace.SpanStartOption) (logrus.FieldLogger, context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, name, opts...)
+	sc := span.SpanContext()
+	sl := l.WithFields(logrus.Fields{
+		"trace.id": sc.TraceID().String(),
+		"span.id":  sc.SpanID().String(),
+	})
+	return sl, ctx, span
 }
 
-func (l LogrusAdapter) Infof(msg string, args ...interface{}) {
-	l.logger.Infof(msg, args)
-}
-
-func StartSpan(l logrus.FieldLogger, name string, opts ...opentracing.StartSpanOption) (logrus.FieldLogger, opentracing.Span) {
-	span := opentracing.StartSpan(name, opts...)
-	sl := l.WithField("span.id", fmt.Sprintf("%v", span))
-	return sl, span
+// EndSpan ends a span, marking it failed if the operation it covers returned an error
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
@@ -0,0 +1,121 @@
+package tracing_test
+
+import (
+	"atlas-notes/tracing"
+	"context"
+	tenant "github.com/Chronicle20/atlas-tenant"
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"testing"
+)
+
+const (
+	jaegerHeader = "uber-trace-id"
+	jaegerTrace  = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+func setup(t *testing.T, configurators ...tracing.Configurator) *tracetest.InMemoryExporter {
+	t.Helper()
+	l, _ := test.NewNullLogger()
+	exporter := tracetest.NewInMemoryExporter()
+	configurators = append([]tracing.Configurator{tracing.SetExporter(exporter), tracing.SetSyncExport(true)}, configurators...)
+	tc, err := tracing.InitTracer(l)("atlas-notes", configurators...)
+	if err != nil {
+		t.Fatalf("Failed to initialize tracer: %v", err)
+	}
+	t.Cleanup(tracing.Teardown(l)(tc))
+	return exporter
+}
+
+func TestExtractJaegerHeader(t *testing.T) {
+	exporter := setup(t)
+
+	h := http.Header{}
+	h.Set(jaegerHeader, jaegerTrace+":00f067aa0ba902b7:0:1")
+	sc, err := opentracing.GlobalTracer().Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	if err != nil {
+		t.Fatalf("Failed to extract span context: %v", err)
+	}
+	span := opentracing.GlobalTracer().StartSpan("handle", opentracing.ChildOf(sc))
+	span.Finish()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span exported, got %d", len(spans))
+	}
+	if got := spans[0].SpanContext.TraceID().String(); got != jaegerTrace {
+		t.Fatalf("Expected span to join trace [%s], got [%s]", jaegerTrace, got)
+	}
+}
+
+func TestInjectHeaders(t *testing.T) {
+	setup(t)
+
+	span := opentracing.GlobalTracer().StartSpan("produce")
+	defer span.Finish()
+	h := http.Header{}
+	err := opentracing.GlobalTracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(h))
+	if err != nil {
+		t.Fatalf("Failed to inject span context: %v", err)
+	}
+	if h.Get("traceparent") == "" {
+		t.Fatalf("Expected W3C traceparent header to be written.")
+	}
+	if h.Get(jaegerHeader) == "" {
+		t.Fatalf("Expected Jaeger header to be written for services yet to migrate.")
+	}
+}
+
+func TestStartSpanTenant(t *testing.T) {
+	exporter := setup(t)
+
+	te, err := tenant.Create(uuid.New(), "GMS", 83, 1)
+	if err != nil {
+		t.Fatalf("Failed to create tenant: %v", err)
+	}
+	l, _ := test.NewNullLogger()
+	sl, _, span := tracing.StartSpan(l, tenant.WithContext(context.Background(), te), "task")
+	span.End()
+
+	if e, ok := sl.(*logrus.Entry); !ok || e.Data["trace.id"] != span.SpanContext().TraceID().String() {
+		t.Fatalf("Expected logger to carry the trace id.")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span exported, got %d", len(spans))
+	}
+	found := false
+	for _, a := range spans[0].Attributes {
+		if a.Key == tracing.AttributeTenantId && a.Value.AsString() == te.Id().String() {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Expected span to be stamped with tenant [%s].", te.Id().String())
+	}
+}
+
+func TestParentBasedSampling(t *testing.T) {
+	exporter := setup(t, tracing.SetSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0))))
+	l, _ := test.NewNullLogger()
+
+	_, _, root := tracing.StartSpan(l, context.Background(), "root")
+	root.End()
+	if c := len(exporter.GetSpans()); c != 0 {
+		t.Fatalf("Expected root span not to be sampled, got %d spans", c)
+	}
+
+	carrier := propagation.MapCarrier{"traceparent": "00-" + jaegerTrace + "-00f067aa0ba902b7-01"}
+	ctx := tracing.Propagator().Extract(context.Background(), carrier)
+	_, _, child := tracing.StartSpan(l, ctx, "child")
+	child.End()
+	if c := len(exporter.GetSpans()); c != 1 {
+		t.Fatalf("Expected child of a sampled parent to be sampled, got %d spans", c)
+	}
+}